@@ -0,0 +1,105 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// errNoActiveUsers is returned when active users are requested but
+// their tracking is not enabled.
+var errNoActiveUsers = errors.New("redisstore: active user tracking is not enabled")
+
+// WithActiveUsers enables tracking of active users, so that their
+// number can be retrieved with ActiveUsers. Each time a session is
+// created or retrieved by FetchByID, its user key is added to
+// a HyperLogLog of the current interval of the provided resolution,
+// stored under "<prefix>:active:<interval start>" and kept for the
+// provided retention period. Counts are approximate (with a standard
+// error of 0.81%), while each HyperLogLog takes up to 12kB of memory.
+// Defaults to 0 (disabled).
+func WithActiveUsers(resolution, retention time.Duration) setter {
+	return func(r *RedisStore) {
+		if retention < resolution {
+			retention = resolution
+		}
+
+		r.activeResolution = resolution
+		r.activeRetention = retention
+	}
+}
+
+// ActiveUsers returns the approximate number of distinct users whose
+// sessions were created or retrieved by FetchByID within the provided
+// window before now. The window is rounded up to whole intervals of
+// the configured resolution and is limited by the retention period
+// (see WithActiveUsers).
+func (r *RedisStore) ActiveUsers(ctx context.Context, window time.Duration) (n int64, err error) {
+	if r.activeResolution <= 0 {
+		return 0, errNoActiveUsers
+	}
+
+	c, end, err := r.begin(ctx, "ActiveUsers")
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() { err = end(err) }()
+
+	if window > r.activeRetention {
+		window = r.activeRetention
+	}
+
+	now := r.now()
+	start := now.Add(-window).Truncate(r.activeResolution)
+
+	var keys []interface{}
+
+	for t := start; !t.After(now); t = t.Add(r.activeResolution) {
+		keys = append(keys, r.activeKey(c, t))
+	}
+
+	return redis.Int64(c.Do("PFCOUNT", keys...))
+}
+
+// markActive adds the user key to the HyperLogLog of the current
+// interval, if active user tracking is enabled.
+func (r *RedisStore) markActive(c redis.Conn, userKey string) error {
+	if r.activeResolution <= 0 {
+		return nil
+	}
+
+	now := r.now()
+	key := r.activeKey(c, now)
+	exp := now.Truncate(r.activeResolution).Add(r.activeResolution + r.activeRetention)
+
+	if err := c.Send("PFADD", key, userKey); err != nil {
+		return err
+	}
+
+	if err := c.Send("PEXPIREAT", key, exp.UnixNano()/int64(time.Millisecond)); err != nil {
+		return err
+	}
+
+	if err := c.Flush(); err != nil {
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Receive(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// activeKey returns the key of the HyperLogLog of the interval the
+// provided time belongs to, scoped to the tenant of the connection.
+func (r *RedisStore) activeKey(c redis.Conn, t time.Time) string {
+	start := t.Truncate(r.activeResolution).Unix()
+	return r.buildKey(connTenant(c), "active", strconv.FormatInt(start, 10))
+}
@@ -0,0 +1,72 @@
+package redisstore
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithActiveUsers(t *testing.T) {
+	r := RedisStore{}
+	WithActiveUsers(time.Minute, time.Hour)(&r)
+	assert.Equal(t, time.Minute, r.activeResolution)
+	assert.Equal(t, time.Hour, r.activeRetention)
+
+	WithActiveUsers(time.Hour, time.Minute)(&r)
+	assert.Equal(t, time.Hour, r.activeRetention)
+}
+
+func Test_RedisStore_ActiveUsers(t *testing.T) {
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	_, err := r.ActiveUsers(context.Background(), time.Hour)
+	assert.Equal(t, errNoActiveUsers, err)
+
+	r.activeResolution = time.Hour
+	r.activeRetention = time.Hour * 2
+
+	bucket := func(t time.Time) string {
+		return prefix + ":active:" + strconv.FormatInt(t.Truncate(time.Hour).Unix(), 10)
+	}
+
+	now := time.Now()
+
+	conn.Command("PFCOUNT", bucket(now.Add(-time.Hour*2)), bucket(now.Add(-time.Hour)), bucket(now)).
+		Expect(int64(5))
+
+	// the window is limited by the retention period
+	n, err := r.ActiveUsers(context.Background(), time.Hour*24)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+}
+
+func Test_RedisStore_markActive(t *testing.T) {
+	conn := redigomock.NewConn()
+	r := RedisStore{prefix: prefix}
+
+	require.NoError(t, r.markActive(conn, "u123"))
+
+	r.activeResolution = time.Hour
+	r.activeRetention = time.Hour
+
+	now := time.Now().Truncate(time.Hour)
+	key := prefix + ":active:" + strconv.FormatInt(now.Unix(), 10)
+
+	pfadd := conn.Command("PFADD", key, "u123").Expect(int64(1))
+	conn.Command("PEXPIREAT", key, now.Add(time.Hour*2).UnixNano()/int64(time.Millisecond)).Expect(int64(1))
+
+	require.NoError(t, r.markActive(conn, "u123"))
+	assert.Equal(t, 1, conn.Stats(pfadd))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
@@ -0,0 +1,91 @@
+package redisstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// Actions recorded in the audit stream.
+const (
+	// AuditCreated is recorded when a session is created.
+	AuditCreated = "created"
+
+	// AuditDeleted is recorded when a session, or all sessions of
+	// a user, are deleted.
+	AuditDeleted = "deleted"
+
+	// AuditExtended is recorded when a session's expiration time is
+	// changed by ExtendByID.
+	AuditExtended = "extended"
+
+	// AuditUpdated is recorded when a session's metadata is changed.
+	AuditUpdated = "updated"
+
+	// AuditRenewed is recorded when a session's ID is replaced.
+	AuditRenewed = "renewed"
+
+	// AuditRevoked is recorded when a session ID is revoked.
+	AuditRevoked = "revoked"
+)
+
+// WithAudit enables recording of an entry in the "<prefix>:audit"
+// Redis stream after each successful mutating operation. Each entry
+// holds the action, a hash of the session ID (id_hash), the user key,
+// the IP address and the time of the operation (at); entries of
+// renewed sessions hold a hash of the old ID (old_id_hash) as well,
+// entries of operations scoped to a tenant hold the tenant (tenant),
+// while entries of sessions deleted by their user key hold no session
+// data other than the user key. If encryption is enabled (see
+// WithEncryption), user keys and IP addresses are replaced by their
+// keyed hashes. The stream is capped at approximately maxLen entries.
+// Expiration of sessions as well as their refreshes caused by idle
+// timeout are not recorded.
+// Defaults to 0 (disabled).
+func WithAudit(maxLen int64) setter {
+	return func(r *RedisStore) {
+		r.auditMaxLen = maxLen
+	}
+}
+
+// audit records the action performed on the session in the audit
+// stream, if auditing is enabled. fields specify additional entry
+// fields and their values.
+func (r *RedisStore) audit(c redis.Conn, action string, s sessionup.Session, fields ...interface{}) error {
+	if r.auditMaxLen <= 0 {
+		return nil
+	}
+
+	var id string
+
+	if s.ID != "" {
+		id = hashValue(s.ID)
+	}
+
+	args := []interface{}{
+		r.prefixed(r.prefix, "audit"), "MAXLEN", "~", r.auditMaxLen, "*",
+		"action", action,
+		"id_hash", id,
+		"user_key", r.enc.hash(s.UserKey),
+		"ip", r.enc.hash(ipToString(s.IP)),
+		"at", r.now().UTC().Format(time.RFC3339Nano),
+	}
+
+	if tenant := connTenant(c); tenant != "" {
+		args = append(args, "tenant", tenant)
+	}
+
+	_, err := c.Do("XADD", append(args, fields...)...)
+
+	return err
+}
+
+// hashValue returns a short hash of the provided value that can be
+// recorded without revealing it.
+func hashValue(v string) string {
+	sum := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,107 @@
+package redisstore
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithAudit(t *testing.T) {
+	r := RedisStore{}
+	WithAudit(1000)(&r)
+	assert.Equal(t, int64(1000), r.auditMaxLen)
+}
+
+func Test_RedisStore_audit(t *testing.T) {
+	s := sessionup.Session{
+		ID:      "id123",
+		UserKey: "u123",
+		IP:      net.ParseIP("127.0.0.1"),
+	}
+
+	conn := redigomock.NewConn()
+	r := RedisStore{prefix: prefix}
+	assert.NoError(t, r.audit(conn, AuditCreated, s))
+
+	r.auditMaxLen = 100
+	args := []interface{}{
+		prefix + ":audit", "MAXLEN", "~", int64(100), "*",
+		"action", AuditRenewed,
+		"id_hash", hashValue(s.ID),
+		"user_key", s.UserKey,
+		"ip", "127.0.0.1",
+		"at", redigomock.NewAnyData(),
+		"old_id_hash", hashValue("id0"),
+	}
+
+	conn.Command("XADD", args...).ExpectError(assert.AnError)
+	assert.Equal(t, assert.AnError, r.audit(conn, AuditRenewed, s, "old_id_hash", hashValue("id0")))
+
+	conn.Clear()
+	conn.Command("XADD", args...).Expect([]byte("1-0"))
+	assert.NoError(t, r.audit(conn, AuditRenewed, s, "old_id_hash", hashValue("id0")))
+
+	conn.Clear()
+	conn.Command("XADD",
+		prefix+":audit", "MAXLEN", "~", int64(100), "*",
+		"action", AuditDeleted,
+		"id_hash", "",
+		"user_key", s.UserKey,
+		"ip", "",
+		"at", redigomock.NewAnyData(),
+	).Expect([]byte("1-0"))
+	assert.NoError(t, r.audit(conn, AuditDeleted, sessionup.Session{UserKey: s.UserKey}))
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	// personal data is hashed if encryption is enabled.
+	r.enc = newEncryption(key1)
+	conn.Clear()
+	cmd := conn.Command("XADD",
+		prefix+":audit", "MAXLEN", "~", int64(100), "*",
+		"action", AuditCreated,
+		"id_hash", hashValue(s.ID),
+		"user_key", r.enc.hash(s.UserKey),
+		"ip", r.enc.hash("127.0.0.1"),
+		"at", redigomock.NewAnyData(),
+	).Expect([]byte("1-0"))
+	assert.NoError(t, r.audit(conn, AuditCreated, s))
+	assert.Equal(t, 1, conn.Stats(cmd))
+}
+
+func Test_RedisStore_DeleteByUserKey_audited(t *testing.T) {
+	uKey := prefix + ":user:u123"
+
+	conn := redigomock.NewConn()
+	conn.Script([]byte(deleteByUserKeyScriptSrc), 1, uKey, prefix+":session:", prefix+":payload:", "").Expect(int64(2))
+	cmd := conn.Command("XADD",
+		prefix+":audit", "MAXLEN", "~", int64(100), "*",
+		"action", AuditDeleted,
+		"id_hash", "",
+		"user_key", "u123",
+		"ip", "",
+		"at", redigomock.NewAnyData(),
+	).Expect([]byte("1-0"))
+
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithAudit(100))
+
+	require.NoError(t, r.DeleteByUserKey(context.Background(), "u123"))
+	assert.Equal(t, 1, conn.Stats(cmd))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_hashValue(t *testing.T) {
+	assert.Len(t, hashValue("id123"), 16)
+	assert.Equal(t, hashValue("id123"), hashValue("id123"))
+	assert.NotEqual(t, hashValue("id123"), hashValue("id124"))
+	assert.NotContains(t, hashValue("id123"), "id123")
+}
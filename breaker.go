@@ -0,0 +1,79 @@
+package redisstore
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// breaker is a circuit breaker that stops operations from being
+// attempted after consecutive connection failures.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+
+	// probing is set while a trial operation is in progress after
+	// the cooldown period has passed.
+	probing bool
+}
+
+// WithBreaker enables a circuit breaker that trips after the provided
+// number of consecutive operations fail due to connection errors.
+// While it is tripped, operations fail immediately with ErrUnavailable
+// instead of waiting for pool or dial timeouts. Once the cooldown
+// period passes, a single operation is let through: the breaker is
+// reset if it succeeds and tripped again otherwise.
+// Defaults to disabled.
+func WithBreaker(threshold int, cooldown time.Duration) setter {
+	return func(r *RedisStore) {
+		r.breaker = nil
+
+		if threshold > 0 {
+			r.breaker = &breaker{threshold: threshold, cooldown: cooldown}
+		}
+	}
+}
+
+// allow checks whether an operation may be attempted.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false
+	}
+
+	b.probing = true
+
+	return true
+}
+
+// record updates the breaker's state with the outcome of an
+// operation. Operations interrupted by their context do not
+// affect the state, apart from ending the trial.
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	var e *Error
+
+	switch {
+	case errors.Is(err, ErrConnection):
+		b.failures++
+
+		if b.failures >= b.threshold {
+			b.openUntil = time.Now().Add(b.cooldown)
+		}
+	case err == nil || !errors.As(err, &e) || e.Kind != nil:
+		b.failures = 0
+	}
+}
@@ -0,0 +1,93 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithBreaker(t *testing.T) {
+	r := RedisStore{}
+	WithBreaker(3, time.Second)(&r)
+	require.NotNil(t, r.breaker)
+	assert.Equal(t, 3, r.breaker.threshold)
+	assert.Equal(t, time.Second, r.breaker.cooldown)
+
+	WithBreaker(0, time.Second)(&r)
+	assert.Nil(t, r.breaker)
+}
+
+func Test_breaker(t *testing.T) {
+	connErr := &Error{Op: "op", Kind: ErrConnection, Err: assert.AnError}
+	b := &breaker{threshold: 2, cooldown: time.Hour}
+
+	assert.True(t, b.allow())
+	b.record(connErr)
+	assert.True(t, b.allow())
+	b.record(nil)
+	assert.True(t, b.allow())
+	b.record(connErr)
+	assert.True(t, b.allow())
+	b.record(&Error{Op: "op", Kind: ErrCommand, Err: assert.AnError})
+	assert.Zero(t, b.failures)
+
+	b.record(connErr)
+	b.record(&Error{Op: "op", Err: context.Canceled})
+	assert.Equal(t, 1, b.failures)
+
+	b.record(connErr)
+	assert.False(t, b.allow())
+
+	b.openUntil = time.Now().Add(-time.Second)
+	assert.True(t, b.allow())
+	assert.False(t, b.allow())
+
+	b.record(connErr)
+	assert.False(t, b.allow())
+
+	b.openUntil = time.Now().Add(-time.Second)
+	assert.True(t, b.allow())
+	b.record(nil)
+	assert.True(t, b.allow())
+	assert.True(t, b.allow())
+}
+
+func Test_RedisStore_begin_breaker(t *testing.T) {
+	var dials int
+
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			dials++
+			if dials <= 2 {
+				return nil, assert.AnError
+			}
+
+			return redigomock.NewConn(), nil
+		},
+	}, prefix, WithBreaker(2, time.Hour))
+
+	for i := 0; i < 2; i++ {
+		_, _, err := r.begin(context.Background(), "Op")
+		assert.True(t, errors.Is(err, ErrConnection))
+	}
+
+	c, end, err := r.begin(context.Background(), "Op")
+	assert.Equal(t, &Error{Op: "op", Kind: ErrUnavailable, Err: ErrUnavailable}, err)
+	assert.Nil(t, c)
+	assert.Nil(t, end)
+	assert.Equal(t, 2, dials)
+
+	r.breaker.openUntil = time.Now()
+
+	_, end, err = r.begin(context.Background(), "Op")
+	require.NoError(t, err)
+	assert.NoError(t, end(nil))
+	assert.Zero(t, r.breaker.failures)
+	assert.NoError(t, r.Close(context.Background()))
+}
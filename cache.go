@@ -0,0 +1,381 @@
+package redisstore
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/swithek/sessionup"
+)
+
+// localCache is an in-process LRU cache of sessions retrieved by ID.
+type localCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	ll    *list.List
+	items map[cacheKey]*list.Element
+
+	// now returns the current time; it is set to the store's clock
+	// (see WithClock).
+	now func() time.Time
+
+	// enc is used to match the hashed user keys of invalidation
+	// messages; it is set to the store's encryption (see
+	// WithEncryption).
+	enc *encryption
+
+	// epoch is incremented whenever sessions are invalidated, so that
+	// sessions retrieved before an invalidation are not cached after
+	// it (see addSince).
+	epoch uint64
+
+	// synced is set when the invalidation listener is running.
+	synced bool
+
+	// starting is set while the invalidation listener is being
+	// started.
+	starting bool
+
+	// failures is the number of consecutive failed attempts to start
+	// the invalidation listener, while retryAt is the time before
+	// which no new attempt is made.
+	failures int
+	retryAt  time.Time
+}
+
+const (
+	// cacheRetryDelay is the delay before the invalidation listener
+	// is started again after a failed attempt. It is doubled after
+	// each consecutive failure, up to cacheRetryMaxDelay.
+	cacheRetryDelay = 100 * time.Millisecond
+
+	// cacheRetryMaxDelay is the maximum delay between two attempts
+	// to start the invalidation listener.
+	cacheRetryMaxDelay = 30 * time.Second
+)
+
+// cacheKey identifies a cached session by its tenant and ID.
+type cacheKey struct {
+	tenant string
+	id     string
+}
+
+// cacheEntry holds a cached session and the time it should be
+// evicted at.
+type cacheEntry struct {
+	key cacheKey
+	s   sessionup.Session
+	exp time.Time
+}
+
+// WithLocalCache enables an in-process LRU cache of up to size
+// sessions retrieved by FetchByID, each kept for no longer than the
+// provided ttl. Cached sessions are invalidated by the messages
+// received from the invalidation channel (see WithInvalidations),
+// which is enabled with its default name if it has not been set
+// before. The cache is bypassed while the store is not subscribed to
+// the channel, as well as when idle timeout or last seen tracking
+// are enabled, since they require a write on each retrieval.
+// Defaults to disabled.
+func WithLocalCache(size int, ttl time.Duration) setter {
+	return func(r *RedisStore) {
+		if size <= 0 || ttl <= 0 {
+			r.cache = nil
+			return
+		}
+
+		r.cache = newLocalCache(size, ttl)
+
+		if r.invalidations == "" {
+			r.defaultChannel = true
+		}
+	}
+}
+
+// newLocalCache creates a new local cache.
+func newLocalCache(size int, ttl time.Duration) *localCache {
+	return &localCache{
+		size:  size,
+		ttl:   ttl,
+		now:   time.Now,
+		ll:    list.New(),
+		items: make(map[cacheKey]*list.Element),
+	}
+}
+
+// get returns the cached session of the tenant by the provided ID.
+// The second returned value indicates whether the session was found
+// or not.
+func (lc *localCache) get(tenant, id string) (sessionup.Session, bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	el, ok := lc.items[cacheKey{tenant: tenant, id: id}]
+	if !ok {
+		return sessionup.Session{}, false
+	}
+
+	e := el.Value.(*cacheEntry)
+	if !lc.now().Before(e.exp) {
+		lc.removeElement(el)
+		return sessionup.Session{}, false
+	}
+
+	lc.ll.MoveToFront(el)
+
+	return copySession(e.s), true
+}
+
+// add inserts the session of the tenant into the cache, evicting the
+// least recently used session if the cache is full. Nothing is
+// inserted while the cache is not synced.
+func (lc *localCache) add(tenant string, s sessionup.Session) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.insert(tenant, s)
+}
+
+// addSince inserts the session of the tenant into the cache like add,
+// unless the cache was invalidated after the provided epoch was
+// retrieved (see currentEpoch), since the session may then be stale.
+func (lc *localCache) addSince(tenant string, s sessionup.Session, epoch uint64) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if lc.epoch != epoch {
+		return
+	}
+
+	lc.insert(tenant, s)
+}
+
+// currentEpoch returns the number of invalidations the cache has
+// handled so far. It should be retrieved before the session is
+// fetched and passed to addSince afterwards.
+func (lc *localCache) currentEpoch() uint64 {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	return lc.epoch
+}
+
+// insert inserts the session of the tenant into the cache, if it is
+// synced. The mutex must be held by the caller.
+func (lc *localCache) insert(tenant string, s sessionup.Session) {
+	if !lc.synced {
+		return
+	}
+
+	exp := lc.now().Add(lc.ttl)
+	if s.ExpiresAt.Before(exp) {
+		exp = s.ExpiresAt
+	}
+
+	key := cacheKey{tenant: tenant, id: s.ID}
+	e := &cacheEntry{key: key, s: copySession(s), exp: exp}
+
+	if el, ok := lc.items[key]; ok {
+		el.Value = e
+		lc.ll.MoveToFront(el)
+
+		return
+	}
+
+	lc.items[key] = lc.ll.PushFront(e)
+
+	if lc.ll.Len() > lc.size {
+		lc.removeElement(lc.ll.Back())
+	}
+}
+
+// invalidate removes all sessions matching the invalidation message
+// from the cache.
+func (lc *localCache) invalidate(inv Invalidation) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.epoch++
+
+	if inv.All {
+		for key, el := range lc.items {
+			if key.tenant == inv.Tenant {
+				lc.removeElement(el)
+			}
+		}
+
+		return
+	}
+
+	if inv.ID != "" {
+		if el, ok := lc.items[cacheKey{tenant: inv.Tenant, id: inv.ID}]; ok {
+			lc.removeElement(el)
+		}
+	}
+
+	if inv.UserKey == "" && inv.UserKeyHash == "" {
+		return
+	}
+
+	keep := make(map[string]struct{}, len(inv.Except))
+	for _, id := range inv.Except {
+		keep[id] = struct{}{}
+	}
+
+	for key, el := range lc.items {
+		if _, ok := keep[key.id]; ok || key.tenant != inv.Tenant {
+			continue
+		}
+
+		if lc.matchesUser(el.Value.(*cacheEntry).s.UserKey, inv) {
+			lc.removeElement(el)
+		}
+	}
+}
+
+// matchesUser checks whether the provided user key is the one whose
+// sessions were invalidated. Messages published while encryption is
+// enabled hold only the keyed hash of the user key (see Invalidation).
+func (lc *localCache) matchesUser(key string, inv Invalidation) bool {
+	if inv.UserKeyHash == "" {
+		return key == inv.UserKey
+	}
+
+	for _, h := range lc.enc.hashes(key) {
+		if h == inv.UserKeyHash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setSynced marks the cache as synced (or not). All sessions are
+// removed when the cache stops being synced, since invalidation
+// messages may have been missed.
+func (lc *localCache) setSynced(synced bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.synced = synced
+
+	if !synced {
+		lc.epoch++
+		lc.ll.Init()
+		lc.items = make(map[cacheKey]*list.Element)
+	}
+}
+
+// removeElement removes the list element and its session from the
+// cache. The mutex must be held by the caller.
+func (lc *localCache) removeElement(el *list.Element) {
+	lc.ll.Remove(el)
+	delete(lc.items, el.Value.(*cacheEntry).key)
+}
+
+// cacheEnabled checks whether the local cache should be used,
+// starting the invalidation listener if it is not running yet. The
+// listener is started by a single caller at a time, outside of the
+// cache's lock, and failed attempts are retried with an exponential
+// backoff; the cache is bypassed in the meantime.
+func (r *RedisStore) cacheEnabled() bool {
+	if r.cache == nil || r.idleTimeout > 0 || r.lastSeen {
+		return false
+	}
+
+	if !r.cache.startSync() {
+		return r.cache.isSynced()
+	}
+
+	// the listener lives as long as the store, hence it does not
+	// depend on the context of the operation that started it
+	ii, err := r.Invalidations(context.Background())
+
+	r.cache.finishSync(err)
+
+	if err != nil {
+		return false
+	}
+
+	go func() {
+		for inv := range ii {
+			r.cache.invalidate(inv)
+		}
+
+		r.cache.setSynced(false)
+	}()
+
+	return true
+}
+
+// startSync checks whether the invalidation listener should be
+// started by the caller, i.e. it is neither running nor being started,
+// and no failed attempt was made too recently.
+func (lc *localCache) startSync() bool {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if lc.synced || lc.starting || lc.now().Before(lc.retryAt) {
+		return false
+	}
+
+	lc.starting = true
+
+	return true
+}
+
+// finishSync records the result of the attempt to start the
+// invalidation listener.
+func (lc *localCache) finishSync(err error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.starting = false
+
+	if err == nil {
+		lc.synced = true
+		lc.failures = 0
+		lc.retryAt = time.Time{}
+
+		return
+	}
+
+	delay := cacheRetryMaxDelay
+	if lc.failures < 16 {
+		if d := cacheRetryDelay << uint(lc.failures); d < delay {
+			delay = d
+		}
+	}
+
+	lc.failures++
+	lc.retryAt = lc.now().Add(delay)
+}
+
+// isSynced checks whether the invalidation listener is running.
+func (lc *localCache) isSynced() bool {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	return lc.synced
+}
+
+// copySession returns a copy of the session that does not share
+// its metadata or IP address with the original.
+func copySession(s sessionup.Session) sessionup.Session {
+	if s.IP != nil {
+		s.IP = append(s.IP[:0:0], s.IP...)
+	}
+
+	if s.Meta != nil {
+		mm := make(map[string]string, len(s.Meta))
+		for k, v := range s.Meta {
+			mm[k] = v
+		}
+
+		s.Meta = mm
+	}
+
+	return s
+}
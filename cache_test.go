@@ -0,0 +1,339 @@
+package redisstore
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithLocalCache(t *testing.T) {
+	r := RedisStore{prefix: prefix}
+	WithLocalCache(0, time.Minute)(&r)
+	assert.Nil(t, r.cache)
+	assert.Empty(t, r.invalidations)
+
+	WithLocalCache(10, time.Minute)(&r)
+	require.NotNil(t, r.cache)
+	assert.Equal(t, 10, r.cache.size)
+	assert.Equal(t, time.Minute, r.cache.ttl)
+	assert.True(t, r.defaultChannel)
+
+	r.invalidations = "channel"
+	WithLocalCache(10, time.Minute)(&r)
+	assert.Equal(t, "channel", r.invalidations)
+}
+
+func Test_localCache(t *testing.T) {
+	s1 := sessionup.Session{
+		ID:        "id1",
+		UserKey:   "u1",
+		ExpiresAt: time.Now().Add(time.Hour),
+		IP:        net.ParseIP("127.0.0.1"),
+		Meta:      map[string]string{"test": "1"},
+	}
+	s2 := sessionup.Session{ID: "id2", UserKey: "u1", ExpiresAt: time.Now().Add(time.Hour)}
+	s3 := sessionup.Session{ID: "id3", UserKey: "u2", ExpiresAt: time.Now().Add(time.Hour)}
+
+	lc := newLocalCache(2, time.Hour)
+
+	// not synced
+	lc.add("", s1)
+	_, ok := lc.get("", s1.ID)
+	assert.False(t, ok)
+
+	lc.setSynced(true)
+
+	lc.add("", s1)
+	s, ok := lc.get("", s1.ID)
+	require.True(t, ok)
+	assert.Equal(t, s1, s)
+
+	// returned sessions must not share data with cached ones
+	s.Meta["test"] = "2"
+	s.IP[0] = 1
+	s, _ = lc.get("", s1.ID)
+	assert.Equal(t, s1, s)
+
+	// least recently used session is evicted
+	lc.add("", s2)
+	lc.get("", s1.ID)
+	lc.add("", s3)
+
+	_, ok = lc.get("", s2.ID)
+	assert.False(t, ok)
+	_, ok = lc.get("", s1.ID)
+	assert.True(t, ok)
+	_, ok = lc.get("", s3.ID)
+	assert.True(t, ok)
+
+	// replacement
+	s1.Meta = nil
+	lc.add("", s1)
+	s, ok = lc.get("", s1.ID)
+	assert.True(t, ok)
+	assert.Equal(t, s1, s)
+	assert.Equal(t, 2, lc.ll.Len())
+
+	// expired session
+	lc.add("", sessionup.Session{ID: "id4", ExpiresAt: time.Now().Add(-time.Second)})
+	_, ok = lc.get("", "id4")
+	assert.False(t, ok)
+	assert.NotContains(t, lc.items, "id4")
+
+	// expired entry
+	lc = newLocalCache(2, time.Nanosecond)
+	lc.setSynced(true)
+	lc.add("", s1)
+	time.Sleep(time.Millisecond)
+	_, ok = lc.get("", s1.ID)
+	assert.False(t, ok)
+
+	lc.setSynced(false)
+	assert.Empty(t, lc.items)
+	assert.Zero(t, lc.ll.Len())
+}
+
+func Test_localCache_invalidate(t *testing.T) {
+	lc := newLocalCache(10, time.Hour)
+	lc.setSynced(true)
+
+	for _, s := range []sessionup.Session{
+		{ID: "id1", UserKey: "u1"},
+		{ID: "id2", UserKey: "u1"},
+		{ID: "id3", UserKey: "u1"},
+		{ID: "id4", UserKey: "u2"},
+	} {
+		s.ExpiresAt = time.Now().Add(time.Hour)
+		lc.add("", s)
+	}
+
+	lc.add("t1", sessionup.Session{ID: "id1", UserKey: "u1", ExpiresAt: time.Now().Add(time.Hour)})
+
+	lc.invalidate(Invalidation{ID: "id4"})
+	assert.NotContains(t, lc.items, cacheKey{id: "id4"})
+	assert.Len(t, lc.items, 4)
+
+	lc.invalidate(Invalidation{UserKey: "u1", Except: []string{"id2"}})
+	assert.Len(t, lc.items, 2)
+	assert.Contains(t, lc.items, cacheKey{id: "id2"})
+	assert.Contains(t, lc.items, cacheKey{tenant: "t1", id: "id1"})
+
+	lc.invalidate(Invalidation{ID: "id1", Tenant: "t1"})
+	assert.Len(t, lc.items, 1)
+	assert.Contains(t, lc.items, cacheKey{id: "id2"})
+
+	lc.add("t1", sessionup.Session{ID: "id1", UserKey: "u1", ExpiresAt: time.Now().Add(time.Hour)})
+	lc.invalidate(Invalidation{All: true})
+	assert.Len(t, lc.items, 1)
+	assert.Contains(t, lc.items, cacheKey{tenant: "t1", id: "id1"})
+
+	// hashed user keys are matched with all encryption keys.
+	lc.add("", sessionup.Session{ID: "id5", UserKey: "u1", ExpiresAt: time.Now().Add(time.Hour)})
+	lc.invalidate(Invalidation{UserKeyHash: newEncryption(key1).hash("u1")})
+	assert.Len(t, lc.items, 2)
+
+	lc.enc = newEncryption(key2, key1)
+	lc.invalidate(Invalidation{UserKeyHash: newEncryption(key1).hash("u1")})
+	assert.Len(t, lc.items, 1)
+	assert.NotContains(t, lc.items, cacheKey{id: "id5"})
+}
+
+func Test_localCache_addSince(t *testing.T) {
+	s := sessionup.Session{ID: "id1", UserKey: "u1", ExpiresAt: time.Now().Add(time.Hour)}
+
+	lc := newLocalCache(10, time.Hour)
+	lc.setSynced(true)
+
+	epoch := lc.currentEpoch()
+	lc.invalidate(Invalidation{ID: "id1"})
+	lc.addSince("", s, epoch)
+	assert.Empty(t, lc.items)
+
+	lc.addSince("", s, lc.currentEpoch())
+	assert.Contains(t, lc.items, cacheKey{id: "id1"})
+}
+
+func Test_localCache_clock(t *testing.T) {
+	now := time.Now()
+	s := sessionup.Session{ID: "id1", UserKey: "u1", ExpiresAt: now.Add(time.Hour)}
+
+	r := New(nil, prefix, WithLocalCache(10, time.Minute), WithClock(ClockFunc(func() time.Time {
+		return now
+	})))
+	r.cache.setSynced(true)
+	r.cache.add("", s)
+
+	_, ok := r.cache.get("", s.ID)
+	assert.True(t, ok)
+
+	now = now.Add(time.Minute)
+
+	_, ok = r.cache.get("", s.ID)
+	assert.False(t, ok)
+}
+
+func Test_RedisStore_cacheEnabled(t *testing.T) {
+	t.Run("Disabled cache", func(t *testing.T) {
+		r := RedisStore{}
+		assert.False(t, r.cacheEnabled())
+
+		r.cache = newLocalCache(10, time.Hour)
+		r.idleTimeout = time.Minute
+		assert.False(t, r.cacheEnabled())
+
+		r.idleTimeout = 0
+		r.lastSeen = true
+		assert.False(t, r.cacheEnabled())
+	})
+
+	t.Run("Failed subscription", func(t *testing.T) {
+		var dials int
+
+		r := New(&redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				dials++
+				return nil, assert.AnError
+			},
+		}, prefix, WithLocalCache(10, time.Hour))
+
+		assert.False(t, r.cacheEnabled())
+		assert.False(t, r.cache.synced)
+		assert.False(t, r.cache.starting)
+		assert.Equal(t, 1, r.cache.failures)
+
+		// the subscription is not retried before the backoff delay
+		// passes
+		assert.False(t, r.cacheEnabled())
+		assert.Equal(t, 1, dials)
+
+		r.cache.retryAt = time.Now()
+		assert.False(t, r.cacheEnabled())
+		assert.Equal(t, 2, dials)
+		assert.Equal(t, 2, r.cache.failures)
+		assert.True(t, r.cache.retryAt.After(time.Now().Add(cacheRetryDelay)))
+	})
+
+	t.Run("Subscription in progress", func(t *testing.T) {
+		r := RedisStore{cache: newLocalCache(10, time.Hour)}
+		r.cache.starting = true
+
+		assert.False(t, r.cacheEnabled())
+	})
+
+	t.Run("Successful subscription", func(t *testing.T) {
+		conn := newPubSubConn()
+		r := New(&redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return conn, nil
+			},
+		}, prefix, WithLocalCache(10, time.Hour))
+
+		require.True(t, r.cacheEnabled())
+		assert.True(t, r.cacheEnabled())
+		assert.Equal(t, 1, count(conn.sent(), "PSUBSCRIBE"))
+
+		r.cache.add("", sessionup.Session{ID: "id1", ExpiresAt: time.Now().Add(time.Hour)})
+		r.cache.add("", sessionup.Session{ID: "id2", ExpiresAt: time.Now().Add(time.Hour)})
+
+		conn.publish(r.invalidations, r.invalidations, `{"id":"id1"}`)
+
+		// the connection failure is handled only after the
+		// invalidation message
+		conn.replies <- assert.AnError
+
+		for synced(r.cache) {
+			time.Sleep(time.Millisecond)
+		}
+
+		_, ok := r.cache.get("", "id1")
+		assert.False(t, ok)
+		_, ok = r.cache.get("", "id2")
+		assert.False(t, ok)
+
+		assert.NoError(t, r.Close(context.Background()))
+	})
+}
+
+func Test_RedisStore_FetchByID_cached(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+		CreatedAt: time.Now().UTC().Round(0),
+		IP:        net.ParseIP("127.0.0.1"),
+	}
+
+	sKey := prefix + ":session:" + inp.ID
+
+	conn := redigomock.NewConn()
+	cmd := conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": inp.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at": inp.ExpiresAt.Format(time.RFC3339Nano),
+		"id":         inp.ID,
+		"user_key":   inp.UserKey,
+		"ip":         inp.IP.String(),
+	})
+
+	psc := newPubSubConn()
+
+	var dials int
+
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			dials++
+
+			// the second connection is used by the invalidation
+			// listener
+			if dials == 2 {
+				return psc, nil
+			}
+
+			return conn, nil
+		},
+	}, prefix, WithLocalCache(10, time.Hour))
+
+	for i := 0; i < 3; i++ {
+		s, ok, err := r.FetchByID(context.Background(), inp.ID)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, inp, s)
+	}
+
+	assert.Equal(t, 1, conn.Stats(cmd))
+
+	conn.Command("PUBLISH", r.invalidations, []byte(`{"id":"id123"}`))
+	require.NoError(t, r.invalidate(conn, Invalidation{ID: inp.ID}))
+
+	_, ok, err := r.FetchByID(context.Background(), inp.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, conn.Stats(cmd))
+
+	assert.NoError(t, r.Close(context.Background()))
+}
+
+func synced(lc *localCache) bool {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	return lc.synced
+}
+
+func count(ss []string, v string) int {
+	var n int
+
+	for i := range ss {
+		if ss[i] == v {
+			n++
+		}
+	}
+
+	return n
+}
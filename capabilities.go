@@ -0,0 +1,119 @@
+package redisstore
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Capabilities describes the commands supported by the Redis server,
+// as detected by DetectCapabilities.
+type Capabilities struct {
+	// Version specifies the version of the server, e.g. "7.0.5".
+	Version string
+
+	// VariadicHSET indicates whether HSET accepts multiple field-value
+	// pairs (Redis 4.0), which makes the deprecated HMSET unnecessary.
+	VariadicHSET bool
+
+	// Unlink indicates whether UNLINK is available (Redis 4.0).
+	Unlink bool
+
+	// Copy indicates whether COPY is available (Redis 6.2). It is
+	// required by Rekey.
+	Copy bool
+
+	// ExpireTime indicates whether PEXPIRETIME is available
+	// (Redis 7.0).
+	ExpireTime bool
+
+	// Functions indicates whether Redis Functions are available
+	// (Redis 7.0). They are used if enabled with WithFunctions.
+	Functions bool
+
+	// ExpireOptions indicates whether PEXPIREAT accepts the NX and GT
+	// options (Redis 7.0), which allow expiration times to be moved
+	// only forward without reading them first.
+	ExpireOptions bool
+}
+
+// DetectCapabilities retrieves the version of the Redis server and
+// determines which commands the store may use. It should be called
+// once the store is created; until then (or if detection fails), the
+// store uses commands supported by older servers, such as HMSET, and
+// falls back to them when newer commands are rejected.
+// All servers behind the pool are expected to run the same version.
+func (r *RedisStore) DetectCapabilities(ctx context.Context) (caps Capabilities, err error) {
+	c, end, err := r.begin(ctx, "DetectCapabilities")
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	defer func() { err = end(err) }()
+
+	info, err := redis.String(c.Do("INFO", "server"))
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	caps = parseCapabilities(info)
+	r.caps.Store(caps)
+
+	return caps, nil
+}
+
+// Capabilities returns the capabilities of the Redis server detected
+// by DetectCapabilities. Zero value is returned if they have not been
+// detected yet.
+func (r *RedisStore) Capabilities() Capabilities {
+	caps, _ := r.caps.Load().(Capabilities)
+	return caps
+}
+
+// parseCapabilities determines the capabilities of the server from
+// the output of INFO.
+func parseCapabilities(info string) Capabilities {
+	var caps Capabilities
+
+	for _, line := range strings.Split(info, "\n") {
+		if v := strings.TrimPrefix(line, "redis_version:"); v != line {
+			caps.Version = strings.TrimSpace(v)
+			break
+		}
+	}
+
+	var ver [2]int
+
+	for i, p := range strings.SplitN(caps.Version, ".", 3) {
+		if i == len(ver) {
+			break
+		}
+
+		ver[i], _ = strconv.Atoi(p)
+	}
+
+	atLeast := func(major, minor int) bool {
+		return ver[0] > major || ver[0] == major && ver[1] >= minor
+	}
+
+	caps.VariadicHSET = atLeast(4, 0)
+	caps.Unlink = atLeast(4, 0)
+	caps.Copy = atLeast(6, 2)
+	caps.ExpireTime = atLeast(7, 0)
+	caps.Functions = atLeast(7, 0)
+	caps.ExpireOptions = atLeast(7, 0)
+
+	return caps
+}
+
+// hashSetCmd returns the name of the command used to write multiple
+// fields of a session hash.
+func (r *RedisStore) hashSetCmd() string {
+	if r.Capabilities().VariadicHSET {
+		return "HSET"
+	}
+
+	return "HMSET"
+}
@@ -0,0 +1,109 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseCapabilities(t *testing.T) {
+	info := func(v string) string {
+		return "# Server\r\nredis_git_sha1:00000000\r\nredis_version:" + v + "\r\nredis_mode:standalone\r\n"
+	}
+
+	assert.Equal(t, Capabilities{}, parseCapabilities("# Server\r\n"))
+	assert.Equal(t, Capabilities{Version: "3.2.12"}, parseCapabilities(info("3.2.12")))
+	assert.Equal(t, Capabilities{
+		Version:      "4.0.0",
+		VariadicHSET: true,
+		Unlink:       true,
+	}, parseCapabilities(info("4.0.0")))
+	assert.Equal(t, Capabilities{
+		Version:      "6.2.6",
+		VariadicHSET: true,
+		Unlink:       true,
+		Copy:         true,
+	}, parseCapabilities(info("6.2.6")))
+	assert.Equal(t, Capabilities{
+		Version:       "7.0.5",
+		VariadicHSET:  true,
+		Unlink:        true,
+		Copy:          true,
+		ExpireTime:    true,
+		Functions:     true,
+		ExpireOptions: true,
+	}, parseCapabilities(info("7.0.5")))
+	assert.True(t, parseCapabilities(info("10.1")).ExpireTime)
+}
+
+func Test_RedisStore_DetectCapabilities(t *testing.T) {
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	assert.Equal(t, Capabilities{}, r.Capabilities())
+	assert.Equal(t, "HMSET", r.hashSetCmd())
+
+	conn.Command("INFO", "server").ExpectError(assert.AnError)
+	_, err := r.DetectCapabilities(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, Capabilities{}, r.Capabilities())
+
+	conn.Clear()
+	conn.Command("INFO", "server").Expect("# Server\r\nredis_version:6.2.6\r\n")
+
+	caps, err := r.DetectCapabilities(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "6.2.6", caps.Version)
+	assert.Equal(t, caps, r.Capabilities())
+	assert.Equal(t, "HSET", r.hashSetCmd())
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_capabilities_oldServer(t *testing.T) {
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+	r.caps.Store(Capabilities{Version: "3.2.12"})
+
+	err := r.Rekey(context.Background(), "new")
+	assert.True(t, errors.Is(err, ErrNotSupported))
+
+	conn.Command("SCAN", int64(0), "MATCH", prefix+":session:*", "COUNT", scanCount).
+		ExpectSlice([]byte("0"), []interface{}{[]byte(prefix + ":session:id1")})
+	conn.Command("DEL", prefix+":session:id1").Expect(int64(1))
+	conn.GenericCommand("SCAN").ExpectSlice([]byte("0"), []interface{}{})
+	conn.Command("SCAN", int64(0), "MATCH", prefix+":user:*", "COUNT", scanCount).
+		ExpectSlice([]byte("0"), []interface{}{})
+
+	assert.NoError(t, r.DeleteAll(context.Background()))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_expirations(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.Command("PTTL", "k1").Expect(int64(1000))
+	conn.Command("PTTL", "k2").Expect(int64(-1))
+	conn.Command("PEXPIRETIME", "k1").Expect(int64(1614834367008))
+	conn.Command("PEXPIRETIME", "k2").Expect(int64(-2))
+
+	exps, err := expirations(conn, []string{"k1", "k2"}, false)
+	require.NoError(t, err)
+	assert.InDelta(t, expireAfter(1000), exps[0], 1000)
+	assert.Equal(t, int64(-1), exps[1])
+
+	exps, err = expirations(conn, []string{"k1", "k2"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1614834367008, -2}, exps)
+}
@@ -0,0 +1,110 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// errInvalidInterval is returned when the cleanup interval is not
+// positive.
+var errInvalidInterval = errors.New("redisstore: cleanup interval must be positive")
+
+// Cleanup removes entries of expired sessions from all user session
+// sets of the store. Sets that become empty are deleted by Redis.
+// Entries are normally removed only when a new session of the same
+// user is created, so sets of users that never sign in again keep
+// them until the set itself expires.
+// Entries of sessions that no longer exist are removed from the
+// creation time index as well, if it is enabled (see
+// WithCreatedIndex).
+func (r *RedisStore) Cleanup(ctx context.Context) (err error) {
+	c, end, err := r.begin(ctx, "Cleanup")
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = end(err) }()
+
+	if r.createdIndex {
+		if err = r.pruneCreatedIndex(ctx, c); err != nil {
+			return err
+		}
+	}
+
+	now := r.now().UnixNano()
+
+	return scan(ctx, c, r.pattern(c, true), func(keys []string) error {
+		// pipeline all set updates of the batch so that they are
+		// done in a single round trip
+		for i := range keys {
+			if err := c.Send("ZREMRANGEBYSCORE", keys[i], "-inf", now); err != nil {
+				return err
+			}
+		}
+
+		if err := c.Flush(); err != nil {
+			return err
+		}
+
+		for range keys {
+			if _, err := redis.Int64(c.Receive()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// StartCleanup starts a background worker that calls Cleanup
+// periodically, with a small random delay added to each interval so
+// that multiple instances do not run it simultaneously. The worker
+// stops when the provided context is done or the store is closed.
+// Errors returned by Cleanup are not reported, apart from being
+// recorded in its trace spans.
+func (r *RedisStore) StartCleanup(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return errInvalidInterval
+	}
+
+	r.closeMu.RLock()
+	defer r.closeMu.RUnlock()
+
+	if r.closed {
+		return wrapErr("startCleanup", ErrClosed)
+	}
+
+	r.workers.Add(1)
+
+	go func() {
+		defer r.workers.Done()
+
+		t := time.NewTimer(jitter(interval))
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.stop:
+				return
+			case <-t.C:
+			}
+
+			_ = r.Cleanup(ctx)
+
+			t.Reset(jitter(interval))
+		}
+	}()
+
+	return nil
+}
+
+// jitter adds a random delay of up to 10% to the provided duration.
+func jitter(d time.Duration) time.Duration {
+	return d + time.Duration(rand.Int63n(int64(d)/10+1))
+}
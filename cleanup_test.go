@@ -0,0 +1,192 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RedisStore_Cleanup(t *testing.T) {
+	pattern := prefix + ":user:*"
+	uKey1 := prefix + ":user:u1"
+	uKey2 := prefix + ":user:u2"
+
+	cc := map[string]struct {
+		Cancelled bool
+		Conn      func() (*redigomock.Conn, func(*testing.T))
+		Err       bool
+	}{
+		"Cancelled context": {
+			Cancelled: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during scan": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SCAN", int64(0), "MATCH", pattern, "COUNT", scanCount).ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during expired session removal": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SCAN", int64(0), "MATCH", pattern, "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{[]byte(uKey1), []byte(uKey2)})
+				conn.Command("ZREMRANGEBYSCORE", uKey1, "-inf", redigomock.NewAnyInt()).ExpectError(assert.AnError)
+				conn.Command("ZREMRANGEBYSCORE", uKey2, "-inf", redigomock.NewAnyInt()).Expect(int64(0))
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Successful execution": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SCAN", int64(0), "MATCH", pattern, "COUNT", scanCount).
+					ExpectSlice([]byte("3"), []interface{}{[]byte(uKey1)})
+				conn.Command("SCAN", int64(3), "MATCH", pattern, "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{[]byte(uKey2)})
+				conn.Command("ZREMRANGEBYSCORE", uKey1, "-inf", redigomock.NewAnyInt()).Expect(int64(2))
+				conn.Command("ZREMRANGEBYSCORE", uKey2, "-inf", redigomock.NewAnyInt()).Expect(int64(0))
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+					Wait:      true,
+					MaxActive: 10,
+				},
+				prefix: prefix,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if c.Cancelled {
+				cancel()
+			}
+
+			err := r.Cleanup(ctx)
+			check(t)
+
+			if c.Err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_RedisStore_StartCleanup(t *testing.T) {
+	newStore := func() (*RedisStore, <-chan struct{}) {
+		calls := make(chan struct{}, 10)
+
+		conn := redigomock.NewConn()
+		conn.GenericCommand("SCAN").Handle(func([]interface{}) (interface{}, error) {
+			select {
+			case calls <- struct{}{}:
+			default:
+			}
+
+			return []interface{}{[]byte("0"), []interface{}{}}, nil
+		})
+
+		return New(&redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return conn, nil
+			},
+		}, prefix), calls
+	}
+
+	wait := func(t *testing.T, calls <-chan struct{}) {
+		select {
+		case <-calls:
+		case <-time.After(time.Second):
+			t.Fatal("cleanup was not executed")
+		}
+	}
+
+	t.Run("Invalid interval", func(t *testing.T) {
+		r, _ := newStore()
+		assert.Equal(t, errInvalidInterval, r.StartCleanup(context.Background(), 0))
+	})
+
+	t.Run("Closed store", func(t *testing.T) {
+		r, _ := newStore()
+		require.NoError(t, r.Close(context.Background()))
+
+		err := r.StartCleanup(context.Background(), time.Millisecond)
+		assert.Equal(t, &Error{Op: "startCleanup", Kind: ErrClosed, Err: ErrClosed}, err)
+	})
+
+	t.Run("Stopped by context", func(t *testing.T) {
+		r, calls := newStore()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		require.NoError(t, r.StartCleanup(ctx, time.Millisecond))
+		wait(t, calls)
+		wait(t, calls)
+
+		cancel()
+		r.workers.Wait()
+	})
+
+	t.Run("Stopped by store closing", func(t *testing.T) {
+		r, calls := newStore()
+
+		require.NoError(t, r.StartCleanup(context.Background(), time.Millisecond))
+		wait(t, calls)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		assert.NoError(t, r.Close(ctx))
+	})
+}
+
+func Test_jitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		d := jitter(time.Second)
+		assert.True(t, d >= time.Second)
+		assert.True(t, d <= time.Second+time.Second/10)
+	}
+}
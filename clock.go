@@ -0,0 +1,38 @@
+package redisstore
+
+import "time"
+
+// Clock provides the current time to the store. It is used for
+// expiration and TTL calculations, index pruning and time-based
+// lookups, so that tests and replay tooling can control time.
+// Note that Redis still expires keys according to its own clock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// ClockFunc is an adapter that allows ordinary functions to be used as
+// clocks.
+type ClockFunc func() time.Time
+
+// Now calls fn().
+func (fn ClockFunc) Now() time.Time {
+	return fn()
+}
+
+// WithClock sets the clock used by the store.
+// Defaults to the system clock.
+func WithClock(c Clock) setter {
+	return func(r *RedisStore) {
+		r.clock = c
+	}
+}
+
+// now returns the current time according to the store's clock.
+func (r *RedisStore) now() time.Time {
+	if r.clock == nil {
+		return time.Now()
+	}
+
+	return r.clock.Now()
+}
@@ -0,0 +1,52 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithClock(t *testing.T) {
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	r := RedisStore{}
+	assert.WithinDuration(t, time.Now(), r.now(), time.Second)
+
+	WithClock(ClockFunc(func() time.Time { return now }))(&r)
+	assert.Equal(t, now, r.now())
+}
+
+func Test_RedisStore_Clock(t *testing.T) {
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	sKey := prefix + ":session:id123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithClock(ClockFunc(func() time.Time { return now })))
+
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": now.Add(-time.Hour).Format(time.RFC3339Nano),
+		"expires_at": now.Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+
+	ttl, err := r.TTL(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, ttl)
+
+	// sessions kept alive by TTL jitter are expired according to the
+	// clock
+	r.ttlJitter = time.Minute
+	assert.False(t, r.expired(sessionup.Session{ExpiresAt: now.Add(time.Nanosecond)}))
+	assert.True(t, r.expired(sessionup.Session{ExpiresAt: now}))
+}
@@ -0,0 +1,381 @@
+package redisstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"sort"
+	"time"
+)
+
+// Codec converts sessions into single values and back. It allows
+// sessions to be stored in a format other than a hash or JSON (see
+// WithCodec).
+type Codec interface {
+	// Encode converts the session into its binary representation.
+	Encode(d DetailedSession) ([]byte, error)
+
+	// Decode converts the binary representation of a session back
+	// into session structure.
+	Decode(b []byte) (DetailedSession, error)
+}
+
+// WithCodec determines whether each session should be stored as
+// a single value encoded by the provided codec (e.g. ProtobufCodec)
+// instead of a hash. If encryption is enabled, whole values are
+// encrypted, even if only metadata encryption is requested.
+// Stores using different formats should not share the same prefix.
+// Defaults to nil (sessions are stored as hashes or JSON values).
+func WithCodec(c Codec) setter {
+	return func(r *RedisStore) {
+		r.codec = c
+	}
+}
+
+// errMalformedProto is returned when protobuf data cannot be decoded.
+var errMalformedProto = errors.New("malformed protobuf data")
+
+// protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Session message field numbers (see session.proto).
+const (
+	protoID           = 1
+	protoUserKey      = 2
+	protoCreatedAt    = 3
+	protoExpiresAt    = 4
+	protoIP           = 5
+	protoAgentOS      = 6
+	protoAgentBrowser = 7
+	protoMeta         = 8
+	protoLastSeenAt   = 9
+	protoLabel        = 10
+	protoAbsoluteExp  = 11
+	protoIdleExp      = 12
+	protoAttributes   = 13
+	protoDeviceType   = 14
+	protoIsMobile     = 15
+	protoIsBot        = 16
+	protoLoginMethod  = 17
+	protoLoginProv    = 18
+	protoMFALevel     = 19
+)
+
+// ProtobufCodec encodes sessions as Protocol Buffers messages, as
+// described by the redisstore.v1.Session message in session.proto,
+// so that they can be read and written by services in other
+// languages. Unknown fields are skipped when decoding, which allows
+// fields to be added to the message in a backwards compatible way.
+type ProtobufCodec struct{}
+
+// Encode converts the session into a protobuf message.
+func (ProtobufCodec) Encode(d DetailedSession) ([]byte, error) {
+	var b []byte
+
+	b = appendString(b, protoID, d.ID)
+	b = appendString(b, protoUserKey, d.UserKey)
+	b = appendTime(b, protoCreatedAt, d.CreatedAt)
+	b = appendTime(b, protoExpiresAt, d.ExpiresAt)
+	b = appendString(b, protoIP, ipToString(d.IP))
+	b = appendString(b, protoAgentOS, d.Agent.OS)
+	b = appendString(b, protoAgentBrowser, d.Agent.Browser)
+
+	b = appendMap(b, protoMeta, d.Meta)
+	b = appendTime(b, protoLastSeenAt, d.LastSeenAt)
+	b = appendString(b, protoLabel, d.Label)
+	b = appendTime(b, protoAbsoluteExp, d.AbsoluteExpiresAt)
+	b = appendTime(b, protoIdleExp, d.IdleExpiresAt)
+	b = appendMap(b, protoAttributes, d.Attributes)
+	b = appendString(b, protoDeviceType, d.Device.Type)
+	b = appendBool(b, protoIsMobile, d.Device.Mobile)
+	b = appendBool(b, protoIsBot, d.Device.Bot)
+	b = appendString(b, protoLoginMethod, d.Login.Method)
+	b = appendString(b, protoLoginProv, d.Login.Provider)
+	b = appendInt(b, protoMFALevel, d.Login.MFALevel)
+
+	return b, nil
+}
+
+// Decode converts a protobuf message into session structure.
+func (ProtobufCodec) Decode(b []byte) (DetailedSession, error) {
+	var d DetailedSession
+
+	err := walkProto(b, func(num int, wire int, v uint64, data []byte) error {
+		if wire == wireVarint {
+			switch num {
+			case protoIsMobile:
+				d.Device.Mobile = v != 0
+			case protoIsBot:
+				d.Device.Bot = v != 0
+			case protoMFALevel:
+				d.Login.MFALevel = int(int32(v))
+			}
+
+			return nil
+		}
+
+		if wire != wireBytes {
+			return nil
+		}
+
+		var err error
+
+		switch num {
+		case protoID:
+			d.ID = string(data)
+		case protoUserKey:
+			d.UserKey = string(data)
+		case protoCreatedAt:
+			d.CreatedAt, err = parseProtoTime(data)
+		case protoExpiresAt:
+			d.ExpiresAt, err = parseProtoTime(data)
+		case protoIP:
+			d.IP = net.ParseIP(string(data))
+		case protoAgentOS:
+			d.Agent.OS = string(data)
+		case protoAgentBrowser:
+			d.Agent.Browser = string(data)
+		case protoMeta:
+			err = parseProtoEntry(data, &d.Meta)
+		case protoLastSeenAt:
+			d.LastSeenAt, err = parseProtoTime(data)
+		case protoLabel:
+			d.Label = string(data)
+		case protoAbsoluteExp:
+			d.AbsoluteExpiresAt, err = parseProtoTime(data)
+		case protoIdleExp:
+			d.IdleExpiresAt, err = parseProtoTime(data)
+		case protoAttributes:
+			err = parseProtoEntry(data, &d.Attributes)
+		case protoDeviceType:
+			d.Device.Type = string(data)
+		case protoLoginMethod:
+			d.Login.Method = string(data)
+		case protoLoginProv:
+			d.Login.Provider = string(data)
+		}
+
+		return err
+	})
+	if err != nil {
+		return DetailedSession{}, err
+	}
+
+	return d, nil
+}
+
+// parseProtoEntry decodes a map entry and adds it to the map,
+// creating it if needed.
+func parseProtoEntry(b []byte, m *map[string]string) error {
+	var k, v string
+
+	err := walkProto(b, func(num int, wire int, _ uint64, data []byte) error {
+		if wire != wireBytes {
+			return nil
+		}
+
+		switch num {
+		case 1:
+			k = string(data)
+		case 2:
+			v = string(data)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if *m == nil {
+		*m = make(map[string]string)
+	}
+
+	(*m)[k] = v
+
+	return nil
+}
+
+// parseProtoTime decodes a google.protobuf.Timestamp message. The
+// time is returned in UTC.
+func parseProtoTime(b []byte) (time.Time, error) {
+	var secs, nanos int64
+
+	err := walkProto(b, func(num int, wire int, v uint64, _ []byte) error {
+		if wire != wireVarint {
+			return nil
+		}
+
+		switch num {
+		case 1:
+			secs = int64(v)
+		case 2:
+			nanos = int64(int32(v))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(secs, nanos).UTC(), nil
+}
+
+// walkProto calls fn for each field of the provided protobuf message.
+// v holds the value of varint and fixed-size fields, while data holds
+// the value of length-delimited fields.
+func walkProto(b []byte, fn func(num int, wire int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 || tag>>3 == 0 || tag>>3 > math.MaxInt32 {
+			return errMalformedProto
+		}
+
+		b = b[n:]
+
+		var (
+			v    uint64
+			data []byte
+		)
+
+		switch tag & 7 {
+		case wireVarint:
+			v, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errMalformedProto
+			}
+
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errMalformedProto
+			}
+
+			v, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errMalformedProto
+			}
+
+			v, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return errMalformedProto
+			}
+
+			data, b = b[n:n+int(l)], b[n+int(l):]
+		default:
+			return errMalformedProto
+		}
+
+		if err := fn(int(tag>>3), int(tag&7), v, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appendTag appends the key of a field with the provided number and
+// wire type.
+func appendTag(b []byte, num, wire int) []byte {
+	return appendVarint(b, uint64(num)<<3|uint64(wire))
+}
+
+// appendVarint appends a base 128 varint.
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+
+	return append(b, byte(v))
+}
+
+// appendBytes appends a length-delimited value.
+func appendBytes(b, v []byte) []byte {
+	return append(appendVarint(b, uint64(len(v))), v...)
+}
+
+// appendString appends a string field, unless it is empty.
+func appendString(b []byte, num int, v string) []byte {
+	if v == "" {
+		return b
+	}
+
+	return appendBytes(appendTag(b, num, wireBytes), []byte(v))
+}
+
+// appendBool appends a bool field, unless it is false.
+func appendBool(b []byte, num int, v bool) []byte {
+	if !v {
+		return b
+	}
+
+	return appendVarint(appendTag(b, num, wireVarint), 1)
+}
+
+// appendInt appends an int32 field, unless it is 0. Negative values
+// are sign-extended, as required by the protobuf encoding.
+func appendInt(b []byte, num int, v int) []byte {
+	if v == 0 {
+		return b
+	}
+
+	return appendVarint(appendTag(b, num, wireVarint), uint64(int64(int32(v))))
+}
+
+// appendMap appends an entry of a map<string, string> field for each
+// key of the map. Entries are sorted by their keys to keep the
+// encoding deterministic.
+func appendMap(b []byte, num int, m map[string]string) []byte {
+	kk := make([]string, 0, len(m))
+	for k := range m {
+		kk = append(kk, k)
+	}
+
+	sort.Strings(kk)
+
+	for _, k := range kk {
+		var e []byte
+		e = appendTag(e, 1, wireBytes)
+		e = appendBytes(e, []byte(k))
+		e = appendTag(e, 2, wireBytes)
+		e = appendBytes(e, []byte(m[k]))
+
+		b = appendTag(b, num, wireBytes)
+		b = appendBytes(b, e)
+	}
+
+	return b
+}
+
+// appendTime appends a google.protobuf.Timestamp field, unless the
+// time is zero.
+func appendTime(b []byte, num int, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+
+	var ts []byte
+
+	if secs := t.Unix(); secs != 0 {
+		ts = appendTag(ts, 1, wireVarint)
+		ts = appendVarint(ts, uint64(secs))
+	}
+
+	if nanos := t.Nanosecond(); nanos != 0 {
+		ts = appendTag(ts, 2, wireVarint)
+		ts = appendVarint(ts, uint64(nanos))
+	}
+
+	return appendBytes(appendTag(b, num, wireBytes), ts)
+}
@@ -0,0 +1,106 @@
+package redisstore
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithCodec(t *testing.T) {
+	r := RedisStore{}
+	WithCodec(ProtobufCodec{})(&r)
+	assert.Equal(t, ProtobufCodec{}, r.codec)
+	assert.True(t, r.singleValue())
+	assert.Equal(t, "GET", r.fetchCmd())
+}
+
+func Test_ProtobufCodec(t *testing.T) {
+	d := DetailedSession{
+		Session: sessionup.Session{
+			CreatedAt: time.Date(2021, 3, 4, 5, 6, 7, 8, time.UTC),
+			ExpiresAt: time.Date(2021, 3, 5, 5, 6, 7, 0, time.UTC),
+			ID:        "id123",
+			UserKey:   "u123",
+			IP:        net.ParseIP("127.0.0.1"),
+			Meta:      map[string]string{"b": "2", "a": "1", "empty": ""},
+		},
+		LastSeenAt: time.Date(1969, 1, 1, 0, 0, 0, 5, time.UTC),
+		Label:      "work laptop",
+		Attributes: map[string]string{"country": "DE", "city": "Berlin"},
+		Device:     Device{Type: DeviceMobile, Mobile: true},
+		Login:      Login{Method: "passkey", Provider: "google", MFALevel: 2},
+	}
+	d.Agent.OS = "gnu/linux"
+	d.Agent.Browser = "firefox"
+
+	var c ProtobufCodec
+
+	b, err := c.Encode(d)
+	require.NoError(t, err)
+
+	b2, err := c.Encode(d)
+	require.NoError(t, err)
+	assert.Equal(t, b, b2)
+
+	res, err := c.Decode(b)
+	require.NoError(t, err)
+	assert.Equal(t, d, res)
+
+	// hand-encoded message: id (1) "x", unknown varint field (30),
+	// unknown fixed32 (31) and fixed64 (32) fields
+	res, err = c.Decode([]byte{
+		0x0a, 0x01, 'x',
+		0xf0, 0x01, 0x96, 0x01,
+		0xfd, 0x01, 1, 2, 3, 4,
+		0x81, 0x02, 1, 2, 3, 4, 5, 6, 7, 8,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, DetailedSession{Session: sessionup.Session{ID: "x"}}, res)
+
+	res, err = c.Decode(nil)
+	require.NoError(t, err)
+	assert.Zero(t, res)
+
+	for _, b := range [][]byte{
+		{0x0a, 0x05, 'x'},
+		{0x0b},
+		{0x00},
+		{0x08},
+		{0x1a, 0x02, 0x08, 0x80},
+		{0x85, 0x01, 1},
+	} {
+		_, err = c.Decode(b)
+		assert.Error(t, err)
+	}
+}
+
+func Test_RedisStore_encodeDetailed_codec(t *testing.T) {
+	d := DetailedSession{
+		Session: sessionup.Session{
+			CreatedAt: time.Now().UTC(),
+			ExpiresAt: time.Now().UTC().Add(time.Hour),
+			ID:        "id123",
+			UserKey:   "u123",
+			Meta:      map[string]string{"test": "1"},
+		},
+	}
+
+	for _, enc := range []*encryption{nil, newEncryption(key1)} {
+		r := RedisStore{codec: ProtobufCodec{}, enc: enc, encMetaOnly: true}
+
+		cmd, data, err := r.encodeDetailed(d)
+		require.NoError(t, err)
+		assert.Equal(t, "SET", cmd)
+		require.Len(t, data, 1)
+		assert.Equal(t, enc != nil, isSealed(data[0].([]byte)))
+
+		res, ok, err := r.decodeDetailed("id123", data[0], nil)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, d, res)
+	}
+}
@@ -0,0 +1,91 @@
+package redisstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+)
+
+// compressedPrefix marks values compressed by the store. It is
+// followed by the gzip-compressed data.
+const compressedPrefix = "\x00gz1"
+
+// WithCompression enables gzip compression of session metadata (or of
+// whole sessions, if they are stored as JSON values) whose encoded
+// size exceeds the provided threshold in bytes. Data is compressed
+// before it is encrypted. Compressed values are recognized when they
+// are read, hence compression can be enabled or disabled at any time.
+// Defaults to 0 (disabled).
+func WithCompression(threshold int) setter {
+	return func(r *RedisStore) {
+		r.compressThreshold = threshold
+	}
+}
+
+// compress compresses the provided data if compression is enabled and
+// the data exceeds the threshold. Otherwise, the data is returned as
+// is.
+func (r *RedisStore) compress(data []byte) ([]byte, error) {
+	if r.compressThreshold <= 0 || len(data) <= r.compressThreshold {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString(compressedPrefix)
+
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// compressFields compresses the value of the metadata field of the
+// provided hash field-value pairs, if needed.
+func (r *RedisStore) compressFields(ff []interface{}) error {
+	for i := 0; i+1 < len(ff); i += 2 {
+		if name, _ := ff[i].(string); name != "meta" {
+			continue
+		}
+
+		v, _ := ff[i+1].(string)
+
+		res, err := r.compress([]byte(v))
+		if err != nil {
+			return err
+		}
+
+		ff[i+1] = string(res)
+	}
+
+	return nil
+}
+
+// decompress decompresses the provided data. Data that was not
+// compressed is returned as is.
+func decompress(data []byte) ([]byte, error) {
+	if !isCompressed(data) {
+		return data, nil
+	}
+
+	rd, err := gzip.NewReader(bytes.NewReader(data[len(compressedPrefix):]))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rd.Close()
+
+	return ioutil.ReadAll(rd)
+}
+
+// isCompressed checks whether the provided data was compressed by the
+// store.
+func isCompressed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(compressedPrefix))
+}
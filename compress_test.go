@@ -0,0 +1,103 @@
+package redisstore
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithCompression(t *testing.T) {
+	r := RedisStore{}
+	WithCompression(512)(&r)
+	assert.Equal(t, 512, r.compressThreshold)
+}
+
+func Test_RedisStore_compress(t *testing.T) {
+	data := []byte(strings.Repeat("claims", 100))
+
+	r := RedisStore{}
+	res, err := r.compress(data)
+	require.NoError(t, err)
+	assert.Equal(t, data, res)
+
+	r.compressThreshold = len(data)
+	res, err = r.compress(data)
+	require.NoError(t, err)
+	assert.Equal(t, data, res)
+
+	r.compressThreshold = 10
+	res, err = r.compress(data)
+	require.NoError(t, err)
+	assert.True(t, isCompressed(res))
+	assert.Less(t, len(res), len(data))
+
+	res, err = decompress(res)
+	require.NoError(t, err)
+	assert.Equal(t, data, res)
+
+	res, err = decompress(data)
+	require.NoError(t, err)
+	assert.Equal(t, data, res)
+
+	_, err = decompress([]byte(compressedPrefix + "invalid"))
+	assert.Error(t, err)
+}
+
+func Test_RedisStore_compressFields(t *testing.T) {
+	meta := strings.Repeat("a", 100)
+	ff := []interface{}{"id", meta, "meta", meta}
+
+	r := RedisStore{compressThreshold: 10}
+	require.NoError(t, r.compressFields(ff))
+	assert.Equal(t, meta, ff[1])
+	assert.True(t, isCompressed([]byte(ff[3].(string))))
+}
+
+func Test_RedisStore_encodeDetailed_compressed(t *testing.T) {
+	s := sessionup.Session{
+		CreatedAt: time.Now().UTC().Round(0),
+		ExpiresAt: time.Now().UTC().Add(time.Hour).Round(0),
+		ID:        "id123",
+		UserKey:   "u123",
+		Meta:      map[string]string{"claims": strings.Repeat("x", 1000)},
+	}
+
+	for _, asJSON := range []bool{false, true} {
+		for _, enc := range []*encryption{nil, newEncryption(key1)} {
+			r := RedisStore{asJSON: asJSON, enc: enc, compressThreshold: 100}
+
+			_, data, err := r.encodeDetailed(DetailedSession{Session: s})
+			require.NoError(t, err)
+
+			var reply interface{}
+
+			if asJSON {
+				b, _ := data[0].([]byte)
+				assert.Less(t, len(b), 1000)
+
+				reply = b
+			} else {
+				vv := make([]interface{}, len(data))
+				for i := range data {
+					switch v := data[i].(type) {
+					case string:
+						vv[i] = []byte(v)
+					default:
+						vv[i] = v
+					}
+				}
+
+				reply = vv
+			}
+
+			res, ok, err := r.decodeDetailed("id123", reply, nil)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, s, res.Session)
+		}
+	}
+}
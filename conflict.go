@@ -0,0 +1,62 @@
+package redisstore
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ConflictStats holds the counters of WATCH/MULTI transactions aborted
+// due to concurrent modifications of their watched keys.
+type ConflictStats struct {
+	// Aborted specifies the total number of aborted transaction
+	// attempts, including the ones that were retried.
+	Aborted int64
+
+	// Surfaced specifies the total number of operations that failed
+	// with ErrTxConflict, since all of their attempts were aborted.
+	Surfaced int64
+}
+
+// WithConflictPolicy determines how aggressively transactions aborted
+// due to concurrent modifications of their watched keys are retried
+// before ErrTxConflict is returned to the caller. retries specifies
+// the number of retries after the first attempt (0 surfaces conflicts
+// immediately), while backoff specifies the delay before the first
+// retry, which doubles with each subsequent one and has a small random
+// delay added, so that bursts of conflicting operations (e.g. SSO
+// logins of the same user) are spread out.
+// It replaces the linear backoff set by WithTxRetries.
+func WithConflictPolicy(retries int, backoff time.Duration) setter {
+	return func(r *RedisStore) {
+		if retries < 0 {
+			retries = 0
+		}
+
+		r.txAttempts = retries + 1
+		r.txBackoff = backoff
+		r.txExpBackoff = true
+	}
+}
+
+// Conflicts returns the counters of aborted transactions since the
+// store was created.
+func (r *RedisStore) Conflicts() ConflictStats {
+	return ConflictStats{
+		Aborted:  atomic.LoadInt64(&r.txConflicts),
+		Surfaced: atomic.LoadInt64(&r.txSurfaced),
+	}
+}
+
+// txDelay returns the delay before the provided retry of an aborted
+// transaction.
+func (r *RedisStore) txDelay(retry int) time.Duration {
+	if !r.txExpBackoff {
+		return r.txBackoff * time.Duration(retry)
+	}
+
+	if r.txBackoff <= 0 {
+		return 0
+	}
+
+	return jitter(r.txBackoff << uint(retry-1))
+}
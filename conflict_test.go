@@ -0,0 +1,54 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithConflictPolicy(t *testing.T) {
+	r := RedisStore{}
+	WithConflictPolicy(2, time.Millisecond)(&r)
+	assert.Equal(t, 3, r.txAttempts)
+	assert.Equal(t, time.Millisecond, r.txBackoff)
+	assert.True(t, r.txExpBackoff)
+
+	WithConflictPolicy(-1, 0)(&r)
+	assert.Equal(t, 1, r.txAttempts)
+
+	WithTxRetries(2, time.Millisecond)(&r)
+	assert.False(t, r.txExpBackoff)
+}
+
+func Test_RedisStore_txDelay(t *testing.T) {
+	r := RedisStore{txBackoff: time.Millisecond * 10}
+	assert.Equal(t, time.Millisecond*30, r.txDelay(3))
+
+	r.txExpBackoff = true
+	d := r.txDelay(3)
+	assert.True(t, d >= time.Millisecond*40 && d <= time.Millisecond*44)
+
+	r.txBackoff = 0
+	assert.Zero(t, r.txDelay(3))
+}
+
+func Test_RedisStore_Conflicts(t *testing.T) {
+	r := RedisStore{}
+	WithConflictPolicy(1, 0)(&r)
+
+	assert.Equal(t, ErrTxConflict, r.retryTx(context.Background(), func() error { return ErrTxConflict }))
+
+	calls := 0
+	assert.NoError(t, r.retryTx(context.Background(), func() error {
+		calls++
+		if calls == 1 {
+			return ErrTxConflict
+		}
+
+		return nil
+	}))
+
+	assert.Equal(t, ConflictStats{Aborted: 3, Surfaced: 1}, r.Conflicts())
+}
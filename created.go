@@ -0,0 +1,203 @@
+package redisstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// WithCreatedIndex determines whether sessions should be indexed by
+// their creation times, so that they can be retrieved with
+// FetchCreatedBetween. The index is a single sorted set of session
+// keys, scored by their creation times, stored under
+// "<prefix>:created:all". Entries are removed along with their
+// sessions when these are deleted, while entries of expired sessions
+// are removed by Cleanup and whenever FetchCreatedBetween encounters
+// them.
+// Only sessions created (or renewed) after the index is enabled are
+// indexed.
+// Defaults to false.
+func WithCreatedIndex(t bool) setter {
+	return func(r *RedisStore) {
+		r.createdIndex = t
+	}
+}
+
+// FetchCreatedBetween retrieves up to limit sessions (or all of them,
+// if limit is not positive) created between the provided times, both
+// inclusive, ordered by their creation times. If none are found, both
+// return values will be nil.
+// errNoIndex is returned if the creation time index is not enabled
+// (see WithCreatedIndex).
+func (r *RedisStore) FetchCreatedBetween(ctx context.Context, from, to time.Time, limit int) (ss []sessionup.Session, err error) {
+	if !r.createdIndex {
+		return nil, errNoIndex
+	}
+
+	c, end, err := r.begin(ctx, "FetchCreatedBetween")
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { err = end(err) }()
+
+	key := r.createdKey(c)
+
+	for offset := 0; ; {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		keys, err := redis.Strings(c.Do("ZRANGEBYSCORE", key, from.UnixNano(), to.UnixNano(),
+			"LIMIT", offset, scanCount))
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := r.fetchKeys(c, keys)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = r.removeMissing(c, key, keys, found); err != nil {
+			return nil, err
+		}
+
+		ss = append(ss, found...)
+
+		if limit > 0 && len(ss) >= limit {
+			return ss[:limit], nil
+		}
+
+		if len(keys) < scanCount {
+			return ss, nil
+		}
+
+		// removed entries no longer shift the offset
+		offset += len(found)
+	}
+}
+
+// addToCreatedIndex adds the session to the creation time index, if it
+// is enabled.
+func (r *RedisStore) addToCreatedIndex(c redis.Conn, s sessionup.Session) error {
+	if !r.createdIndex {
+		return nil
+	}
+
+	_, err := c.Do("ZADD", r.createdKey(c), s.CreatedAt.UnixNano(), r.key(c, false, s.ID))
+
+	return err
+}
+
+// pruneCreatedIndex removes entries of sessions that no longer exist
+// from the creation time index.
+func (r *RedisStore) pruneCreatedIndex(ctx context.Context, c redis.Conn) error {
+	key := r.createdKey(c)
+
+	var cursor int64
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		vv, err := redis.Values(c.Do("ZSCAN", key, cursor, "COUNT", scanCount))
+		if err != nil {
+			return err
+		}
+
+		var entries []string
+		if _, err = redis.Scan(vv, &cursor, &entries); err != nil {
+			return err
+		}
+
+		// entries hold pairs of session keys and their scores
+		keys := make([]string, 0, len(entries)/2)
+		for i := 0; i < len(entries); i += 2 {
+			keys = append(keys, entries[i])
+		}
+
+		if err = r.removeExpired(c, key, keys); err != nil {
+			return err
+		}
+
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// removeMissing removes the provided session keys that were not found
+// from the creation time index.
+func (r *RedisStore) removeMissing(c redis.Conn, key string, keys []string, found []sessionup.Session) error {
+	if len(found) == len(keys) {
+		return nil
+	}
+
+	live := make(map[string]struct{}, len(found))
+	for _, s := range found {
+		live[r.key(c, false, s.ID)] = struct{}{}
+	}
+
+	args := redis.Args{}.Add(key)
+
+	for _, k := range keys {
+		if _, ok := live[k]; !ok {
+			args = args.Add(k)
+		}
+	}
+
+	_, err := c.Do("ZREM", args...)
+
+	return err
+}
+
+// removeExpired checks which of the provided session keys no longer
+// exist and removes them from the creation time index.
+func (r *RedisStore) removeExpired(c redis.Conn, key string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// pipeline all checks so that they are done in a single round
+	// trip
+	for i := range keys {
+		if err := c.Send("EXISTS", keys[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Flush(); err != nil {
+		return err
+	}
+
+	args := redis.Args{}.Add(key)
+
+	for i := range keys {
+		ok, err := redis.Bool(c.Receive())
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			args = args.Add(keys[i])
+		}
+	}
+
+	if len(args) == 1 {
+		return nil
+	}
+
+	_, err := c.Do("ZREM", args...)
+
+	return err
+}
+
+// createdKey returns the key of the creation time index, scoped to the
+// tenant of the connection.
+func (r *RedisStore) createdKey(c redis.Conn) string {
+	return r.buildKey(connTenant(c), "created", "all")
+}
@@ -0,0 +1,92 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithCreatedIndex(t *testing.T) {
+	r := RedisStore{}
+	WithCreatedIndex(true)(&r)
+	assert.True(t, r.createdIndex)
+	assert.Equal(t, []string{"created"}, r.indexNamespaces())
+}
+
+func Test_RedisStore_FetchCreatedBetween(t *testing.T) {
+	key := prefix + ":created:all"
+	sKey1 := prefix + ":session:id1"
+	sKey2 := prefix + ":session:id2"
+	from := time.Now().Add(-time.Hour)
+	to := time.Now()
+
+	_, err := New(&redis.Pool{}, prefix).FetchCreatedBetween(context.Background(), from, to, 0)
+	assert.Equal(t, errNoIndex, err)
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithCreatedIndex(true))
+
+	conn.Command("ZRANGEBYSCORE", key, from.UnixNano(), to.UnixNano(), "LIMIT", 0, scanCount).
+		ExpectStringSlice(sKey1, sKey2)
+	conn.Command("HGETALL", sKey1).ExpectMap(map[string]string{
+		"created_at": from.Format(time.RFC3339Nano),
+		"expires_at": to.Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id1",
+		"user_key":   "u123",
+	})
+	conn.Command("HGETALL", sKey2).ExpectError(redis.ErrNil)
+	zrem := conn.Command("ZREM", key, sKey2).Expect(int64(1))
+
+	ss, err := r.FetchCreatedBetween(context.Background(), from, to, 0)
+	require.NoError(t, err)
+	require.Len(t, ss, 1)
+	assert.Equal(t, "id1", ss[0].ID)
+	assert.Equal(t, 1, conn.Stats(zrem))
+}
+
+func Test_RedisStore_addToCreatedIndex(t *testing.T) {
+	now := time.Now()
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{}, prefix)
+
+	require.NoError(t, r.addToCreatedIndex(conn, sessionup.Session{ID: "id1", CreatedAt: now}))
+
+	r.createdIndex = true
+	zadd := conn.Command("ZADD", prefix+":created:all", now.UnixNano(), prefix+":session:id1").Expect(int64(1))
+	require.NoError(t, r.addToCreatedIndex(conn, sessionup.Session{ID: "id1", CreatedAt: now}))
+	assert.Equal(t, 1, conn.Stats(zadd))
+}
+
+func Test_RedisStore_Cleanup_createdIndex(t *testing.T) {
+	key := prefix + ":created:all"
+	sKey1 := prefix + ":session:id1"
+	sKey2 := prefix + ":session:id2"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithCreatedIndex(true))
+
+	conn.Command("ZSCAN", key, int64(0), "COUNT", scanCount).
+		ExpectSlice([]byte("0"), []interface{}{[]byte(sKey1), []byte("1"), []byte(sKey2), []byte("2")})
+	conn.Command("EXISTS", sKey1).Expect(int64(1))
+	conn.Command("EXISTS", sKey2).Expect(int64(0))
+	zrem := conn.Command("ZREM", key, sKey2).Expect(int64(1))
+	conn.Command("SCAN", int64(0), "MATCH", prefix+":user:*", "COUNT", scanCount).
+		ExpectSlice([]byte("0"), []interface{}{})
+
+	require.NoError(t, r.Cleanup(context.Background()))
+	assert.Equal(t, 1, conn.Stats(zrem))
+}
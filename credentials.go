@@ -0,0 +1,45 @@
+package redisstore
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// WithCredentialsProvider sets the function that provides the username
+// (might be empty) and password used to authenticate each new
+// connection established by stores that manage their own connections
+// (see NewFromURL). It is called on each dial, which allows
+// short-lived credentials (e.g. Vault leases or ElastiCache IAM
+// tokens) to be rotated without recreating the store; connections
+// that are already authenticated are not affected.
+// An error returned by the function is returned by the operation that
+// required the new connection.
+// It has no effect on stores that use the provided pool or dialer.
+// Defaults to nil (the credentials in the URL are used).
+func WithCredentialsProvider(fn func(ctx context.Context) (user, pass string, err error)) setter {
+	return func(r *RedisStore) {
+		r.credentials = fn
+	}
+}
+
+// dialOptions returns the options used to establish new connections
+// by stores that manage their own connections.
+func (r *RedisStore) dialOptions(ctx context.Context) ([]redis.DialOption, error) {
+	var opts []redis.DialOption
+
+	if r.tlsConfig != nil {
+		opts = append(opts, redis.DialTLSConfig(r.tlsConfig))
+	}
+
+	if r.credentials != nil {
+		user, pass, err := r.credentials(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, redis.DialUsername(user), redis.DialPassword(pass))
+	}
+
+	return opts, nil
+}
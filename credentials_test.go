@@ -0,0 +1,132 @@
+package redisstore
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithCredentialsProvider(t *testing.T) {
+	r := RedisStore{}
+	WithCredentialsProvider(func(context.Context) (string, string, error) {
+		return "user", "pass", nil
+	})(&r)
+	assert.NotNil(t, r.credentials)
+}
+
+func Test_RedisStore_dialOptions(t *testing.T) {
+	r := RedisStore{}
+	opts, err := r.dialOptions(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, opts)
+
+	WithServerName("redis.local")(&r)
+	opts, err = r.dialOptions(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, opts, 1)
+
+	WithCredentialsProvider(func(context.Context) (string, string, error) {
+		return "", "", assert.AnError
+	})(&r)
+	_, err = r.dialOptions(context.Background())
+	assert.Equal(t, assert.AnError, err)
+
+	WithCredentialsProvider(func(context.Context) (string, string, error) {
+		return "user", "pass", nil
+	})(&r)
+	opts, err = r.dialOptions(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, opts, 3)
+}
+
+func Test_NewFromURL_credentials(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer ln.Close()
+
+	auths := make(chan string, 2)
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			args, err := readCommand(bufio.NewReader(c))
+			if err == nil {
+				auths <- strings.Join(args, " ")
+			}
+
+			fmt.Fprint(c, "+OK\r\n")
+			c.Close()
+		}
+	}()
+
+	var calls int
+
+	r, err := NewFromURL("redis://old:secret@"+ln.Addr().String(), prefix,
+		WithCredentialsProvider(func(context.Context) (string, string, error) {
+			calls++
+			return "user", "token" + strconv.Itoa(calls), nil
+		}),
+	)
+	require.NoError(t, err)
+
+	for i := 1; i <= 2; i++ {
+		c, err := r.pool.(*redis.Pool).DialContext(context.Background())
+		require.NoError(t, err)
+		c.Close()
+
+		assert.Equal(t, "AUTH user token"+strconv.Itoa(i), <-auths)
+	}
+
+	r, err = NewFromURL("redis://"+ln.Addr().String(), prefix,
+		WithCredentialsProvider(func(context.Context) (string, string, error) {
+			return "", "", assert.AnError
+		}),
+	)
+	require.NoError(t, err)
+
+	_, err = r.pool.(*redis.Pool).DialContext(context.Background())
+	assert.Equal(t, assert.AnError, err)
+}
+
+// readCommand reads a single command sent by a client.
+func readCommand(br *bufio.Reader) ([]string, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+
+	for i := range args {
+		if _, err = br.ReadString('\n'); err != nil {
+			return nil, err
+		}
+
+		arg, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		args[i] = strings.TrimSpace(arg)
+	}
+
+	return args, nil
+}
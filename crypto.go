@@ -0,0 +1,280 @@
+package redisstore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// sealedPrefix marks values encrypted by the store. It is followed
+// by the key ID, nonce and the ciphertext itself.
+const sealedPrefix = "\x00enc1"
+
+// keyIDLen specifies the length of the key ID stored alongside
+// each encrypted value.
+const keyIDLen = 4
+
+// hashLen specifies the length (in bytes) of keyed hashes of values
+// that cannot be encrypted.
+const hashLen = 16
+
+var (
+	// errNoKeys is returned when encrypted data is found but no
+	// encryption keys are configured.
+	errNoKeys = errors.New("encrypted data found but encryption is not enabled")
+
+	// errUnknownKey is returned when encrypted data references a key
+	// that is not configured.
+	errUnknownKey = errors.New("encrypted data references an unknown key")
+
+	// errMalformedData is returned when encrypted data is too short
+	// to be valid.
+	errMalformedData = errors.New("malformed encrypted data")
+
+	// errForeignData is returned when encrypted data read for a session
+	// belongs to another session.
+	errForeignData = errors.New("encrypted data belongs to another session")
+)
+
+// encryption holds the AES-GCM ciphers used to encrypt and decrypt
+// session data.
+type encryption struct {
+	current  []byte
+	aeads    map[string]cipher.AEAD
+	hashKeys [][]byte
+	err      error
+}
+
+// WithEncryption enables AES-GCM encryption of session data at rest.
+// key is used to encrypt all new data, while oldKeys are only used to
+// decrypt data written before a key rotation. Each encrypted value
+// carries the ID of its key, so that keys can be rotated without
+// invalidating existing sessions. Encrypted values are bound to the
+// IDs of their sessions, hence they cannot be copied into other
+// sessions. All keys must be 16, 24 or 32 bytes long; if they are not,
+// all operations that read or write session data will fail.
+// Personal data that must remain comparable is replaced by keyed
+// hashes (HMAC-SHA256) derived from the keys instead: values of
+// enabled secondary indexes in key names (see WithIPIndex,
+// WithAgentIndex and WithIndexedMeta), user keys and IP addresses in
+// audit entries (see WithAudit) and user keys in published
+// invalidation messages (see WithInvalidations). Values hashed before
+// a key rotation are still found until they expire. Note that user
+// keys remain exposed in the key names of user session sets.
+// By default encryption is disabled.
+func WithEncryption(key []byte, oldKeys ...[]byte) setter {
+	return func(r *RedisStore) {
+		r.enc = newEncryption(key, oldKeys...)
+	}
+}
+
+// WithMetaEncryptionOnly determines whether only session metadata
+// should be encrypted when encryption is enabled.
+// Defaults to false.
+func WithMetaEncryptionOnly(m bool) setter {
+	return func(r *RedisStore) {
+		r.encMetaOnly = m
+	}
+}
+
+// newEncryption creates a new encryption instance with the provided
+// keys.
+func newEncryption(key []byte, oldKeys ...[]byte) *encryption {
+	e := &encryption{
+		current: keyID(key),
+		aeads:   make(map[string]cipher.AEAD),
+	}
+
+	for _, k := range append([][]byte{key}, oldKeys...) {
+		e.hashKeys = append(e.hashKeys, hashKey(k))
+
+		b, err := aes.NewCipher(k)
+		if err != nil {
+			e.err = err
+			return e
+		}
+
+		aead, err := cipher.NewGCM(b)
+		if err != nil {
+			e.err = err
+			return e
+		}
+
+		e.aeads[string(keyID(k))] = aead
+	}
+
+	return e
+}
+
+// seal encrypts the provided data of the session with the provided ID
+// with the current key. aad (e.g. the name of a hash field) and the
+// session ID are authenticated along with the data and must match
+// when opening it, so that encrypted values cannot be moved between
+// fields or sessions.
+func (e *encryption) seal(aad, id string, data []byte) ([]byte, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	aead := e.aeads[string(e.current)]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, len(sealedPrefix)+keyIDLen+len(nonce)+len(data)+aead.Overhead())
+	res = append(res, sealedPrefix...)
+	res = append(res, e.current...)
+	res = append(res, nonce...)
+
+	return aead.Seal(res, nonce, data, boundAAD(aad, id)), nil
+}
+
+// open decrypts the provided data of the session with the provided ID.
+// Data that was not encrypted is returned as is. It is safe to call
+// this method on nil encryption.
+func (e *encryption) open(aad, id string, data []byte) ([]byte, error) {
+	if !isSealed(data) {
+		return data, nil
+	}
+
+	if e == nil {
+		return nil, errNoKeys
+	}
+
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	data = data[len(sealedPrefix):]
+	if len(data) < keyIDLen {
+		return nil, errMalformedData
+	}
+
+	aead, ok := e.aeads[string(data[:keyIDLen])]
+	if !ok {
+		return nil, errUnknownKey
+	}
+
+	data = data[keyIDLen:]
+	if len(data) < aead.NonceSize() {
+		return nil, errMalformedData
+	}
+
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], boundAAD(aad, id))
+}
+
+// hash returns the keyed hash of the provided value, computed with
+// the current key, so that it can be stored and compared without being
+// revealed. Empty values, as well as all values if encryption is not
+// enabled (i.e. e is nil), are returned as is.
+func (e *encryption) hash(v string) string {
+	if e == nil || v == "" {
+		return v
+	}
+
+	return hashWith(e.hashKeys[0], v)
+}
+
+// hashes returns the keyed hashes of the provided value computed with
+// all keys, starting with the current one, so that values hashed
+// before a key rotation can be found as well. It is safe to call this
+// method on nil encryption.
+func (e *encryption) hashes(v string) []string {
+	if e == nil || v == "" {
+		return []string{v}
+	}
+
+	hh := make([]string, 0, len(e.hashKeys))
+	for _, k := range e.hashKeys {
+		hh = append(hh, hashWith(k, v))
+	}
+
+	return uniqueIDs(hh)
+}
+
+// sealFields encrypts the values of the provided hash field-value
+// pairs of the session with the provided ID. If metaOnly is true, only
+// the metadata field is encrypted.
+func (e *encryption) sealFields(ff []interface{}, id string, metaOnly bool) error {
+	for i := 0; i+1 < len(ff); i += 2 {
+		name, _ := ff[i].(string)
+		if metaOnly && name != "meta" {
+			continue
+		}
+
+		v, _ := ff[i+1].(string)
+
+		res, err := e.seal(name, id, []byte(v))
+		if err != nil {
+			return err
+		}
+
+		ff[i+1] = res
+	}
+
+	return nil
+}
+
+// openFields decrypts all encrypted values of the provided hash
+// fields map of the session with the provided ID in place. It is safe
+// to call this method on nil encryption.
+func (e *encryption) openFields(vv map[string]string, id string) error {
+	for k, v := range vv {
+		if !isSealed([]byte(v)) {
+			continue
+		}
+
+		res, err := e.open(k, id, []byte(v))
+		if err != nil {
+			return err
+		}
+
+		vv[k] = string(res)
+	}
+
+	return nil
+}
+
+// isSealed checks whether the provided data was encrypted by the
+// store.
+func isSealed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(sealedPrefix))
+}
+
+// boundAAD binds the provided additional data to the session ID.
+// The additional data never contains a colon, hence the result is
+// unambiguous.
+func boundAAD(aad, id string) []byte {
+	return []byte(aad + ":" + id)
+}
+
+// hashKey derives the key of keyed hashes from the provided encryption
+// key, so that the latter is used for encryption only.
+func hashKey(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("redisstore hash"))
+
+	return mac.Sum(nil)
+}
+
+// hashWith returns the hex encoded keyed hash of the provided value.
+func hashWith(key []byte, v string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(v))
+
+	return hex.EncodeToString(mac.Sum(nil)[:hashLen])
+}
+
+// keyID derives a short ID of the provided key.
+func keyID(key []byte) []byte {
+	sum := sha256.Sum256(key)
+	return sum[:keyIDLen]
+}
@@ -0,0 +1,335 @@
+package redisstore
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+var (
+	key1 = bytes.Repeat([]byte{1}, 32)
+	key2 = bytes.Repeat([]byte{2}, 16)
+)
+
+func Test_WithEncryption(t *testing.T) {
+	r := RedisStore{}
+	WithEncryption(key1, key2)(&r)
+	require.NotNil(t, r.enc)
+	assert.NoError(t, r.enc.err)
+	assert.Equal(t, keyID(key1), r.enc.current)
+	assert.Len(t, r.enc.aeads, 2)
+}
+
+func Test_WithMetaEncryptionOnly(t *testing.T) {
+	r := RedisStore{}
+	WithMetaEncryptionOnly(true)(&r)
+	assert.True(t, r.encMetaOnly)
+}
+
+func Test_newEncryption(t *testing.T) {
+	e := newEncryption([]byte("short"))
+	assert.Error(t, e.err)
+
+	e = newEncryption(key1, []byte("short"))
+	assert.Error(t, e.err)
+
+	e = newEncryption(key1, key2)
+	assert.NoError(t, e.err)
+	assert.Contains(t, e.aeads, string(keyID(key1)))
+	assert.Contains(t, e.aeads, string(keyID(key2)))
+}
+
+func Test_encryption_seal(t *testing.T) {
+	e := newEncryption([]byte("short"))
+	res, err := e.seal("field", "id123", []byte("data"))
+	assert.Error(t, err)
+	assert.Nil(t, res)
+
+	e = newEncryption(key1)
+	res, err = e.seal("field", "id123", []byte("data"))
+	require.NoError(t, err)
+	assert.True(t, isSealed(res))
+	assert.NotContains(t, string(res), "data")
+
+	res2, err := e.seal("field", "id123", []byte("data"))
+	require.NoError(t, err)
+	assert.NotEqual(t, res, res2)
+}
+
+func Test_encryption_open(t *testing.T) {
+	old := newEncryption(key2)
+	sealedOld, err := old.seal("field", "id123", []byte("data"))
+	require.NoError(t, err)
+
+	e := newEncryption(key1, key2)
+	sealed, err := e.seal("field", "id123", []byte("data"))
+	require.NoError(t, err)
+
+	// values that are not bound to a session ID are rejected
+	aead := e.aeads[string(e.current)]
+	nonce := make([]byte, aead.NonceSize())
+	unbound := append([]byte(sealedPrefix), e.current...)
+	unbound = append(unbound, nonce...)
+	unbound = aead.Seal(unbound, nonce, []byte("data"), []byte("field"))
+
+	cc := map[string]struct {
+		Enc    *encryption
+		AAD    string
+		ID     string
+		Data   []byte
+		Result []byte
+		Err    error
+	}{
+		"Plain data": {
+			AAD:    "field",
+			ID:     "id123",
+			Data:   []byte("data"),
+			Result: []byte("data"),
+		},
+		"Encryption disabled": {
+			AAD:  "field",
+			ID:   "id123",
+			Data: sealed,
+			Err:  errNoKeys,
+		},
+		"Invalid keys": {
+			Enc:  newEncryption([]byte("short")),
+			AAD:  "field",
+			ID:   "id123",
+			Data: sealed,
+			Err:  assert.AnError,
+		},
+		"Missing key ID": {
+			Enc:  e,
+			AAD:  "field",
+			ID:   "id123",
+			Data: []byte(sealedPrefix + "12"),
+			Err:  errMalformedData,
+		},
+		"Unknown key": {
+			Enc:  newEncryption(key2),
+			AAD:  "field",
+			ID:   "id123",
+			Data: sealed,
+			Err:  errUnknownKey,
+		},
+		"Missing nonce": {
+			Enc:  e,
+			AAD:  "field",
+			ID:   "id123",
+			Data: sealed[:len(sealedPrefix)+keyIDLen+2],
+			Err:  errMalformedData,
+		},
+		"Mismatched additional data": {
+			Enc:  e,
+			AAD:  "other",
+			ID:   "id123",
+			Data: sealed,
+			Err:  assert.AnError,
+		},
+		"Mismatched session ID": {
+			Enc:  e,
+			AAD:  "field",
+			ID:   "id124",
+			Data: sealed,
+			Err:  assert.AnError,
+		},
+		"Data not bound to session ID": {
+			Enc:  e,
+			AAD:  "field",
+			ID:   "id123",
+			Data: unbound,
+			Err:  assert.AnError,
+		},
+		"Successful decryption with old key": {
+			Enc:    e,
+			AAD:    "field",
+			ID:     "id123",
+			Data:   sealedOld,
+			Result: []byte("data"),
+		},
+		"Successful decryption": {
+			Enc:    e,
+			AAD:    "field",
+			ID:     "id123",
+			Data:   sealed,
+			Result: []byte("data"),
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := c.Enc.open(c.AAD, c.ID, c.Data)
+			if c.Err != nil {
+				if c.Err == assert.AnError {
+					assert.Error(t, err)
+				} else {
+					assert.Equal(t, c.Err, err)
+				}
+
+				assert.Nil(t, res)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.Result, res)
+		})
+	}
+}
+
+func Test_encryption_hash(t *testing.T) {
+	var e *encryption
+	assert.Equal(t, "u123", e.hash("u123"))
+	assert.Equal(t, []string{"u123"}, e.hashes("u123"))
+
+	e = newEncryption(key1)
+	assert.Equal(t, "", e.hash(""))
+	assert.Len(t, e.hash("u123"), hashLen*2)
+	assert.NotEqual(t, "u123", e.hash("u123"))
+	assert.Equal(t, e.hash("u123"), newEncryption(key1).hash("u123"))
+	assert.NotEqual(t, e.hash("u123"), e.hash("u124"))
+	assert.NotEqual(t, e.hash("u123"), newEncryption(key2).hash("u123"))
+
+	// values hashed before a key rotation can still be matched.
+	e = newEncryption(key2, key1)
+	assert.Equal(t, []string{
+		newEncryption(key2).hash("u123"),
+		newEncryption(key1).hash("u123"),
+	}, e.hashes("u123"))
+	assert.Len(t, newEncryption(key1, key1).hashes("u123"), 1)
+}
+
+func Test_encryption_sealFields(t *testing.T) {
+	e := newEncryption([]byte("short"))
+	assert.Error(t, e.sealFields([]interface{}{"ip", "127.0.0.1"}, "id123", false))
+
+	e = newEncryption(key1)
+
+	ff := []interface{}{"ip", "127.0.0.1", "meta", "a=1"}
+	require.NoError(t, e.sealFields(ff, "id123", true))
+	assert.Equal(t, "127.0.0.1", ff[1])
+	assert.True(t, isSealed(ff[3].([]byte)))
+
+	ff = []interface{}{"ip", "127.0.0.1", "meta", "a=1"}
+	require.NoError(t, e.sealFields(ff, "id123", false))
+	assert.True(t, isSealed(ff[1].([]byte)))
+	assert.True(t, isSealed(ff[3].([]byte)))
+}
+
+func Test_encryption_openFields(t *testing.T) {
+	e := newEncryption(key1)
+
+	sealed, err := e.seal("ip", "id123", []byte("127.0.0.1"))
+	require.NoError(t, err)
+
+	var nilEnc *encryption
+	assert.Equal(t, errNoKeys, nilEnc.openFields(map[string]string{"ip": string(sealed)}, "id123"))
+	assert.Error(t, e.openFields(map[string]string{"meta": string(sealed)}, "id123"))
+	assert.Error(t, e.openFields(map[string]string{"ip": string(sealed)}, "id124"))
+
+	vv := map[string]string{"ip": string(sealed), "id": "id123"}
+	require.NoError(t, e.openFields(vv, "id123"))
+	assert.Equal(t, map[string]string{"ip": "127.0.0.1", "id": "id123"}, vv)
+}
+
+func Test_isSealed(t *testing.T) {
+	assert.False(t, isSealed([]byte("data")))
+	assert.True(t, isSealed([]byte(sealedPrefix+"data")))
+}
+
+func Test_keyID(t *testing.T) {
+	assert.Len(t, keyID(key1), keyIDLen)
+	assert.Equal(t, keyID(key1), keyID(key1))
+	assert.NotEqual(t, keyID(key1), keyID(key2))
+}
+
+func Test_RedisStore_encode_decode_encrypted(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+		CreatedAt: time.Now().UTC().Round(0),
+		IP:        net.ParseIP("127.0.0.1"),
+		Meta:      map[string]string{"test": "1"},
+	}
+	inp.Agent.OS = "gnu/linux"
+	inp.Agent.Browser = "firefox"
+
+	cc := map[string]struct {
+		JSON     bool
+		MetaOnly bool
+	}{
+		"Hash": {},
+		"Hash with metadata only": {
+			MetaOnly: true,
+		},
+		"JSON": {
+			JSON: true,
+		},
+		"JSON with metadata only": {
+			JSON:     true,
+			MetaOnly: true,
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			r := RedisStore{
+				asJSON:      c.JSON,
+				enc:         newEncryption(key1),
+				encMetaOnly: c.MetaOnly,
+			}
+
+			_, data, err := r.encode(inp)
+			require.NoError(t, err)
+
+			// convert written values into the form returned by redis
+			vv := make([]interface{}, len(data))
+			for i := range data {
+				if v, ok := data[i].(string); ok {
+					vv[i] = []byte(v)
+					continue
+				}
+
+				vv[i] = data[i]
+			}
+
+			var raw interface{} = vv
+			if c.JSON {
+				raw = vv[0]
+			}
+
+			for i := range vv {
+				assert.NotContains(t, string(vv[i].([]byte)), "test=1")
+			}
+
+			s, ok, err := r.decode("id123", raw, nil)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, inp, s)
+
+			// data copied from another session must be rejected
+			_, _, err = r.decode("id124", raw, nil)
+			assert.True(t, errors.Is(err, ErrEncryption))
+
+			r.enc = nil
+			_, _, err = r.decode("id123", raw, nil)
+			assert.True(t, errors.Is(err, ErrEncryption))
+			assert.True(t, errors.Is(err, errNoKeys))
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/swithek/sessionup"
+)
+
+// DegradedStore is a sessionup.Store that keeps serving recently seen
+// sessions while the store it wraps (usually RedisStore) is
+// unavailable, e.g. during a brief Redis restart. Sessions retrieved
+// by ID are kept in a bounded in-memory cache for a short time and
+// are returned by FetchByID if the wrapped store fails with
+// ErrConnection or ErrUnavailable. Deletions that fail for the same
+// reasons are queued and replayed, in order, before the next
+// operation once the wrapped store is available again.
+// The cache is read-only: Create and FetchByUserKey are not served by
+// it.
+type DegradedStore struct {
+	store sessionup.Store
+	cache *localCache
+
+	mu         sync.Mutex
+	pending    []func(context.Context) error
+	maxPending int
+}
+
+// NewDegraded returns a fresh instance of DegradedStore that wraps
+// the provided store. size specifies the maximum number of cached
+// sessions, as well as the maximum number of queued deletions, while
+// ttl specifies the maximum time a session is served from the cache
+// after it was last retrieved from the wrapped store.
+func NewDegraded(store sessionup.Store, size int, ttl time.Duration) *DegradedStore {
+	cache := newLocalCache(size, ttl)
+	cache.setSynced(true)
+
+	return &DegradedStore{
+		store:      store,
+		cache:      cache,
+		maxPending: size,
+	}
+}
+
+// Create inserts the provided session into the wrapped store and, if
+// that succeeds, into the cache.
+func (d *DegradedStore) Create(ctx context.Context, s sessionup.Session) error {
+	if err := d.replay(ctx); err != nil {
+		return err
+	}
+
+	if err := d.store.Create(ctx, s); err != nil {
+		return err
+	}
+
+	d.cache.add("", s)
+
+	return nil
+}
+
+// FetchByID retrieves a session by the provided ID from the wrapped
+// store, caching the result. The cached session is returned if the
+// wrapped store is unavailable.
+func (d *DegradedStore) FetchByID(ctx context.Context, id string) (sessionup.Session, bool, error) {
+	err := d.replay(ctx)
+	if err == nil {
+		var (
+			s  sessionup.Session
+			ok bool
+		)
+
+		s, ok, err = d.store.FetchByID(ctx, id)
+		if err == nil {
+			if ok {
+				d.cache.add("", s)
+			} else {
+				d.cache.invalidate(Invalidation{ID: id})
+			}
+
+			return s, ok, nil
+		}
+	}
+
+	if !unavailable(err) {
+		return sessionup.Session{}, false, err
+	}
+
+	if s, ok := d.cache.get("", id); ok {
+		return s, true, nil
+	}
+
+	return sessionup.Session{}, false, err
+}
+
+// FetchByUserKey retrieves all sessions associated with the provided
+// user key from the wrapped store.
+func (d *DegradedStore) FetchByUserKey(ctx context.Context, key string) ([]sessionup.Session, error) {
+	if err := d.replay(ctx); err != nil {
+		return nil, err
+	}
+
+	return d.store.FetchByUserKey(ctx, key)
+}
+
+// DeleteByID deletes the session by the provided ID from the cache
+// and the wrapped store. If the wrapped store is unavailable, the
+// deletion is queued for replay and nil is returned, unless the queue
+// is full.
+func (d *DegradedStore) DeleteByID(ctx context.Context, id string) error {
+	d.cache.invalidate(Invalidation{ID: id})
+
+	return d.delete(ctx, func(ctx context.Context) error {
+		return d.store.DeleteByID(ctx, id)
+	})
+}
+
+// DeleteByUserKey deletes all sessions associated with the provided
+// user key, except those whose IDs are provided as the last argument,
+// from the cache and the wrapped store. If the wrapped store is
+// unavailable, the deletion is queued in the same way as in
+// DeleteByID.
+func (d *DegradedStore) DeleteByUserKey(ctx context.Context, key string, expIDs ...string) error {
+	d.cache.invalidate(Invalidation{UserKey: key, Except: expIDs})
+
+	return d.delete(ctx, func(ctx context.Context) error {
+		return d.store.DeleteByUserKey(ctx, key, expIDs...)
+	})
+}
+
+// Pending returns the number of deletions waiting to be replayed.
+func (d *DegradedStore) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.pending)
+}
+
+// delete executes the deletion after all queued ones, queueing it if
+// the wrapped store is unavailable.
+func (d *DegradedStore) delete(ctx context.Context, fn func(context.Context) error) error {
+	err := d.replay(ctx)
+	if err == nil {
+		err = fn(ctx)
+	}
+
+	if !unavailable(err) {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.pending) >= d.maxPending {
+		return err
+	}
+
+	d.pending = append(d.pending, fn)
+
+	return nil
+}
+
+// replay executes the queued deletions in order, stopping at the
+// first one that fails because the wrapped store is unavailable; it
+// and the rest are kept in the queue. Deletions that fail for other
+// reasons are dropped.
+func (d *DegradedStore) replay(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for len(d.pending) > 0 {
+		if err := d.pending[0](ctx); unavailable(err) {
+			return err
+		}
+
+		d.pending[0] = nil
+		d.pending = d.pending[1:]
+	}
+
+	d.pending = nil
+
+	return nil
+}
+
+// unavailable checks whether the error was returned because Redis
+// could not be reached.
+func unavailable(err error) bool {
+	return errors.Is(err, ErrConnection) || errors.Is(err, ErrUnavailable)
+}
@@ -0,0 +1,90 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_NewDegraded(t *testing.T) {
+	s := storeMock{name: "s", calls: &[]string{}}
+
+	d := NewDegraded(s, 2, time.Minute)
+	assert.Equal(t, s, d.store)
+	require.NotNil(t, d.cache)
+	assert.True(t, d.cache.synced)
+	assert.Equal(t, 2, d.cache.size)
+	assert.Equal(t, time.Minute, d.cache.ttl)
+	assert.Equal(t, 2, d.maxPending)
+}
+
+func Test_unavailable(t *testing.T) {
+	assert.False(t, unavailable(nil))
+	assert.False(t, unavailable(assert.AnError))
+	assert.False(t, unavailable(&Error{Kind: ErrCommand, Err: assert.AnError}))
+	assert.True(t, unavailable(&Error{Kind: ErrConnection, Err: assert.AnError}))
+	assert.True(t, unavailable(&Error{Kind: ErrUnavailable, Err: ErrUnavailable}))
+}
+
+func Test_DegradedStore(t *testing.T) {
+	connErr := &Error{Op: "op", Kind: ErrConnection, Err: assert.AnError}
+	ctx := context.Background()
+
+	var calls []string
+
+	s := &storeMock{name: "s", calls: &calls}
+	d := NewDegraded(s, 2, time.Minute)
+
+	// sessions are served from the cache while the store is
+	// unavailable.
+	res, ok, err := d.FetchByID(ctx, "id1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	s.err = connErr
+
+	cached, ok, err := d.FetchByID(ctx, "id1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, res, cached)
+
+	_, ok, err = d.FetchByID(ctx, "id2")
+	assert.Equal(t, connErr, err)
+	assert.False(t, ok)
+
+	_, err = d.FetchByUserKey(ctx, "s")
+	assert.Equal(t, connErr, err)
+
+	assert.Equal(t, connErr, d.Create(ctx, sessionup.Session{ID: "id2"}))
+
+	// deletions are queued until the queue is full.
+	require.NoError(t, d.DeleteByID(ctx, "id1"))
+	require.NoError(t, d.DeleteByUserKey(ctx, "s"))
+	assert.Equal(t, connErr, d.DeleteByID(ctx, "id3"))
+	assert.Equal(t, 2, d.Pending())
+
+	_, ok, err = d.FetchByID(ctx, "id1")
+	assert.Equal(t, connErr, err)
+	assert.False(t, ok)
+
+	// queued deletions are replayed once the store is available.
+	s.err = nil
+	calls = nil
+
+	_, err = d.FetchByUserKey(ctx, "s")
+	require.NoError(t, err)
+	assert.Zero(t, d.Pending())
+	assert.Equal(t, []string{"s.DeleteByID", "s.DeleteByUserKey", "s.FetchByUserKey"}, calls)
+
+	// deletions that fail for other reasons are not queued.
+	s.err = assert.AnError
+	assert.Equal(t, assert.AnError, d.DeleteByID(ctx, "id1"))
+	assert.Zero(t, d.Pending())
+
+	_, _, err = d.FetchByID(ctx, "id1")
+	assert.Equal(t, assert.AnError, err)
+}
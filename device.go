@@ -0,0 +1,133 @@
+package redisstore
+
+import (
+	"context"
+	"strings"
+
+	"github.com/swithek/sessionup"
+)
+
+// Device types determined by ClassifyUserAgent.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// Device describes the device a session was created on, in addition
+// to the OS and browser stored by sessionup.
+type Device struct {
+	// Type specifies the type of the device (e.g. "desktop",
+	// "mobile" or "tablet"). It is empty if it is unknown.
+	Type string
+
+	// Mobile specifies whether the device is a phone or a tablet.
+	Mobile bool
+
+	// Bot specifies whether the session was created by an automated
+	// client, e.g. a crawler.
+	Bot bool
+}
+
+// WithDeviceClassifier sets the function that describes the device
+// each session created by Create was created on, usually based on the
+// request held by the context (see NewRequestContext). The device is
+// stored along with the session ("device_type", "is_mobile" and
+// "is_bot" fields of its hash) and is available as
+// DetailedSession.Device. UserAgentClassifier can be used if no
+// dedicated device detection library is needed.
+// Defaults to nil (no device data).
+func WithDeviceClassifier(fn func(ctx context.Context, s sessionup.Session) Device) setter {
+	return func(r *RedisStore) {
+		r.classifier = fn
+	}
+}
+
+// UserAgentClassifier is a device classifier that describes the device
+// by the User-Agent header of the request held by the context (see
+// NewRequestContext) with ClassifyUserAgent. The device is zero if the
+// context holds no request.
+func UserAgentClassifier(ctx context.Context, _ sessionup.Session) Device {
+	req, ok := RequestFromContext(ctx)
+	if !ok {
+		return Device{}
+	}
+
+	return ClassifyUserAgent(req.Header.Get("User-Agent"))
+}
+
+// ClassifyUserAgent describes the device by its User-Agent header
+// value, using common substrings of the values sent by bots, tablets
+// and phones. Devices that match none of them are treated as desktops.
+// The device is zero if the value is empty.
+func ClassifyUserAgent(ua string) Device {
+	if ua == "" {
+		return Device{}
+	}
+
+	ua = strings.ToLower(ua)
+
+	switch {
+	case containsAny(ua, "bot", "crawler", "spider", "slurp", "curl/", "wget/", "python-requests"):
+		return Device{Type: DeviceBot, Bot: true}
+	case containsAny(ua, "ipad", "tablet") || (strings.Contains(ua, "android") && !strings.Contains(ua, "mobile")):
+		return Device{Type: DeviceTablet, Mobile: true}
+	case containsAny(ua, "mobi", "iphone", "ipod", "android"):
+		return Device{Type: DeviceMobile, Mobile: true}
+	}
+
+	return Device{Type: DeviceDesktop}
+}
+
+// containsAny checks whether the string contains any of the provided
+// substrings.
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// classify describes the device the session was created on, if the
+// device classifier is set.
+func (r *RedisStore) classify(ctx context.Context, s sessionup.Session) Device {
+	if r.classifier == nil {
+		return Device{}
+	}
+
+	return r.classifier(ctx, s)
+}
+
+// deviceFields converts the device into field-value pairs of the
+// session hash. Unset fields are omitted.
+func deviceFields(dev Device) []interface{} {
+	var ff []interface{}
+
+	if dev.Type != "" {
+		ff = append(ff, "device_type", dev.Type)
+	}
+
+	if dev.Mobile {
+		ff = append(ff, "is_mobile", "1")
+	}
+
+	if dev.Bot {
+		ff = append(ff, "is_bot", "1")
+	}
+
+	return ff
+}
+
+// deviceFromFields extracts the device from the fields of the session
+// hash.
+func deviceFromFields(vv map[string]string) Device {
+	return Device{
+		Type:   vv["device_type"],
+		Mobile: vv["is_mobile"] == "1",
+		Bot:    vv["is_bot"] == "1",
+	}
+}
@@ -0,0 +1,90 @@
+package redisstore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithDeviceClassifier(t *testing.T) {
+	r := RedisStore{}
+	WithDeviceClassifier(UserAgentClassifier)(&r)
+	assert.NotNil(t, r.classifier)
+}
+
+func Test_ClassifyUserAgent(t *testing.T) {
+	cc := map[string]Device{
+		"": {},
+		"Mozilla/5.0 (X11; Linux x86_64; rv:91.0) Gecko/20100101 Firefox/91.0":     {Type: DeviceDesktop},
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)": {Type: DeviceBot, Bot: true},
+		"curl/7.68.0": {Type: DeviceBot, Bot: true},
+		"Mozilla/5.0 (iPad; CPU OS 14_7 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1.2 Safari/604.1": {Type: DeviceTablet, Mobile: true},
+		"Mozilla/5.0 (Linux; Android 11; SM-T870) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/93.0.4577.62 Safari/537.36":  {Type: DeviceTablet, Mobile: true},
+		"Mozilla/5.0 (Linux; Android 11; Pixel 5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/93.0 Mobile Safari/537.36":   {Type: DeviceMobile, Mobile: true},
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 14_7 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148":      {Type: DeviceMobile, Mobile: true},
+	}
+
+	for ua, dev := range cc {
+		assert.Equal(t, dev, ClassifyUserAgent(ua), ua)
+	}
+}
+
+func Test_UserAgentClassifier(t *testing.T) {
+	assert.Zero(t, UserAgentClassifier(context.Background(), sessionup.Session{}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "curl/7.68.0")
+
+	assert.Equal(t, Device{Type: DeviceBot, Bot: true}, UserAgentClassifier(NewRequestContext(context.Background(), req), sessionup.Session{}))
+}
+
+func Test_deviceFields(t *testing.T) {
+	assert.Empty(t, deviceFields(Device{}))
+	assert.Zero(t, deviceFromFields(map[string]string{}))
+
+	dev := Device{Type: DeviceTablet, Mobile: true, Bot: true}
+	ff := deviceFields(dev)
+	assert.Equal(t, []interface{}{"device_type", "tablet", "is_mobile", "1", "is_bot", "1"}, ff)
+
+	vv := make(map[string]string)
+	for i := 0; i < len(ff); i += 2 {
+		vv[ff[i].(string)] = ff[i+1].(string)
+	}
+
+	assert.Equal(t, dev, deviceFromFields(vv))
+}
+
+func Test_RedisStore_Create_device(t *testing.T) {
+	var args []interface{}
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithDeviceClassifier(func(context.Context, sessionup.Session) Device {
+		return Device{Type: DeviceDesktop}
+	}))
+
+	conn.GenericCommand("EVALSHA").Handle(func(aa []interface{}) (interface{}, error) {
+		args = aa
+		return int64(1), nil
+	})
+
+	require.NoError(t, r.Create(context.Background(), sessionup.Session{
+		ID:        "id123",
+		UserKey:   "u123",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}))
+	assert.Contains(t, args, "device_type")
+	assert.Contains(t, args, DeviceDesktop)
+	assert.NotContains(t, args, "is_mobile")
+}
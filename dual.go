@@ -0,0 +1,99 @@
+package redisstore
+
+import (
+	"context"
+
+	"github.com/swithek/sessionup"
+)
+
+// ReadSource determines which store of DualWriteStore serves reads.
+type ReadSource int
+
+const (
+	// Primary makes DualWriteStore read from its primary store.
+	Primary ReadSource = iota
+
+	// Secondary makes DualWriteStore read from its secondary store.
+	Secondary
+)
+
+// DualWriteStore is a sessionup.Store that writes sessions to two
+// stores and reads them from one of them. It is meant to be used
+// while migrating sessions from one store to another: new sessions
+// are written to both stores while reads are served by the old one,
+// until they can be safely switched to the new one.
+type DualWriteStore struct {
+	primary   sessionup.Store
+	secondary sessionup.Store
+	readFrom  ReadSource
+}
+
+// NewDualWrite returns a fresh instance of DualWriteStore.
+// readFrom parameter determines the store that is used by
+// FetchByID and FetchByUserKey.
+func NewDualWrite(primary, secondary sessionup.Store, readFrom ReadSource) *DualWriteStore {
+	return &DualWriteStore{
+		primary:   primary,
+		secondary: secondary,
+		readFrom:  readFrom,
+	}
+}
+
+// Create inserts the provided session into the primary store and,
+// if that succeeds, into the secondary store.
+func (d *DualWriteStore) Create(ctx context.Context, s sessionup.Session) error {
+	if err := d.primary.Create(ctx, s); err != nil {
+		return err
+	}
+
+	return d.secondary.Create(ctx, s)
+}
+
+// FetchByID retrieves a session by the provided ID from the store
+// selected for reads.
+func (d *DualWriteStore) FetchByID(ctx context.Context, id string) (sessionup.Session, bool, error) {
+	return d.reader().FetchByID(ctx, id)
+}
+
+// FetchByUserKey retrieves all sessions associated with the provided
+// user key from the store selected for reads.
+func (d *DualWriteStore) FetchByUserKey(ctx context.Context, key string) ([]sessionup.Session, error) {
+	return d.reader().FetchByUserKey(ctx, key)
+}
+
+// DeleteByID deletes the session by the provided ID from both stores.
+// The secondary store is updated even if the primary one fails, so
+// that deleted sessions do not outlive the migration; the first
+// error is returned.
+func (d *DualWriteStore) DeleteByID(ctx context.Context, id string) error {
+	err := d.primary.DeleteByID(ctx, id)
+
+	if serr := d.secondary.DeleteByID(ctx, id); err == nil {
+		err = serr
+	}
+
+	return err
+}
+
+// DeleteByUserKey deletes all sessions associated with the provided
+// user key, except those whose IDs are provided as the last argument,
+// from both stores. The secondary store is updated even if the
+// primary one fails; the first error is returned.
+func (d *DualWriteStore) DeleteByUserKey(ctx context.Context, key string, expIDs ...string) error {
+	err := d.primary.DeleteByUserKey(ctx, key, expIDs...)
+
+	if serr := d.secondary.DeleteByUserKey(ctx, key, expIDs...); err == nil {
+		err = serr
+	}
+
+	return err
+}
+
+// reader returns the store selected for reads.
+func (d *DualWriteStore) reader() sessionup.Store {
+	if d.readFrom == Secondary {
+		return d.secondary
+	}
+
+	return d.primary
+}
@@ -0,0 +1,153 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+// storeMock is a sessionup.Store that records the called methods and
+// returns the configured error.
+type storeMock struct {
+	name  string
+	err   error
+	calls *[]string
+}
+
+func (s storeMock) record(method string) error {
+	*s.calls = append(*s.calls, s.name+"."+method)
+	return s.err
+}
+
+func (s storeMock) Create(context.Context, sessionup.Session) error {
+	return s.record("Create")
+}
+
+func (s storeMock) FetchByID(_ context.Context, id string) (sessionup.Session, bool, error) {
+	if err := s.record("FetchByID"); err != nil {
+		return sessionup.Session{}, false, err
+	}
+
+	return sessionup.Session{ID: id, UserKey: s.name, ExpiresAt: time.Now().Add(time.Hour)}, true, nil
+}
+
+func (s storeMock) FetchByUserKey(_ context.Context, key string) ([]sessionup.Session, error) {
+	if err := s.record("FetchByUserKey"); err != nil {
+		return nil, err
+	}
+
+	return []sessionup.Session{{UserKey: key, ID: s.name}}, nil
+}
+
+func (s storeMock) DeleteByID(context.Context, string) error {
+	return s.record("DeleteByID")
+}
+
+func (s storeMock) DeleteByUserKey(context.Context, string, ...string) error {
+	return s.record("DeleteByUserKey")
+}
+
+func Test_NewDualWrite(t *testing.T) {
+	var calls []string
+
+	p := storeMock{name: "p", calls: &calls}
+	s := storeMock{name: "s", calls: &calls}
+
+	d := NewDualWrite(p, s, Secondary)
+	assert.Equal(t, p, d.primary)
+	assert.Equal(t, s, d.secondary)
+	assert.Equal(t, Secondary, d.readFrom)
+}
+
+func Test_DualWriteStore_Create(t *testing.T) {
+	var calls []string
+
+	d := NewDualWrite(
+		storeMock{name: "p", err: assert.AnError, calls: &calls},
+		storeMock{name: "s", calls: &calls},
+		Primary,
+	)
+
+	assert.Equal(t, assert.AnError, d.Create(context.Background(), sessionup.Session{}))
+	assert.Equal(t, []string{"p.Create"}, calls)
+
+	calls = nil
+	d.primary = storeMock{name: "p", calls: &calls}
+	d.secondary = storeMock{name: "s", err: assert.AnError, calls: &calls}
+
+	assert.Equal(t, assert.AnError, d.Create(context.Background(), sessionup.Session{}))
+	assert.Equal(t, []string{"p.Create", "s.Create"}, calls)
+}
+
+func Test_DualWriteStore_FetchByID(t *testing.T) {
+	var calls []string
+
+	d := NewDualWrite(storeMock{name: "p", calls: &calls}, storeMock{name: "s", calls: &calls}, Primary)
+
+	s, ok, err := d.FetchByID(context.Background(), "id1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "p", s.UserKey)
+
+	d.readFrom = Secondary
+
+	s, ok, err = d.FetchByID(context.Background(), "id1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "s", s.UserKey)
+	assert.Equal(t, []string{"p.FetchByID", "s.FetchByID"}, calls)
+}
+
+func Test_DualWriteStore_FetchByUserKey(t *testing.T) {
+	var calls []string
+
+	d := NewDualWrite(storeMock{name: "p", calls: &calls}, storeMock{name: "s", calls: &calls}, Secondary)
+
+	ss, err := d.FetchByUserKey(context.Background(), "u1")
+	require.NoError(t, err)
+	assert.Equal(t, []sessionup.Session{{UserKey: "u1", ID: "s"}}, ss)
+	assert.Equal(t, []string{"s.FetchByUserKey"}, calls)
+}
+
+func Test_DualWriteStore_DeleteByID(t *testing.T) {
+	var calls []string
+
+	d := NewDualWrite(
+		storeMock{name: "p", err: assert.AnError, calls: &calls},
+		storeMock{name: "s", calls: &calls},
+		Primary,
+	)
+
+	assert.Equal(t, assert.AnError, d.DeleteByID(context.Background(), "id1"))
+	assert.Equal(t, []string{"p.DeleteByID", "s.DeleteByID"}, calls)
+
+	calls = nil
+	d.primary = storeMock{name: "p", calls: &calls}
+	d.secondary = storeMock{name: "s", err: assert.AnError, calls: &calls}
+
+	assert.Equal(t, assert.AnError, d.DeleteByID(context.Background(), "id1"))
+	assert.Equal(t, []string{"p.DeleteByID", "s.DeleteByID"}, calls)
+}
+
+func Test_DualWriteStore_DeleteByUserKey(t *testing.T) {
+	var calls []string
+
+	d := NewDualWrite(
+		storeMock{name: "p", err: assert.AnError, calls: &calls},
+		storeMock{name: "s", calls: &calls},
+		Primary,
+	)
+
+	assert.Equal(t, assert.AnError, d.DeleteByUserKey(context.Background(), "u1", "id1"))
+	assert.Equal(t, []string{"p.DeleteByUserKey", "s.DeleteByUserKey"}, calls)
+
+	calls = nil
+	d.primary = storeMock{name: "p", calls: &calls}
+
+	assert.NoError(t, d.DeleteByUserKey(context.Background(), "u1"))
+	assert.Equal(t, []string{"p.DeleteByUserKey", "s.DeleteByUserKey"}, calls)
+}
@@ -0,0 +1,100 @@
+package redisstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/swithek/sessionup"
+)
+
+// attrPrefix is the prefix of hash fields that hold session
+// attributes.
+const attrPrefix = "attr_"
+
+// Enricher derives additional data from sessions being created, e.g.
+// the country, city and ASN of their IP addresses, so that it can be
+// displayed along with the rest of session data (e.g. "Chrome on
+// Linux from Berlin").
+type Enricher interface {
+	// Enrich returns the attributes of the session. It is called by
+	// Create before the session is inserted and should not block for
+	// long, as it delays the whole operation. Attributes that cannot
+	// be determined should be omitted; enrichment is best-effort and
+	// cannot reject the session (see Hooks.BeforeCreate).
+	Enrich(ctx context.Context, s sessionup.Session) map[string]string
+}
+
+// EnricherFunc is an adapter that allows ordinary functions to be used
+// as enrichers.
+type EnricherFunc func(ctx context.Context, s sessionup.Session) map[string]string
+
+// Enrich calls fn(ctx, s).
+func (fn EnricherFunc) Enrich(ctx context.Context, s sessionup.Session) map[string]string {
+	return fn(ctx, s)
+}
+
+// WithEnricher sets the enricher that derives attributes of each
+// session created by Create. The attributes are stored along with the
+// session (as "attr_<name>" fields of its hash) and are available as
+// DetailedSession.Attributes.
+// Defaults to nil (no attributes).
+func WithEnricher(e Enricher) setter {
+	return func(r *RedisStore) {
+		r.enricher = e
+	}
+}
+
+// enrich returns the attributes of the session derived by the
+// enricher, if it is set.
+func (r *RedisStore) enrich(ctx context.Context, s sessionup.Session) map[string]string {
+	if r.enricher == nil {
+		return nil
+	}
+
+	aa := r.enricher.Enrich(ctx, s)
+	if len(aa) == 0 {
+		return nil
+	}
+
+	return aa
+}
+
+// attributeFields converts session attributes into field-value pairs
+// of the session hash, sorted by attribute names.
+func attributeFields(aa map[string]string) []interface{} {
+	kk := make([]string, 0, len(aa))
+	for k := range aa {
+		kk = append(kk, k)
+	}
+
+	sort.Strings(kk)
+
+	ff := make([]interface{}, 0, len(aa)*2)
+
+	for _, k := range kk {
+		ff = append(ff, attrPrefix+k, aa[k])
+	}
+
+	return ff
+}
+
+// attributesFromFields extracts session attributes from the fields of
+// the session hash.
+func attributesFromFields(vv map[string]string) map[string]string {
+	var aa map[string]string
+
+	for k, v := range vv {
+		if !strings.HasPrefix(k, attrPrefix) {
+			continue
+		}
+
+		if aa == nil {
+			aa = make(map[string]string)
+		}
+
+		aa[strings.TrimPrefix(k, attrPrefix)] = v
+	}
+
+	return aa
+}
@@ -0,0 +1,104 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithEnricher(t *testing.T) {
+	r := RedisStore{}
+	WithEnricher(EnricherFunc(func(context.Context, sessionup.Session) map[string]string {
+		return nil
+	}))(&r)
+	assert.NotNil(t, r.enricher)
+}
+
+func Test_RedisStore_enrich(t *testing.T) {
+	r := RedisStore{}
+	assert.Nil(t, r.enrich(context.Background(), sessionup.Session{}))
+
+	r.enricher = EnricherFunc(func(_ context.Context, s sessionup.Session) map[string]string {
+		if s.ID == "" {
+			return map[string]string{}
+		}
+
+		return map[string]string{"country": "DE"}
+	})
+
+	assert.Nil(t, r.enrich(context.Background(), sessionup.Session{}))
+	assert.Equal(t, map[string]string{"country": "DE"}, r.enrich(context.Background(), sessionup.Session{ID: "id123"}))
+}
+
+func Test_attributeFields(t *testing.T) {
+	assert.Empty(t, attributeFields(nil))
+	assert.Equal(t, []interface{}{"attr_country", "DE"}, attributeFields(map[string]string{"country": "DE"}))
+	assert.Equal(t, []interface{}{"attr_asn", "1", "attr_city", "Berlin", "attr_country", "DE"},
+		attributeFields(map[string]string{"country": "DE", "city": "Berlin", "asn": "1"}))
+
+	assert.Nil(t, attributesFromFields(map[string]string{"id": "id123"}))
+	assert.Equal(t, map[string]string{"country": "DE", "asn": "3320"}, attributesFromFields(map[string]string{
+		"id":           "id123",
+		"attr_country": "DE",
+		"attr_asn":     "3320",
+	}))
+}
+
+func Test_RedisStore_decodeDetailed_attributes(t *testing.T) {
+	r := RedisStore{}
+
+	d, ok, err := r.decodeDetailed("id123", []interface{}{
+		[]byte("created_at"), []byte(time.Now().Format(time.RFC3339Nano)),
+		[]byte("expires_at"), []byte(time.Now().Add(time.Hour).Format(time.RFC3339Nano)),
+		[]byte("id"), []byte("id123"),
+		[]byte("user_key"), []byte("u123"),
+		[]byte("attr_city"), []byte("Berlin"),
+	}, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"city": "Berlin"}, d.Attributes)
+
+	WithJSON(true)(&r)
+
+	_, data, err := r.encodeDetailed(d)
+	require.NoError(t, err)
+
+	res, ok, err := r.decodeDetailed("id123", data[0], nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, d.Attributes, res.Attributes)
+}
+
+func Test_RedisStore_Create_enricher(t *testing.T) {
+	var args []interface{}
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithEnricher(EnricherFunc(func(_ context.Context, s sessionup.Session) map[string]string {
+		return map[string]string{"ip": s.IP.String()}
+	})))
+
+	conn.GenericCommand("EVALSHA").Handle(func(aa []interface{}) (interface{}, error) {
+		args = aa
+		return int64(1), nil
+	})
+
+	s := sessionup.Session{
+		ID:        "id123",
+		UserKey:   "u123",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	require.NoError(t, r.Create(context.Background(), s))
+	assert.Contains(t, args, "attr_ip")
+	assert.Contains(t, args, "<nil>")
+}
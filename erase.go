@@ -0,0 +1,268 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// ErasureReport describes the data removed by EraseUser.
+type ErasureReport struct {
+	// Sessions is the number of deleted sessions.
+	Sessions int
+
+	// Payloads is the number of deleted session payloads (see
+	// SetPayload).
+	Payloads int
+
+	// IndexEntries is the number of entries removed from secondary
+	// indexes (e.g. the IP, metadata or creation time indexes).
+	IndexEntries int
+
+	// AuditEntries is the number of entries removed from the audit
+	// stream (see WithAudit).
+	AuditEntries int
+}
+
+// EraseUser deletes all data of the user associated with the provided
+// key, e.g. to fulfil data-subject deletion requests: the user's
+// sessions, their payloads, the user session set, the entries of the
+// sessions in secondary indexes and the audit stream entries that
+// reference the user or its sessions. The returned report describes
+// what was removed.
+// Index entries of sessions that have already expired cannot be
+// determined and are removed lazily, as usual, while the user remains
+// counted by ActiveUsers until its buckets expire. The erasure itself is
+// not recorded in the audit stream.
+// If invalidations are enabled, an invalidation message is published
+// once the sessions are deleted.
+// errNoIndex is returned if user session sets are disabled (see
+// WithoutUserIndex).
+func (r *RedisStore) EraseUser(ctx context.Context, key string) (rep ErasureReport, err error) {
+	if r.noUserIndex {
+		return ErasureReport{}, errNoIndex
+	}
+
+	defer func() { r.afterDelete(ctx, sessionup.Session{UserKey: key}, err) }()
+
+	c, end, err := r.begin(ctx, "EraseUser", userKeyAttr(key))
+	if err != nil {
+		return ErasureReport{}, err
+	}
+
+	defer func() { err = end(err) }()
+
+	var ids []string
+
+	err = r.retryTx(ctx, func() error {
+		var err error
+		rep, ids, err = r.eraseUserTx(c, key)
+
+		return err
+	})
+	if err != nil {
+		return ErasureReport{}, err
+	}
+
+	if err = r.invalidate(c, Invalidation{UserKey: key}); err != nil {
+		return rep, err
+	}
+
+	if rep.AuditEntries, err = r.eraseAudit(ctx, c, key, ids); err != nil {
+		return rep, err
+	}
+
+	return rep, r.waitReplicas(c)
+}
+
+// eraseUserTx deletes the sessions of the user, along with their
+// payloads, index entries and the user session set, by using
+// a WATCH/MULTI transaction. IDs of the deleted sessions are returned
+// as well.
+func (r *RedisStore) eraseUserTx(c redis.Conn, key string) (ErasureReport, []string, error) {
+	uKey := r.key(c, true, key)
+
+	if _, err := c.Do("WATCH", uKey); err != nil {
+		return ErasureReport{}, nil, err
+	}
+
+	keys, err := redis.Strings(c.Do("ZRANGE", uKey, 0, -1))
+	if err != nil && !errors.Is(err, redis.ErrNil) {
+		return ErasureReport{}, nil, err
+	}
+
+	if len(keys) > 0 {
+		if _, err = c.Do("WATCH", redis.Args{}.AddFlat(keys)...); err != nil {
+			return ErasureReport{}, nil, err
+		}
+	}
+
+	ss, err := r.fetchKeys(c, keys)
+	if err != nil {
+		return ErasureReport{}, nil, err
+	}
+
+	var (
+		rep  ErasureReport
+		cmds [][]interface{}
+		// counters point to the report fields that the replies of
+		// the respective commands are added to
+		counters []*int
+		ids      = make([]string, 0, len(ss))
+	)
+
+	add := func(n *int, cmd ...interface{}) {
+		cmds = append(cmds, cmd)
+		counters = append(counters, n)
+	}
+
+	for _, s := range ss {
+		sKey := r.key(c, false, s.ID)
+		ids = append(ids, s.ID)
+
+		for _, k := range r.indexKeys(c, s, true) {
+			add(&rep.IndexEntries, "ZREM", k, sKey)
+		}
+
+		if r.createdIndex {
+			add(&rep.IndexEntries, "ZREM", r.createdKey(c), sKey)
+		}
+
+		add(&rep.Payloads, "DEL", r.payloadKey(c, s.ID))
+	}
+
+	// keys of sessions that could not be fetched are deleted as well,
+	// in case they expired in the meantime
+	if len(keys) > 0 {
+		add(&rep.Sessions, append([]interface{}{"DEL"}, redis.Args{}.AddFlat(keys)...)...)
+	}
+
+	add(nil, "DEL", uKey)
+
+	if _, err = c.Do("MULTI"); err != nil {
+		return ErasureReport{}, nil, err
+	}
+
+	for _, cmd := range cmds {
+		if _, err = c.Do(cmd[0].(string), cmd[1:]...); err != nil {
+			return ErasureReport{}, nil, err
+		}
+	}
+
+	vv, err := redis.Values(c.Do("EXEC"))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			err = ErrTxConflict
+		}
+
+		return ErasureReport{}, nil, err
+	}
+
+	for i := range vv {
+		if i >= len(counters) || counters[i] == nil {
+			continue
+		}
+
+		n, err := redis.Int(vv[i], nil)
+		if err != nil {
+			return ErasureReport{}, nil, err
+		}
+
+		*counters[i] += n
+	}
+
+	return rep, ids, nil
+}
+
+// eraseAudit removes the entries of the audit stream that reference
+// the user or any of the provided session IDs and returns their
+// number. Only entries of the connection's tenant are removed.
+func (r *RedisStore) eraseAudit(ctx context.Context, c redis.Conn, key string, ids []string) (int, error) {
+	stream := r.prefixed(r.prefix, "audit")
+	tenant := connTenant(c)
+
+	hashes := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		hashes[hashValue(id)] = struct{}{}
+	}
+
+	// user keys are hashed if encryption is enabled (see audit)
+	userKeys := make(map[string]struct{})
+	for _, k := range r.enc.hashes(key) {
+		userKeys[k] = struct{}{}
+	}
+
+	var n int
+
+	// the starting entry is included in the range, so it is skipped
+	// on every page but the first
+	for start := "-"; ; {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+
+		ee, err := redis.Values(c.Do("XRANGE", stream, start, "+", "COUNT", scanCount))
+		if err != nil && !errors.Is(err, redis.ErrNil) {
+			return n, err
+		}
+
+		args := redis.Args{}.Add(stream)
+
+		for i := range ee {
+			e, err := redis.Values(ee[i], nil)
+			if err != nil || len(e) != 2 {
+				return n, withKind(ErrParse, errors.New("invalid audit entry"))
+			}
+
+			id, err := redis.String(e[0], nil)
+			if err != nil {
+				return n, err
+			}
+
+			if id == start {
+				continue
+			}
+
+			ff, err := redis.StringMap(e[1], nil)
+			if err != nil {
+				return n, err
+			}
+
+			if ff["tenant"] != tenant {
+				continue
+			}
+
+			_, keyOK := userKeys[ff["user_key"]]
+			_, idOK := hashes[ff["id_hash"]]
+			_, oldIDOK := hashes[ff["old_id_hash"]]
+
+			if keyOK || idOK || oldIDOK {
+				args = args.Add(id)
+			}
+		}
+
+		if len(args) > 1 {
+			v, err := redis.Int(c.Do("XDEL", args...))
+			if err != nil {
+				return n, err
+			}
+
+			n += v
+		}
+
+		if len(ee) < scanCount {
+			return n, nil
+		}
+
+		last, err := redis.Values(ee[len(ee)-1], nil)
+		if err != nil {
+			return n, err
+		}
+
+		if start, err = redis.String(last[0], nil); err != nil {
+			return n, err
+		}
+	}
+}
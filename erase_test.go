@@ -0,0 +1,151 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_RedisStore_EraseUser(t *testing.T) {
+	uKey := prefix + ":user:u123"
+	sKey1 := prefix + ":session:id1"
+	sKey2 := prefix + ":session:id2"
+	ipKey := prefix + ":ip:10.0.0.1"
+	createdKey := prefix + ":created:all"
+	stream := prefix + ":audit"
+
+	var deleted sessionup.Session
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithHooks(Hooks{
+		AfterDelete: func(_ context.Context, s sessionup.Session, _ error) {
+			deleted = s
+		},
+	}))
+
+	r.noUserIndex = true
+	_, err := r.EraseUser(context.Background(), "u123")
+	assert.Equal(t, errNoIndex, err)
+
+	r.noUserIndex = false
+	r.ipIndex = true
+	r.createdIndex = true
+
+	conn.Command("WATCH", uKey).ExpectError(assert.AnError)
+	_, err = r.EraseUser(context.Background(), "u123")
+	assert.True(t, errors.Is(err, assert.AnError))
+	assert.Equal(t, "u123", deleted.UserKey)
+
+	conn.Clear()
+	conn.Command("WATCH", uKey).Expect("OK")
+	conn.Command("ZRANGE", uKey, 0, -1).ExpectSlice([]byte(sKey1), []byte(sKey2))
+	conn.Command("WATCH", sKey1, sKey2).Expect("OK")
+	conn.Command("HGETALL", sKey1).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id1",
+		"user_key":   "u123",
+		"ip":         "10.0.0.1",
+	})
+	conn.Command("HGETALL", sKey2).ExpectMap(map[string]string{})
+	conn.Command("MULTI").Expect("OK")
+	conn.Command("ZREM", ipKey, sKey1).Expect("QUEUED")
+	conn.Command("ZREM", createdKey, sKey1).Expect("QUEUED")
+	conn.Command("DEL", prefix+":payload:id1").Expect("QUEUED")
+	conn.Command("DEL", sKey1, sKey2).Expect("QUEUED")
+	conn.Command("DEL", uKey).Expect("QUEUED")
+	conn.Command("EXEC").ExpectSlice(int64(1), int64(1), int64(1), int64(1), int64(1))
+	conn.Command("XRANGE", stream, "-", "+", "COUNT", scanCount).ExpectSlice(
+		[]interface{}{[]byte("1-0"), []interface{}{
+			[]byte("action"), []byte(AuditCreated), []byte("user_key"), []byte("u123"),
+		}},
+		[]interface{}{[]byte("2-0"), []interface{}{
+			[]byte("action"), []byte(AuditRevoked), []byte("id_hash"), []byte(hashValue("id1")),
+		}},
+		[]interface{}{[]byte("3-0"), []interface{}{
+			[]byte("action"), []byte(AuditCreated), []byte("user_key"), []byte("u456"),
+		}},
+		[]interface{}{[]byte("4-0"), []interface{}{
+			[]byte("action"), []byte(AuditCreated), []byte("user_key"), []byte("u123"), []byte("tenant"), []byte("t1"),
+		}},
+	)
+	xdel := conn.Command("XDEL", stream, "1-0", "2-0").Expect(int64(2))
+
+	rep, err := r.EraseUser(context.Background(), "u123")
+	require.NoError(t, err)
+	assert.Equal(t, ErasureReport{Sessions: 1, Payloads: 1, IndexEntries: 2, AuditEntries: 2}, rep)
+	assert.Equal(t, 1, conn.Stats(xdel))
+
+	// conflicting transactions are retried
+	conn.Command("EXEC").ExpectError(redis.ErrNil)
+	_, err = r.EraseUser(context.Background(), "u123")
+	assert.True(t, errors.Is(err, ErrTxConflict))
+}
+
+func Test_RedisStore_eraseAudit(t *testing.T) {
+	stream := prefix + ":audit"
+
+	entry := func(id, userKey string) []interface{} {
+		return []interface{}{[]byte(id), []interface{}{[]byte("user_key"), []byte(userKey)}}
+	}
+
+	conn := redigomock.NewConn()
+	r := RedisStore{prefix: prefix}
+
+	conn.Command("XRANGE", stream, "-", "+", "COUNT", scanCount).ExpectError(assert.AnError)
+	_, err := r.eraseAudit(context.Background(), conn, "u123", nil)
+	assert.Error(t, err)
+
+	conn.Clear()
+	conn.Command("XRANGE", stream, "-", "+", "COUNT", scanCount).ExpectSlice([]byte("invalid"))
+	_, err = r.eraseAudit(context.Background(), conn, "u123", nil)
+	assert.True(t, errors.Is(err, ErrParse))
+
+	// pages start with the last entry of the previous page
+	first := make([]interface{}, scanCount)
+	for i := range first {
+		first[i] = entry(time.Duration(i+1).String(), "u456")
+	}
+
+	first[0] = entry("1ns", "u123")
+
+	conn.Clear()
+	conn.Command("XRANGE", stream, "-", "+", "COUNT", scanCount).ExpectSlice(first...)
+	conn.Command("XDEL", stream, "1ns").Expect(int64(1))
+	conn.Command("XRANGE", stream, "100ns", "+", "COUNT", scanCount).ExpectSlice(
+		entry("100ns", "u456"),
+		entry("101ns", "u123"),
+	)
+	conn.Command("XDEL", stream, "101ns").Expect(int64(1))
+
+	n, err := r.eraseAudit(context.Background(), conn, "u123", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	// user keys hashed with old encryption keys are matched as well.
+	r.enc = newEncryption(key2, key1)
+
+	conn.Clear()
+	conn.Command("XRANGE", stream, "-", "+", "COUNT", scanCount).ExpectSlice(
+		entry("1ns", r.enc.hash("u123")),
+		entry("2ns", newEncryption(key1).hash("u123")),
+		entry("3ns", "u123"),
+	)
+	conn.Command("XDEL", stream, "1ns", "2ns").Expect(int64(2))
+
+	n, err = r.eraseAudit(context.Background(), conn, "u123", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
@@ -0,0 +1,226 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+var (
+	// ErrConnection is returned when a connection could not be
+	// retrieved from the pool or the connection to Redis failed
+	// while executing a command.
+	ErrConnection = errors.New("connection error")
+
+	// ErrCommand is returned when Redis rejects a command.
+	ErrCommand = errors.New("command error")
+
+	// ErrParse is returned when session data could not be encoded
+	// or decoded.
+	ErrParse = errors.New("invalid session data")
+
+	// ErrEncryption is returned when session data could not be
+	// encrypted or decrypted.
+	ErrEncryption = errors.New("encryption error")
+
+	// ErrNotSupported is returned when Redis does not support
+	// (or does not allow) a command used by the store.
+	ErrNotSupported = errors.New("command not supported")
+
+	// ErrTxConflict is returned when a transaction keeps being aborted
+	// because its watched keys were modified concurrently and no more
+	// retry attempts are left.
+	ErrTxConflict = errors.New("transaction aborted due to a concurrent modification")
+
+	// ErrClosed is returned when an operation is attempted on
+	// a closed store.
+	ErrClosed = errors.New("store is closed")
+
+	// ErrMaxSessions is returned when a new session is rejected because
+	// its user already has the maximum number of sessions.
+	ErrMaxSessions = errors.New("maximum number of user sessions reached")
+
+	// ErrWriteConcern is returned when a write is not acknowledged
+	// by the required number of replicas in time.
+	ErrWriteConcern = errors.New("write not acknowledged by enough replicas")
+
+	// ErrUnavailable is returned when an operation is rejected
+	// without being attempted because the circuit breaker is tripped.
+	ErrUnavailable = errors.New("redis is unavailable")
+
+	// ErrMetaTooLarge is returned when session metadata exceeds the
+	// configured size or entry limit.
+	ErrMetaTooLarge = errors.New("session metadata too large")
+
+	// ErrInvalidSession is returned when a session is rejected because
+	// some of its required fields are not set, or when the new ID
+	// provided to RenewID is empty or unchanged.
+	ErrInvalidSession = errors.New("invalid session")
+
+	// ErrNoSession is returned by deletions and TTL when they did not
+	// find the session, if not found errors are enabled (see
+	// WithNotFoundErrors).
+	ErrNoSession = errors.New("session not found")
+
+	// ErrSuspiciousSession is returned when a retrieved session is
+	// rejected by a fetch validator (see WithFetchValidator).
+	ErrSuspiciousSession = errors.New("suspicious session")
+
+	// ErrInternal is returned when an operation fails due to an
+	// unexpected error, such as a reply of an unexpected type.
+	ErrInternal = errors.New("internal error")
+)
+
+// Error describes a failed store operation.
+// It can be matched against its kind (ErrConnection, ErrCommand,
+// ErrParse, ErrEncryption, ErrNotSupported, ErrTxConflict, ErrClosed,
+// ErrMaxSessions, ErrWriteConcern, ErrUnavailable, ErrMetaTooLarge,
+// ErrInvalidSession, ErrNoSession, ErrSuspiciousSession or ErrInternal)
+// as well as the underlying error with errors.Is.
+type Error struct {
+	// Op specifies the name of the failed operation.
+	Op string
+
+	// Kind specifies the category of the error. It is nil when
+	// the operation was interrupted by its context.
+	Kind error
+
+	// Err specifies the underlying error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	return "redisstore: " + e.Op + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is checks whether the target error matches the error's kind.
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// withKind marks the error with the provided kind, unless it
+// already has one.
+func withKind(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	return &Error{Kind: kind, Err: err}
+}
+
+// wrapErr wraps the error returned by the named operation.
+// sessionup.ErrDuplicateID is returned as is, since sessionup.Store
+// implementations are expected to return it directly.
+func wrapErr(op string, err error) error {
+	if err == nil || errors.Is(err, sessionup.ErrDuplicateID) {
+		return err
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return &Error{Op: op, Kind: e.Kind, Err: e.Err}
+	}
+
+	return &Error{Op: op, Kind: classify(err), Err: err}
+}
+
+// classify determines the kind of an unmarked error.
+func classify(err error) error {
+	if errors.Is(err, ErrTxConflict) {
+		return ErrTxConflict
+	}
+
+	if errors.Is(err, ErrClosed) {
+		return ErrClosed
+	}
+
+	if errors.Is(err, ErrMaxSessions) {
+		return ErrMaxSessions
+	}
+
+	if errors.Is(err, ErrWriteConcern) {
+		return ErrWriteConcern
+	}
+
+	if errors.Is(err, ErrUnavailable) {
+		return ErrUnavailable
+	}
+
+	if errors.Is(err, ErrNoSession) {
+		return ErrNoSession
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+
+	if unsupported(err) {
+		return ErrNotSupported
+	}
+
+	var rerr redis.Error
+	if errors.As(err, &rerr) {
+		return ErrCommand
+	}
+
+	if connFailed(err) {
+		return ErrConnection
+	}
+
+	return ErrInternal
+}
+
+// poolErrs holds the messages of the unexported errors returned by
+// redigo when its pool or connection is closed.
+var poolErrs = map[string]bool{
+	"redigo: connection pool closed": true,
+	"redigo: connection closed":      true,
+	"redigo: get on closed pool":     true,
+	"redigo: closed":                 true,
+}
+
+// connFailed checks whether the error was returned because the
+// connection to Redis failed or could not be retrieved from the pool.
+func connFailed(err error) bool {
+	var nerr net.Error
+	if errors.As(err, &nerr) {
+		return true
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, redis.ErrPoolExhausted) {
+		return true
+	}
+
+	return poolErrs[err.Error()]
+}
+
+// unsupported checks whether the error was returned because the
+// server does not support (or forbids) the executed command.
+func unsupported(err error) bool {
+	var rerr redis.Error
+	if !errors.As(err, &rerr) {
+		return false
+	}
+
+	msg := strings.ToLower(rerr.Error())
+
+	return strings.HasPrefix(msg, "err unknown command") ||
+		strings.HasPrefix(msg, "noperm")
+}
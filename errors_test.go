@@ -0,0 +1,82 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/swithek/sessionup"
+)
+
+func Test_Error(t *testing.T) {
+	err := &Error{Op: "create", Kind: ErrCommand, Err: assert.AnError}
+	assert.Equal(t, "redisstore: create: "+assert.AnError.Error(), err.Error())
+	assert.Equal(t, assert.AnError, err.Unwrap())
+	assert.True(t, errors.Is(err, ErrCommand))
+	assert.True(t, errors.Is(err, assert.AnError))
+	assert.False(t, errors.Is(err, ErrParse))
+
+	var e *Error
+	assert.True(t, errors.As(fmt.Errorf("wrapped: %w", err), &e))
+	assert.Equal(t, err, e)
+
+	err = &Error{Op: "create", Err: context.Canceled}
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.False(t, errors.Is(err, ErrConnection))
+}
+
+func Test_withKind(t *testing.T) {
+	assert.Nil(t, withKind(ErrParse, nil))
+
+	err := withKind(ErrParse, assert.AnError)
+	assert.Equal(t, &Error{Kind: ErrParse, Err: assert.AnError}, err)
+	assert.Equal(t, err, withKind(ErrEncryption, err))
+}
+
+func Test_wrapErr(t *testing.T) {
+	assert.Nil(t, wrapErr("create", nil))
+	assert.Equal(t, sessionup.ErrDuplicateID, wrapErr("create", sessionup.ErrDuplicateID))
+
+	assert.Equal(t,
+		&Error{Op: "create", Kind: ErrParse, Err: assert.AnError},
+		wrapErr("create", withKind(ErrParse, assert.AnError)),
+	)
+
+	assert.Equal(t,
+		&Error{Op: "create", Kind: ErrTxConflict, Err: ErrTxConflict},
+		wrapErr("create", ErrTxConflict),
+	)
+}
+
+func Test_classify(t *testing.T) {
+	assert.Equal(t, ErrTxConflict, classify(ErrTxConflict))
+	assert.Equal(t, ErrClosed, classify(ErrClosed))
+	assert.Equal(t, ErrMaxSessions, classify(ErrMaxSessions))
+	assert.Equal(t, ErrWriteConcern, classify(ErrWriteConcern))
+	assert.Equal(t, ErrUnavailable, classify(ErrUnavailable))
+	assert.Equal(t, ErrNoSession, classify(ErrNoSession))
+	assert.Nil(t, classify(context.Canceled))
+	assert.Nil(t, classify(fmt.Errorf("wrapped: %w", context.DeadlineExceeded)))
+	assert.Equal(t, ErrNotSupported, classify(redis.Error("ERR unknown command 'HSET'")))
+	assert.Equal(t, ErrCommand, classify(redis.Error("WRONGTYPE Operation against a key holding the wrong kind of value")))
+	assert.Equal(t, ErrConnection, classify(&net.OpError{Op: "dial", Err: assert.AnError}))
+	assert.Equal(t, ErrConnection, classify(io.EOF))
+	assert.Equal(t, ErrConnection, classify(fmt.Errorf("wrapped: %w", io.ErrUnexpectedEOF)))
+	assert.Equal(t, ErrConnection, classify(redis.ErrPoolExhausted))
+	assert.Equal(t, ErrConnection, classify(errors.New("redigo: connection pool closed")))
+	assert.Equal(t, ErrInternal, classify(errors.New("redigo: unexpected type for Int64, got type string")))
+	assert.Equal(t, ErrInternal, classify(assert.AnError))
+}
+
+func Test_unsupported(t *testing.T) {
+	assert.False(t, unsupported(assert.AnError))
+	assert.False(t, unsupported(redis.Error("ERR syntax error")))
+	assert.True(t, unsupported(redis.Error("ERR unknown command 'EVALSHA'")))
+	assert.True(t, unsupported(redis.Error("NOPERM this user has no permissions to run the 'evalsha' command")))
+	assert.True(t, unsupported(fmt.Errorf("wrapped: %w", redis.Error("ERR unknown command `evalsha`"))))
+}
@@ -0,0 +1,279 @@
+package redisstore
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// EventType determines what happened to a session.
+type EventType int
+
+const (
+	// EventExpired is emitted when a session expires.
+	EventExpired EventType = iota
+
+	// EventDeleted is emitted when a session is deleted.
+	EventDeleted
+
+	// EventCreated is emitted when a session is created.
+	EventCreated
+
+	// EventExtended is emitted when the expiration time of a session
+	// is changed.
+	EventExtended
+)
+
+// eventBuffer is the number of events of local operations buffered for
+// each consumer of Events.
+const eventBuffer = 64
+
+// SessionEvent describes a change of a session observed by the store.
+// Events are only delivered to consumers within the process and are
+// never written to Redis.
+type SessionEvent struct {
+	// Type specifies what happened to the session.
+	Type EventType
+
+	// ID specifies the ID of the session. It is empty when the
+	// sessions of a user were deleted or extended by their user key.
+	ID string
+
+	// UserKey specifies the user key of the session. It is empty for
+	// events reported by keyspace notifications.
+	UserKey string
+}
+
+// eventHub holds the consumers of Events.
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan SessionEvent]struct{}
+}
+
+// eventPatterns holds the channel patterns of keyspace events that
+// are relevant to sessions.
+var eventPatterns = []interface{}{
+	"__keyevent@*__:expired",
+	"__keyevent@*__:del",
+	"__keyevent@*__:unlink",
+}
+
+// eventTypes maps names of keyspace events to event types.
+var eventTypes = map[string]EventType{
+	"expired": EventExpired,
+	"del":     EventDeleted,
+	"unlink":  EventDeleted,
+}
+
+// Subscribe starts listening for expiration and deletion of sessions
+// by using Redis keyspace notifications. The events are sent to the
+// returned channel until the provided context is done or the store is
+// closed, at which point the channel is closed. It is closed as well
+// if the subscription connection fails.
+// Keyspace notifications must be enabled on the server (e.g. with
+// the "Egx" value of the notify-keyspace-events option), otherwise
+// no events are received. Note that Redis does not guarantee the
+// delivery of notifications: events are lost while the subscriber
+// is disconnected.
+func (r *RedisStore) Subscribe(ctx context.Context) (<-chan SessionEvent, error) {
+	events := make(chan SessionEvent)
+
+	err := r.subscribe(ctx, "subscribe", eventPatterns, events, func() {
+		close(events)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// subscribe starts listening for the keyspace events of sessions that
+// match the provided patterns (a subset of eventPatterns) and sends
+// them to the provided channel, waiting for the consumer; done is
+// called once the listener stops (see listen).
+func (r *RedisStore) subscribe(ctx context.Context, op string, patterns []interface{}, events chan<- SessionEvent, done func()) error {
+	sPrefix := r.buildKey(r.tenantOf(ctx), "session", "")
+
+	names := make(map[string]struct{}, len(patterns))
+	for _, p := range patterns {
+		p := p.(string)
+		names[p[strings.LastIndex(p, ":")+1:]] = struct{}{}
+	}
+
+	return r.listen(ctx, op, patterns, func(m redis.Message) {
+		i := strings.LastIndex(m.Channel, ":")
+		key := string(m.Data)
+
+		if i < 0 || !strings.HasPrefix(key, sPrefix) {
+			return
+		}
+
+		if _, ok := names[m.Channel[i+1:]]; !ok {
+			return
+		}
+
+		typ, ok := eventTypes[m.Channel[i+1:]]
+		if !ok {
+			return
+		}
+
+		select {
+		case events <- SessionEvent{Type: typ, ID: keyUnescaper.Replace(key[len(sPrefix):])}:
+		case <-ctx.Done():
+		case <-r.stop:
+		}
+	}, done)
+}
+
+// Events returns a single stream of lifecycle events of sessions:
+// sessions created, extended and deleted by operations of this store
+// instance, and sessions that expired, as reported by Redis keyspace
+// notifications (see Subscribe for their requirements). Deletions done
+// by other instances are not included.
+// Events are sent to the returned channel until the provided context
+// is done or the store is closed, at which point the channel is closed.
+// Operations never wait for the consumer: the channel buffers up to 64
+// events and events of local operations that arrive while it is full
+// are dropped and counted (see DroppedEvents).
+func (r *RedisStore) Events(ctx context.Context) (<-chan SessionEvent, error) {
+	events := make(chan SessionEvent, eventBuffer)
+
+	r.hub.mu.Lock()
+	if r.hub.subs == nil {
+		r.hub.subs = make(map[chan SessionEvent]struct{})
+	}
+
+	r.hub.subs[events] = struct{}{}
+	r.hub.mu.Unlock()
+
+	unsubscribe := func() {
+		r.hub.mu.Lock()
+		delete(r.hub.subs, events)
+		r.hub.mu.Unlock()
+	}
+
+	// deletions are reported by local operations, hence only
+	// expirations are taken from keyspace notifications
+	err := r.subscribe(ctx, "events", eventPatterns[:1], events, func() {
+		unsubscribe()
+		close(events)
+	})
+	if err != nil {
+		unsubscribe()
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// DroppedEvents returns the total number of events of local operations
+// that were not delivered to consumers of Events because their
+// channels were full.
+func (r *RedisStore) DroppedEvents() int64 {
+	return atomic.LoadInt64(&r.droppedEvents)
+}
+
+// emit sends the event of a local operation to all consumers of
+// Events that are ready to receive it, counting the consumers that
+// are not. Events of operations that failed or found no sessions
+// (i.e. without a user key) are skipped.
+func (r *RedisStore) emit(typ EventType, s sessionup.Session, err error) {
+	if err != nil || s.UserKey == "" {
+		return
+	}
+
+	r.hub.mu.Lock()
+	defer r.hub.mu.Unlock()
+
+	for events := range r.hub.subs {
+		select {
+		case events <- SessionEvent{Type: typ, ID: s.ID, UserKey: s.UserKey}:
+		default:
+			atomic.AddInt64(&r.droppedEvents, 1)
+		}
+	}
+}
+
+// listen subscribes to the provided channel patterns and passes each
+// received message to fn until the context is done, the store is
+// closed or the connection fails; done is called afterwards.
+// fn must return once the context is done or the store is closed.
+func (r *RedisStore) listen(ctx context.Context, op string, patterns []interface{}, fn func(redis.Message), done func()) error {
+	r.closeMu.RLock()
+	defer r.closeMu.RUnlock()
+
+	if r.closed {
+		return wrapErr(op, ErrClosed)
+	}
+
+	c, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return wrapErr(op, withKind(ErrConnection, err))
+	}
+
+	psc := redis.PubSubConn{Conn: c}
+
+	if err = psc.PSubscribe(patterns...); err != nil {
+		c.Close()
+		return wrapErr(op, err)
+	}
+
+	// wait until all subscriptions are confirmed
+	for range patterns {
+		if err, ok := psc.Receive().(error); ok {
+			c.Close()
+			return wrapErr(op, err)
+		}
+	}
+
+	stopped := make(chan struct{})
+	unsubscribed := make(chan struct{})
+
+	r.workers.Add(2)
+
+	go func() {
+		defer r.workers.Done()
+		defer close(unsubscribed)
+
+		select {
+		case <-ctx.Done():
+		case <-r.stop:
+		case <-stopped:
+			return
+		}
+
+		// the listener exits once the server confirms that no
+		// subscriptions are left
+		psc.PUnsubscribe()
+	}()
+
+	go func() {
+		defer r.workers.Done()
+		defer done()
+		defer func() {
+			close(stopped)
+			<-unsubscribed
+			c.Close()
+		}()
+
+		for {
+			switch v := psc.Receive().(type) {
+			case redis.Message:
+				fn(v)
+			case redis.Subscription:
+				if v.Count == 0 {
+					return
+				}
+			case error:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
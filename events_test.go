@@ -0,0 +1,280 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+// pubSubConn is a redis.Conn that replies to subscription commands
+// the way Redis does and allows pushing messages to its subscriber.
+type pubSubConn struct {
+	mu      sync.Mutex
+	cmds    []string
+	err     error
+	replies chan interface{}
+}
+
+func newPubSubConn() *pubSubConn {
+	return &pubSubConn{replies: make(chan interface{}, 100)}
+}
+
+func (c *pubSubConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if cmd == "" {
+		return nil, nil
+	}
+
+	return nil, c.Send(cmd, args...)
+}
+
+func (c *pubSubConn) Send(cmd string, args ...interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cmds = append(c.cmds, cmd)
+
+	switch cmd {
+	case "PSUBSCRIBE":
+		for i, a := range args {
+			c.replies <- []interface{}{[]byte("psubscribe"), []byte(a.(string)), int64(i + 1)}
+		}
+	case "PUNSUBSCRIBE":
+		c.replies <- []interface{}{[]byte("punsubscribe"), nil, int64(0)}
+	case "ECHO":
+		c.replies <- args[0]
+	}
+
+	return nil
+}
+
+func (c *pubSubConn) Flush() error {
+	return c.err
+}
+
+func (c *pubSubConn) Receive() (interface{}, error) {
+	v, ok := <-c.replies
+	if !ok {
+		return nil, io.EOF
+	}
+
+	if err, ok := v.(error); ok {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func (c *pubSubConn) Close() error {
+	return nil
+}
+
+func (c *pubSubConn) Err() error {
+	return nil
+}
+
+func (c *pubSubConn) publish(pattern, channel, data string) {
+	c.replies <- []interface{}{
+		[]byte("pmessage"),
+		[]byte(pattern),
+		[]byte(channel),
+		[]byte(data),
+	}
+}
+
+func (c *pubSubConn) publishEvent(event, key string) {
+	c.publish("__keyevent@*__:"+event, "__keyevent@0__:"+event, key)
+}
+
+func (c *pubSubConn) sent() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.cmds...)
+}
+
+func Test_RedisStore_Subscribe(t *testing.T) {
+	newStore := func(conn redis.Conn) *RedisStore {
+		return New(&redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return conn, nil
+			},
+		}, prefix)
+	}
+
+	t.Run("Closed store", func(t *testing.T) {
+		r := RedisStore{closed: true}
+
+		ee, err := r.Subscribe(context.Background())
+		assert.Equal(t, &Error{Op: "subscribe", Kind: ErrClosed, Err: ErrClosed}, err)
+		assert.Nil(t, ee)
+	})
+
+	t.Run("Connection error", func(t *testing.T) {
+		r := New(&redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return nil, assert.AnError
+			},
+		}, prefix)
+
+		ee, err := r.Subscribe(context.Background())
+		assert.True(t, errors.Is(err, ErrConnection))
+		assert.True(t, errors.Is(err, assert.AnError))
+		assert.Nil(t, ee)
+	})
+
+	t.Run("PSUBSCRIBE error", func(t *testing.T) {
+		conn := newPubSubConn()
+		conn.err = assert.AnError
+
+		ee, err := newStore(conn).Subscribe(context.Background())
+		assert.True(t, errors.Is(err, assert.AnError))
+		assert.Nil(t, ee)
+	})
+
+	t.Run("Subscription confirmation error", func(t *testing.T) {
+		conn := newPubSubConn()
+		conn.replies <- redis.Error("NOPERM this user has no permissions")
+
+		ee, err := newStore(conn).Subscribe(context.Background())
+		assert.True(t, errors.Is(err, ErrNotSupported))
+		assert.Nil(t, ee)
+	})
+
+	t.Run("Connection failure", func(t *testing.T) {
+		conn := newPubSubConn()
+		r := newStore(conn)
+
+		ee, err := r.Subscribe(context.Background())
+		require.NoError(t, err)
+
+		conn.replies <- assert.AnError
+
+		_, ok := <-ee
+		assert.False(t, ok)
+		assert.NoError(t, r.Close(context.Background()))
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		conn := newPubSubConn()
+		r := newStore(conn)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		ee, err := r.Subscribe(ctx)
+		require.NoError(t, err)
+
+		conn.publishEvent("expired", r.key(nil, false, "id1"))
+		conn.publishEvent("del", r.key(nil, true, "u123"))
+		conn.publishEvent("del", "other:session:id2")
+		conn.publishEvent("set", r.key(nil, false, "id3"))
+		conn.publishEvent("del", r.key(nil, false, "id4"))
+		conn.publishEvent("unlink", r.key(nil, false, "id5"))
+		conn.publishEvent("expired", r.key(nil, false, "id:6"))
+
+		var res []SessionEvent
+		for i := 0; i < 4; i++ {
+			res = append(res, <-ee)
+		}
+
+		assert.Equal(t, []SessionEvent{
+			{Type: EventExpired, ID: "id1"},
+			{Type: EventDeleted, ID: "id4"},
+			{Type: EventDeleted, ID: "id5"},
+			{Type: EventExpired, ID: "id:6"},
+		}, res)
+
+		cancel()
+
+		_, ok := <-ee
+		assert.False(t, ok)
+		assert.NoError(t, r.Close(context.Background()))
+		assert.Contains(t, conn.sent(), "PUNSUBSCRIBE")
+	})
+
+	t.Run("Closed store after subscription", func(t *testing.T) {
+		conn := newPubSubConn()
+		r := newStore(conn)
+
+		ee, err := r.Subscribe(context.Background())
+		require.NoError(t, err)
+
+		assert.NoError(t, r.Close(context.Background()))
+
+		_, ok := <-ee
+		assert.False(t, ok)
+		assert.Contains(t, conn.sent(), "PUNSUBSCRIBE")
+	})
+}
+
+func Test_RedisStore_Events(t *testing.T) {
+	conn := newPubSubConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ee, err := r.Events(ctx)
+	require.NoError(t, err)
+
+	r.afterCreate(ctx, sessionup.Session{ID: "id1", UserKey: "u1"}, nil)
+	r.afterCreate(ctx, sessionup.Session{ID: "id2", UserKey: "u1"}, assert.AnError)
+	r.afterExtend(ctx, sessionup.Session{ID: "id1", UserKey: "u1"}, nil)
+	r.afterExtend(ctx, sessionup.Session{ID: "id3"}, nil)
+	r.afterDelete(ctx, sessionup.Session{UserKey: "u1"}, nil)
+
+	conn.publishEvent("del", r.key(nil, false, "id4"))
+	conn.publishEvent("expired", r.key(nil, false, "id5"))
+
+	var res []SessionEvent
+	for i := 0; i < 4; i++ {
+		res = append(res, <-ee)
+	}
+
+	assert.Equal(t, []SessionEvent{
+		{Type: EventCreated, ID: "id1", UserKey: "u1"},
+		{Type: EventExtended, ID: "id1", UserKey: "u1"},
+		{Type: EventDeleted, UserKey: "u1"},
+		{Type: EventExpired, ID: "id5"},
+	}, res)
+
+	cancel()
+
+	_, ok := <-ee
+	assert.False(t, ok)
+	assert.Empty(t, r.hub.subs)
+	assert.NoError(t, r.Close(context.Background()))
+
+	// events are not sent once the consumer is gone.
+	r.afterCreate(context.Background(), sessionup.Session{ID: "id1", UserKey: "u1"}, nil)
+	assert.Zero(t, r.DroppedEvents())
+}
+
+func Test_RedisStore_Events_dropped(t *testing.T) {
+	conn := newPubSubConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	ee, err := r.Events(context.Background())
+	require.NoError(t, err)
+
+	for i := 0; i < eventBuffer+2; i++ {
+		r.afterCreate(context.Background(), sessionup.Session{ID: "id1", UserKey: "u1"}, nil)
+	}
+
+	assert.Len(t, ee, eventBuffer)
+	assert.Equal(t, int64(2), r.DroppedEvents())
+	assert.NoError(t, r.Close(context.Background()))
+}
@@ -0,0 +1,87 @@
+package redisstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// WithExpiryIndex determines whether sessions should be indexed by
+// their expiration times in a single sorted set, so that sessions
+// about to expire can be retrieved with ExpiringWithin. The index is
+// stored under "<prefix>:expiry:all" and maintained like other
+// secondary indexes (see WithIPIndex): entries are updated whenever
+// expiration times of sessions change, while entries of expired
+// sessions are removed each time a session is indexed.
+// Defaults to false.
+func WithExpiryIndex(t bool) setter {
+	return func(r *RedisStore) {
+		r.expiryIndex = t
+	}
+}
+
+// ExpiringWithin retrieves up to limit sessions (or all of them, if
+// limit is not positive) that expire within the provided duration
+// from now, ordered by their expiration times. If none are found, both
+// return values will be nil.
+// errNoIndex is returned if the expiry index is not enabled (see
+// WithExpiryIndex).
+func (r *RedisStore) ExpiringWithin(ctx context.Context, d time.Duration, limit int) (ss []sessionup.Session, err error) {
+	if !r.expiryIndex {
+		return nil, errNoIndex
+	}
+
+	c, end, err := r.begin(ctx, "ExpiringWithin")
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { err = end(err) }()
+
+	now := r.now()
+	until := now.Add(d)
+	key := r.expiryKey(c)
+
+	for offset := 0; ; offset += scanCount {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		keys, err := redis.Strings(c.Do("ZRANGEBYSCORE", key, now.UnixNano(), until.UnixNano(),
+			"LIMIT", offset, scanCount))
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := r.fetchKeys(c, keys)
+		if err != nil {
+			return nil, err
+		}
+
+		// expiration times may have been changed since the sessions
+		// were indexed
+		for i := range found {
+			if found[i].ExpiresAt.After(until) {
+				continue
+			}
+
+			ss = append(ss, found[i])
+
+			if limit > 0 && len(ss) == limit {
+				return ss, nil
+			}
+		}
+
+		if len(keys) < scanCount {
+			return ss, nil
+		}
+	}
+}
+
+// expiryKey returns the key of the expiry index, scoped to the tenant
+// of the connection.
+func (r *RedisStore) expiryKey(c redis.Conn) string {
+	return r.buildKey(connTenant(c), "expiry", "all")
+}
@@ -0,0 +1,69 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithExpiryIndex(t *testing.T) {
+	r := RedisStore{prefix: prefix}
+	WithExpiryIndex(true)(&r)
+	assert.True(t, r.expiryIndex)
+	assert.Equal(t, []string{"expiry"}, r.indexNamespaces())
+	assert.Equal(t, []string{prefix + ":expiry:all"}, r.indexKeys(nil, sessionup.Session{ID: "id123"}, false))
+}
+
+func Test_RedisStore_ExpiringWithin(t *testing.T) {
+	key := prefix + ":expiry:all"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	_, err := r.ExpiringWithin(context.Background(), time.Hour, 0)
+	assert.Equal(t, errNoIndex, err)
+
+	r.expiryIndex = true
+
+	conn.Command("ZRANGEBYSCORE", key, redigomock.NewAnyInt(), redigomock.NewAnyInt(), "LIMIT", 0, scanCount).
+		ExpectError(assert.AnError)
+	_, err = r.ExpiringWithin(context.Background(), time.Hour, 0)
+	assert.Error(t, err)
+
+	session := func(id string, exp time.Time) map[string]string {
+		return map[string]string{
+			"created_at": time.Now().Format(time.RFC3339Nano),
+			"expires_at": exp.Format(time.RFC3339Nano),
+			"id":         id,
+			"user_key":   "u123",
+		}
+	}
+
+	conn.Clear()
+	conn.Command("ZRANGEBYSCORE", key, redigomock.NewAnyInt(), redigomock.NewAnyInt(), "LIMIT", 0, scanCount).
+		ExpectSlice([]byte(prefix+":session:id1"), []byte(prefix+":session:id2"), []byte(prefix+":session:id3"))
+	conn.Command("HGETALL", prefix+":session:id1").ExpectMap(session("id1", time.Now().Add(time.Minute)))
+	conn.Command("HGETALL", prefix+":session:id2").ExpectMap(session("id2", time.Now().Add(time.Hour*2)))
+	conn.Command("HGETALL", prefix+":session:id3").ExpectMap(session("id3", time.Now().Add(time.Minute*2)))
+
+	ss, err := r.ExpiringWithin(context.Background(), time.Hour, 0)
+	require.NoError(t, err)
+	require.Len(t, ss, 2)
+	assert.Equal(t, "id1", ss[0].ID)
+	assert.Equal(t, "id3", ss[1].ID)
+
+	ss, err = r.ExpiringWithin(context.Background(), time.Hour, 1)
+	require.NoError(t, err)
+	require.Len(t, ss, 1)
+	assert.Equal(t, "id1", ss[0].ID)
+}
@@ -0,0 +1,129 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// Export writes all sessions of the store into the provided writer as
+// newline-delimited JSON, in the same format that is used by WithJSON
+// (along with the time the session was last seen at, if tracked).
+// Encrypted data is written decrypted.
+// Sessions that are created or deleted while the export is in progress
+// may or may not be included.
+func (r *RedisStore) Export(ctx context.Context, w io.Writer) (err error) {
+	c, end, err := r.begin(ctx, "Export")
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = end(err) }()
+
+	enc := json.NewEncoder(w)
+
+	return scan(ctx, c, r.pattern(c, false), func(keys []string) error {
+		dd, err := r.fetchKeysDetailed(c, keys)
+		if err != nil {
+			return err
+		}
+
+		for i := range dd {
+			if err = enc.Encode(toDetailedRecord(dd[i])); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Import reads newline-delimited JSON sessions (as written by Export)
+// from the provided reader and inserts them into the store, keeping
+// their expiration times. Sessions that have already expired, as well
+// as sessions whose IDs are already taken, are skipped.
+// Each session is validated and inserted the same way as by Create:
+// hooks are called, the maximum session lifetime and redaction are
+// applied, and the session is indexed, audited and counted as active.
+// However, the expiration time is not overridden by the TTL found in
+// its metadata (see WithTTLFromMeta), and its attributes, device and
+// login data are imported as they are instead of being derived anew.
+// Sessions are inserted one by one, hence those read before a failure
+// remain in the store.
+// If invalidations are enabled, an invalidation message is published
+// for each inserted session.
+func (r *RedisStore) Import(ctx context.Context, rd io.Reader) (err error) {
+	c, end, err := r.begin(ctx, "Import")
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = end(err) }()
+
+	dec := json.NewDecoder(rd)
+
+	for n := 1; ; n++ {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		var raw json.RawMessage
+		if err = dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return r.waitReplicas(c)
+			}
+
+			return withKind(ErrParse, fmt.Errorf("session %d: %w", n, err))
+		}
+
+		d, err := parseJSON(raw, r.enc)
+		if err != nil {
+			return withKind(ErrParse, fmt.Errorf("session %d: %w", n, err))
+		}
+
+		if !d.ExpiresAt.After(r.now()) {
+			continue
+		}
+
+		err = r.importSession(ctx, c, d)
+		if errors.Is(err, sessionup.ErrDuplicateID) {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("session %d: %w", n, err)
+		}
+	}
+}
+
+// importSession validates and inserts the imported session.
+func (r *RedisStore) importSession(ctx context.Context, c redis.Conn, d DetailedSession) (err error) {
+	defer func() { r.afterCreate(ctx, d.Session, err) }()
+
+	if err = validate(d.Session); err != nil {
+		return err
+	}
+
+	if err = r.checkMeta(d.Meta); err != nil {
+		return err
+	}
+
+	r.capLifetime(&d)
+
+	if err = r.beforeCreate(ctx, d.Session); err != nil {
+		return err
+	}
+
+	d.Session = r.Redact(d.Session)
+
+	if err = r.insert(ctx, c, d); err != nil {
+		return err
+	}
+
+	return r.invalidate(c, Invalidation{ID: d.ID})
+}
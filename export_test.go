@@ -0,0 +1,245 @@
+package redisstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+// failingWriter is an io.Writer that always fails.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, assert.AnError
+}
+
+func exportedSession(id string) sessionup.Session {
+	s := sessionup.Session{
+		UserKey:   "u123",
+		ID:        id,
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+		CreatedAt: time.Now().UTC().Round(0),
+		IP:        net.ParseIP("127.0.0.1"),
+		Meta:      map[string]string{"test": "1"},
+	}
+	s.Agent.OS = "gnu/linux"
+	s.Agent.Browser = "firefox"
+
+	return s
+}
+
+func Test_RedisStore_Export(t *testing.T) {
+	s1 := exportedSession("id1")
+	s2 := exportedSession("id2")
+	seen := time.Now().UTC().Round(0)
+
+	pattern := prefix + ":session:*"
+
+	newConn := func() *redigomock.Conn {
+		conn := redigomock.NewConn()
+		conn.Command("SCAN", int64(0), "MATCH", pattern, "COUNT", scanCount).
+			ExpectSlice([]byte("0"), []interface{}{
+				[]byte(prefix + ":session:id1"),
+				[]byte(prefix + ":session:id2"),
+				[]byte(prefix + ":session:id3"),
+			})
+		conn.Command("HGETALL", prefix+":session:id1").ExpectMap(map[string]string{
+			"created_at":    s1.CreatedAt.Format(time.RFC3339Nano),
+			"expires_at":    s1.ExpiresAt.Format(time.RFC3339Nano),
+			"id":            s1.ID,
+			"user_key":      s1.UserKey,
+			"ip":            s1.IP.String(),
+			"agent_os":      s1.Agent.OS,
+			"agent_browser": s1.Agent.Browser,
+			"meta":          "test=1",
+			"last_seen_at":  seen.Format(time.RFC3339Nano),
+		})
+		conn.Command("HGETALL", prefix+":session:id2").ExpectMap(map[string]string{
+			"created_at":    s2.CreatedAt.Format(time.RFC3339Nano),
+			"expires_at":    s2.ExpiresAt.Format(time.RFC3339Nano),
+			"id":            s2.ID,
+			"user_key":      s2.UserKey,
+			"ip":            s2.IP.String(),
+			"agent_os":      s2.Agent.OS,
+			"agent_browser": s2.Agent.Browser,
+			"meta":          "test=1",
+		})
+		conn.Command("HGETALL", prefix+":session:id3").ExpectMap(map[string]string{})
+
+		return conn
+	}
+
+	t.Run("Writer error", func(t *testing.T) {
+		conn := newConn()
+		r := RedisStore{
+			pool: &redis.Pool{
+				Dial: func() (redis.Conn, error) {
+					return conn, nil
+				},
+			},
+			prefix: prefix,
+		}
+
+		err := r.Export(context.Background(), failingWriter{})
+		assert.True(t, errors.Is(err, assert.AnError))
+	})
+
+	t.Run("Successful export", func(t *testing.T) {
+		conn := newConn()
+		r := RedisStore{
+			pool: &redis.Pool{
+				Dial: func() (redis.Conn, error) {
+					return conn, nil
+				},
+			},
+			prefix: prefix,
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, r.Export(context.Background(), &buf))
+		assert.NoError(t, conn.ExpectationsWereMet())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+
+		d, err := parseJSON([]byte(lines[0]), nil)
+		require.NoError(t, err)
+		assert.Equal(t, DetailedSession{Session: s1, LastSeenAt: seen}, d)
+
+		d, err = parseJSON([]byte(lines[1]), nil)
+		require.NoError(t, err)
+		assert.Equal(t, DetailedSession{Session: s2}, d)
+	})
+}
+
+func Test_RedisStore_Import(t *testing.T) {
+	s1 := exportedSession("id1")
+	s2 := exportedSession("id2")
+	s3 := exportedSession("id3")
+	s3.ExpiresAt = time.Now().Add(-time.Hour)
+
+	line := func(s sessionup.Session) string {
+		b, err := json.Marshal(toRecord(s))
+		require.NoError(t, err)
+
+		return string(b) + "\n"
+	}
+
+	script := func(conn *redigomock.Conn, s sessionup.Session) *redigomock.Cmd {
+		data, err := json.Marshal(toRecord(s))
+		require.NoError(t, err)
+
+		return conn.Script(
+			[]byte(createScriptSrc), 2,
+			prefix+":session:"+s.ID, prefix+":user:"+s.UserKey,
+			redigomock.NewAnyInt(), redigomock.NewAnyInt(),
+			s.ExpiresAt.UnixNano(), s.ExpiresAt.UnixNano()/int64(time.Millisecond),
+			0, 0,
+			"GET", "SET", data,
+		)
+	}
+
+	cc := map[string]struct {
+		Input string
+		Conn  func() (*redigomock.Conn, func(*testing.T))
+		Err   error
+	}{
+		"Invalid JSON": {
+			Input: line(s1) + "{",
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				script(conn, s1).Expect(int64(1))
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: ErrParse,
+		},
+		"Invalid session": {
+			Input: `{"created_at":"123"}`,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: ErrParse,
+		},
+		"Session without ID": {
+			Input: `{"user_key":"u1","expires_at":"` + s1.ExpiresAt.Format(time.RFC3339Nano) + `"}`,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: ErrInvalidSession,
+		},
+		"Error returned during insertion": {
+			Input: line(s1),
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				script(conn, s1).ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Successful import": {
+			Input: line(s1) + line(s2) + line(s3),
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				script(conn, s1).Expect(int64(1))
+				script(conn, s2).Expect(int64(0))
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+				},
+				prefix: prefix,
+				asJSON: true,
+			}
+
+			err := r.Import(context.Background(), strings.NewReader(c.Input))
+			check(t)
+
+			if c.Err != nil {
+				assert.True(t, errors.Is(err, c.Err))
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// readOps holds the names of operations that only read data and can
+// always be served by the fallback pool.
+var readOps = map[string]bool{
+	"ActiveUsers":            true,
+	"Exists":                 true,
+	"ExpiringWithin":         true,
+	"Export":                 true,
+	"FetchAll":               true,
+	"FetchByAgent":           true,
+	"FetchByID":              true,
+	"FetchByIP":              true,
+	"FetchByMeta":            true,
+	"FetchByUserKey":         true,
+	"FetchCreatedBetween":    true,
+	"FetchDetailedByUserKey": true,
+	"FetchWhere":             true,
+	"GetPayload":             true,
+	"IsRevoked":              true,
+	"IterateByUserKey":       true,
+	"ListUserKeys":           true,
+	"Stats":                  true,
+	"TTL":                    true,
+}
+
+// fallback holds the standby pool used while the primary one is
+// unavailable.
+type fallback struct {
+	pool     Pooler
+	writes   bool
+	interval time.Duration
+
+	// down is set to 1 while the primary pool is considered to be
+	// unavailable.
+	down int32
+}
+
+// WithFallbackPool sets a pool of connections to a standby Redis
+// server (e.g. a replica promoted by hand or a server in another
+// zone) that is used once the primary pool fails due to a connection
+// error. Reads (e.g. FetchByID) are always served by the standby
+// server while the primary one is unavailable; writes are served by
+// it only if writes is true and fail otherwise. Note that FetchByID
+// writes to the session as well if idle timeouts or last seen
+// tracking are enabled.
+// Once failed over, the primary server is probed with PING every
+// probeInterval and used again once it responds.
+// The operation that encounters the connection error is not repeated,
+// unless retries are enabled (see WithRetry).
+// Defaults to nil (no failover).
+func WithFallbackPool(pool Pooler, writes bool, probeInterval time.Duration) setter {
+	return func(r *RedisStore) {
+		r.fallback = nil
+
+		if pool != nil {
+			r.fallback = &fallback{pool: pool, writes: writes, interval: probeInterval}
+		}
+	}
+}
+
+// FailedOver checks whether the store currently uses the fallback
+// pool (see WithFallbackPool).
+func (r *RedisStore) FailedOver() bool {
+	return r.fallback != nil && atomic.LoadInt32(&r.fallback.down) == 1
+}
+
+// getConn retrieves a connection for the named operation, either from
+// the primary pool or, if it is unavailable and the operation may be
+// failed over, from the fallback pool. The returned bool is true if
+// the connection belongs to the primary pool.
+func (r *RedisStore) getConn(ctx context.Context, name string) (redis.Conn, bool, error) {
+	fb := r.fallback
+	if fb == nil || (!fb.writes && !readOps[name]) {
+		c, err := r.pool.GetContext(ctx)
+		return c, true, err
+	}
+
+	if atomic.LoadInt32(&fb.down) == 0 {
+		c, err := r.pool.GetContext(ctx)
+		if err == nil {
+			return c, true, nil
+		}
+
+		r.failOver()
+	}
+
+	c, err := fb.pool.GetContext(ctx)
+
+	return c, false, err
+}
+
+// failOver marks the primary pool as unavailable and starts probing
+// it in the background, unless it is already marked.
+func (r *RedisStore) failOver() {
+	fb := r.fallback
+	if fb == nil || !atomic.CompareAndSwapInt32(&fb.down, 0, 1) {
+		return
+	}
+
+	r.closeMu.RLock()
+	defer r.closeMu.RUnlock()
+
+	if r.closed {
+		return
+	}
+
+	r.workers.Add(1)
+
+	go func() {
+		defer r.workers.Done()
+
+		t := time.NewTicker(fb.interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-t.C:
+			}
+
+			if r.probe() == nil {
+				atomic.StoreInt32(&fb.down, 0)
+				return
+			}
+		}
+	}()
+}
+
+// probe checks whether the primary server responds.
+func (r *RedisStore) probe() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.fallback.interval)
+	defer cancel()
+
+	c, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer c.Close()
+
+	_, err = redis.DoWithTimeout(c, r.fallback.interval, "PING")
+
+	return err
+}
+
+// recordPrimary fails over if an operation that used a connection of
+// the primary pool failed due to a connection error.
+func (r *RedisStore) recordPrimary(err error) {
+	if r.fallback != nil && errors.Is(err, ErrConnection) {
+		r.failOver()
+	}
+}
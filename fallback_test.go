@@ -0,0 +1,160 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithFallbackPool(t *testing.T) {
+	pool := &redis.Pool{}
+
+	r := RedisStore{}
+	WithFallbackPool(pool, true, time.Second)(&r)
+	require.NotNil(t, r.fallback)
+	assert.Equal(t, pool, r.fallback.pool)
+	assert.True(t, r.fallback.writes)
+	assert.Equal(t, time.Second, r.fallback.interval)
+
+	WithFallbackPool(nil, true, time.Second)(&r)
+	assert.Nil(t, r.fallback)
+}
+
+func Test_RedisStore_fallback(t *testing.T) {
+	sKey := prefix + ":session:id123"
+
+	var up int32
+
+	primary := redigomock.NewConn()
+	primary.Command("PING").Expect("PONG")
+
+	standby := redigomock.NewConn()
+	standby.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			if atomic.LoadInt32(&up) == 0 {
+				return nil, assert.AnError
+			}
+
+			return primary, nil
+		},
+	}, prefix, WithFallbackPool(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return standby, nil
+		},
+	}, false, time.Millisecond*10))
+
+	defer r.Close(context.Background())
+
+	s, ok, err := r.FetchByID(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "u123", s.UserKey)
+	assert.True(t, r.FailedOver())
+
+	// writes are not failed over.
+	err = r.Create(context.Background(), sessionup.Session{
+		ID:        "id123",
+		UserKey:   "u123",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	assert.True(t, errors.Is(err, ErrConnection))
+
+	atomic.StoreInt32(&up, 1)
+
+	assert.Eventually(t, func() bool {
+		return !r.FailedOver()
+	}, time.Second, time.Millisecond*5)
+
+	primary.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+
+	_, ok, err = r.FetchByID(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func Test_RedisStore_fallback_writes(t *testing.T) {
+	primary := redigomock.NewConn()
+	primary.GenericCommand("EVALSHA").ExpectError(io.EOF)
+
+	standby := redigomock.NewConn()
+	standby.GenericCommand("EVALSHA").Expect(int64(1))
+
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return primary, nil
+		},
+	}, prefix, WithFallbackPool(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return standby, nil
+		},
+	}, true, time.Hour))
+
+	defer r.Close(context.Background())
+
+	s := sessionup.Session{
+		ID:        "id123",
+		UserKey:   "u123",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	err := r.Create(context.Background(), s)
+	assert.True(t, errors.Is(err, ErrConnection))
+	assert.True(t, r.FailedOver())
+
+	require.NoError(t, r.Create(context.Background(), s))
+	assert.Equal(t, 1, standby.Stats(standby.GenericCommand("EVALSHA")))
+}
+
+func Test_RedisStore_fallback_reads(t *testing.T) {
+	sKey := prefix + ":session:id123"
+	pKey := prefix + ":payload:id123"
+
+	standby := redigomock.NewConn()
+	standby.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+	standby.Command("PTTL", sKey).Expect(int64(1000))
+	standby.Command("PTTL", pKey).Expect(int64(1000))
+	standby.Command("GET", pKey).Expect([]byte("data"))
+
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return nil, assert.AnError
+		},
+	}, prefix, WithFallbackPool(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return standby, nil
+		},
+	}, false, time.Hour))
+
+	defer r.Close(context.Background())
+
+	ttl, err := r.TTL(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.True(t, ttl > 0)
+	assert.True(t, r.FailedOver())
+
+	data, ok, err := r.GetPayload(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("data"), data)
+}
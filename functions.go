@@ -0,0 +1,164 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// functionsLibrary is the name of the Redis Functions library that
+// holds the store's server-side logic. It is versioned, so that stores
+// that expect different logic can share the same server.
+const functionsLibrary = "redisstore_v1"
+
+// luaScript is server-side logic of the store that is run either as
+// a Lua script (with EVALSHA) or as a Redis Function (with FCALL).
+type luaScript struct {
+	*redis.Script
+
+	// name is the name of the script, unique within the library.
+	name string
+
+	// keyCount is the number of keys passed to the script; -1 means
+	// that it is passed as the first argument.
+	keyCount int
+
+	// src is the script's source, which refers to its keys and
+	// arguments as KEYS and ARGV.
+	src string
+}
+
+// newLuaScript returns a new script of the provided name, number of
+// keys and source.
+func newLuaScript(name string, keyCount int, src string) *luaScript {
+	return &luaScript{
+		Script:   redis.NewScript(keyCount, src),
+		name:     name,
+		keyCount: keyCount,
+		src:      src,
+	}
+}
+
+// function returns the name the script is registered under as
+// a Redis Function.
+func (s *luaScript) function() string {
+	return functionsLibrary + "_" + s.name
+}
+
+// luaScripts holds all scripts registered in the functions library.
+var luaScripts = []*luaScript{
+	createScript,
+	createSimpleScript,
+	deleteByUserKeyScript,
+	indexScript,
+}
+
+// WithFunctions determines whether the store's server-side logic
+// should be run as Redis Functions (Redis 7.0), which, unlike scripts
+// run with EVALSHA, are named, persisted by the server, replicated
+// and visible to operators (e.g. with FUNCTION LIST). The library,
+// named "redisstore_v1", is loaded (or replaced) by LoadFunctions or,
+// if it is missing, by the first operation that needs it.
+// If functions are not supported by the server, Lua scripts are used
+// instead.
+// Defaults to false.
+func WithFunctions(t bool) setter {
+	return func(r *RedisStore) {
+		r.functions = t
+	}
+}
+
+// LoadFunctions loads the store's functions library into Redis,
+// replacing its previous version, if any. It should be called when
+// functions are enabled (see WithFunctions) and the server's functions
+// were flushed or the store was upgraded.
+func (r *RedisStore) LoadFunctions(ctx context.Context) (err error) {
+	c, end, err := r.begin(ctx, "LoadFunctions")
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = end(err) }()
+
+	return loadFunctions(c)
+}
+
+// loadFunctions loads the store's functions library into Redis.
+func loadFunctions(c redis.Conn) error {
+	_, err := c.Do("FUNCTION", "LOAD", "REPLACE", functionsLibrarySrc())
+	return err
+}
+
+// functionsLibrarySrc returns the source of the functions library,
+// which registers each script as a function.
+func functionsLibrarySrc() string {
+	var b strings.Builder
+
+	b.WriteString("#!lua name=" + functionsLibrary + "\n")
+
+	for _, s := range luaScripts {
+		fmt.Fprintf(&b, "\nredis.register_function(%q, function(KEYS, ARGV)%s\nend)\n", s.function(), s.src)
+	}
+
+	return b.String()
+}
+
+// runScript runs the script as a Redis Function, if functions are
+// enabled and supported by the server, or with EVALSHA otherwise.
+// args hold the keys and arguments of the script, as expected by
+// redis.Script.Do. The functions library is loaded if it is missing.
+func (r *RedisStore) runScript(c redis.Conn, s *luaScript, args ...interface{}) (interface{}, error) {
+	if !r.functionsEnabled() {
+		return s.Do(c, args...)
+	}
+
+	fargs := make([]interface{}, 0, len(args)+2)
+	fargs = append(fargs, s.function())
+
+	if s.keyCount >= 0 {
+		fargs = append(fargs, s.keyCount)
+	}
+
+	fargs = append(fargs, args...)
+
+	v, err := c.Do("FCALL", fargs...)
+	if err != nil && functionNotFound(err) {
+		if err = loadFunctions(c); err == nil {
+			v, err = c.Do("FCALL", fargs...)
+		}
+	}
+
+	if err != nil && unsupported(err) {
+		atomic.StoreInt32(&r.noFunctions, 1)
+		return s.Do(c, args...)
+	}
+
+	return v, err
+}
+
+// functionsEnabled checks whether scripts should be run as Redis
+// Functions.
+func (r *RedisStore) functionsEnabled() bool {
+	if !r.functions || atomic.LoadInt32(&r.noFunctions) == 1 {
+		return false
+	}
+
+	caps := r.Capabilities()
+
+	return caps.Version == "" || caps.Functions
+}
+
+// functionNotFound checks whether the error was returned because the
+// called function is not loaded.
+func functionNotFound(err error) bool {
+	var rerr redis.Error
+	if !errors.As(err, &rerr) {
+		return false
+	}
+
+	return strings.HasPrefix(strings.ToLower(rerr.Error()), "err function not found")
+}
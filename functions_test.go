@@ -0,0 +1,124 @@
+package redisstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithFunctions(t *testing.T) {
+	r := RedisStore{}
+	WithFunctions(true)(&r)
+	assert.True(t, r.functions)
+}
+
+func Test_functionsLibrarySrc(t *testing.T) {
+	src := functionsLibrarySrc()
+	assert.True(t, strings.HasPrefix(src, "#!lua name=redisstore_v1\n"))
+
+	for _, s := range luaScripts {
+		assert.Contains(t, src, `redis.register_function("redisstore_v1_`+s.name+`", function(KEYS, ARGV)`)
+		assert.Contains(t, src, s.src)
+	}
+}
+
+func Test_RedisStore_LoadFunctions(t *testing.T) {
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	conn.Command("FUNCTION", "LOAD", "REPLACE", functionsLibrarySrc()).ExpectError(assert.AnError)
+	assert.Error(t, r.LoadFunctions(context.Background()))
+
+	conn.Clear()
+	conn.Command("FUNCTION", "LOAD", "REPLACE", functionsLibrarySrc()).Expect("redisstore_v1")
+	assert.NoError(t, r.LoadFunctions(context.Background()))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_functionsEnabled(t *testing.T) {
+	r := RedisStore{}
+	assert.False(t, r.functionsEnabled())
+
+	r.functions = true
+	assert.True(t, r.functionsEnabled())
+
+	r.caps.Store(Capabilities{Version: "6.2.0"})
+	assert.False(t, r.functionsEnabled())
+
+	r.caps.Store(Capabilities{Version: "7.0.0", Functions: true})
+	assert.True(t, r.functionsEnabled())
+
+	r.noFunctions = 1
+	assert.False(t, r.functionsEnabled())
+}
+
+func Test_RedisStore_runScript(t *testing.T) {
+	script := newLuaScript("test", 1, "return 1")
+	conn := redigomock.NewConn()
+
+	r := RedisStore{}
+	conn.Script([]byte("return 1"), 1, "key").Expect(int64(1))
+
+	v, err := r.runScript(conn, script, "key")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), v)
+
+	r.functions = true
+
+	conn.Clear()
+	conn.Command("FCALL", "redisstore_v1_test", 1, "key").ExpectError(assert.AnError)
+
+	_, err = r.runScript(conn, script, "key")
+	assert.Equal(t, assert.AnError, err)
+
+	conn.Clear()
+	conn.Command("FCALL", "redisstore_v1_test", 1, "key").Expect(int64(1))
+
+	v, err = r.runScript(conn, script, "key")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), v)
+
+	conn.Clear()
+	conn.Command("FCALL", "redisstore_v1_test", 1, "key").
+		ExpectError(redis.Error("ERR Function not found")).
+		Expect(int64(1))
+	conn.Command("FUNCTION", "LOAD", "REPLACE", functionsLibrarySrc()).Expect("redisstore_v1")
+
+	v, err = r.runScript(conn, script, "key")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), v)
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	conn.Clear()
+	conn.Command("FCALL", "redisstore_v1_test", 1, "key").ExpectError(redis.Error("ERR unknown command 'FCALL'"))
+	conn.Script([]byte("return 1"), 1, "key").Expect(int64(1))
+
+	v, err = r.runScript(conn, script, "key")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), v)
+	assert.False(t, r.functionsEnabled())
+
+	indexed := newLuaScript("index", -1, "return 1")
+	r.noFunctions = 0
+
+	conn.Clear()
+	conn.Command("FCALL", "redisstore_v1_index", 2, "key1", "key2", "arg").Expect(int64(1))
+
+	_, err = r.runScript(conn, indexed, 2, "key1", "key2", "arg")
+	assert.NoError(t, err)
+}
+
+func Test_functionNotFound(t *testing.T) {
+	assert.False(t, functionNotFound(assert.AnError))
+	assert.False(t, functionNotFound(redis.Error("ERR unknown command 'FCALL'")))
+	assert.True(t, functionNotFound(redis.Error("ERR Function not found")))
+}
@@ -3,8 +3,14 @@ module github.com/swithek/sessionup-redisstore
 go 1.15
 
 require (
+	github.com/alicebob/miniredis/v2 v2.30.4
 	github.com/gomodule/redigo v1.8.2
+	github.com/gorilla/securecookie v1.1.1
+	github.com/gorilla/sessions v1.2.1
 	github.com/rafaeljusto/redigomock v2.4.0+incompatible
-	github.com/stretchr/testify v1.5.1
+	github.com/stretchr/testify v1.7.0
 	github.com/swithek/sessionup v1.4.0
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
+	xojoc.pw/useragent v0.0.0-20170215185434-52903803fc66
 )
@@ -0,0 +1,198 @@
+// Package gorillastore adapts redisstore to the Store interface of
+// github.com/gorilla/sessions, so that legacy handlers built on gorilla
+// sessions and new handlers using sessionup can share the same session
+// keyspace.
+package gorillastore
+
+import (
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/swithek/sessionup"
+	redisstore "github.com/swithek/sessionup-redisstore"
+)
+
+// Store implements the sessions.Store interface on top of RedisStore.
+// Only the ID of a session is kept in its cookie, signed (and
+// optionally encrypted) by securecookie, while the session's values
+// are gob encoded and stored as the payload of the session (see
+// RedisStore.Upsert).
+type Store struct {
+	store   *redisstore.RedisStore
+	codecs  []securecookie.Codec
+	options sessions.Options
+	ttl     time.Duration
+	userKey func(r *http.Request, sess *sessions.Session) string
+}
+
+// setter is used to set Store configuration options.
+type setter func(*Store)
+
+// WithOptions sets the default cookie options of new sessions.
+// Options.MaxAge determines how long sessions are kept in the store;
+// a negative value deletes a session when it is saved.
+// Defaults to a path of "/" and a max age of 30 days.
+func WithOptions(o sessions.Options) setter {
+	return func(s *Store) {
+		s.options = o
+	}
+}
+
+// WithSessionTTL sets the duration for which sessions with a max age
+// of zero (i.e. sessions whose cookies are deleted when the browser is
+// closed) are kept in the store.
+// Defaults to 24 hours.
+func WithSessionTTL(d time.Duration) setter {
+	return func(s *Store) {
+		s.ttl = d
+	}
+}
+
+// WithUserKey sets the function that determines the user key of a new
+// session, e.g. by reading the ID of the authenticated user from the
+// session's values. Sessions of the same user can then be managed
+// together with sessionup (e.g. with RedisStore.DeleteByUserKey).
+// Defaults to a function that returns the session's ID, so that each
+// session belongs to its own anonymous user.
+func WithUserKey(fn func(r *http.Request, sess *sessions.Session) string) setter {
+	return func(s *Store) {
+		s.userKey = fn
+	}
+}
+
+// New returns a new Store that persists gorilla sessions in the
+// provided RedisStore and uses the provided codecs (see
+// securecookie.CodecsFromPairs) to encode session IDs in cookies.
+func New(r *redisstore.RedisStore, codecs []securecookie.Codec, opts ...setter) *Store {
+	s := &Store{
+		store:  r,
+		codecs: codecs,
+		options: sessions.Options{
+			Path:   "/",
+			MaxAge: 86400 * 30,
+		},
+		ttl: 24 * time.Hour,
+		userKey: func(_ *http.Request, sess *sessions.Session) string {
+			return sess.ID
+		},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Get returns the session with the provided name, after adding it to
+// the registry of the request (see sessions.GetRegistry).
+// A new session is returned if the request does not reference an
+// existing one; its IsNew field is set to true.
+func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns the session with the provided name without adding it to
+// the registry of the request.
+// A new session is returned if the request does not reference an
+// existing one. If the session's cookie cannot be decoded, a new
+// session is returned along with the error.
+func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
+	sess := sessions.NewSession(s, name)
+	opts := s.options
+	sess.Options = &opts
+	sess.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return sess, nil
+	}
+
+	var id string
+
+	if err = securecookie.DecodeMulti(name, c.Value, &id, s.codecs...); err != nil {
+		return sess, err
+	}
+
+	b, ok, err := s.store.GetPayload(r.Context(), id)
+	if err != nil || !ok {
+		return sess, err
+	}
+
+	if err = (securecookie.GobEncoder{}).Deserialize(b, &sess.Values); err != nil {
+		return sess, err
+	}
+
+	sess.ID = id
+	sess.IsNew = false
+
+	return sess, nil
+}
+
+// Save persists the provided session and adds its cookie to the
+// response. If the session's max age is negative, the session is
+// deleted along with its cookie.
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, sess *sessions.Session) error {
+	if sess.Options.MaxAge < 0 {
+		if sess.ID != "" {
+			err := s.store.DeleteByID(r.Context(), sess.ID)
+			if err != nil && !errors.Is(err, redisstore.ErrNoSession) {
+				return err
+			}
+		}
+
+		http.SetCookie(w, sessions.NewCookie(sess.Name(), "", sess.Options))
+
+		return nil
+	}
+
+	if sess.ID == "" {
+		sess.ID = strings.TrimRight(base32.StdEncoding.EncodeToString(
+			securecookie.GenerateRandomKey(32)), "=")
+	}
+
+	b, err := (securecookie.GobEncoder{}).Serialize(sess.Values)
+	if err != nil {
+		return err
+	}
+
+	exp := time.Now().Add(s.ttl)
+	if sess.Options.MaxAge > 0 {
+		exp = time.Now().Add(time.Duration(sess.Options.MaxAge) * time.Second)
+	}
+
+	if err = s.commit(r, sess, b, exp); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(sess.Name(), sess.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, sessions.NewCookie(sess.Name(), encoded, sess.Options))
+
+	return nil
+}
+
+// commit creates or extends the session and replaces its payload with
+// the provided data in a single transaction.
+func (s *Store) commit(r *http.Request, sess *sessions.Session, b []byte, exp time.Time) error {
+	return s.store.Upsert(r.Context(), s.session(r, sess, exp), b)
+}
+
+// session returns the sessionup representation of a gorilla session
+// that is used if it does not exist yet.
+func (s *Store) session(r *http.Request, sess *sessions.Session, exp time.Time) sessionup.Session {
+	return sessionup.Session{
+		CreatedAt: time.Now(),
+		ExpiresAt: exp,
+		ID:        sess.ID,
+		UserKey:   s.userKey(r, sess),
+	}
+}
@@ -0,0 +1,119 @@
+package gorillastore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup-redisstore/redisstoretest"
+)
+
+var codecs = securecookie.CodecsFromPairs([]byte("0123456789abcdef0123456789abcdef"))
+
+func Test_New(t *testing.T) {
+	s := New(nil, codecs)
+	assert.Equal(t, sessions.Options{Path: "/", MaxAge: 86400 * 30}, s.options)
+	assert.Equal(t, 24*time.Hour, s.ttl)
+	assert.Equal(t, "id123", s.userKey(nil, &sessions.Session{ID: "id123"}))
+
+	s = New(nil, codecs,
+		WithOptions(sessions.Options{Path: "/app"}),
+		WithSessionTTL(time.Hour),
+		WithUserKey(func(*http.Request, *sessions.Session) string {
+			return "u123"
+		}),
+	)
+	assert.Equal(t, sessions.Options{Path: "/app"}, s.options)
+	assert.Equal(t, time.Hour, s.ttl)
+	assert.Equal(t, "u123", s.userKey(nil, &sessions.Session{ID: "id123"}))
+}
+
+func Test_Store(t *testing.T) {
+	r := redisstoretest.NewTestStore(t)
+	s := New(r, codecs, WithUserKey(func(_ *http.Request, sess *sessions.Session) string {
+		return sess.Values["user"].(string)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	sess, err := s.Get(req, "sess")
+	require.NoError(t, err)
+	assert.True(t, sess.IsNew)
+	assert.Empty(t, sess.ID)
+
+	sess.Values["user"] = "u123"
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, s.Save(req, rec, sess))
+	require.NotEmpty(t, sess.ID)
+
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "sess", cookies[0].Name)
+	assert.NotContains(t, cookies[0].Value, sess.ID)
+
+	rs, ok, err := r.FetchByID(context.Background(), sess.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "u123", rs.UserKey)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), rs.ExpiresAt, time.Minute)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+
+	loaded, err := s.New(req, "sess")
+	require.NoError(t, err)
+	assert.False(t, loaded.IsNew)
+	assert.Equal(t, sess.ID, loaded.ID)
+	assert.Equal(t, "u123", loaded.Values["user"])
+
+	// existing sessions are extended and their values replaced
+	loaded.Options.MaxAge = 0
+	loaded.Values["theme"] = "dark"
+	require.NoError(t, s.Save(req, httptest.NewRecorder(), loaded))
+
+	rs, _, err = r.FetchByID(context.Background(), sess.ID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), rs.ExpiresAt, time.Minute)
+
+	loaded, err = s.New(req, "sess")
+	require.NoError(t, err)
+	assert.Equal(t, "dark", loaded.Values["theme"])
+
+	// negative max age deletes the session and its cookie
+	loaded.Options.MaxAge = -1
+	rec = httptest.NewRecorder()
+	require.NoError(t, s.Save(req, rec, loaded))
+
+	cookies = rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Empty(t, cookies[0].Value)
+	assert.Negative(t, cookies[0].MaxAge)
+
+	ok, err = r.Exists(context.Background(), sess.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	loaded, err = s.New(req, "sess")
+	require.NoError(t, err)
+	assert.True(t, loaded.IsNew)
+	assert.Empty(t, loaded.ID)
+}
+
+func Test_Store_New_invalidCookie(t *testing.T) {
+	s := New(redisstoretest.NewTestStore(t), codecs)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "sess", Value: "invalid"})
+
+	sess, err := s.New(req, "sess")
+	assert.Error(t, err)
+	assert.True(t, sess.IsNew)
+	assert.Empty(t, sess.ID)
+}
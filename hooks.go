@@ -0,0 +1,100 @@
+package redisstore
+
+import (
+	"context"
+
+	"github.com/swithek/sessionup"
+)
+
+// Hooks holds functions that are called around mutating operations,
+// e.g. to emit domain events or enforce additional policies without
+// wrapping the whole store. Any of them may be nil.
+// After* hooks are called once the operation is complete, along with
+// its error (nil on success); they are called from the goroutine that
+// performed the operation and should not block.
+type Hooks struct {
+	// BeforeCreate is called by Create, Import and Upsert with the
+	// session that is about to be inserted, after it is validated.
+	// A non-nil error rejects the session; it is returned as an
+	// ErrInvalidSession error.
+	BeforeCreate func(ctx context.Context, s sessionup.Session) error
+
+	// AfterCreate is called by Create and Import with the inserted
+	// (or rejected) session, as well as by Upsert if the session did
+	// not exist.
+	AfterCreate func(ctx context.Context, s sessionup.Session, err error)
+
+	// AfterDelete is called by DeleteByID and DeleteByIDs once for each
+	// requested ID, with the deleted session or, if it was not found
+	// (or the deletion failed), a session with only its ID set.
+	// DeleteByUserKey calls it once with a session that has only its
+	// user key set, while DeleteOlderThan calls it for each deleted
+	// session. Create and Upsert call it for each session evicted over
+	// the user session limit (see WithMaxUserSessions).
+	AfterDelete func(ctx context.Context, s sessionup.Session, err error)
+
+	// AfterExtend is called by ExtendByID, SetTTL, Touch and Upsert
+	// (unless it inserted the session) with the extended session or,
+	// if it was not found (or the extension failed), a session with
+	// only its ID set. ExtendByUserKey calls
+	// it once with a session that has only its user key set.
+	AfterExtend func(ctx context.Context, s sessionup.Session, err error)
+}
+
+// WithHooks sets the functions that are called around mutating
+// operations.
+// Defaults to no hooks.
+func WithHooks(h Hooks) setter {
+	return func(r *RedisStore) {
+		r.hooks = h
+	}
+}
+
+// beforeCreate calls the BeforeCreate hook, if it is set.
+func (r *RedisStore) beforeCreate(ctx context.Context, s sessionup.Session) error {
+	if r.hooks.BeforeCreate == nil {
+		return nil
+	}
+
+	return withKind(ErrInvalidSession, r.hooks.BeforeCreate(ctx, s))
+}
+
+// afterCreate emits the event of the created session and calls the
+// AfterCreate hook, if it is set.
+func (r *RedisStore) afterCreate(ctx context.Context, s sessionup.Session, err error) {
+	r.emit(EventCreated, s, err)
+
+	if r.hooks.AfterCreate != nil {
+		r.hooks.AfterCreate(ctx, s, err)
+	}
+}
+
+// afterDelete emits the event of the deleted session and calls the
+// AfterDelete hook, if it is set.
+func (r *RedisStore) afterDelete(ctx context.Context, s sessionup.Session, err error) {
+	r.emit(EventDeleted, s, err)
+
+	if r.hooks.AfterDelete != nil {
+		r.hooks.AfterDelete(ctx, s, err)
+	}
+}
+
+// afterExtend emits the event of the extended session and calls the
+// AfterExtend hook, if it is set.
+func (r *RedisStore) afterExtend(ctx context.Context, s sessionup.Session, err error) {
+	r.emit(EventExtended, s, err)
+
+	if r.hooks.AfterExtend != nil {
+		r.hooks.AfterExtend(ctx, s, err)
+	}
+}
+
+// sessionOrID returns the session or, if it is empty (i.e. it was not
+// found), a session with only the provided ID set.
+func sessionOrID(s sessionup.Session, id string) sessionup.Session {
+	if s.ID == "" {
+		return sessionup.Session{ID: id}
+	}
+
+	return s
+}
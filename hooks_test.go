@@ -0,0 +1,222 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithHooks(t *testing.T) {
+	r := RedisStore{}
+	WithHooks(Hooks{AfterDelete: func(context.Context, sessionup.Session, error) {}})(&r)
+	assert.NotNil(t, r.hooks.AfterDelete)
+}
+
+func Test_sessionOrID(t *testing.T) {
+	assert.Equal(t, sessionup.Session{ID: "id123"}, sessionOrID(sessionup.Session{}, "id123"))
+	assert.Equal(t, sessionup.Session{ID: "id1", UserKey: "u1"}, sessionOrID(sessionup.Session{ID: "id1", UserKey: "u1"}, "id123"))
+}
+
+func Test_RedisStore_Create_hooks(t *testing.T) {
+	inp := sessionup.Session{
+		ID:        "id123",
+		UserKey:   "u123",
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: time.Now().UTC().Add(time.Hour),
+	}
+
+	var (
+		created sessionup.Session
+		res     error
+	)
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithHooks(Hooks{
+		BeforeCreate: func(_ context.Context, s sessionup.Session) error {
+			if s.Meta["blocked"] != "" {
+				return assert.AnError
+			}
+
+			return nil
+		},
+		AfterCreate: func(_ context.Context, s sessionup.Session, err error) {
+			created, res = s, err
+		},
+	}))
+
+	blocked := inp
+	blocked.Meta = map[string]string{"blocked": "1"}
+
+	err := r.Create(context.Background(), blocked)
+	assert.True(t, errors.Is(err, ErrInvalidSession))
+	assert.True(t, errors.Is(err, assert.AnError))
+	assert.Equal(t, blocked, created)
+	assert.Equal(t, err, res)
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	conn.Clear()
+	conn.GenericCommand("EVALSHA").Expect(int64(1))
+
+	require.NoError(t, r.Create(context.Background(), inp))
+	assert.Equal(t, inp, created)
+	assert.NoError(t, res)
+}
+
+func Test_RedisStore_Create_evicted(t *testing.T) {
+	inp := sessionup.Session{
+		ID:        "id123",
+		UserKey:   "u123",
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: time.Now().UTC().Add(time.Hour),
+	}
+
+	var deleted []sessionup.Session
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithMaxUserSessions(1, EvictOldest), WithIPIndex(true), WithHooks(Hooks{
+		AfterDelete: func(_ context.Context, s sessionup.Session, err error) {
+			assert.NoError(t, err)
+			deleted = append(deleted, s)
+		},
+	}))
+
+	conn.GenericCommand("EVALSHA").ExpectSlice([]byte(prefix+":session:id1"), []interface{}{
+		[]byte("created_at"), []byte(inp.CreatedAt.Format(time.RFC3339Nano)),
+		[]byte("expires_at"), []byte(inp.ExpiresAt.Format(time.RFC3339Nano)),
+		[]byte("id"), []byte("id1"),
+		[]byte("user_key"), []byte("u123"),
+		[]byte("ip"), []byte("127.0.0.1"),
+	})
+	del := conn.Command("DEL", prefix+":session:id1", prefix+":payload:id1").Expect(int64(1))
+	conn.Command("ZREM", prefix+":user:u123", prefix+":session:id1").Expect(int64(0))
+	zrem := conn.Command("ZREM", prefix+":ip:127.0.0.1", prefix+":session:id1").Expect(int64(1))
+
+	require.NoError(t, r.Create(context.Background(), inp))
+	require.Len(t, deleted, 1)
+	assert.Equal(t, "id1", deleted[0].ID)
+	assert.Equal(t, 1, conn.Stats(del))
+	assert.Equal(t, 1, conn.Stats(zrem))
+}
+
+func Test_RedisStore_DeleteByID_hooks(t *testing.T) {
+	sKey := prefix + ":session:id123"
+	pKey := prefix + ":payload:id123"
+
+	var (
+		deleted sessionup.Session
+		res     error
+	)
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithHooks(Hooks{
+		AfterDelete: func(_ context.Context, s sessionup.Session, err error) {
+			deleted, res = s, err
+		},
+	}))
+
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectError(assert.AnError)
+	conn.GenericCommand("UNWATCH")
+
+	err := r.DeleteByID(context.Background(), "id123")
+	assert.Error(t, err)
+	assert.Equal(t, sessionup.Session{ID: "id123"}, deleted)
+	assert.Equal(t, err, res)
+
+	conn.Clear()
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+	conn.GenericCommand("MULTI").Expect("OK")
+	conn.Command("ZREM", prefix+":user:u123", sKey).Expect("QUEUED")
+	conn.Command("DEL", sKey, pKey).Expect("QUEUED")
+	conn.GenericCommand("EXEC").ExpectSlice(int64(1), int64(1))
+
+	require.NoError(t, r.DeleteByID(context.Background(), "id123"))
+	assert.Equal(t, "u123", deleted.UserKey)
+	assert.NoError(t, res)
+}
+
+func Test_RedisStore_DeleteByIDs_hooks(t *testing.T) {
+	sKey1 := prefix + ":session:id1"
+	sKey2 := prefix + ":session:id2"
+
+	deleted := make(map[string]sessionup.Session)
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithHooks(Hooks{
+		AfterDelete: func(_ context.Context, s sessionup.Session, err error) {
+			assert.NoError(t, err)
+			deleted[s.ID] = s
+		},
+	}))
+
+	conn.Command("WATCH", sKey1, sKey2).Expect("OK")
+	conn.Command("HGETALL", sKey1).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id1",
+		"user_key":   "u123",
+	})
+	conn.Command("HGETALL", sKey2).ExpectError(redis.ErrNil)
+	conn.GenericCommand("MULTI").Expect("OK")
+	conn.Command("ZREM", prefix+":user:u123", sKey1).Expect("QUEUED")
+	conn.Command("DEL", sKey1, prefix+":payload:id1").Expect("QUEUED")
+	conn.GenericCommand("EXEC").ExpectSlice(int64(1), int64(1))
+
+	require.NoError(t, r.DeleteByIDs(context.Background(), "id1", "id2"))
+	assert.Equal(t, "u123", deleted["id1"].UserKey)
+	assert.Equal(t, sessionup.Session{ID: "id2"}, deleted["id2"])
+}
+
+func Test_RedisStore_ExtendByID_hooks(t *testing.T) {
+	sKey := prefix + ":session:id123"
+
+	var extended sessionup.Session
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithHooks(Hooks{
+		AfterExtend: func(_ context.Context, s sessionup.Session, err error) {
+			assert.NoError(t, err)
+			extended = s
+		},
+	}))
+
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+	conn.GenericCommand("UNWATCH")
+
+	require.NoError(t, r.ExtendByID(context.Background(), "id123", time.Now().Add(time.Hour)))
+	assert.Equal(t, sessionup.Session{ID: "id123"}, extended)
+}
@@ -0,0 +1,138 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/swithek/sessionup"
+)
+
+// errNoIdleExpiration is returned when a session is touched while
+// idle expiration is not enabled.
+var errNoIdleExpiration = errors.New("redisstore: idle expiration is not enabled")
+
+// WithIdleExpiration enables separate idle and absolute expiration
+// times (e.g. 30 minutes of inactivity, but no longer than 12 hours
+// in total). The expiration time of each created session is treated
+// as its absolute expiration time, while its idle expiration time is
+// set to its creation time plus d and can be moved with Touch. Both
+// are stored in the session ("absolute_expires_at" and
+// "idle_expires_at" fields), while its ExpiresAt field and the TTL of
+// its key are set to the sooner of the two.
+// If the idle timeout is enabled as well (see WithIdleTimeout),
+// FetchByID moves the idle expiration time instead of the absolute one.
+// Defaults to 0 (disabled).
+func WithIdleExpiration(d time.Duration) setter {
+	return func(r *RedisStore) {
+		r.idleExpiration = d
+	}
+}
+
+// Touch moves the idle expiration time of the session with the
+// provided ID to the current time plus the duration set with
+// WithIdleExpiration, without changing its absolute expiration time.
+// Sessions created before idle expiration was enabled get their
+// current expiration time as the absolute one.
+// If session is not found, this function will be no-op.
+// errNoIdleExpiration is returned if idle expiration is not enabled.
+// If invalidations are enabled, an invalidation message is published
+// afterwards.
+func (r *RedisStore) Touch(ctx context.Context, id string) (err error) {
+	if r.idleExpiration <= 0 {
+		return errNoIdleExpiration
+	}
+
+	var (
+		s  sessionup.Session
+		ok bool
+	)
+
+	defer func() { r.afterExtend(ctx, sessionOrID(s, id), err) }()
+
+	c, end, err := r.begin(ctx, "Touch")
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = end(err) }()
+
+	err = r.retryTx(ctx, func() error {
+		var err error
+		s, ok, err = r.extendByIDTx(c, id, func(d *DetailedSession) {
+			if d.AbsoluteExpiresAt.IsZero() {
+				d.AbsoluteExpiresAt = d.ExpiresAt
+			}
+
+			setIdleDeadline(d, r.now().Add(r.idleExpiration))
+		})
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = r.invalidate(c, Invalidation{ID: id}); err != nil {
+		return err
+	}
+
+	if !ok {
+		return nil
+	}
+
+	return r.addToIndexes(ctx, c, s)
+}
+
+// withDeadlines prepares the session for insertion, setting its idle
+// and absolute expiration times if idle expiration is enabled.
+func (r *RedisStore) withDeadlines(s sessionup.Session) DetailedSession {
+	d := DetailedSession{Session: s}
+	if r.idleExpiration <= 0 {
+		return d
+	}
+
+	created := s.CreatedAt
+	if created.IsZero() {
+		created = r.now()
+	}
+
+	d.AbsoluteExpiresAt = s.ExpiresAt
+	setIdleDeadline(&d, created.Add(r.idleExpiration))
+
+	return d
+}
+
+// setIdleDeadline sets the idle expiration time of the session.
+// Sessions without separate expiration times simply expire at t.
+func setIdleDeadline(d *DetailedSession, t time.Time) {
+	if d.AbsoluteExpiresAt.IsZero() {
+		d.ExpiresAt = t
+		return
+	}
+
+	d.IdleExpiresAt = t
+	d.ExpiresAt = earliest(d.AbsoluteExpiresAt, t)
+}
+
+// setAbsoluteDeadline sets the absolute expiration time of the
+// session. Sessions without separate expiration times simply expire
+// at t.
+func setAbsoluteDeadline(d *DetailedSession, t time.Time) {
+	if d.AbsoluteExpiresAt.IsZero() {
+		d.ExpiresAt = t
+		return
+	}
+
+	d.AbsoluteExpiresAt = t
+	d.ExpiresAt = earliest(d.IdleExpiresAt, t)
+}
+
+// earliest returns the sooner of the two times.
+func earliest(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+
+	return a
+}
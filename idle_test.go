@@ -0,0 +1,172 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithIdleExpiration(t *testing.T) {
+	r := RedisStore{}
+	WithIdleExpiration(time.Minute)(&r)
+	assert.Equal(t, time.Minute, r.idleExpiration)
+}
+
+func Test_RedisStore_withDeadlines(t *testing.T) {
+	now := time.Now()
+	s := sessionup.Session{CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+	r := RedisStore{}
+	assert.Equal(t, DetailedSession{Session: s}, r.withDeadlines(s))
+
+	r.idleExpiration = time.Minute
+	d := r.withDeadlines(s)
+	assert.Equal(t, now.Add(time.Hour), d.AbsoluteExpiresAt)
+	assert.Equal(t, now.Add(time.Minute), d.IdleExpiresAt)
+	assert.Equal(t, now.Add(time.Minute), d.ExpiresAt)
+
+	r.idleExpiration = time.Hour * 2
+	d = r.withDeadlines(s)
+	assert.Equal(t, now.Add(time.Hour), d.ExpiresAt)
+}
+
+func Test_setIdleDeadline(t *testing.T) {
+	now := time.Now()
+
+	d := DetailedSession{}
+	setIdleDeadline(&d, now)
+	assert.Equal(t, now, d.ExpiresAt)
+	assert.True(t, d.IdleExpiresAt.IsZero())
+
+	d = DetailedSession{AbsoluteExpiresAt: now.Add(time.Hour)}
+	setIdleDeadline(&d, now.Add(time.Minute))
+	assert.Equal(t, now.Add(time.Minute), d.IdleExpiresAt)
+	assert.Equal(t, now.Add(time.Minute), d.ExpiresAt)
+
+	setIdleDeadline(&d, now.Add(time.Hour*2))
+	assert.Equal(t, now.Add(time.Hour*2), d.IdleExpiresAt)
+	assert.Equal(t, now.Add(time.Hour), d.ExpiresAt)
+}
+
+func Test_setAbsoluteDeadline(t *testing.T) {
+	now := time.Now()
+
+	d := DetailedSession{}
+	setAbsoluteDeadline(&d, now)
+	assert.Equal(t, now, d.ExpiresAt)
+	assert.True(t, d.AbsoluteExpiresAt.IsZero())
+
+	d = DetailedSession{AbsoluteExpiresAt: now, IdleExpiresAt: now.Add(time.Minute)}
+	setAbsoluteDeadline(&d, now.Add(time.Hour))
+	assert.Equal(t, now.Add(time.Hour), d.AbsoluteExpiresAt)
+	assert.Equal(t, now.Add(time.Minute), d.ExpiresAt)
+}
+
+func Test_RedisStore_encodeDetailed_deadlines(t *testing.T) {
+	now := time.Now().UTC()
+	d := DetailedSession{
+		Session: sessionup.Session{
+			ID:        "id123",
+			UserKey:   "u123",
+			CreatedAt: now,
+			ExpiresAt: now.Add(time.Minute),
+		},
+		AbsoluteExpiresAt: now.Add(time.Hour),
+		IdleExpiresAt:     now.Add(time.Minute),
+	}
+
+	for _, r := range []*RedisStore{{}, {asJSON: true}, {codec: ProtobufCodec{}}} {
+		_, data, err := r.encodeDetailed(d)
+		require.NoError(t, err)
+
+		var reply interface{}
+
+		if r.singleValue() {
+			reply = data[0]
+		} else {
+			vv := make([]interface{}, len(data))
+			for i := range data {
+				vv[i] = []byte(data[i].(string))
+			}
+
+			reply = vv
+		}
+
+		res, ok, err := r.decodeDetailed("id123", reply, nil)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.True(t, d.AbsoluteExpiresAt.Equal(res.AbsoluteExpiresAt))
+		assert.True(t, d.IdleExpiresAt.Equal(res.IdleExpiresAt))
+	}
+}
+
+func Test_RedisStore_Touch(t *testing.T) {
+	now := time.Now().UTC()
+	abs := now.Add(time.Hour)
+	sKey := prefix + ":session:id123"
+	pKey := prefix + ":payload:id123"
+	uKey := prefix + ":user:u123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	assert.Equal(t, errNoIdleExpiration, r.Touch(context.Background(), "id123"))
+
+	WithIdleExpiration(time.Minute)(r)
+
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": now.Format(time.RFC3339Nano),
+		"expires_at": abs.Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+	conn.Command("WATCH", uKey).Expect("OK")
+	conn.Command("PTTL", uKey).Expect(int64(1000))
+	conn.GenericCommand("MULTI").Expect("OK")
+	conn.Command("ZADD", uKey, redigomock.NewAnyInt(), sKey)
+	conn.Command("PEXPIREAT", uKey, redigomock.NewAnyInt())
+	conn.Command("HMSET", sKey,
+		"created_at", now.Format(time.RFC3339Nano),
+		"expires_at", redigomock.NewAnyData(),
+		"id", "id123",
+		"user_key", "u123",
+		"ip", "",
+		"agent_os", "",
+		"agent_browser", "",
+		"meta", "",
+		"schema_version", "1",
+		"absolute_expires_at", abs.Format(time.RFC3339Nano),
+		"idle_expires_at", redigomock.NewAnyData(),
+	)
+	conn.Command("PEXPIREAT", sKey, redigomock.NewAnyInt())
+	conn.Command("PEXPIREAT", pKey, redigomock.NewAnyInt())
+	conn.GenericCommand("EXEC").ExpectSlice()
+
+	require.NoError(t, r.Touch(context.Background(), "id123"))
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	conn.Clear()
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+	conn.GenericCommand("UNWATCH")
+
+	require.NoError(t, r.Touch(context.Background(), "id123"))
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	conn.Clear()
+	conn.Command("WATCH", sKey).ExpectError(assert.AnError)
+	conn.GenericCommand("UNWATCH")
+
+	assert.Error(t, r.Touch(context.Background(), "id123"))
+}
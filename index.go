@@ -0,0 +1,469 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// errNoIndex is returned when sessions are requested by a secondary
+// index that is not enabled.
+var errNoIndex = errors.New("redisstore: index is not enabled")
+
+// indexScriptSrc adds the session key (ARGV[5]) to each secondary
+// index set (KEYS), removes expired entries from them and extends
+// their expiration times to the session's, if it is later.
+// ARGV holds the current time in nanoseconds and milliseconds, as
+// well as session's expiration time in nanoseconds and milliseconds.
+const indexScriptSrc = `
+for i = 1, #KEYS do
+	local exp = redis.call("PTTL", KEYS[i]) + tonumber(ARGV[2])
+	local sexp = tonumber(ARGV[4])
+	if sexp > exp then
+		exp = sexp
+	end
+
+	redis.call("ZREMRANGEBYSCORE", KEYS[i], "-inf", ARGV[1])
+	redis.call("ZADD", KEYS[i], ARGV[3], ARGV[5])
+	redis.call("PEXPIREAT", KEYS[i], exp)
+end
+
+return 1
+`
+
+var indexScript = newLuaScript("index", -1, indexScriptSrc)
+
+// WithIPIndex determines whether sessions should be indexed by their
+// IP addresses, so that they can be retrieved with FetchByIP.
+// Each index is a sorted set of session keys, similar to user session
+// sets, stored under "<prefix>:ip:<address>". If encryption is enabled
+// (see WithEncryption), addresses are replaced by their keyed hashes
+// in key names; otherwise redacting them (see WithRedaction) leaves
+// only their networks exposed. Entries are removed along with their
+// sessions when these are deleted.
+// Defaults to false.
+func WithIPIndex(t bool) setter {
+	return func(r *RedisStore) {
+		r.ipIndex = t
+	}
+}
+
+// WithAgentIndex determines whether sessions should be indexed by the
+// operating systems and browsers of their User-Agents, so that they can
+// be retrieved with FetchByAgent. The indexes are sorted sets of
+// session keys, stored under "<prefix>:agent_os:<os>" and
+// "<prefix>:agent_browser:<browser>". If encryption is enabled (see
+// WithEncryption), these values are replaced by their keyed hashes in
+// key names. Entries are removed along with their sessions when these
+// are deleted.
+// Defaults to false.
+func WithAgentIndex(t bool) setter {
+	return func(r *RedisStore) {
+		r.agentIndex = t
+	}
+}
+
+// WithIndexedMeta enables indexing of sessions by the values of the
+// provided metadata keys, so that they can be retrieved with
+// FetchByMeta. Each index is a sorted set of session keys, stored under
+// "<prefix>:meta:<key>:<value>". If encryption is enabled (see
+// WithEncryption), indexed values are replaced by their keyed hashes
+// in key names. Entries are removed along with their sessions when
+// these are deleted.
+// Defaults to no keys.
+func WithIndexedMeta(keys ...string) setter {
+	return func(r *RedisStore) {
+		r.indexedMeta = keys
+	}
+}
+
+// FetchByMeta retrieves all sessions whose metadata entry of the
+// provided key holds the provided value. If none are found, both
+// return values will be nil.
+// errNoIndex is returned if the key is not indexed (see
+// WithIndexedMeta).
+func (r *RedisStore) FetchByMeta(ctx context.Context, key, value string) ([]sessionup.Session, error) {
+	if !r.metaIndexed(key) {
+		return nil, errNoIndex
+	}
+
+	ss, err := r.fetchIndexed(ctx, "FetchByMeta", metaNamespace(key), value)
+	if err != nil {
+		return nil, err
+	}
+
+	// metadata may have been changed since the session was indexed
+	var res []sessionup.Session
+
+	for i := range ss {
+		if v, ok := ss[i].Meta[key]; ok && v == value {
+			res = append(res, ss[i])
+		}
+	}
+
+	return res, nil
+}
+
+// metaIndexed checks whether the metadata key is indexed.
+func (r *RedisStore) metaIndexed(key string) bool {
+	for _, k := range r.indexedMeta {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// metaNamespace returns the namespace of the metadata key's index.
+func metaNamespace(key string) string {
+	return "meta:" + keyEscaper.Replace(key)
+}
+
+// FetchByIP retrieves all sessions created from the provided IP
+// address. If none are found (or the address is nil), both return
+// values will be nil. If IP addresses are redacted (see WithRedaction),
+// sessions created from the same network are retrieved.
+// errNoIndex is returned if the IP index is not enabled.
+func (r *RedisStore) FetchByIP(ctx context.Context, ip net.IP) (ss []sessionup.Session, err error) {
+	if !r.ipIndex {
+		return nil, errNoIndex
+	}
+
+	if r.redactIP {
+		ip = maskIP(ip)
+	}
+
+	v := ipToString(ip)
+	if v == "" {
+		return nil, nil
+	}
+
+	return r.fetchIndexed(ctx, "FetchByIP", "ip", v)
+}
+
+// FetchByAgent retrieves all sessions whose User-Agent data matches the
+// provided operating system and browser. An empty value matches any
+// operating system or browser respectively. If none are found (or both
+// values are empty), both return values will be nil.
+// errNoIndex is returned if the agent index is not enabled (see
+// WithAgentIndex).
+func (r *RedisStore) FetchByAgent(ctx context.Context, os, browser string) ([]sessionup.Session, error) {
+	if !r.agentIndex {
+		return nil, errNoIndex
+	}
+
+	if r.redactAgent {
+		os, browser = r.hashAgent(os), r.hashAgent(browser)
+	}
+
+	namespace, v := "agent_os", os
+	if os == "" {
+		namespace, v = "agent_browser", browser
+	}
+
+	if v == "" {
+		return nil, nil
+	}
+
+	ss, err := r.fetchIndexed(ctx, "FetchByAgent", namespace, v)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []sessionup.Session
+
+	for i := range ss {
+		if (os == "" || ss[i].Agent.OS == os) && (browser == "" || ss[i].Agent.Browser == browser) {
+			res = append(res, ss[i])
+		}
+	}
+
+	return res, nil
+}
+
+// fetchIndexed retrieves all live sessions found in the secondary
+// index set of the namespace and value.
+func (r *RedisStore) fetchIndexed(ctx context.Context, name, namespace, v string) (ss []sessionup.Session, err error) {
+	c, end, err := r.begin(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { err = end(err) }()
+
+	now := r.now().UnixNano()
+
+	var keys []string
+
+	// entries added before a key rotation are stored under the
+	// hashes of old keys (see WithEncryption)
+	for _, h := range r.enc.hashes(v) {
+		kk, err := redis.Strings(c.Do("ZRANGEBYSCORE", r.buildKey(connTenant(c), namespace, h), now, "+inf"))
+		if err != nil && !errors.Is(err, redis.ErrNil) {
+			return nil, err
+		}
+
+		keys = append(keys, kk...)
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	return r.fetchKeys(c, uniqueIDs(keys))
+}
+
+// indexNamespaces returns the namespaces of all enabled secondary
+// indexes.
+func (r *RedisStore) indexNamespaces() []string {
+	var nn []string
+
+	if r.ipIndex {
+		nn = append(nn, "ip")
+	}
+
+	if r.agentIndex {
+		nn = append(nn, "agent_os", "agent_browser")
+	}
+
+	for _, k := range r.indexedMeta {
+		nn = append(nn, metaNamespace(k))
+	}
+
+	if r.createdIndex {
+		nn = append(nn, "created")
+	}
+
+	if r.expiryIndex {
+		nn = append(nn, "expiry")
+	}
+
+	return nn
+}
+
+// indexPattern returns a SCAN pattern that matches all secondary
+// index sets of the namespace, scoped to the tenant of the connection.
+func (r *RedisStore) indexPattern(c redis.Conn, namespace string) string {
+	return globEscaper.Replace(r.buildKey(connTenant(c), namespace, "")) + "*"
+}
+
+// indexKeys returns the keys of all secondary index sets the session
+// belongs to. If encryption is enabled, indexed values are replaced by
+// their keyed hashes; if rotated is true, the keys of values hashed
+// with old encryption keys are returned as well, so that entries added
+// before a key rotation can be removed.
+func (r *RedisStore) indexKeys(c redis.Conn, s sessionup.Session, rotated bool) []string {
+	var kk []string
+
+	add := func(namespace, v string) {
+		vv := []string{r.enc.hash(v)}
+		if rotated {
+			vv = r.enc.hashes(v)
+		}
+
+		for _, v := range vv {
+			kk = append(kk, r.buildKey(connTenant(c), namespace, v))
+		}
+	}
+
+	if v := ipToString(s.IP); r.ipIndex && v != "" {
+		add("ip", v)
+	}
+
+	if r.agentIndex && s.Agent.OS != "" {
+		add("agent_os", s.Agent.OS)
+	}
+
+	if r.agentIndex && s.Agent.Browser != "" {
+		add("agent_browser", s.Agent.Browser)
+	}
+
+	for _, k := range r.indexedMeta {
+		if v, ok := s.Meta[k]; ok {
+			add(metaNamespace(k), v)
+		}
+	}
+
+	if r.expiryIndex {
+		kk = append(kk, r.expiryKey(c))
+	}
+
+	return kk
+}
+
+// unindexCmds returns the commands that remove the provided sessions
+// from all secondary index sets they belong to, including the creation
+// time index.
+func (r *RedisStore) unindexCmds(c redis.Conn, ss ...sessionup.Session) [][]interface{} {
+	var cmds [][]interface{}
+
+	for _, s := range ss {
+		sKey := r.key(c, false, s.ID)
+
+		for _, k := range r.indexKeys(c, s, true) {
+			cmds = append(cmds, []interface{}{"ZREM", k, sKey})
+		}
+
+		if r.createdIndex {
+			cmds = append(cmds, []interface{}{"ZREM", r.createdKey(c), sKey})
+		}
+	}
+
+	return cmds
+}
+
+// unindexKeyCmds retrieves the sessions stored under the provided keys
+// and returns the commands that remove them from all secondary index
+// sets (see unindexCmds). Sessions are not retrieved if no secondary
+// indexes are enabled.
+func (r *RedisStore) unindexKeyCmds(c redis.Conn, keys []string) ([][]interface{}, error) {
+	if len(keys) == 0 || len(r.indexNamespaces()) == 0 {
+		return nil, nil
+	}
+
+	ss, err := r.fetchKeys(c, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.unindexCmds(c, ss...), nil
+}
+
+// moveIndexCmds returns the commands that replace the old session key
+// with the new one in all secondary index sets the session belongs to,
+// including the creation time index, e.g. when its ID is renewed.
+// The expiration times of the sets are extended to the session's, if
+// it is later, the same way as when sessions are added to them.
+func (r *RedisStore) moveIndexCmds(c redis.Conn, s sessionup.Session, oldKey, newKey string) ([][]interface{}, error) {
+	keys := r.indexKeys(c, s, false)
+
+	for i := range keys {
+		if err := c.Send("PTTL", keys[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+
+	now := r.now().UnixNano() / int64(time.Millisecond)
+	sExpMilli := r.expireAt(s.ExpiresAt)
+
+	var cmds [][]interface{}
+
+	for i := range keys {
+		ttl, err := redis.Int64(c.Receive())
+		if err != nil {
+			return nil, err
+		}
+
+		exp := ttl + now
+		if sExpMilli > exp {
+			exp = sExpMilli
+		}
+
+		cmds = append(cmds,
+			[]interface{}{"ZREM", keys[i], oldKey},
+			[]interface{}{"ZADD", keys[i], s.ExpiresAt.UnixNano(), newKey},
+			[]interface{}{"PEXPIREAT", keys[i], exp},
+		)
+	}
+
+	if r.createdIndex {
+		cmds = append(cmds,
+			[]interface{}{"ZREM", r.createdKey(c), oldKey},
+			[]interface{}{"ZADD", r.createdKey(c), s.CreatedAt.UnixNano(), newKey},
+		)
+	}
+
+	return cmds, nil
+}
+
+// addToIndexes adds the session to all secondary index sets it
+// belongs to by using a Lua script or, if scripting is not available,
+// a WATCH/MULTI transaction.
+func (r *RedisStore) addToIndexes(ctx context.Context, c redis.Conn, s sessionup.Session) error {
+	keys := r.indexKeys(c, s, false)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if r.scriptsDisabled() {
+		return r.retryTx(ctx, func() error {
+			return r.addToIndexesTx(c, keys, s)
+		})
+	}
+
+	now := r.now().UnixNano()
+
+	args := redis.Args{}.Add(len(keys)).AddFlat(keys).Add(
+		now, now/int64(time.Millisecond),
+		s.ExpiresAt.UnixNano(), r.expireAt(s.ExpiresAt),
+		r.key(c, false, s.ID),
+	)
+
+	_, err := r.runScript(c, indexScript, args...)
+	if err != nil && unsupported(err) {
+		r.disableScripts()
+
+		return r.retryTx(ctx, func() error {
+			return r.addToIndexesTx(c, keys, s)
+		})
+	}
+
+	return err
+}
+
+// addToIndexesTx adds the session to the provided secondary index sets
+// by using a WATCH/MULTI transaction.
+func (r *RedisStore) addToIndexesTx(c redis.Conn, keys []string, s sessionup.Session) error {
+	exps := make([]int64, len(keys))
+
+	for i := range keys {
+		if _, err := c.Do("WATCH", keys[i]); err != nil {
+			return err
+		}
+
+		ttl, err := redis.Int64(c.Do("PTTL", keys[i]))
+		if err != nil {
+			return err
+		}
+
+		exps[i] = ttl
+	}
+
+	now := r.now().UnixNano()
+	sKey := r.key(c, false, s.ID)
+	sExpMilli := r.expireAt(s.ExpiresAt)
+
+	if _, err := c.Do("MULTI"); err != nil {
+		return err
+	}
+
+	for i := range keys {
+		exp := exps[i] + now/int64(time.Millisecond)
+		if sExpMilli > exp {
+			exp = sExpMilli
+		}
+
+		if _, err := c.Do("ZREMRANGEBYSCORE", keys[i], "-inf", now); err != nil {
+			return err
+		}
+
+		if _, err := c.Do("ZADD", keys[i], s.ExpiresAt.UnixNano(), sKey); err != nil {
+			return err
+		}
+
+		if _, err := c.Do("PEXPIREAT", keys[i], exp); err != nil {
+			return err
+		}
+	}
+
+	return exec(c)
+}
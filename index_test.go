@@ -0,0 +1,303 @@
+package redisstore
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithIPIndex(t *testing.T) {
+	r := RedisStore{}
+	WithIPIndex(true)(&r)
+	assert.True(t, r.ipIndex)
+}
+
+func Test_WithAgentIndex(t *testing.T) {
+	r := RedisStore{}
+	WithAgentIndex(true)(&r)
+	assert.True(t, r.agentIndex)
+}
+
+func Test_WithIndexedMeta(t *testing.T) {
+	r := RedisStore{}
+	WithIndexedMeta("role", "device_id")(&r)
+	assert.Equal(t, []string{"role", "device_id"}, r.indexedMeta)
+	assert.True(t, r.metaIndexed("role"))
+	assert.False(t, r.metaIndexed("other"))
+}
+
+func Test_RedisStore_FetchByMeta(t *testing.T) {
+	mKey := prefix + ":meta:device_id:d%3A1"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithIndexedMeta("device_id"))
+
+	_, err := r.FetchByMeta(context.Background(), "role", "admin")
+	assert.Equal(t, errNoIndex, err)
+
+	conn.Command("ZRANGEBYSCORE", mKey, redigomock.NewAnyInt(), "+inf").ExpectError(assert.AnError)
+	_, err = r.FetchByMeta(context.Background(), "device_id", "d:1")
+	assert.Error(t, err)
+
+	session := func(id, device string) map[string]string {
+		return map[string]string{
+			"created_at": time.Now().Format(time.RFC3339Nano),
+			"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+			"id":         id,
+			"user_key":   "u123",
+			"meta":       "device_id=" + device,
+		}
+	}
+
+	conn.Clear()
+	conn.Command("ZRANGEBYSCORE", mKey, redigomock.NewAnyInt(), "+inf").
+		ExpectSlice([]byte(prefix+":session:id1"), []byte(prefix+":session:id2"))
+	conn.Command("HGETALL", prefix+":session:id1").ExpectMap(session("id1", "d%3A1"))
+	conn.Command("HGETALL", prefix+":session:id2").ExpectMap(session("id2", "d2"))
+
+	ss, err := r.FetchByMeta(context.Background(), "device_id", "d:1")
+	require.NoError(t, err)
+	require.Len(t, ss, 1)
+	assert.Equal(t, "id1", ss[0].ID)
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_FetchByIP(t *testing.T) {
+	ipKey := prefix + ":ip:10.0.0.1"
+	sKey := prefix + ":session:id123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	_, err := r.FetchByIP(context.Background(), net.ParseIP("10.0.0.1"))
+	assert.Equal(t, errNoIndex, err)
+
+	r.ipIndex = true
+
+	ss, err := r.FetchByIP(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Nil(t, ss)
+
+	conn.Command("ZRANGEBYSCORE", ipKey, redigomock.NewAnyInt(), "+inf").ExpectError(assert.AnError)
+	_, err = r.FetchByIP(context.Background(), net.ParseIP("10.0.0.1"))
+	assert.Error(t, err)
+
+	conn.Clear()
+	conn.Command("ZRANGEBYSCORE", ipKey, redigomock.NewAnyInt(), "+inf").ExpectError(redis.ErrNil)
+	ss, err = r.FetchByIP(context.Background(), net.ParseIP("10.0.0.1"))
+	require.NoError(t, err)
+	assert.Nil(t, ss)
+
+	conn.Clear()
+	conn.Command("ZRANGEBYSCORE", ipKey, redigomock.NewAnyInt(), "+inf").ExpectSlice([]byte(sKey))
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+		"ip":         "10.0.0.1",
+	})
+
+	ss, err = r.FetchByIP(context.Background(), net.ParseIP("10.0.0.1"))
+	require.NoError(t, err)
+	require.Len(t, ss, 1)
+	assert.Equal(t, "id123", ss[0].ID)
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	// redacted addresses are looked up by their networks
+	r.redactIP = true
+
+	conn.Clear()
+	conn.Command("ZRANGEBYSCORE", prefix+":ip:10.0.0.0", redigomock.NewAnyInt(), "+inf").ExpectError(redis.ErrNil)
+	_, err = r.FetchByIP(context.Background(), net.ParseIP("10.0.0.1"))
+	require.NoError(t, err)
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_FetchByAgent(t *testing.T) {
+	osKey := prefix + ":agent_os:GNU/Linux"
+	browserKey := prefix + ":agent_browser:Firefox"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	_, err := r.FetchByAgent(context.Background(), "GNU/Linux", "Firefox")
+	assert.Equal(t, errNoIndex, err)
+
+	r.agentIndex = true
+
+	ss, err := r.FetchByAgent(context.Background(), "", "")
+	require.NoError(t, err)
+	assert.Nil(t, ss)
+
+	conn.Command("ZRANGEBYSCORE", osKey, redigomock.NewAnyInt(), "+inf").ExpectError(assert.AnError)
+	_, err = r.FetchByAgent(context.Background(), "GNU/Linux", "Firefox")
+	assert.Error(t, err)
+
+	session := func(id, browser string) map[string]string {
+		return map[string]string{
+			"created_at":    time.Now().Format(time.RFC3339Nano),
+			"expires_at":    time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+			"id":            id,
+			"user_key":      "u123",
+			"agent_os":      "GNU/Linux",
+			"agent_browser": browser,
+		}
+	}
+
+	conn.Clear()
+	conn.Command("ZRANGEBYSCORE", osKey, redigomock.NewAnyInt(), "+inf").
+		ExpectSlice([]byte(prefix+":session:id1"), []byte(prefix+":session:id2"))
+	conn.Command("HGETALL", prefix+":session:id1").ExpectMap(session("id1", "Firefox"))
+	conn.Command("HGETALL", prefix+":session:id2").ExpectMap(session("id2", "Chrome"))
+
+	ss, err = r.FetchByAgent(context.Background(), "GNU/Linux", "Firefox")
+	require.NoError(t, err)
+	require.Len(t, ss, 1)
+	assert.Equal(t, "id1", ss[0].ID)
+
+	conn.Command("ZRANGEBYSCORE", browserKey, redigomock.NewAnyInt(), "+inf").
+		ExpectSlice([]byte(prefix + ":session:id1"))
+
+	ss, err = r.FetchByAgent(context.Background(), "", "Firefox")
+	require.NoError(t, err)
+	require.Len(t, ss, 1)
+	assert.Equal(t, "id1", ss[0].ID)
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_indexKeys(t *testing.T) {
+	r := RedisStore{prefix: prefix}
+	s := sessionup.Session{ID: "id123", IP: net.ParseIP("::1")}
+
+	assert.Empty(t, r.indexKeys(nil, s, false))
+	assert.Empty(t, r.indexNamespaces())
+
+	r.ipIndex = true
+	assert.Equal(t, []string{prefix + ":ip:%3A%3A1"}, r.indexKeys(nil, s, false))
+	assert.Equal(t, []string{prefix + ":t1:ip:%3A%3A1"}, r.indexKeys(&scopedConn{tenant: "t1"}, s, false))
+	assert.Empty(t, r.indexKeys(nil, sessionup.Session{ID: "id123"}, false))
+	assert.Equal(t, []string{"ip"}, r.indexNamespaces())
+	assert.Equal(t, prefix+":ip:*", r.indexPattern(nil, "ip"))
+
+	r.indexedMeta = []string{"role", "device_id"}
+	s.Meta = map[string]string{"role": "admin"}
+	assert.Equal(t, []string{prefix + ":ip:%3A%3A1", prefix + ":meta:role:admin"}, r.indexKeys(nil, s, false))
+	assert.Equal(t, []string{"ip", "meta:role", "meta:device_id"}, r.indexNamespaces())
+
+	r.agentIndex = true
+	s.Agent.OS = "Windows"
+	assert.Equal(t, []string{
+		prefix + ":ip:%3A%3A1",
+		prefix + ":agent_os:Windows",
+		prefix + ":meta:role:admin",
+	}, r.indexKeys(nil, s, false))
+	assert.Equal(t, []string{"ip", "agent_os", "agent_browser", "meta:role", "meta:device_id"}, r.indexNamespaces())
+
+	// values are hashed if encryption is enabled.
+	r.enc = newEncryption(key2, key1)
+	r.indexedMeta = nil
+	s.Agent.OS = ""
+	assert.Equal(t, []string{prefix + ":ip:" + r.enc.hash("::1")}, r.indexKeys(nil, s, false))
+	assert.Equal(t, []string{
+		prefix + ":ip:" + newEncryption(key2).hash("::1"),
+		prefix + ":ip:" + newEncryption(key1).hash("::1"),
+	}, r.indexKeys(nil, s, true))
+}
+
+func Test_RedisStore_addToIndexes(t *testing.T) {
+	ipKey := prefix + ":ip:10.0.0.1"
+	sKey := prefix + ":session:id123"
+	s := sessionup.Session{
+		ID:        "id123",
+		IP:        net.ParseIP("10.0.0.1"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	script := func(conn *redigomock.Conn) *redigomock.Cmd {
+		return conn.Script([]byte(indexScriptSrc), 1, ipKey,
+			redigomock.NewAnyInt(), redigomock.NewAnyInt(),
+			s.ExpiresAt.UnixNano(), s.ExpiresAt.UnixNano()/int64(time.Millisecond),
+			sKey,
+		)
+	}
+
+	tx := func(conn *redigomock.Conn) {
+		conn.Command("WATCH", ipKey)
+		conn.Command("PTTL", ipKey).Expect(int64(-2))
+		conn.GenericCommand("MULTI")
+		conn.Command("ZREMRANGEBYSCORE", ipKey, "-inf", redigomock.NewAnyInt())
+		conn.Command("ZADD", ipKey, s.ExpiresAt.UnixNano(), sKey)
+		conn.Command("PEXPIREAT", ipKey, s.ExpiresAt.UnixNano()/int64(time.Millisecond))
+		conn.GenericCommand("EXEC").ExpectSlice()
+	}
+
+	t.Run("No indexes", func(t *testing.T) {
+		conn := redigomock.NewConn()
+		r := RedisStore{prefix: prefix}
+
+		assert.NoError(t, r.addToIndexes(context.Background(), conn, s))
+		assert.NoError(t, conn.ExpectationsWereMet())
+	})
+
+	t.Run("Error returned by script", func(t *testing.T) {
+		conn := redigomock.NewConn()
+		script(conn).ExpectError(assert.AnError)
+
+		r := RedisStore{prefix: prefix, ipIndex: true}
+
+		assert.Equal(t, assert.AnError, r.addToIndexes(context.Background(), conn, s))
+		assert.NoError(t, conn.ExpectationsWereMet())
+	})
+
+	t.Run("Successful execution with script", func(t *testing.T) {
+		conn := redigomock.NewConn()
+		script(conn).Expect(int64(1))
+
+		r := RedisStore{prefix: prefix, ipIndex: true}
+
+		assert.NoError(t, r.addToIndexes(context.Background(), conn, s))
+		assert.NoError(t, conn.ExpectationsWereMet())
+	})
+
+	t.Run("Successful execution with transaction fallback", func(t *testing.T) {
+		conn := redigomock.NewConn()
+		script(conn).ExpectError(redis.Error("ERR unknown command 'EVALSHA'"))
+		tx(conn)
+
+		r := RedisStore{prefix: prefix, ipIndex: true, txAttempts: 1}
+
+		assert.NoError(t, r.addToIndexes(context.Background(), conn, s))
+		assert.True(t, r.scriptsDisabled())
+		assert.NoError(t, conn.ExpectationsWereMet())
+	})
+
+	t.Run("Successful execution with transaction", func(t *testing.T) {
+		conn := redigomock.NewConn()
+		tx(conn)
+
+		r := RedisStore{prefix: prefix, ipIndex: true, txAttempts: 1, noScripts: 1}
+
+		assert.NoError(t, r.addToIndexes(context.Background(), conn, s))
+		assert.NoError(t, conn.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,125 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// errNoInvalidations is returned when invalidation messages are
+// requested but broadcasting is not enabled.
+var errNoInvalidations = errors.New("redisstore: invalidations are not enabled")
+
+// Invalidation describes sessions that were removed from the store
+// or modified, and should no longer be served from local caches.
+type Invalidation struct {
+	// ID specifies the ID of the removed or modified session. It is
+	// empty when the sessions were removed by their user key.
+	ID string `json:"id,omitempty"`
+
+	// All specifies whether all sessions were removed or modified,
+	// e.g. by DeleteAll.
+	All bool `json:"all,omitempty"`
+
+	// UserKey specifies the user key whose sessions were removed.
+	// It is empty when a single session was removed by its ID, as
+	// well as in published messages if encryption is enabled (see
+	// WithEncryption).
+	UserKey string `json:"user_key,omitempty"`
+
+	// UserKeyHash specifies the keyed hash of the user key whose
+	// sessions were removed. It is published instead of UserKey if
+	// encryption is enabled.
+	UserKeyHash string `json:"user_key_hash,omitempty"`
+
+	// Except specifies the IDs of the user's sessions that were
+	// not removed.
+	Except []string `json:"except,omitempty"`
+
+	// Tenant specifies the tenant the sessions belong to. It is
+	// empty when keys are not scoped (see WithTenantFromContext).
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// WithInvalidations enables broadcasting of invalidation messages on
+// the provided Redis Pub/Sub channel each time sessions are deleted
+// (e.g. by DeleteByID, DeleteByUserKey or DeleteAll), replaced by
+// RenewID, modified by ExtendByID or UpdateMeta, imported by Import or
+// copied by Rekey.
+// If the channel is empty, "<prefix>:invalidations" is used.
+// Defaults to disabled.
+func WithInvalidations(channel string) setter {
+	return func(r *RedisStore) {
+		r.invalidations = channel
+		r.defaultChannel = channel == ""
+	}
+}
+
+// Invalidations starts listening for invalidation messages published
+// by all store instances that use the same channel (see
+// WithInvalidations). The messages are sent to the returned channel
+// until the provided context is done or the store is closed, at which
+// point the channel is closed. It is closed as well if the subscription
+// connection fails.
+// Pub/Sub does not guarantee delivery: messages published while the
+// listener is disconnected are lost.
+func (r *RedisStore) Invalidations(ctx context.Context) (<-chan Invalidation, error) {
+	if r.invalidations == "" {
+		return nil, errNoInvalidations
+	}
+
+	invs := make(chan Invalidation)
+	patterns := []interface{}{globEscaper.Replace(r.invalidations)}
+
+	err := r.listen(ctx, "invalidations", patterns, func(m redis.Message) {
+		var inv Invalidation
+		if err := json.Unmarshal(m.Data, &inv); err != nil {
+			return
+		}
+
+		select {
+		case invs <- inv:
+		case <-ctx.Done():
+		case <-r.stop:
+		}
+	}, func() {
+		close(invs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invs, nil
+}
+
+// invalidate publishes the invalidation message, if broadcasting
+// is enabled. Sessions of the local cache are invalidated
+// immediately. The message is marked with the tenant of the
+// connection; its user key is replaced by its keyed hash if
+// encryption is enabled.
+func (r *RedisStore) invalidate(c redis.Conn, inv Invalidation) error {
+	inv.Tenant = connTenant(c)
+
+	if r.cache != nil {
+		r.cache.invalidate(inv)
+	}
+
+	if r.invalidations == "" {
+		return nil
+	}
+
+	if r.enc != nil && inv.UserKey != "" {
+		inv.UserKey, inv.UserKeyHash = "", r.enc.hash(inv.UserKey)
+	}
+
+	b, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Do("PUBLISH", r.invalidations, b)
+
+	return err
+}
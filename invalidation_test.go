@@ -0,0 +1,98 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithInvalidations(t *testing.T) {
+	r := RedisStore{prefix: prefix}
+	WithInvalidations("")(&r)
+	assert.Empty(t, r.invalidations)
+	assert.True(t, r.defaultChannel)
+
+	WithInvalidations("channel")(&r)
+	assert.Equal(t, "channel", r.invalidations)
+	assert.False(t, r.defaultChannel)
+
+	r1 := New(nil, prefix, WithInvalidations(""))
+	assert.Equal(t, prefix+":invalidations", r1.invalidations)
+
+	// the leading colon is applied regardless of the order of options
+	r1 = New(nil, "", WithInvalidations(""), WithLeadingColon(true))
+	assert.Equal(t, ":invalidations", r1.invalidations)
+}
+
+func Test_RedisStore_Invalidations(t *testing.T) {
+	t.Run("Disabled invalidations", func(t *testing.T) {
+		r := RedisStore{}
+
+		ii, err := r.Invalidations(context.Background())
+		assert.Equal(t, errNoInvalidations, err)
+		assert.Nil(t, ii)
+	})
+
+	t.Run("Closed store", func(t *testing.T) {
+		r := RedisStore{closed: true, invalidations: "channel"}
+
+		ii, err := r.Invalidations(context.Background())
+		assert.Equal(t, &Error{Op: "invalidations", Kind: ErrClosed, Err: ErrClosed}, err)
+		assert.Nil(t, ii)
+	})
+
+	t.Run("Successful listening", func(t *testing.T) {
+		conn := newPubSubConn()
+		r := New(&redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return conn, nil
+			},
+		}, "pre*", WithInvalidations(""))
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		ii, err := r.Invalidations(ctx)
+		require.NoError(t, err)
+
+		pattern := `pre\*:invalidations`
+		conn.publish(pattern, r.invalidations, `{"id":"id1"}`)
+		conn.publish(pattern, r.invalidations, `{"id":`)
+		conn.publish(pattern, r.invalidations, `{"user_key":"u123","except":["id2"]}`)
+
+		assert.Equal(t, Invalidation{ID: "id1"}, <-ii)
+		assert.Equal(t, Invalidation{UserKey: "u123", Except: []string{"id2"}}, <-ii)
+
+		cancel()
+
+		_, ok := <-ii
+		assert.False(t, ok)
+		assert.NoError(t, r.Close(context.Background()))
+		assert.Contains(t, conn.sent(), "PSUBSCRIBE")
+	})
+}
+
+func Test_RedisStore_invalidate(t *testing.T) {
+	conn := redigomock.NewConn()
+	r := RedisStore{}
+	assert.NoError(t, r.invalidate(conn, Invalidation{ID: "id1"}))
+
+	r.invalidations = "channel"
+	conn.Command("PUBLISH", "channel", []byte(`{"user_key":"u123","except":["id2"]}`)).ExpectError(assert.AnError)
+	assert.Equal(t, assert.AnError, r.invalidate(conn, Invalidation{UserKey: "u123", Except: []string{"id2"}}))
+
+	conn.Clear()
+	conn.Command("PUBLISH", "channel", []byte(`{"id":"id1"}`))
+	assert.NoError(t, r.invalidate(conn, Invalidation{ID: "id1"}))
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	// user keys are hashed if encryption is enabled.
+	r.enc = newEncryption(key1)
+	conn.Clear()
+	cmd := conn.Command("PUBLISH", "channel", []byte(`{"user_key_hash":"`+r.enc.hash("u123")+`"}`))
+	assert.NoError(t, r.invalidate(conn, Invalidation{UserKey: "u123"}))
+	assert.Equal(t, 1, conn.Stats(cmd))
+}
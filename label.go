@@ -0,0 +1,103 @@
+package redisstore
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// SetLabel sets the label of the session with the provided ID, i.e.
+// a name given to it by its user (e.g. "work laptop"). An empty label
+// removes the existing one. The label is available as
+// DetailedSession.Label.
+// If session is not found, this function will be no-op.
+func (r *RedisStore) SetLabel(ctx context.Context, id, label string) (err error) {
+	c, end, err := r.begin(ctx, "SetLabel")
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = end(err) }()
+
+	var (
+		s  sessionup.Session
+		ok bool
+	)
+
+	err = r.retryTx(ctx, func() error {
+		var err error
+		s, ok, err = r.setLabelTx(c, id, label)
+
+		return err
+	})
+	if err != nil || !ok {
+		return err
+	}
+
+	return r.audit(c, AuditUpdated, s)
+}
+
+// setLabelTx sets the label of the session with the provided ID by
+// using a WATCH/MULTI transaction.
+// The updated session is returned; the second returned value indicates
+// whether the session was found or not (true == found).
+func (r *RedisStore) setLabelTx(c redis.Conn, id, label string) (sessionup.Session, bool, error) {
+	sKey := r.key(c, false, id)
+
+	if _, err := c.Do("WATCH", sKey); err != nil {
+		return sessionup.Session{}, false, err
+	}
+
+	d, ok, err := r.fetchDetailed(c, id)
+	if err != nil || !ok {
+		return sessionup.Session{}, false, err
+	}
+
+	d.Label = label
+
+	// only the label field of a hash needs to be updated
+	cmd := "HSET"
+	args := []interface{}{sKey, "label", label}
+
+	switch {
+	case r.singleValue():
+		var data []interface{}
+
+		cmd, data, err = r.encodeDetailed(d)
+		if err != nil {
+			return sessionup.Session{}, false, err
+		}
+
+		args = keyArgs(sKey, data)
+	case label == "":
+		cmd = "HDEL"
+		args = args[:2]
+	case r.enc != nil && !r.encMetaOnly:
+		if err = r.enc.sealFields(args[1:], id, false); err != nil {
+			return sessionup.Session{}, false, withKind(ErrEncryption, err)
+		}
+	}
+
+	if _, err = c.Do("MULTI"); err != nil {
+		return sessionup.Session{}, false, err
+	}
+
+	if _, err = c.Do(cmd, args...); err != nil {
+		return sessionup.Session{}, false, err
+	}
+
+	if r.singleValue() {
+		// overwriting a value discards its expiration time
+		_, err = c.Do("PEXPIREAT", sKey, r.expireAt(d.ExpiresAt))
+		if err != nil {
+			return sessionup.Session{}, false, err
+		}
+	}
+
+	if err = exec(c); err != nil {
+		return sessionup.Session{}, false, err
+	}
+
+	return d.Session, true, nil
+}
@@ -0,0 +1,205 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_RedisStore_SetLabel(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+		CreatedAt: time.Now().UTC().Round(0),
+	}
+
+	sKey := prefix + ":session:" + inp.ID
+
+	fields := map[string]string{
+		"created_at": inp.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at": inp.ExpiresAt.Format(time.RFC3339Nano),
+		"id":         inp.ID,
+		"user_key":   inp.UserKey,
+	}
+
+	data, err := json.Marshal(toRecord(inp))
+	require.NoError(t, err)
+
+	outData, err := json.Marshal(toDetailedRecord(DetailedSession{Session: inp, Label: "work laptop"}))
+	require.NoError(t, err)
+
+	cc := map[string]struct {
+		Cancelled bool
+		JSON      bool
+		Label     string
+		Conn      func() (*redigomock.Conn, func(*testing.T))
+		Err       bool
+	}{
+		"Cancelled context": {
+			Cancelled: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session fetch": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Not found": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Error returned during label update": {
+			Label: "work laptop",
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI")
+				conn.Command("HSET", sKey, "label", "work laptop").ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Successful label removal": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI")
+				conn.Command("HDEL", sKey, "label")
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful label update": {
+			Label: "work laptop",
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI")
+				conn.Command("HSET", sKey, "label", "work laptop")
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful label update in JSON mode": {
+			JSON:  true,
+			Label: "work laptop",
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("GET", sKey).Expect(data)
+				conn.GenericCommand("MULTI")
+				conn.Command("SET", sKey, outData)
+				conn.Command("PEXPIREAT", sKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+					Wait:      true,
+					MaxActive: 10,
+				},
+				prefix: prefix,
+				asJSON: c.JSON,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if c.Cancelled {
+				cancel()
+			}
+
+			err := r.SetLabel(ctx, inp.ID, c.Label)
+			check(t)
+
+			if c.Err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_RedisStore_decodeDetailed_label(t *testing.T) {
+	r := RedisStore{}
+
+	d, ok, err := r.decodeDetailed("id123", []interface{}{
+		[]byte("created_at"), []byte(time.Now().Format(time.RFC3339Nano)),
+		[]byte("expires_at"), []byte(time.Now().Add(time.Hour).Format(time.RFC3339Nano)),
+		[]byte("id"), []byte("id123"),
+		[]byte("user_key"), []byte("u123"),
+		[]byte("label"), []byte("work laptop"),
+	}, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "work laptop", d.Label)
+}
@@ -0,0 +1,44 @@
+package redisstore
+
+import "time"
+
+// WithMaxSessionLifetime sets the maximum lifetime of sessions,
+// counted from their creation times, regardless of the expiration
+// times requested by callers. Create caps expiration times of sessions
+// that would outlive it, while ExtendByID, SetTTL, ExtendByUserKey,
+// Touch and the idle timeout (see WithIdleTimeout) never move them
+// past it. Sessions without creation times are capped relative to the
+// current time instead.
+// Defaults to 0 (no limit).
+func WithMaxSessionLifetime(d time.Duration) setter {
+	return func(r *RedisStore) {
+		r.maxLifetime = d
+	}
+}
+
+// capLifetime caps the expiration times of the session, so that it
+// does not outlive the maximum session lifetime.
+func (r *RedisStore) capLifetime(d *DetailedSession) {
+	if r.maxLifetime <= 0 {
+		return
+	}
+
+	created := d.CreatedAt
+	if created.IsZero() {
+		created = r.now()
+	}
+
+	max := created.Add(r.maxLifetime)
+
+	if d.ExpiresAt.After(max) {
+		d.ExpiresAt = max
+	}
+
+	if d.AbsoluteExpiresAt.After(max) {
+		d.AbsoluteExpiresAt = max
+	}
+
+	if d.IdleExpiresAt.After(max) {
+		d.IdleExpiresAt = max
+	}
+}
@@ -0,0 +1,105 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithMaxSessionLifetime(t *testing.T) {
+	r := RedisStore{}
+	WithMaxSessionLifetime(time.Hour)(&r)
+	assert.Equal(t, time.Hour, r.maxLifetime)
+}
+
+func Test_RedisStore_capLifetime(t *testing.T) {
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	d := DetailedSession{Session: sessionup.Session{CreatedAt: now, ExpiresAt: now.Add(time.Hour * 3)}}
+
+	r := RedisStore{clock: ClockFunc(func() time.Time { return now })}
+	r.capLifetime(&d)
+	assert.Equal(t, now.Add(time.Hour*3), d.ExpiresAt)
+
+	r.maxLifetime = time.Hour * 2
+	r.capLifetime(&d)
+	assert.Equal(t, now.Add(time.Hour*2), d.ExpiresAt)
+
+	d = DetailedSession{
+		Session:           sessionup.Session{CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		AbsoluteExpiresAt: now.Add(time.Hour * 3),
+		IdleExpiresAt:     now.Add(time.Hour),
+	}
+	r.capLifetime(&d)
+	assert.Equal(t, now.Add(time.Hour), d.ExpiresAt)
+	assert.Equal(t, now.Add(time.Hour*2), d.AbsoluteExpiresAt)
+	assert.Equal(t, now.Add(time.Hour), d.IdleExpiresAt)
+
+	// sessions without creation times are capped relative to now
+	d = DetailedSession{Session: sessionup.Session{ExpiresAt: now.Add(time.Hour * 3)}}
+	r.capLifetime(&d)
+	assert.Equal(t, now.Add(time.Hour*2), d.ExpiresAt)
+}
+
+func Test_RedisStore_maxSessionLifetime(t *testing.T) {
+	sKey := prefix + ":session:id123"
+	pKey := prefix + ":payload:id123"
+	uKey := prefix + ":user:u123"
+	created := time.Now().Add(-time.Minute * 30)
+
+	var s sessionup.Session
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithMaxSessionLifetime(time.Hour), WithHooks(Hooks{
+		AfterCreate: func(_ context.Context, cs sessionup.Session, _ error) {
+			s = cs
+		},
+	}))
+
+	conn.GenericCommand("EVALSHA").Expect(int64(1))
+
+	require.NoError(t, r.Create(context.Background(), sessionup.Session{
+		CreatedAt: created,
+		ExpiresAt: created.Add(time.Hour * 24),
+		ID:        "id123",
+		UserKey:   "u123",
+	}))
+	assert.True(t, s.ExpiresAt.Equal(created.Add(time.Hour)))
+
+	conn.Clear()
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": created.Format(time.RFC3339Nano),
+		"expires_at": created.Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+	conn.Command("WATCH", uKey).Expect("OK")
+	conn.Command("PTTL", uKey).Expect(int64(1000))
+	conn.Command("MULTI").Expect("OK")
+
+	var score int64
+
+	conn.Command("ZADD", uKey, redigomock.NewAnyInt(), sKey).Handle(func(args []interface{}) (interface{}, error) {
+		score = args[1].(int64)
+		return "QUEUED", nil
+	})
+	conn.Command("PEXPIREAT", uKey, redigomock.NewAnyInt()).Expect("QUEUED")
+	conn.GenericCommand("HMSET").Expect("QUEUED")
+	conn.Command("PEXPIREAT", sKey, redigomock.NewAnyInt()).Expect("QUEUED")
+	conn.Command("PEXPIREAT", pKey, redigomock.NewAnyInt()).Expect("QUEUED")
+	conn.Command("EXEC").ExpectSlice(int64(1), int64(1), "OK", int64(1))
+
+	require.NoError(t, r.SetTTL(context.Background(), "id123", time.Hour*2))
+	assert.Equal(t, created.Add(time.Hour).UnixNano(), score)
+}
@@ -0,0 +1,104 @@
+package redisstore
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/swithek/sessionup"
+)
+
+// Login describes how a session was established. Unlike metadata, it
+// is stored in dedicated fields, so that security-relevant decisions
+// (e.g. requiring a stronger login for sensitive actions) do not
+// depend on parsing arbitrary metadata values.
+type Login struct {
+	// Method specifies how the user authenticated (e.g. "password",
+	// "passkey" or "sso").
+	Method string
+
+	// Provider specifies the identity provider that authenticated the
+	// user (e.g. "google"), if any.
+	Provider string
+
+	// MFALevel specifies the level of multi-factor authentication
+	// performed during the login (e.g. an authenticator assurance
+	// level). 0 means that no additional factors were used.
+	MFALevel int
+}
+
+// loginKey is the context key of login data.
+type loginKey struct{}
+
+// NewLoginContext returns a copy of the context that holds the
+// provided login data, so that Create can store it along with the
+// session created by sessionup.Manager.Init.
+func NewLoginContext(ctx context.Context, l Login) context.Context {
+	return context.WithValue(ctx, loginKey{}, l)
+}
+
+// LoginFromContext returns the login data held by the context, if any.
+func LoginFromContext(ctx context.Context) (Login, bool) {
+	l, ok := ctx.Value(loginKey{}).(Login)
+	return l, ok
+}
+
+// WithLoginFunc sets the function that describes how each session
+// created by Create was established. The login data is stored along
+// with the session ("login_method", "login_provider" and "mfa_level"
+// fields of its hash) and is available as DetailedSession.Login.
+// Defaults to the data held by the context (see NewLoginContext).
+func WithLoginFunc(fn func(ctx context.Context, s sessionup.Session) Login) setter {
+	return func(r *RedisStore) {
+		r.loginFunc = fn
+	}
+}
+
+// login describes how the session was established, either by using
+// the login function or the data held by the context.
+func (r *RedisStore) login(ctx context.Context, s sessionup.Session) Login {
+	if r.loginFunc != nil {
+		return r.loginFunc(ctx, s)
+	}
+
+	l, _ := LoginFromContext(ctx)
+
+	return l
+}
+
+// loginFields converts the login data into field-value pairs of the
+// session hash. Unset fields are omitted.
+func loginFields(l Login) []interface{} {
+	var ff []interface{}
+
+	if l.Method != "" {
+		ff = append(ff, "login_method", l.Method)
+	}
+
+	if l.Provider != "" {
+		ff = append(ff, "login_provider", l.Provider)
+	}
+
+	if l.MFALevel != 0 {
+		ff = append(ff, "mfa_level", strconv.Itoa(l.MFALevel))
+	}
+
+	return ff
+}
+
+// loginFromFields extracts the login data from the fields of the
+// session hash.
+func loginFromFields(vv map[string]string) (Login, error) {
+	l := Login{
+		Method:   vv["login_method"],
+		Provider: vv["login_provider"],
+	}
+
+	if v := vv["mfa_level"]; v != "" {
+		var err error
+		if l.MFALevel, err = strconv.Atoi(v); err != nil {
+			return Login{}, err
+		}
+	}
+
+	return l, nil
+}
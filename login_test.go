@@ -0,0 +1,60 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_LoginContext(t *testing.T) {
+	_, ok := LoginFromContext(context.Background())
+	assert.False(t, ok)
+
+	l := Login{Method: "password", MFALevel: 1}
+
+	res, ok := LoginFromContext(NewLoginContext(context.Background(), l))
+	assert.True(t, ok)
+	assert.Equal(t, l, res)
+}
+
+func Test_RedisStore_login(t *testing.T) {
+	ctx := NewLoginContext(context.Background(), Login{Method: "password"})
+
+	r := RedisStore{}
+	assert.Zero(t, r.login(context.Background(), sessionup.Session{}))
+	assert.Equal(t, Login{Method: "password"}, r.login(ctx, sessionup.Session{}))
+
+	WithLoginFunc(func(_ context.Context, s sessionup.Session) Login {
+		return Login{Method: "sso", Provider: s.UserKey}
+	})(&r)
+	assert.Equal(t, Login{Method: "sso", Provider: "u123"}, r.login(ctx, sessionup.Session{UserKey: "u123"}))
+}
+
+func Test_loginFields(t *testing.T) {
+	assert.Nil(t, loginFields(Login{}))
+	assert.Equal(t, []interface{}{
+		"login_method", "passkey",
+		"login_provider", "google",
+		"mfa_level", "2",
+	}, loginFields(Login{Method: "passkey", Provider: "google", MFALevel: 2}))
+}
+
+func Test_loginFromFields(t *testing.T) {
+	l, err := loginFromFields(map[string]string{})
+	require.NoError(t, err)
+	assert.Zero(t, l)
+
+	_, err = loginFromFields(map[string]string{"mfa_level": "x"})
+	assert.Error(t, err)
+
+	l, err = loginFromFields(map[string]string{
+		"login_method":   "passkey",
+		"login_provider": "google",
+		"mfa_level":      "2",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Login{Method: "passkey", Provider: "google", MFALevel: 2}, l)
+}
@@ -0,0 +1,39 @@
+package redisstore
+
+import (
+	"errors"
+	"time"
+
+	"github.com/swithek/sessionup"
+)
+
+// defaultManagerTTL is the lifetime of sessions created by managers
+// returned by NewManager, unless it is changed with
+// sessionup.ExpiresIn.
+const defaultManagerTTL = 24 * time.Hour
+
+// errNoPool is returned when a store is requested without a connection
+// pool.
+var errNoPool = errors.New("redisstore: connection pool is nil")
+
+// NewManager returns a sessionup.Manager backed by a new RedisStore
+// that uses the provided pool and prefix, along with the store itself,
+// so that it can be closed or used directly.
+// Apart from sessionup's defaults, sessions of the manager expire after
+// 24 hours, since the store rejects sessions without an expiration
+// time. The provided manager options (e.g. sessionup.ExpiresIn or
+// sessionup.CookieName) are applied afterwards and take precedence.
+func NewManager(pool Pooler, prefix string, managerOpts ...func(*sessionup.Manager)) (*sessionup.Manager, *RedisStore, error) {
+	if pool == nil {
+		return nil, nil, errNoPool
+	}
+
+	r := New(pool, prefix)
+	m := sessionup.NewManager(r, sessionup.ExpiresIn(defaultManagerTTL))
+
+	for _, opt := range managerOpts {
+		opt(m)
+	}
+
+	return m, r, nil
+}
@@ -0,0 +1,51 @@
+package redisstore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_NewManager(t *testing.T) {
+	m, r, err := NewManager(nil, prefix)
+	assert.Equal(t, errNoPool, err)
+	assert.Nil(t, m)
+	assert.Nil(t, r)
+
+	conn := redigomock.NewConn()
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}
+
+	m, r, err = NewManager(pool, prefix, sessionup.CookieName("sid"))
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	assert.Equal(t, prefix, r.prefix)
+
+	var exp int64
+
+	conn.GenericCommand("EVALSHA").Handle(func(args []interface{}) (interface{}, error) {
+		// the script's SHA, key count and keys are followed by the
+		// current time and session's expiration time
+		exp = args[6].(int64)
+		return int64(1), nil
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, m.Init(rec, req.WithContext(context.Background()), "u123"))
+
+	require.Len(t, rec.Result().Cookies(), 1)
+	assert.Equal(t, "sid", rec.Result().Cookies()[0].Name)
+	assert.InDelta(t, time.Now().Add(defaultManagerTTL).UnixNano(), exp, float64(time.Minute))
+}
@@ -0,0 +1,48 @@
+package redisstore
+
+import (
+	"fmt"
+)
+
+// WithMaxMetaSize sets the maximum total size (in bytes) of the keys
+// and values of session metadata. Create and UpdateMeta reject
+// sessions with larger metadata with an ErrMetaTooLarge error.
+// Defaults to 0 (no limit).
+func WithMaxMetaSize(n int) setter {
+	return func(r *RedisStore) {
+		r.maxMetaSize = n
+	}
+}
+
+// WithMaxMetaKeys sets the maximum number of session metadata
+// entries. Create and UpdateMeta reject sessions with more entries
+// with an ErrMetaTooLarge error.
+// Defaults to 0 (no limit).
+func WithMaxMetaKeys(n int) setter {
+	return func(r *RedisStore) {
+		r.maxMetaKeys = n
+	}
+}
+
+// checkMeta checks whether the metadata is within the configured
+// limits.
+func (r *RedisStore) checkMeta(mm map[string]string) error {
+	if r.maxMetaKeys > 0 && len(mm) > r.maxMetaKeys {
+		return withKind(ErrMetaTooLarge, fmt.Errorf("%d metadata entries exceed the limit of %d", len(mm), r.maxMetaKeys))
+	}
+
+	if r.maxMetaSize <= 0 {
+		return nil
+	}
+
+	var n int
+	for k, v := range mm {
+		n += len(k) + len(v)
+	}
+
+	if n > r.maxMetaSize {
+		return withKind(ErrMetaTooLarge, fmt.Errorf("%d bytes of metadata exceed the limit of %d", n, r.maxMetaSize))
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithMaxMetaSize(t *testing.T) {
+	r := RedisStore{}
+	WithMaxMetaSize(1024)(&r)
+	assert.Equal(t, 1024, r.maxMetaSize)
+}
+
+func Test_WithMaxMetaKeys(t *testing.T) {
+	r := RedisStore{}
+	WithMaxMetaKeys(10)(&r)
+	assert.Equal(t, 10, r.maxMetaKeys)
+}
+
+func Test_RedisStore_checkMeta(t *testing.T) {
+	mm := map[string]string{"key1": "value", "key2": "value"}
+
+	r := RedisStore{}
+	assert.NoError(t, r.checkMeta(mm))
+
+	r.maxMetaKeys = 2
+	assert.NoError(t, r.checkMeta(mm))
+
+	r.maxMetaKeys = 1
+	assert.True(t, errors.Is(r.checkMeta(mm), ErrMetaTooLarge))
+
+	r.maxMetaKeys = 0
+	r.maxMetaSize = 18
+	assert.NoError(t, r.checkMeta(mm))
+
+	r.maxMetaSize = 17
+	assert.True(t, errors.Is(r.checkMeta(mm), ErrMetaTooLarge))
+}
+
+func Test_RedisStore_Create_metaTooLarge(t *testing.T) {
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithMaxMetaKeys(1))
+
+	err := r.Create(context.Background(), sessionup.Session{
+		ID:        "id123",
+		UserKey:   "u123",
+		ExpiresAt: time.Now().Add(time.Hour),
+		Meta:      map[string]string{"a": "1", "b": "2"},
+	})
+	assert.True(t, errors.Is(err, ErrMetaTooLarge))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_UpdateMeta_metaTooLarge(t *testing.T) {
+	sKey := prefix + ":session:id123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithMaxMetaKeys(1))
+
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+		"meta":       "a=1",
+	})
+	conn.GenericCommand("UNWATCH")
+
+	err := r.UpdateMeta(context.Background(), "id123", map[string]string{"b": "2"}, true)
+	assert.True(t, errors.Is(err, ErrMetaTooLarge))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
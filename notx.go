@@ -0,0 +1,203 @@
+package redisstore
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// WithTransactions determines whether Create, DeleteByID, DeleteByIDs
+// and DeleteByUserKey should use WATCH/MULTI transactions. When
+// disabled, their commands are pipelined without any isolation
+// instead, which takes roughly half the commands and round trips,
+// while concurrent writes of the same session follow last-writer-wins
+// semantics. In that case Create (when scripting is not available)
+// neither detects duplicate IDs nor enforces the user session limit
+// (see WithMaxUserSessions). Create and DeleteByUserKey still use
+// their Lua scripts whenever scripting is available, since each takes
+// a single command.
+// All other operations (e.g. ExtendByID, RenewID or SetPayload)
+// always use transactions, regardless of this option.
+// Defaults to true.
+func WithTransactions(t bool) setter {
+	return func(r *RedisStore) {
+		r.noTx = !t
+	}
+}
+
+// createNoTx inserts the provided session into the store by using
+// pipelined commands without a transaction. On servers that support
+// expiration options (see Capabilities.ExpireOptions), the expiration
+// time of the user session set is moved atomically; otherwise, it is
+// read first and may be shortened by concurrent creations.
+func (r *RedisStore) createNoTx(c redis.Conn, d DetailedSession) error {
+	s := d.Session
+	sKey := r.key(c, false, s.ID)
+	uKey := r.key(c, true, s.UserKey)
+
+	cmd, data, err := r.encodeDetailed(d)
+	if err != nil {
+		return err
+	}
+
+	now := r.now().UnixNano()
+	sExpMilli := r.expireAt(s.ExpiresAt)
+
+	cmds := [][]interface{}{
+		cmdArgs(cmd, sKey, data),
+		{"PEXPIREAT", sKey, sExpMilli},
+	}
+
+	if !r.noUserIndex {
+		cmds = append(cmds,
+			[]interface{}{"ZREMRANGEBYSCORE", uKey, "-inf", now},
+			[]interface{}{"ZADD", uKey, s.ExpiresAt.UnixNano(), sKey},
+		)
+	}
+
+	if !r.noUserIndex && r.Capabilities().ExpireOptions {
+		// the user session set's expiration time is set if it has
+		// none and is otherwise only moved forward, so that
+		// concurrent creations cannot shorten it
+		cmds = append(cmds,
+			[]interface{}{"PEXPIREAT", uKey, sExpMilli, "NX"},
+			[]interface{}{"PEXPIREAT", uKey, sExpMilli, "GT"},
+		)
+
+		_, err = pipeline(c, cmds)
+
+		return err
+	}
+
+	if !r.noUserIndex {
+		cmds = append(cmds, []interface{}{"PTTL", uKey})
+	}
+
+	v, err := pipeline(c, cmds)
+	if err != nil || r.noUserIndex {
+		return err
+	}
+
+	ttl, err := redis.Int64(v, nil)
+	if err != nil {
+		return err
+	}
+
+	// extend the user session set only if it expires before the
+	// session
+	if ttl < 0 || now/int64(time.Millisecond)+ttl < sExpMilli {
+		_, err = c.Do("PEXPIREAT", uKey, sExpMilli)
+	}
+
+	return err
+}
+
+// deleteByIDNoTx deletes the session by the provided ID by using
+// pipelined commands without a transaction. The deleted session is
+// returned; the second returned value indicates whether the session
+// was found or not (true == found).
+func (r *RedisStore) deleteByIDNoTx(c redis.Conn, id string) (sessionup.Session, bool, error) {
+	s, ok, err := r.fetch(c, id)
+	if err != nil || !ok {
+		return sessionup.Session{}, false, err
+	}
+
+	if _, err = pipeline(c, r.deleteCmds(c, s)); err != nil {
+		return sessionup.Session{}, false, err
+	}
+
+	return s, true, nil
+}
+
+// deleteByIDsNoTx deletes the sessions with the provided IDs by using
+// pipelined commands without a transaction. The deleted sessions are
+// returned.
+func (r *RedisStore) deleteByIDsNoTx(c redis.Conn, ids []string) ([]sessionup.Session, error) {
+	keys := make([]string, len(ids))
+	for i := range ids {
+		keys[i] = r.key(c, false, ids[i])
+	}
+
+	ss, err := r.fetchKeys(c, keys)
+	if err != nil || len(ss) == 0 {
+		return nil, err
+	}
+
+	if _, err = pipeline(c, r.deleteCmds(c, ss...)); err != nil {
+		return nil, err
+	}
+
+	return ss, nil
+}
+
+// deleteByUserKeyNoTx deletes all sessions associated with the
+// provided user key (except the ones specified), along with their
+// payloads and secondary index entries, by using pipelined commands
+// without a transaction. Sessions created concurrently may not be
+// deleted.
+func (r *RedisStore) deleteByUserKeyNoTx(c redis.Conn, key string, expIDs ...string) error {
+	uKey := r.key(c, true, key)
+
+	ids, err := redis.Strings(c.Do("ZRANGEBYSCORE", uKey, "-inf", "+inf"))
+	if err != nil && !errors.Is(err, redis.ErrNil) {
+		return err
+	}
+
+	del := r.userKeysExcept(c, ids, expIDs)
+
+	unindex, err := r.unindexKeyCmds(c, del)
+	if err != nil {
+		return err
+	}
+
+	var cmds [][]interface{}
+
+	for i := range del {
+		cmds = append(cmds, []interface{}{"DEL", del[i], r.payloadKey(c, r.idFromKey(c, del[i]))})
+
+		if len(expIDs) > 0 {
+			cmds = append(cmds, []interface{}{"ZREM", uKey, del[i]})
+		}
+	}
+
+	cmds = append(cmds, unindex...)
+
+	if len(expIDs) == 0 || len(ids) == 0 {
+		cmds = append(cmds, []interface{}{"DEL", uKey})
+	}
+
+	if len(cmds) == 0 {
+		return nil
+	}
+
+	_, err = pipeline(c, cmds)
+
+	return err
+}
+
+// pipeline sends the provided commands in a single round trip and
+// returns the reply of the last one.
+func pipeline(c redis.Conn, cmds [][]interface{}) (interface{}, error) {
+	for _, cmd := range cmds {
+		if err := c.Send(cmd[0].(string), cmd[1:]...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+
+	for range cmds {
+		var err error
+		if v, err = c.Receive(); err != nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
@@ -0,0 +1,183 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithTransactions(t *testing.T) {
+	r := RedisStore{}
+	WithTransactions(false)(&r)
+	assert.True(t, r.noTx)
+
+	WithTransactions(true)(&r)
+	assert.False(t, r.noTx)
+}
+
+func Test_RedisStore_createNoTx(t *testing.T) {
+	sKey := prefix + ":session:id123"
+	uKey := prefix + ":user:u123"
+
+	d := DetailedSession{Session: sessionup.Session{
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+		ID:        "id123",
+		UserKey:   "u123",
+	}}
+	exp := d.ExpiresAt.UnixNano() / int64(time.Millisecond)
+
+	conn := redigomock.NewConn()
+	r := RedisStore{prefix: prefix, noTx: true}
+
+	conn.GenericCommand("HMSET").ExpectError(assert.AnError)
+	assert.Error(t, r.createNoTx(conn, d))
+
+	conn.Clear()
+	hmset := conn.GenericCommand("HMSET").Expect("OK")
+	conn.Command("PEXPIREAT", sKey, exp).Expect(int64(1))
+	conn.Command("ZREMRANGEBYSCORE", uKey, "-inf", redigomock.NewAnyInt()).Expect(int64(0))
+	conn.Command("ZADD", uKey, d.ExpiresAt.UnixNano(), sKey).Expect(int64(1))
+	conn.Command("PTTL", uKey).Expect(int64(-1))
+	uExp := conn.Command("PEXPIREAT", uKey, exp).Expect(int64(1))
+
+	require.NoError(t, r.createNoTx(conn, d))
+	assert.Equal(t, 1, conn.Stats(hmset))
+	assert.Equal(t, 1, conn.Stats(uExp))
+
+	// the user session set is not shortened
+	conn.Command("PTTL", uKey).Expect(int64(time.Hour * 2 / time.Millisecond))
+
+	require.NoError(t, r.createNoTx(conn, d))
+	assert.Equal(t, 2, conn.Stats(hmset))
+	assert.Equal(t, 1, conn.Stats(uExp))
+
+	// the user session set's expiration time is only moved forward,
+	// without being read first
+	r.caps.Store(Capabilities{ExpireOptions: true})
+
+	conn.Clear()
+	conn.GenericCommand("HMSET").Expect("OK")
+	conn.Command("PEXPIREAT", sKey, exp).Expect(int64(1))
+	conn.Command("ZREMRANGEBYSCORE", uKey, "-inf", redigomock.NewAnyInt()).Expect(int64(0))
+	conn.Command("ZADD", uKey, d.ExpiresAt.UnixNano(), sKey).Expect(int64(1))
+	nx := conn.Command("PEXPIREAT", uKey, exp, "NX").Expect(int64(0))
+	gt := conn.Command("PEXPIREAT", uKey, exp, "GT").Expect(int64(1))
+	pttl := conn.Command("PTTL", uKey).Expect(int64(-1))
+
+	require.NoError(t, r.createNoTx(conn, d))
+	assert.Equal(t, 1, conn.Stats(nx))
+	assert.Equal(t, 1, conn.Stats(gt))
+	assert.Zero(t, conn.Stats(pttl))
+}
+
+func Test_RedisStore_DeleteByID_noTx(t *testing.T) {
+	sKey := prefix + ":session:id123"
+	pKey := prefix + ":payload:id123"
+	uKey := prefix + ":user:u123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithTransactions(false))
+
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+	del := conn.Command("DEL", sKey, pKey).Expect(int64(1))
+	zrem := conn.Command("ZREM", uKey, sKey).Expect(int64(1))
+
+	require.NoError(t, r.DeleteByID(context.Background(), "id123"))
+	assert.Equal(t, 1, conn.Stats(del))
+	assert.Equal(t, 1, conn.Stats(zrem))
+}
+
+func Test_RedisStore_DeleteByIDs_noTx(t *testing.T) {
+	sKey1 := prefix + ":session:id1"
+	sKey2 := prefix + ":session:id2"
+	pKey1 := prefix + ":payload:id1"
+	uKey := prefix + ":user:u123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithTransactions(false))
+
+	conn.Command("HGETALL", sKey1).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id1",
+		"user_key":   "u123",
+	})
+	conn.Command("HGETALL", sKey2).ExpectSlice()
+	zrem := conn.Command("ZREM", uKey, sKey1).Expect(int64(1))
+	del := conn.Command("DEL", sKey1, pKey1).Expect(int64(2))
+	watch := conn.GenericCommand("WATCH").Expect("OK")
+
+	require.NoError(t, r.DeleteByIDs(context.Background(), "id1", "id2"))
+	assert.Equal(t, 1, conn.Stats(zrem))
+	assert.Equal(t, 1, conn.Stats(del))
+	assert.Zero(t, conn.Stats(watch))
+}
+
+func Test_RedisStore_deleteByUserKeyNoTx(t *testing.T) {
+	sKey1 := prefix + ":session:id1"
+	sKey2 := prefix + ":session:id2"
+	uKey := prefix + ":user:u123"
+
+	conn := redigomock.NewConn()
+	r := RedisStore{prefix: prefix, noTx: true}
+
+	conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectError(assert.AnError)
+	assert.Error(t, r.deleteByUserKeyNoTx(conn, "u123"))
+
+	conn.Clear()
+	conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectStringSlice(sKey1, sKey2)
+	del1 := conn.Command("DEL", sKey1, prefix+":payload:id1").Expect(int64(1))
+	del2 := conn.Command("DEL", sKey2, prefix+":payload:id2").Expect(int64(1))
+	zrem := conn.Command("ZREM", uKey, sKey2).Expect(int64(1))
+	delU := conn.Command("DEL", uKey).Expect(int64(1))
+
+	require.NoError(t, r.deleteByUserKeyNoTx(conn, "u123", "id1"))
+	assert.Zero(t, conn.Stats(del1))
+	assert.Equal(t, 1, conn.Stats(del2))
+	assert.Equal(t, 1, conn.Stats(zrem))
+	assert.Zero(t, conn.Stats(delU))
+
+	require.NoError(t, r.deleteByUserKeyNoTx(conn, "u123"))
+	assert.Equal(t, 1, conn.Stats(del1))
+	assert.Equal(t, 2, conn.Stats(del2))
+	assert.Equal(t, 1, conn.Stats(delU))
+
+	// deleted sessions are removed from secondary indexes.
+	r.ipIndex = true
+
+	conn.Clear()
+	conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectStringSlice(sKey1)
+	conn.Command("HGETALL", sKey1).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id1",
+		"user_key":   "u123",
+		"ip":         "127.0.0.1",
+	})
+	conn.Command("DEL", sKey1, prefix+":payload:id1").Expect(int64(1))
+	conn.Command("DEL", uKey).Expect(int64(1))
+	unindex := conn.Command("ZREM", prefix+":ip:127.0.0.1", sKey1).Expect(int64(1))
+
+	require.NoError(t, r.deleteByUserKeyNoTx(conn, "u123"))
+	assert.Equal(t, 1, conn.Stats(unindex))
+}
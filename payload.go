@@ -0,0 +1,191 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// SetPayload stores the provided data, opaque to the store, next to
+// the session of the provided ID, replacing its previous payload.
+// Payloads are meant for small per-session state (e.g. CSRF secrets or
+// SSO artifacts) that should not be kept in session metadata. They are
+// stored under sibling keys that expire along with their sessions and
+// are encrypted if encryption at rest is enabled (see WithEncryption).
+// Payloads are deleted and moved along with their sessions (e.g. by
+// DeleteByID, DeleteByUserKey or RenewID).
+// Empty data deletes the payload.
+// If the session does not exist, this function will be no-op. If not
+// found errors are enabled (see WithNotFoundErrors), ErrNoSession is
+// returned instead.
+func (r *RedisStore) SetPayload(ctx context.Context, id string, data []byte) (err error) {
+	c, end, err := r.begin(ctx, "SetPayload")
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = end(err) }()
+
+	if len(data) > 0 && r.enc != nil {
+		if data, err = r.enc.seal("payload", id, data); err != nil {
+			return withKind(ErrEncryption, err)
+		}
+	}
+
+	var ok bool
+
+	err = r.retryTx(ctx, func() error {
+		var err error
+		ok, err = r.setPayloadTx(c, id, data)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.notFound(ok)
+}
+
+// setPayloadTx stores the payload of the session by using a pipelined
+// WATCH/MULTI transaction, so that it is not stored for a session that
+// is being deleted. The returned boolean indicates whether the session
+// exists.
+func (r *RedisStore) setPayloadTx(c redis.Conn, id string, data []byte) (bool, error) {
+	sKey := r.key(c, false, id)
+	pKey := r.payloadKey(c, id)
+
+	if _, err := c.Do("WATCH", sKey); err != nil {
+		return false, err
+	}
+
+	ttl, err := redis.Int64(c.Do("PTTL", sKey))
+	if err != nil {
+		return false, err
+	}
+
+	if ttl == -2 {
+		_, err = c.Do("UNWATCH")
+		return false, err
+	}
+
+	cmd := []interface{}{"DEL", pKey}
+
+	if len(data) > 0 {
+		cmd = []interface{}{"SET", pKey, data}
+
+		if ttl > 0 {
+			cmd = append(cmd, "PX", ttl)
+		}
+	}
+
+	if err = sendTx(c, [][]interface{}{cmd}); err != nil {
+		return false, err
+	}
+
+	return true, receiveTxs(c, 1)
+}
+
+// GetPayload retrieves the payload stored next to the session of the
+// provided ID (see SetPayload). The returned boolean indicates whether
+// the payload was found; payloads of sessions that no longer exist are
+// never returned. If the session's expiration time was moved without
+// the payload's (e.g. by an older version of the store), the payload's
+// expiration time is moved as well.
+func (r *RedisStore) GetPayload(ctx context.Context, id string) (data []byte, ok bool, err error) {
+	c, end, err := r.begin(ctx, "GetPayload")
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer func() { err = end(err) }()
+
+	pKey := r.payloadKey(c, id)
+
+	if err = c.Send("PTTL", r.key(c, false, id)); err != nil {
+		return nil, false, err
+	}
+
+	if err = c.Send("PTTL", pKey); err != nil {
+		return nil, false, err
+	}
+
+	if err = c.Send("GET", pKey); err != nil {
+		return nil, false, err
+	}
+
+	if err = c.Flush(); err != nil {
+		return nil, false, err
+	}
+
+	sTTL, err := redis.Int64(c.Receive())
+	if err != nil {
+		return nil, false, err
+	}
+
+	pTTL, err := redis.Int64(c.Receive())
+	if err != nil {
+		return nil, false, err
+	}
+
+	data, err = redis.Bytes(c.Receive())
+	if err == redis.ErrNil {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if sTTL == -2 {
+		return nil, false, nil
+	}
+
+	if sTTL > pTTL && pTTL > 0 {
+		if _, err = c.Do("PEXPIRE", pKey, sTTL); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if data, err = r.enc.open("payload", id, data); err != nil {
+		return nil, false, withKind(ErrEncryption, err)
+	}
+
+	return data, true, nil
+}
+
+// payloadKey returns the key of the payload of the provided session
+// ID, scoped to the tenant of the connection.
+func (r *RedisStore) payloadKey(c redis.Conn, id string) string {
+	return r.buildKey(connTenant(c), "payload", id)
+}
+
+// renewPayload retrieves the payload of the session with the old ID so
+// that it can be moved to the new ID. Encrypted payloads are bound to
+// their session IDs, hence they are encrypted anew. If the session has
+// no payload, nil is returned.
+func (r *RedisStore) renewPayload(c redis.Conn, oldID, newID string) ([]byte, error) {
+	data, err := redis.Bytes(c.Do("GET", r.payloadKey(c, oldID)))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if r.enc == nil || !isSealed(data) {
+		return data, nil
+	}
+
+	if data, err = r.enc.open("payload", oldID, data); err != nil {
+		return nil, withKind(ErrEncryption, err)
+	}
+
+	if data, err = r.enc.seal("payload", newID, data); err != nil {
+		return nil, withKind(ErrEncryption, err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,159 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RedisStore_SetPayload(t *testing.T) {
+	sKey := prefix + ":session:id123"
+	pKey := prefix + ":payload:id123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	conn.Command("WATCH", sKey).ExpectError(assert.AnError)
+	err := r.SetPayload(context.Background(), "id123", []byte("data"))
+	assert.True(t, errors.Is(err, assert.AnError))
+
+	conn.Clear()
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("PTTL", sKey).Expect(int64(-2))
+	conn.Command("UNWATCH").Expect("OK")
+	require.NoError(t, r.SetPayload(context.Background(), "id123", []byte("data")))
+
+	r.notFoundErrors = true
+	err = r.SetPayload(context.Background(), "id123", []byte("data"))
+	assert.True(t, errors.Is(err, ErrNoSession))
+
+	conn.Clear()
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("PTTL", sKey).Expect(int64(1000))
+	conn.GenericCommand("MULTI").Expect("OK")
+	set := conn.Command("SET", pKey, []byte("data"), "PX", int64(1000)).Expect("QUEUED")
+	conn.GenericCommand("EXEC").ExpectSlice("OK")
+	require.NoError(t, r.SetPayload(context.Background(), "id123", []byte("data")))
+	assert.Equal(t, 1, conn.Stats(set))
+
+	conn.Command("DEL", pKey).Expect("QUEUED")
+	conn.GenericCommand("EXEC").ExpectSlice(int64(1))
+	require.NoError(t, r.SetPayload(context.Background(), "id123", nil))
+}
+
+func Test_RedisStore_GetPayload(t *testing.T) {
+	sKey := prefix + ":session:id123"
+	pKey := prefix + ":payload:id123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	conn.Command("PTTL", sKey).ExpectError(assert.AnError)
+	conn.Command("PTTL", pKey).Expect(int64(1000))
+	conn.Command("GET", pKey).Expect([]byte("data"))
+	_, _, err := r.GetPayload(context.Background(), "id123")
+	assert.True(t, errors.Is(err, assert.AnError))
+
+	conn.Clear()
+	conn.Command("PTTL", sKey).Expect(int64(1000))
+	conn.Command("PTTL", pKey).Expect(int64(-2))
+	conn.Command("GET", pKey).ExpectError(redis.ErrNil)
+	data, ok, err := r.GetPayload(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, data)
+
+	// payloads of deleted sessions are not returned.
+	conn.Clear()
+	conn.Command("PTTL", sKey).Expect(int64(-2))
+	conn.Command("PTTL", pKey).Expect(int64(1000))
+	conn.Command("GET", pKey).Expect([]byte("data"))
+	_, ok, err = r.GetPayload(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	conn.Clear()
+	conn.Command("PTTL", sKey).Expect(int64(1000))
+	conn.Command("PTTL", pKey).Expect(int64(1000))
+	conn.Command("GET", pKey).Expect([]byte("data"))
+	data, ok, err = r.GetPayload(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("data"), data)
+
+	// payloads follow the extensions of their sessions.
+	conn.Clear()
+	conn.Command("PTTL", sKey).Expect(int64(5000))
+	conn.Command("PTTL", pKey).Expect(int64(1000))
+	conn.Command("GET", pKey).Expect([]byte("data"))
+	pexpire := conn.Command("PEXPIRE", pKey, int64(5000)).Expect(int64(1))
+	data, ok, err = r.GetPayload(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("data"), data)
+	assert.Equal(t, 1, conn.Stats(pexpire))
+
+	sealed, err := newEncryption(key1).seal("payload", "id123", []byte("data"))
+	require.NoError(t, err)
+
+	conn.Clear()
+	conn.Command("PTTL", sKey).Expect(int64(1000))
+	conn.Command("PTTL", pKey).Expect(int64(1000))
+	conn.Command("GET", pKey).Expect(sealed)
+	_, _, err = r.GetPayload(context.Background(), "id123")
+	assert.True(t, errors.Is(err, ErrEncryption))
+}
+
+func Test_RedisStore_renewPayload(t *testing.T) {
+	oldPKey := prefix + ":payload:id123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{}, prefix, WithEncryption(key1))
+
+	conn.Command("GET", oldPKey).ExpectError(redis.ErrNil)
+	data, err := r.renewPayload(conn, "id123", "id456")
+	require.NoError(t, err)
+	assert.Nil(t, data)
+
+	conn.Command("GET", oldPKey).ExpectError(assert.AnError)
+	_, err = r.renewPayload(conn, "id123", "id456")
+	assert.True(t, errors.Is(err, assert.AnError))
+
+	conn.Command("GET", oldPKey).Expect([]byte("data"))
+	data, err = r.renewPayload(conn, "id123", "id456")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), data)
+
+	// encrypted payloads are bound to the new ID.
+	sealed, err := r.enc.seal("payload", "id123", []byte("data"))
+	require.NoError(t, err)
+
+	conn.Command("GET", oldPKey).Expect(sealed)
+	data, err = r.renewPayload(conn, "id123", "id456")
+	require.NoError(t, err)
+
+	_, err = r.enc.open("payload", "id123", data)
+	assert.Error(t, err)
+
+	res, err := r.enc.open("payload", "id456", data)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), res)
+
+	conn.Command("GET", oldPKey).Expect(sealed)
+	r.enc = newEncryption(key2)
+	_, err = r.renewPayload(conn, "id123", "id456")
+	assert.True(t, errors.Is(err, ErrEncryption))
+}
@@ -0,0 +1,41 @@
+package redisstore
+
+import (
+	"sync/atomic"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// PoolStats holds statistics of the connection pool along with
+// counters of the store's recovery mechanisms.
+type PoolStats struct {
+	// PoolStats holds the statistics reported by the connection pool.
+	// It is zero if the pool does not report them (i.e. it has no
+	// Stats() redis.PoolStats method).
+	redis.PoolStats
+
+	// TxConflicts specifies the total number of transactions aborted
+	// due to concurrent modifications of their watched keys.
+	TxConflicts int64
+
+	// Retries specifies the total number of operations retried after
+	// connection errors (see WithRetry).
+	Retries int64
+}
+
+// PoolStats returns the current statistics of the connection pool
+// (the number of active and idle connections, as well as the number
+// of and time spent waiting for connections) and the counters of
+// transaction conflicts and retries since the store was created.
+func (r *RedisStore) PoolStats() PoolStats {
+	var st PoolStats
+
+	if p, ok := r.pool.(interface{ Stats() redis.PoolStats }); ok {
+		st.PoolStats = p.Stats()
+	}
+
+	st.TxConflicts = atomic.LoadInt64(&r.txConflicts)
+	st.Retries = atomic.LoadInt64(&r.retries)
+
+	return st
+}
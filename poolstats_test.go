@@ -0,0 +1,43 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RedisStore_PoolStats(t *testing.T) {
+	conn := redigomock.NewConn()
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+		MaxIdle: 1,
+	}
+
+	r := New(pool, prefix, WithRetry(3, 0), WithTxRetries(2, 0))
+
+	c := pool.Get()
+	assert.Equal(t, 1, r.PoolStats().ActiveCount)
+	c.Close()
+
+	st := r.PoolStats()
+	assert.Equal(t, 1, st.ActiveCount)
+	assert.Equal(t, 1, st.IdleCount)
+
+	err := r.retryTx(context.Background(), func() error { return ErrTxConflict })
+	assert.Equal(t, ErrTxConflict, err)
+	assert.Equal(t, int64(2), r.PoolStats().TxConflicts)
+
+	err = r.retry(context.Background(), func() error { return &Error{Kind: ErrConnection, Err: assert.AnError} })
+	assert.Error(t, err)
+	assert.Equal(t, int64(2), r.PoolStats().Retries)
+
+	r = NewWithDialer(func(context.Context) (redis.Conn, error) {
+		return conn, nil
+	}, prefix)
+	assert.Equal(t, PoolStats{}, r.PoolStats())
+}
@@ -0,0 +1,79 @@
+package redisstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/swithek/sessionup"
+)
+
+// DeleteOlderThan deletes all sessions of the store that were created
+// before the provided time, e.g. to enforce a retention policy, and
+// returns the number of deleted sessions. Session keys are iterated
+// over with SCAN, so sessions created while the function is running
+// are not affected, and each batch of old sessions is deleted in a
+// single transaction.
+// If invalidations or auditing are enabled, each deletion is broadcast
+// and recorded respectively.
+func (r *RedisStore) DeleteOlderThan(ctx context.Context, t time.Time) (n int, err error) {
+	var deleted []sessionup.Session
+
+	defer func() {
+		for _, s := range deleted {
+			r.afterDelete(ctx, s, err)
+		}
+	}()
+
+	c, end, err := r.begin(ctx, "DeleteOlderThan")
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() { err = end(err) }()
+
+	err = scan(ctx, c, r.pattern(c, false), func(keys []string) error {
+		ss, err := r.fetchKeys(c, keys)
+		if err != nil {
+			return err
+		}
+
+		var ids []string
+
+		for _, s := range ss {
+			if s.CreatedAt.Before(t) {
+				ids = append(ids, s.ID)
+			}
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		err = r.retryTx(ctx, func() error {
+			ss, err = r.deleteByIDsTx(c, ids)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		deleted = append(deleted, ss...)
+
+		for _, s := range ss {
+			if err = r.invalidate(c, Invalidation{ID: s.ID}); err != nil {
+				return err
+			}
+
+			if err = r.audit(c, AuditDeleted, s); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return len(deleted), err
+	}
+
+	return len(deleted), r.waitReplicas(c)
+}
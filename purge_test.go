@@ -0,0 +1,59 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RedisStore_DeleteOlderThan(t *testing.T) {
+	sKey1 := prefix + ":session:id1"
+	sKey2 := prefix + ":session:id2"
+	uKey := prefix + ":user:u123"
+	now := time.Now()
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	conn.Command("SCAN", int64(0), "MATCH", prefix+":session:*", "COUNT", scanCount).
+		ExpectError(assert.AnError)
+
+	_, err := r.DeleteOlderThan(context.Background(), now)
+	assert.True(t, errors.Is(err, assert.AnError))
+
+	conn.Clear()
+	conn.Command("SCAN", int64(0), "MATCH", prefix+":session:*", "COUNT", scanCount).
+		ExpectSlice([]byte("0"), []interface{}{[]byte(sKey1), []byte(sKey2)})
+	conn.Command("HGETALL", sKey1).ExpectMap(map[string]string{
+		"created_at": now.Add(-time.Hour).Format(time.RFC3339Nano),
+		"expires_at": now.Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id1",
+		"user_key":   "u123",
+	})
+	conn.Command("HGETALL", sKey2).ExpectMap(map[string]string{
+		"created_at": now.Add(time.Second).Format(time.RFC3339Nano),
+		"expires_at": now.Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id2",
+		"user_key":   "u123",
+	})
+	conn.Command("WATCH", sKey1).Expect("OK")
+	conn.Command("MULTI").Expect("OK")
+	conn.Command("ZREM", uKey, sKey1).Expect("QUEUED")
+	del := conn.Command("DEL", sKey1, prefix+":payload:id1").Expect("QUEUED")
+	conn.Command("EXEC").ExpectSlice(int64(1), int64(1))
+
+	n, err := r.DeleteOlderThan(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 1, conn.Stats(del))
+}
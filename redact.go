@@ -0,0 +1,105 @@
+package redisstore
+
+import (
+	"net"
+
+	"github.com/swithek/sessionup"
+)
+
+// Fields of sessions that can be redacted with WithRedaction.
+const (
+	// RedactIP truncates IP addresses to their /24 (IPv4) or /48
+	// (IPv6) networks.
+	RedactIP = "ip"
+
+	// RedactAgent replaces operating system and browser names with
+	// their keyed hashes (see WithRedactionKey).
+	RedactAgent = "agent"
+)
+
+// WithRedaction determines which fields of sessions (see RedactIP and
+// RedactAgent) are redacted before being stored, in order to comply
+// with data-minimization requirements. Redacted values still allow
+// anomaly checks, e.g. whether sessions of a user were created from
+// the same network or device, as well as lookups with FetchByIP and
+// FetchByAgent, which redact the provided values the same way.
+// Enrichers and device classifiers receive sessions before they are
+// redacted. Since sessionup's request validation compares sessions
+// with raw request data, it should be disabled (see sessionup.Validate)
+// whenever any field is redacted; the IPChanged and BrowserChanged
+// fetch validators redact request data the same way and can be used
+// instead, as can Redact for other checks. Unknown fields are ignored.
+// Defaults to no fields.
+func WithRedaction(fields ...string) setter {
+	return func(r *RedisStore) {
+		r.redactIP, r.redactAgent = false, false
+
+		for _, f := range fields {
+			switch f {
+			case RedactIP:
+				r.redactIP = true
+			case RedactAgent:
+				r.redactAgent = true
+			}
+		}
+	}
+}
+
+// WithRedactionKey sets the key of the HMAC-SHA256 hashes that replace
+// operating system and browser names when they are redacted (see
+// RedactAgent). Since there are only a few hundred such names, plain
+// hashes could be reversed with a dictionary. If no key is set, the
+// current encryption key is used (see WithEncryption), in which case
+// redacted values change whenever the key is rotated; if encryption
+// is not enabled either, the names are removed instead.
+// Defaults to no key.
+func WithRedactionKey(key []byte) setter {
+	return func(r *RedisStore) {
+		r.redactKey = key
+	}
+}
+
+// Redact returns the provided session with its fields redacted the
+// same way they are redacted before being stored (see WithRedaction).
+func (r *RedisStore) Redact(s sessionup.Session) sessionup.Session {
+	if r.redactIP {
+		s.IP = maskIP(s.IP)
+	}
+
+	if r.redactAgent {
+		s.Agent.OS = r.hashAgent(s.Agent.OS)
+		s.Agent.Browser = r.hashAgent(s.Agent.Browser)
+	}
+
+	return s
+}
+
+// maskIP truncates the IP address to its /24 (IPv4) or /48 (IPv6)
+// network.
+func maskIP(ip net.IP) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 8*net.IPv4len))
+	}
+
+	if len(ip) != net.IPv6len {
+		return ip
+	}
+
+	return ip.Mask(net.CIDRMask(48, 8*net.IPv6len))
+}
+
+// hashAgent replaces the User-Agent value with its keyed hash (see
+// WithRedactionKey). Empty values are kept as is, while values are
+// removed if no key is available.
+func (r *RedisStore) hashAgent(v string) string {
+	key := r.redactKey
+	if len(key) == 0 && r.enc != nil {
+		key = r.enc.hashKeys[0]
+	}
+
+	if v == "" || len(key) == 0 {
+		return ""
+	}
+
+	return hashWith(key, v)
+}
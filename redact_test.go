@@ -0,0 +1,116 @@
+package redisstore
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithRedaction(t *testing.T) {
+	r := RedisStore{}
+	WithRedaction(RedactIP, "unknown")(&r)
+	assert.True(t, r.redactIP)
+	assert.False(t, r.redactAgent)
+
+	WithRedaction(RedactAgent)(&r)
+	assert.False(t, r.redactIP)
+	assert.True(t, r.redactAgent)
+}
+
+func Test_RedisStore_Redact(t *testing.T) {
+	s := sessionup.Session{ID: "id123", IP: net.ParseIP("10.1.2.3")}
+	s.Agent.OS = "Linux"
+	s.Agent.Browser = "Firefox"
+
+	r := RedisStore{}
+	assert.Equal(t, s, r.Redact(s))
+
+	r.redactIP = true
+	r.redactAgent = true
+
+	// agent names are removed if no key is available.
+	res := r.Redact(s)
+	assert.Empty(t, res.Agent.OS)
+	assert.Empty(t, res.Agent.Browser)
+
+	r.redactKey = []byte("key")
+
+	res = r.Redact(s)
+	assert.Equal(t, "id123", res.ID)
+	assert.Equal(t, "10.1.2.0", res.IP.String())
+	assert.Equal(t, r.hashAgent("Linux"), res.Agent.OS)
+	assert.Equal(t, r.hashAgent("Firefox"), res.Agent.Browser)
+	assert.Equal(t, "10.1.2.3", s.IP.String())
+}
+
+func Test_RedisStore_Create_redaction(t *testing.T) {
+	var (
+		args  []interface{}
+		after sessionup.Session
+	)
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithRedaction(RedactIP, RedactAgent), WithRedactionKey([]byte("key")), WithHooks(Hooks{
+		AfterCreate: func(_ context.Context, s sessionup.Session, _ error) {
+			after = s
+		},
+	}))
+
+	conn.GenericCommand("EVALSHA").Handle(func(aa []interface{}) (interface{}, error) {
+		args = aa
+		return int64(1), nil
+	})
+
+	s := sessionup.Session{
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+		ID:        "id123",
+		UserKey:   "u123",
+		IP:        net.ParseIP("2001:db8:1:2::1"),
+	}
+	s.Agent.OS = "Linux"
+
+	require.NoError(t, r.Create(context.Background(), s))
+	assert.Contains(t, args, "2001:db8:1::")
+	assert.Contains(t, args, r.hashAgent("Linux"))
+	assert.NotContains(t, args, "Linux")
+	assert.Equal(t, "2001:db8:1::", after.IP.String())
+}
+
+func Test_maskIP(t *testing.T) {
+	assert.Nil(t, maskIP(nil))
+	assert.Equal(t, "192.168.1.0", maskIP(net.ParseIP("192.168.1.200")).String())
+	assert.Equal(t, "2001:db8:abcd::", maskIP(net.ParseIP("2001:db8:abcd:12::1")).String())
+}
+
+func Test_WithRedactionKey(t *testing.T) {
+	r := RedisStore{}
+	WithRedactionKey([]byte("key"))(&r)
+	assert.Equal(t, []byte("key"), r.redactKey)
+}
+
+func Test_RedisStore_hashAgent(t *testing.T) {
+	r := RedisStore{}
+	assert.Zero(t, r.hashAgent("Linux"))
+
+	r.enc = newEncryption(key1)
+	assert.Equal(t, r.enc.hash("Linux"), r.hashAgent("Linux"))
+
+	r.redactKey = []byte("key")
+	assert.Zero(t, r.hashAgent(""))
+	assert.Len(t, r.hashAgent("Linux"), hashLen*2)
+	assert.Equal(t, r.hashAgent("Linux"), r.hashAgent("Linux"))
+	assert.NotEqual(t, r.hashAgent("Linux"), r.hashAgent("Windows"))
+	assert.NotEqual(t, r.enc.hash("Linux"), r.hashAgent("Linux"))
+}
@@ -0,0 +1,70 @@
+// Package redisstoretest provides helpers for tests of code that uses
+// redisstore, such as authentication flows.
+package redisstoretest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	redisstore "github.com/swithek/sessionup-redisstore"
+)
+
+// Prefix specifies the prefix of stores returned by NewTestStore.
+const Prefix = "redisstoretest"
+
+// NewTestStore returns a RedisStore with default options that stores
+// its sessions on a new in-memory Redis server (see NewTestPool).
+// The store is closed once the test is finished.
+func NewTestStore(t testing.TB) *redisstore.RedisStore {
+	t.Helper()
+
+	pool, _ := NewTestPool(t)
+	r := redisstore.New(pool, Prefix)
+
+	t.Cleanup(func() {
+		// cleanup functions run in reverse order, hence the store is
+		// closed before the pool and the server
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := r.Close(ctx); err != nil {
+			t.Errorf("closing test store: %v", err)
+		}
+	})
+
+	return r
+}
+
+// NewTestPool starts a new in-memory Redis server (see
+// github.com/alicebob/miniredis) and returns a connection pool of it,
+// so that a store with custom options can be created:
+//
+//	pool, srv := redisstoretest.NewTestPool(t)
+//	store := redisstore.New(pool, "test", redisstore.WithJSON(true))
+//
+// The server does not expire keys as time passes; its clock can be
+// moved with srv.FastForward instead.
+// The pool and the server are closed once the test is finished.
+func NewTestPool(t testing.TB) (*redis.Pool, *miniredis.Miniredis) {
+	t.Helper()
+
+	srv := miniredis.RunT(t)
+
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", srv.Addr())
+		},
+		MaxIdle: 2,
+	}
+
+	t.Cleanup(func() {
+		if err := pool.Close(); err != nil {
+			t.Errorf("closing test pool: %v", err)
+		}
+	})
+
+	return pool, srv
+}
@@ -0,0 +1,109 @@
+package redisstoretest
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+	redisstore "github.com/swithek/sessionup-redisstore"
+)
+
+func Test_NewTestStore(t *testing.T) {
+	r := NewTestStore(t)
+
+	s := sessionup.Session{
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+		ID:        "id123",
+		UserKey:   "u123",
+	}
+
+	require.NoError(t, r.Create(context.Background(), s))
+
+	res, ok, err := r.FetchByID(context.Background(), s.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, s.UserKey, res.UserKey)
+}
+
+func Test_NewTestPool(t *testing.T) {
+	pool, srv := NewTestPool(t)
+	r := redisstore.New(pool, "test", redisstore.WithJSON(true))
+
+	defer func() {
+		assert.NoError(t, r.Close(context.Background()))
+	}()
+
+	s := sessionup.Session{
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+		ID:        "id123",
+		UserKey:   "u123",
+	}
+
+	require.NoError(t, r.Create(context.Background(), s))
+	assert.True(t, srv.Exists("test:session:id123"))
+
+	ss, err := r.FetchByUserKey(context.Background(), s.UserKey)
+	require.NoError(t, err)
+	require.Len(t, ss, 1)
+	assert.Equal(t, s.ID, ss[0].ID)
+
+	srv.FastForward(time.Hour)
+
+	_, ok, err := r.FetchByID(context.Background(), s.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, r.Create(context.Background(), s))
+	require.NoError(t, r.DeleteByID(context.Background(), s.ID))
+
+	_, ok, err = r.FetchByID(context.Background(), s.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func Test_DeleteByUserKey_payloads(t *testing.T) {
+	pool, srv := NewTestPool(t)
+	r := redisstore.New(pool, "test", redisstore.WithIPIndex(true))
+
+	defer func() {
+		assert.NoError(t, r.Close(context.Background()))
+	}()
+
+	ss := []sessionup.Session{
+		{
+			CreatedAt: time.Now(),
+			ExpiresAt: time.Now().Add(time.Hour),
+			ID:        "id123",
+			UserKey:   "u123",
+			IP:        net.ParseIP("127.0.0.1"),
+		},
+		{
+			CreatedAt: time.Now(),
+			ExpiresAt: time.Now().Add(time.Hour),
+			ID:        "id124",
+			UserKey:   "u123",
+			IP:        net.ParseIP("127.0.0.1"),
+		},
+	}
+
+	for _, s := range ss {
+		require.NoError(t, r.Create(context.Background(), s))
+		require.NoError(t, r.SetPayload(context.Background(), s.ID, []byte("data")))
+	}
+
+	require.NoError(t, r.DeleteByUserKey(context.Background(), "u123"))
+
+	for _, s := range ss {
+		_, ok, err := r.GetPayload(context.Background(), s.ID)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+
+	assert.False(t, srv.Exists("test:ip:127.0.0.1"))
+}
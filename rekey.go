@@ -0,0 +1,275 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Rekey copies all sessions, user session sets, secondary indexes,
+// payloads and revoked IDs of the store to keys under the provided
+// prefix, preserving their expiration times. Each key is copied
+// atomically along with its expiration time; keys that already exist
+// under the new prefix are not overwritten. Members of user session sets and
+// secondary indexes are rewritten to refer to the new session keys.
+// The audit stream is not copied.
+// The store keeps using its current prefix: a new store should be
+// created with the new prefix once the keys are copied. Old keys are
+// not deleted and expire as usual. Sessions created or changed while
+// rekeying is in progress may not be copied.
+// Keys copied under an empty prefix never start with a colon, which
+// allows stores that use WithLeadingColon to migrate their keys.
+// Requires Redis 6.2 or newer; if the server is known to be older
+// (see DetectCapabilities), ErrNotSupported is returned before any
+// keys are copied.
+// If invalidations are enabled, an invalidation message is published
+// once the keys are copied, so that local caches of stores that share
+// the channel do not keep sessions that were changed in the meantime.
+func (r *RedisStore) Rekey(ctx context.Context, newPrefix string) (err error) {
+	c, end, err := r.begin(ctx, "Rekey")
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = end(err) }()
+
+	caps := r.Capabilities()
+	if caps.Version != "" && !caps.Copy {
+		return withKind(ErrNotSupported, errors.New("COPY requires Redis 6.2 or newer"))
+	}
+
+	rekey := func(key string) string {
+		return joinPrefix(newPrefix, strings.TrimPrefix(key, r.prefixed(r.prefix, "")))
+	}
+
+	copyKeys := func(keys []string) error {
+		return copyWithTTL(c, keys, rekey, caps.ExpireTime)
+	}
+
+	if err = scan(ctx, c, r.pattern(c, false), copyKeys); err != nil {
+		return err
+	}
+
+	for _, key := range []string{r.revokedKey(c, ""), r.payloadKey(c, "")} {
+		if err = scan(ctx, c, globEscaper.Replace(key)+"*", copyKeys); err != nil {
+			return err
+		}
+	}
+
+	copyKeySets := func(keys []string) error {
+		return copySets(c, keys, rekey, caps.ExpireTime)
+	}
+
+	for _, ns := range r.indexNamespaces() {
+		if err = scan(ctx, c, r.indexPattern(c, ns), copyKeySets); err != nil {
+			return err
+		}
+	}
+
+	if err = scan(ctx, c, r.pattern(c, true), copyKeySets); err != nil {
+		return err
+	}
+
+	return r.invalidate(c, Invalidation{All: true})
+}
+
+// copyWithTTL copies the provided keys to the keys returned by rekey,
+// along with their expiration times. Keys that no longer exist are
+// skipped. expireTime determines whether PEXPIRETIME is available.
+func copyWithTTL(c redis.Conn, keys []string, rekey func(string) string, expireTime bool) error {
+	exps, err := expirations(c, keys, expireTime)
+	if err != nil {
+		return err
+	}
+
+	// pipeline all transactions so that they are done in a single
+	// round trip
+	var n int
+
+	for i := range keys {
+		if exps[i] == -2 {
+			continue
+		}
+
+		cmds := [][]interface{}{
+			{"COPY", keys[i], rekey(keys[i])},
+		}
+
+		if exps[i] >= 0 {
+			cmds = append(cmds, []interface{}{"PEXPIREAT", rekey(keys[i]), exps[i]})
+		}
+
+		if err = sendTx(c, cmds); err != nil {
+			return err
+		}
+
+		n++
+	}
+
+	return receiveTxs(c, n)
+}
+
+// copySets copies the provided user session sets to the keys returned
+// by rekey, along with their expiration times. Members of the sets are
+// renamed with rekey as well. expireTime determines whether
+// PEXPIRETIME is available.
+func copySets(c redis.Conn, keys []string, rekey func(string) string, expireTime bool) error {
+	exps, err := expirations(c, keys, expireTime)
+	if err != nil {
+		return err
+	}
+
+	for i := range keys {
+		if err = c.Send("ZRANGE", keys[i], 0, -1, "WITHSCORES"); err != nil {
+			return err
+		}
+	}
+
+	if err = c.Flush(); err != nil {
+		return err
+	}
+
+	members := make([][]string, len(keys))
+
+	for i := range keys {
+		members[i], err = redis.Strings(c.Receive())
+		if err != nil {
+			return err
+		}
+	}
+
+	var n int
+
+	for i := range keys {
+		if exps[i] == -2 || len(members[i]) == 0 {
+			continue
+		}
+
+		args := []interface{}{rekey(keys[i])}
+		for j := 0; j+1 < len(members[i]); j += 2 {
+			args = append(args, members[i][j+1], rekey(members[i][j]))
+		}
+
+		cmds := [][]interface{}{
+			append([]interface{}{"ZADD"}, args...),
+		}
+
+		if exps[i] >= 0 {
+			cmds = append(cmds, []interface{}{"PEXPIREAT", rekey(keys[i]), exps[i]})
+		}
+
+		if err = sendTx(c, cmds); err != nil {
+			return err
+		}
+
+		n++
+	}
+
+	return receiveTxs(c, n)
+}
+
+// expirations retrieves the expiration time (in Unix milliseconds) of
+// each provided key: -1 means that the key does not expire, -2 that
+// it does not exist. If expireTime is true, the times are retrieved
+// with PEXPIRETIME; otherwise, they are derived from PTTL.
+func expirations(c redis.Conn, keys []string, expireTime bool) ([]int64, error) {
+	cmd := "PTTL"
+	if expireTime {
+		cmd = "PEXPIRETIME"
+	}
+
+	for i := range keys {
+		if err := c.Send(cmd, keys[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+
+	exps := make([]int64, len(keys))
+
+	for i := range keys {
+		v, err := redis.Int64(c.Receive())
+		if err != nil {
+			return nil, err
+		}
+
+		if v >= 0 && !expireTime {
+			v = expireAfter(v)
+		}
+
+		exps[i] = v
+	}
+
+	return exps, nil
+}
+
+// sendTx queues the provided commands wrapped in MULTI/EXEC.
+func sendTx(c redis.Conn, cmds [][]interface{}) error {
+	if err := c.Send("MULTI"); err != nil {
+		return err
+	}
+
+	for _, cmd := range cmds {
+		if err := c.Send(cmd[0].(string), cmd[1:]...); err != nil {
+			return err
+		}
+	}
+
+	return c.Send("EXEC")
+}
+
+// receiveTxs flushes and reads the replies of n transactions queued
+// by sendTx.
+func receiveTxs(c redis.Conn, n int) error {
+	if n == 0 {
+		return nil
+	}
+
+	if err := c.Flush(); err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		// replies of MULTI and queued commands precede the reply
+		// of EXEC
+		for {
+			v, err := c.Receive()
+			if err != nil {
+				return err
+			}
+
+			// only EXEC replies with nil, when the transaction
+			// is aborted due to a modification of watched keys
+			if v == nil {
+				return ErrTxConflict
+			}
+
+			vv, ok := v.([]interface{})
+			if !ok {
+				continue
+			}
+
+			for j := range vv {
+				if err, ok := vv[j].(redis.Error); ok {
+					return err
+				}
+			}
+
+			break
+		}
+	}
+
+	return nil
+}
+
+// expireAfter returns the time (in Unix milliseconds) after the
+// provided number of milliseconds.
+func expireAfter(ms int64) int64 {
+	return time.Now().UnixNano()/int64(time.Millisecond) + ms
+}
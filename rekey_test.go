@@ -0,0 +1,161 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RedisStore_Rekey(t *testing.T) {
+	sKey1 := prefix + ":session:id1"
+	sKey2 := prefix + ":session:id2"
+	rKey := prefix + ":revoked:id3"
+	uKey := prefix + ":user:u1"
+
+	scanCmd := func(conn *redigomock.Conn, pattern string, keys ...string) *redigomock.Cmd {
+		vv := make([]interface{}, len(keys))
+		for i := range keys {
+			vv[i] = []byte(keys[i])
+		}
+
+		return conn.Command("SCAN", int64(0), "MATCH", pattern, "COUNT", scanCount).ExpectSlice([]byte("0"), vv)
+	}
+
+	cc := map[string]struct {
+		Conn func() (*redigomock.Conn, func(*testing.T))
+		Err  bool
+	}{
+		"Error returned during session scan": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SCAN", int64(0), "MATCH", prefix+":session:*", "COUNT", scanCount).
+					ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: true,
+		},
+		"Error returned during copy": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				scanCmd(conn, prefix+":session:*", sKey1)
+				conn.Command("PTTL", sKey1).Expect(int64(1000))
+				conn.GenericCommand("MULTI").Expect("OK")
+				conn.Command("COPY", sKey1, "new:session:id1").Expect("QUEUED")
+				conn.Command("PEXPIREAT", "new:session:id1", redigomock.NewAnyInt()).Expect("QUEUED")
+				conn.GenericCommand("EXEC").ExpectSlice(redis.Error("ERR unknown command 'COPY'"), int64(1))
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: true,
+		},
+		"Error returned during user session set fetch": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				scanCmd(conn, prefix+":session:*")
+				scanCmd(conn, prefix+":revoked:*")
+				scanCmd(conn, prefix+":payload:*")
+				scanCmd(conn, prefix+":user:*", uKey)
+				conn.Command("PTTL", uKey).Expect(int64(1000))
+				conn.Command("ZRANGE", uKey, 0, -1, "WITHSCORES").ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: true,
+		},
+		"Successful rekeying": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				scanCmd(conn, prefix+":session:*", sKey1, sKey2)
+				conn.Command("PTTL", sKey1).Expect(int64(1000))
+				conn.Command("PTTL", sKey2).Expect(int64(-2))
+				conn.GenericCommand("MULTI").Expect("OK")
+				conn.Command("COPY", sKey1, "new:session:id1").Expect("QUEUED")
+				conn.Command("PEXPIREAT", "new:session:id1", redigomock.NewAnyInt()).Expect("QUEUED")
+				conn.GenericCommand("EXEC").ExpectSlice(int64(1), int64(1))
+
+				scanCmd(conn, prefix+":revoked:*", rKey)
+				conn.Command("PTTL", rKey).Expect(int64(-1))
+				conn.Command("COPY", rKey, "new:revoked:id3").Expect("QUEUED")
+
+				scanCmd(conn, prefix+":payload:*")
+
+				scanCmd(conn, prefix+":user:*", uKey)
+				conn.Command("PTTL", uKey).Expect(int64(1000))
+				conn.Command("ZRANGE", uKey, 0, -1, "WITHSCORES").
+					ExpectSlice([]byte(sKey1), []byte("123"), []byte(sKey2), []byte("456"))
+				conn.Command("ZADD", "new:user:u1", "123", "new:session:id1", "456", "new:session:id2").
+					Expect("QUEUED")
+				conn.Command("PEXPIREAT", "new:user:u1", redigomock.NewAnyInt()).Expect("QUEUED")
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+				},
+				prefix: prefix,
+			}
+
+			err := r.Rekey(context.Background(), "new")
+			check(t)
+
+			if c.Err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_RedisStore_Rekey_leadingColon(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.Command("SCAN", int64(0), "MATCH", ":session:*", "COUNT", scanCount).
+		ExpectSlice([]byte("0"), []interface{}{[]byte(":session:id1")})
+	conn.Command("PTTL", ":session:id1").Expect(int64(1000))
+	conn.GenericCommand("MULTI").Expect("OK")
+	conn.Command("COPY", ":session:id1", "session:id1").Expect("QUEUED")
+	conn.Command("PEXPIREAT", "session:id1", redigomock.NewAnyInt()).Expect("QUEUED")
+	conn.GenericCommand("EXEC").ExpectSlice(int64(1), int64(1))
+	conn.Command("SCAN", int64(0), "MATCH", ":revoked:*", "COUNT", scanCount).
+		ExpectSlice([]byte("0"), []interface{}{})
+	conn.Command("SCAN", int64(0), "MATCH", ":payload:*", "COUNT", scanCount).
+		ExpectSlice([]byte("0"), []interface{}{})
+	conn.Command("SCAN", int64(0), "MATCH", ":user:*", "COUNT", scanCount).
+		ExpectSlice([]byte("0"), []interface{}{})
+
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, "", WithLeadingColon(true))
+
+	assert.NoError(t, r.Rekey(context.Background(), ""))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
@@ -0,0 +1,45 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// WithRetry enables retrying of idempotent operations (FetchByID,
+// FetchByUserKey, FetchDetailedByUserKey, DeleteByID, DeleteByIDs
+// and DeleteByUserKey) that fail due to connection errors, such as
+// brief network outages or Redis restarts. attempts specifies the
+// maximum number of attempts, while backoff specifies the base delay
+// between two attempts (it grows linearly with each attempt).
+// Defaults to 1 attempt (no retries).
+func WithRetry(attempts int, backoff time.Duration) setter {
+	return func(r *RedisStore) {
+		r.retryAttempts = attempts
+		r.retryBackoff = backoff
+	}
+}
+
+// retry calls fn until it succeeds, fails with an error other than
+// ErrConnection or no more attempts are left. The last error is
+// returned if the context is done while waiting for the next attempt.
+func (r *RedisStore) retry(ctx context.Context, fn func() error) error {
+	for i := 1; ; i++ {
+		err := fn()
+		if !errors.Is(err, ErrConnection) || i >= r.retryAttempts {
+			return err
+		}
+
+		atomic.AddInt64(&r.retries, 1)
+
+		t := time.NewTimer(r.retryBackoff * time.Duration(i))
+
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithRetry(t *testing.T) {
+	r := RedisStore{}
+	WithRetry(3, time.Second)(&r)
+	assert.Equal(t, 3, r.retryAttempts)
+	assert.Equal(t, time.Second, r.retryBackoff)
+}
+
+func Test_RedisStore_retry(t *testing.T) {
+	connErr := &Error{Op: "op", Kind: ErrConnection, Err: assert.AnError}
+
+	t.Run("Disabled retries", func(t *testing.T) {
+		r := RedisStore{}
+
+		var calls int
+		err := r.retry(context.Background(), func() error {
+			calls++
+			return connErr
+		})
+
+		assert.Equal(t, connErr, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Non-connection error", func(t *testing.T) {
+		r := RedisStore{retryAttempts: 3}
+		cmdErr := &Error{Op: "op", Kind: ErrCommand, Err: assert.AnError}
+
+		var calls int
+		err := r.retry(context.Background(), func() error {
+			calls++
+			return cmdErr
+		})
+
+		assert.Equal(t, cmdErr, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("No attempts left", func(t *testing.T) {
+		r := RedisStore{retryAttempts: 3, retryBackoff: time.Millisecond}
+
+		var calls int
+		err := r.retry(context.Background(), func() error {
+			calls++
+			return connErr
+		})
+
+		assert.Equal(t, connErr, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		r := RedisStore{retryAttempts: 3, retryBackoff: time.Hour}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var calls int
+		err := r.retry(ctx, func() error {
+			calls++
+			cancel()
+
+			return connErr
+		})
+
+		assert.Equal(t, connErr, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Successful retry", func(t *testing.T) {
+		r := RedisStore{retryAttempts: 3, retryBackoff: time.Millisecond}
+
+		var calls int
+		err := r.retry(context.Background(), func() error {
+			calls++
+			if calls == 1 {
+				return connErr
+			}
+
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func Test_RedisStore_DeleteByID_retry(t *testing.T) {
+	sKey := prefix + ":session:id123"
+
+	conn := redigomock.NewConn()
+	conn.Command("WATCH", sKey)
+	conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+	conn.GenericCommand("UNWATCH")
+
+	var dials int
+
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			dials++
+			if dials == 1 {
+				return nil, assert.AnError
+			}
+
+			return conn, nil
+		},
+	}, prefix, WithRetry(2, time.Millisecond))
+
+	require.NoError(t, r.DeleteByID(context.Background(), "id123"))
+	assert.Equal(t, 2, dials)
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	dials = 0
+	r.retryAttempts = 1
+
+	err := r.DeleteByID(context.Background(), "id123")
+	assert.True(t, errors.Is(err, ErrConnection))
+	assert.Equal(t, 1, dials)
+}
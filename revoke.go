@@ -0,0 +1,107 @@
+package redisstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// WithRevocationCheck determines whether FetchByID and Exists should
+// check if the session ID was revoked (see Revoke) and treat revoked
+// sessions as not found. The check requires an additional command on each retrieval.
+// Defaults to false.
+func WithRevocationCheck(t bool) setter {
+	return func(r *RedisStore) {
+		r.revocationCheck = t
+	}
+}
+
+// Revoke adds the provided session ID to the blocklist until the
+// specified time and deletes the session, if it exists. Revoked IDs
+// remain on the blocklist even after their sessions are gone, which
+// allows them to be rejected by IsRevoked (and by FetchByID, if the
+// revocation check is enabled).
+// If invalidations or auditing are enabled, the deletion is broadcast
+// and recorded (as AuditRevoked) respectively.
+// If the provided time has already passed, this function will be
+// no-op. If not found errors are enabled (see WithNotFoundErrors),
+// ErrNoSession is returned after the ID is blocklisted if no session
+// was deleted.
+func (r *RedisStore) Revoke(ctx context.Context, id string, until time.Time) (err error) {
+	c, end, err := r.begin(ctx, "Revoke")
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = end(err) }()
+
+	ttl := until.Sub(r.now()) / time.Millisecond
+	if ttl <= 0 {
+		return nil
+	}
+
+	_, err = c.Do("SET", r.revokedKey(c, id), until.UTC().Format(time.RFC3339Nano), "PX", int64(ttl))
+	if err != nil {
+		return err
+	}
+
+	var (
+		s  sessionup.Session
+		ok bool
+	)
+
+	err = r.retryTx(ctx, func() error {
+		var err error
+		s, ok, err = r.deleteByIDTx(c, id)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = r.invalidate(c, Invalidation{ID: id}); err != nil {
+		return err
+	}
+
+	if !ok {
+		s = sessionup.Session{ID: id}
+	}
+
+	if err = r.audit(c, AuditRevoked, s); err != nil {
+		return err
+	}
+
+	if err = r.waitReplicas(c); err != nil {
+		return err
+	}
+
+	return r.notFound(ok)
+}
+
+// IsRevoked checks whether the provided session ID is on the
+// blocklist.
+func (r *RedisStore) IsRevoked(ctx context.Context, id string) (revoked bool, err error) {
+	c, end, err := r.begin(ctx, "IsRevoked")
+	if err != nil {
+		return false, err
+	}
+
+	defer func() { err = end(err) }()
+
+	return r.isRevoked(c, id)
+}
+
+// isRevoked checks whether the provided session ID is on the
+// blocklist.
+func (r *RedisStore) isRevoked(c redis.Conn, id string) (bool, error) {
+	return redis.Bool(c.Do("EXISTS", r.revokedKey(c, id)))
+}
+
+// revokedKey returns the blocklist key of the provided session ID,
+// scoped to the tenant of the connection.
+func (r *RedisStore) revokedKey(c redis.Conn, id string) string {
+	return r.buildKey(connTenant(c), "revoked", id)
+}
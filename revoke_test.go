@@ -0,0 +1,233 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithRevocationCheck(t *testing.T) {
+	r := RedisStore{}
+	WithRevocationCheck(true)(&r)
+	assert.True(t, r.revocationCheck)
+}
+
+func Test_RedisStore_Revoke(t *testing.T) {
+	sKey := prefix + ":session:id123"
+	pKey := prefix + ":payload:id123"
+	uKey := prefix + ":user:u123"
+	rKey := prefix + ":revoked:id123"
+	until := time.Now().Add(time.Hour)
+
+	cc := map[string]struct {
+		Until time.Time
+		Audit bool
+		Conn  func() (*redigomock.Conn, func(*testing.T))
+		Err   bool
+	}{
+		"Past time": {
+			Until: time.Now().Add(-time.Second),
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+		},
+		"Error returned during blocklist update": {
+			Until: until,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SET", rKey, until.UTC().Format(time.RFC3339Nano), "PX", redigomock.NewAnyInt()).
+					ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session deletion": {
+			Until: until,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SET", rKey, until.UTC().Format(time.RFC3339Nano), "PX", redigomock.NewAnyInt())
+				conn.Command("WATCH", sKey).ExpectError(assert.AnError)
+				conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: true,
+		},
+		"Successful revocation of missing session": {
+			Until: until,
+			Audit: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SET", rKey, until.UTC().Format(time.RFC3339Nano), "PX", redigomock.NewAnyInt())
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+				conn.Command("XADD",
+					prefix+":audit", "MAXLEN", "~", int64(100), "*",
+					"action", AuditRevoked,
+					"id_hash", hashValue("id123"),
+					"user_key", "",
+					"ip", "",
+					"at", redigomock.NewAnyData(),
+				)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+		},
+		"Successful revocation": {
+			Until: until,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SET", rKey, until.UTC().Format(time.RFC3339Nano), "PX", redigomock.NewAnyInt())
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+					"created_at": time.Now().Format(time.RFC3339Nano),
+					"expires_at": until.Format(time.RFC3339Nano),
+					"id":         "id123",
+					"user_key":   "u123",
+				})
+				conn.GenericCommand("MULTI").Expect("OK")
+				conn.Command("ZREM", uKey, sKey).Expect("QUEUED")
+				conn.Command("DEL", sKey, pKey).Expect("QUEUED")
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+				},
+				prefix: prefix,
+			}
+
+			if c.Audit {
+				r.auditMaxLen = 100
+			}
+
+			err := r.Revoke(context.Background(), "id123", c.Until)
+			check(t)
+
+			if c.Err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_RedisStore_IsRevoked(t *testing.T) {
+	rKey := prefix + ":revoked:id123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	conn.Command("EXISTS", rKey).ExpectError(assert.AnError)
+	_, err := r.IsRevoked(context.Background(), "id123")
+	assert.True(t, errors.Is(err, assert.AnError))
+
+	conn.Clear()
+	conn.Command("EXISTS", rKey).Expect(int64(1))
+	revoked, err := r.IsRevoked(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	conn.Clear()
+	conn.Command("EXISTS", rKey).Expect(int64(0))
+	revoked, err = r.IsRevoked(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func Test_RedisStore_FetchByID_revoked(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.Command("EXISTS", prefix+":revoked:id123").Expect(int64(1))
+
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithRevocationCheck(true))
+
+	s, ok, err := r.FetchByID(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Zero(t, s)
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_Exists_revoked(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.Command("EXISTS", prefix+":revoked:id123").Expect(int64(1))
+	exists := conn.Command("EXISTS", prefix+":session:id123").Expect(int64(1))
+
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithRevocationCheck(true))
+
+	ok, err := r.Exists(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 0, conn.Stats(exists))
+}
+
+func Test_RedisStore_Revoke_notFound(t *testing.T) {
+	sKey := prefix + ":session:id123"
+	rKey := prefix + ":revoked:id123"
+	until := time.Now().Add(time.Hour)
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithNotFoundErrors(true))
+
+	conn.Command("SET", rKey, until.UTC().Format(time.RFC3339Nano), "PX", redigomock.NewAnyInt())
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+	conn.GenericCommand("UNWATCH")
+
+	err := r.Revoke(context.Background(), "id123", until)
+	assert.True(t, errors.Is(err, ErrNoSession))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
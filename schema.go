@@ -0,0 +1,61 @@
+package redisstore
+
+import (
+	"strconv"
+)
+
+// schemaVersion specifies the version of the session hash layout
+// written by the store. It must be incremented, and a migration from
+// the previous version added, whenever the layout changes in a way
+// that prevents older hashes from being parsed as they are.
+const schemaVersion = 1
+
+// migration upgrades the fields of a session hash written with the
+// previous schema version in place.
+type migration func(vv map[string]string) error
+
+// migrations holds the migrations to each schema version from the
+// previous one.
+var migrations = map[int]migration{
+	// hashes written before versioning was introduced hold the
+	// "<nil>" placeholder in the ip field of sessions without
+	// an IP address
+	1: func(vv map[string]string) error {
+		if vv["ip"] == "<nil>" {
+			vv["ip"] = ""
+		}
+
+		return nil
+	},
+}
+
+// migrate upgrades the fields of a session hash to the current schema
+// version. Hashes are upgraded only in memory, each time they are
+// read; they are written with the current version the next time the
+// whole session is written.
+// Hashes written with a newer version (e.g. during a rolling upgrade)
+// are returned as they are.
+func migrate(vv map[string]string) error {
+	var v int
+
+	if raw := vv["schema_version"]; raw != "" {
+		var err error
+
+		v, err = strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+	}
+
+	for v < schemaVersion {
+		v++
+
+		if m, ok := migrations[v]; ok {
+			if err := m(vv); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
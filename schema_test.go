@@ -0,0 +1,61 @@
+package redisstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_migrate(t *testing.T) {
+	cc := map[string]struct {
+		Fields map[string]string
+		Result map[string]string
+		Err    bool
+	}{
+		"Invalid version": {
+			Fields: map[string]string{"schema_version": "x"},
+			Err:    true,
+		},
+		"Unversioned hash": {
+			Fields: map[string]string{"ip": "<nil>"},
+			Result: map[string]string{"ip": ""},
+		},
+		"Current version": {
+			Fields: map[string]string{"ip": "<nil>", "schema_version": "1"},
+			Result: map[string]string{"ip": "<nil>", "schema_version": "1"},
+		},
+		"Newer version": {
+			Fields: map[string]string{"ip": "<nil>", "schema_version": "2"},
+			Result: map[string]string{"ip": "<nil>", "schema_version": "2"},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			err := migrate(c.Fields)
+			if c.Err {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.Result, c.Fields)
+		})
+	}
+}
+
+func Test_RedisStore_decodeDetailed_migrated(t *testing.T) {
+	r := RedisStore{}
+
+	_, _, err := r.decodeDetailed("id123", []interface{}{
+		[]byte("created_at"), []byte("2021-03-04T05:06:07Z"),
+		[]byte("expires_at"), []byte("2999-03-04T05:06:07Z"),
+		[]byte("schema_version"), []byte("x"),
+	}, nil)
+	assert.ErrorIs(t, err, ErrParse)
+}
@@ -0,0 +1,123 @@
+package redisstore
+
+import (
+	"sync/atomic"
+)
+
+// createScriptSrc checks whether the session key (KEYS[1]) is free,
+// removes expired entries from the user session set (KEYS[2]),
+// enforces the user session limit, adds the new session to the set
+// and writes the session data.
+// ARGV holds the current time in nanoseconds and milliseconds,
+// session's expiration time in nanoseconds and milliseconds, the
+// maximum number of user sessions (0 means no limit), 1 if sessions
+// over the limit should be rejected instead of evicted, the name of
+// the command used to retrieve session data, the name of the command
+// used to write session data and its arguments.
+// Returns 0 when the session key is already taken, -1 when the limit
+// is reached and the session is rejected, the keys of the evicted
+// sessions, each followed by its data, as returned by the retrieval
+// command, if any sessions were evicted, and 1 otherwise.
+const createScriptSrc = `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+
+local uexp = redis.call("PTTL", KEYS[2]) + tonumber(ARGV[2])
+local sexp = tonumber(ARGV[4])
+if sexp > uexp then
+	uexp = sexp
+end
+
+redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", ARGV[1])
+
+local max = tonumber(ARGV[5])
+local evicted = {}
+if max > 0 then
+	local n = redis.call("ZCARD", KEYS[2])
+	if n >= max then
+		if ARGV[6] == "1" then
+			return -1
+		end
+
+		local old = redis.call("ZRANGE", KEYS[2], 0, n - max)
+		for i = 1, #old do
+			evicted[2 * i - 1] = old[i]
+			evicted[2 * i] = redis.call(ARGV[7], old[i])
+			redis.call("DEL", old[i])
+			redis.call("ZREM", KEYS[2], old[i])
+		end
+	end
+end
+
+redis.call("ZADD", KEYS[2], ARGV[3], KEYS[1])
+redis.call("PEXPIREAT", KEYS[2], uexp)
+redis.call(ARGV[8], KEYS[1], unpack(ARGV, 9))
+redis.call("PEXPIREAT", KEYS[1], ARGV[4])
+
+if #evicted == 0 then
+	return 1
+end
+
+return evicted
+`
+
+var createScript = newLuaScript("create", 2, createScriptSrc)
+
+// deleteByUserKeyScriptSrc deletes all sessions found in the user
+// session set (KEYS[1]), along with their payloads, except those whose
+// keys are provided in ARGV starting from ARGV[4], and removes them
+// from the set. ARGV[1] and ARGV[2] hold the keys of the session and
+// payload namespaces built for an empty ID, so that the payload key of
+// each session can be derived from its session key. The set itself is
+// deleted once no sessions are left in it.
+// If ARGV[3] holds the name of the command used to retrieve session
+// data, the keys of the deleted sessions, each followed by its data,
+// are returned, so that they can be removed from secondary indexes;
+// otherwise the number of deleted sessions is returned.
+const deleteByUserKeyScriptSrc = `
+local keep = {}
+for i = 4, #ARGV do
+	keep[ARGV[i]] = true
+end
+
+local ids = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", "+inf")
+local left = 0
+local deleted = {}
+
+for i = 1, #ids do
+	if keep[ids[i]] then
+		left = left + 1
+	else
+		if ARGV[3] ~= "" then
+			deleted[#deleted + 1] = ids[i]
+			deleted[#deleted + 1] = redis.call(ARGV[3], ids[i])
+		end
+
+		redis.call("DEL", ids[i], ARGV[2] .. string.sub(ids[i], #ARGV[1] + 1))
+		redis.call("ZREM", KEYS[1], ids[i])
+	end
+end
+
+if left == 0 then
+	redis.call("DEL", KEYS[1])
+end
+
+if ARGV[3] ~= "" then
+	return deleted
+end
+
+return #ids - left
+`
+
+var deleteByUserKeyScript = newLuaScript("delete_by_user_key", 1, deleteByUserKeyScriptSrc)
+
+// scriptsDisabled checks whether Lua scripts should be skipped.
+func (r *RedisStore) scriptsDisabled() bool {
+	return atomic.LoadInt32(&r.noScripts) == 1
+}
+
+// disableScripts ensures that Lua scripts are no longer used.
+func (r *RedisStore) disableScripts() {
+	atomic.StoreInt32(&r.noScripts, 1)
+}
@@ -0,0 +1,15 @@
+package redisstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RedisStore_disableScripts(t *testing.T) {
+	r := RedisStore{}
+	assert.False(t, r.scriptsDisabled())
+
+	r.disableScripts()
+	assert.True(t, r.scriptsDisabled())
+}
@@ -0,0 +1,102 @@
+// Package scsstore adapts redisstore to the Store and CtxStore
+// interfaces of github.com/alexedwards/scs/v2, so that services using
+// scs and services using sessionup can share the same session
+// keyspace.
+package scsstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/swithek/sessionup"
+	redisstore "github.com/swithek/sessionup-redisstore"
+)
+
+// Store implements the scs.Store and scs.CtxStore interfaces on top of
+// RedisStore. Each scs token is used as the ID of a session, while the
+// data encoded by scs is stored as the session's payload (see
+// RedisStore.Upsert).
+type Store struct {
+	store   *redisstore.RedisStore
+	userKey func(ctx context.Context, token string, b []byte) string
+}
+
+// setter is used to set Store configuration options.
+type setter func(*Store)
+
+// WithUserKey sets the function that determines the user key of a new
+// session from its token and the data encoded by scs, e.g. by
+// decoding the ID of the authenticated user. Sessions of the same user
+// can then be managed together with sessionup (e.g. with
+// RedisStore.DeleteByUserKey).
+// Defaults to a function that returns the token itself, so that each
+// session belongs to its own anonymous user.
+func WithUserKey(fn func(ctx context.Context, token string, b []byte) string) setter {
+	return func(s *Store) {
+		s.userKey = fn
+	}
+}
+
+// New returns a new Store that persists scs sessions in the provided
+// RedisStore.
+func New(r *redisstore.RedisStore, opts ...setter) *Store {
+	s := &Store{
+		store: r,
+		userKey: func(_ context.Context, token string, _ []byte) string {
+			return token
+		},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Find returns the data of the session with the provided token. The
+// returned boolean indicates whether the session was found.
+func (s *Store) Find(token string) ([]byte, bool, error) {
+	return s.FindCtx(context.Background(), token)
+}
+
+// FindCtx returns the data of the session with the provided token. The
+// returned boolean indicates whether the session was found.
+func (s *Store) FindCtx(ctx context.Context, token string) ([]byte, bool, error) {
+	return s.store.GetPayload(ctx, token)
+}
+
+// Commit creates or updates the session with the provided token, so
+// that it holds the provided data and expires at the provided time.
+func (s *Store) Commit(token string, b []byte, expiry time.Time) error {
+	return s.CommitCtx(context.Background(), token, b, expiry)
+}
+
+// CommitCtx creates or updates the session with the provided token, so
+// that it holds the provided data and expires at the provided time.
+func (s *Store) CommitCtx(ctx context.Context, token string, b []byte, expiry time.Time) error {
+	return s.store.Upsert(ctx, sessionup.Session{
+		CreatedAt: time.Now(),
+		ExpiresAt: expiry,
+		ID:        token,
+		UserKey:   s.userKey(ctx, token, b),
+	}, b)
+}
+
+// Delete deletes the session with the provided token. It is no-op if
+// the session does not exist.
+func (s *Store) Delete(token string) error {
+	return s.DeleteCtx(context.Background(), token)
+}
+
+// DeleteCtx deletes the session with the provided token. It is no-op
+// if the session does not exist.
+func (s *Store) DeleteCtx(ctx context.Context, token string) error {
+	err := s.store.DeleteByID(ctx, token)
+	if errors.Is(err, redisstore.ErrNoSession) {
+		return nil
+	}
+
+	return err
+}
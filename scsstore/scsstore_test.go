@@ -0,0 +1,89 @@
+package scsstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	redisstore "github.com/swithek/sessionup-redisstore"
+)
+
+const prefix = "test"
+
+func newStore(conn redis.Conn, opts ...setter) *Store {
+	return New(redisstore.New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix), opts...)
+}
+
+func Test_New(t *testing.T) {
+	s := New(nil)
+	assert.Equal(t, "tok", s.userKey(context.Background(), "tok", nil))
+
+	s = New(nil, WithUserKey(func(context.Context, string, []byte) string {
+		return "u123"
+	}))
+	assert.Equal(t, "u123", s.userKey(context.Background(), "tok", nil))
+}
+
+func Test_Store_Find(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.Command("PTTL", prefix+":session:tok").Expect(int64(1000))
+	conn.Command("PTTL", prefix+":payload:tok").Expect(int64(1000))
+	conn.Command("GET", prefix+":payload:tok").Expect([]byte("data"))
+
+	b, ok, err := newStore(conn).Find("tok")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("data"), b)
+}
+
+func Test_Store_Commit(t *testing.T) {
+	sKey := prefix + ":session:tok"
+	uKey := prefix + ":user:u123"
+	pKey := prefix + ":payload:tok"
+	exp := time.Now().Add(time.Hour)
+
+	conn := redigomock.NewConn()
+	conn.Command("WATCH", sKey).ExpectError(assert.AnError)
+
+	s := newStore(conn, WithUserKey(func(context.Context, string, []byte) string {
+		return "u123"
+	}))
+
+	assert.Error(t, s.Commit("tok", []byte("data"), exp))
+
+	conn.Clear()
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+	conn.Command("WATCH", uKey).Expect("OK")
+	conn.Command("PTTL", uKey).Expect(int64(-2))
+	conn.GenericCommand("MULTI").Expect("OK")
+	conn.GenericCommand("ZREMRANGEBYSCORE").Expect("QUEUED")
+	zadd := conn.Command("ZADD", uKey, exp.UnixNano(), sKey).Expect("QUEUED")
+	conn.GenericCommand("PEXPIREAT").Expect("QUEUED")
+	conn.GenericCommand("HMSET").Expect("QUEUED")
+	set := conn.Command("SET", pKey, []byte("data")).Expect("QUEUED")
+	conn.GenericCommand("EXEC").ExpectSlice("OK")
+
+	require.NoError(t, s.Commit("tok", []byte("data"), exp))
+	assert.Equal(t, 1, conn.Stats(zadd))
+	assert.Equal(t, 1, conn.Stats(set))
+}
+
+func Test_Store_Delete(t *testing.T) {
+	sKey := prefix + ":session:tok"
+
+	conn := redigomock.NewConn()
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+	conn.Command("UNWATCH").Expect("OK")
+
+	assert.NoError(t, newStore(conn).Delete("tok"))
+}
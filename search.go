@@ -0,0 +1,174 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// errNoSearch is returned when a search operation is attempted but
+// the search index is not enabled or not supported by the storage
+// format.
+var errNoSearch = errors.New("redisstore: search index is not enabled or is not supported by the storage format")
+
+// WithSearchIndex enables querying of sessions with a RediSearch
+// index (see CreateIndex and FetchWhere). The user_key, ip, agent_os
+// and agent_browser fields are always indexed, as are created_at and
+// expires_at when times are written as integers (see WithTimeFormat);
+// metaKeys specify the metadata entries that should be indexed as
+// well, as meta_<key> fields. Metadata entries are not indexed (nor
+// written into separate fields) when encryption is enabled, and no
+// fields are searchable if the whole session is encrypted.
+// The index is not supported when sessions are stored as single values
+// (see WithJSON and WithCodec).
+// Defaults to disabled.
+func WithSearchIndex(metaKeys ...string) setter {
+	return func(r *RedisStore) {
+		r.search = true
+		r.searchMeta = metaKeys
+	}
+}
+
+// CreateIndex creates the RediSearch index over the session hashes of
+// the store, unless it already exists. Sessions that were created
+// before the index are indexed by Redis in the background.
+// The index does not change when the list of indexed metadata
+// entries does; it has to be dropped (FT.DROPINDEX) and created again.
+func (r *RedisStore) CreateIndex(ctx context.Context) (err error) {
+	if !r.search || r.singleValue() {
+		return errNoSearch
+	}
+
+	c, end, err := r.begin(ctx, "CreateIndex")
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = end(err) }()
+
+	args := []interface{}{
+		r.index(c), "ON", "HASH",
+		"PREFIX", 1, r.key(c, false, ""),
+		"SCHEMA",
+		"user_key", "TAG",
+		"ip", "TAG",
+		"agent_os", "TAG",
+		"agent_browser", "TAG",
+	}
+
+	if r.timeFormat == UnixMilli {
+		args = append(args, "created_at", "NUMERIC", "expires_at", "NUMERIC")
+	}
+
+	if r.indexMeta() {
+		for _, k := range r.searchMeta {
+			args = append(args, "meta_"+k, "TAG")
+		}
+	}
+
+	_, err = c.Do("FT.CREATE", args...)
+
+	var rerr redis.Error
+	if errors.As(err, &rerr) && strings.Contains(strings.ToLower(rerr.Error()), "index already exists") {
+		return nil
+	}
+
+	return err
+}
+
+// FetchWhere retrieves all sessions that match the provided RediSearch
+// query, e.g. "@agent_os:{gnu/linux} @meta_role:{admin}". Special
+// characters in tag values (including '.' in IP addresses) must be
+// escaped with a backslash.
+// If no sessions are found, both return values will be nil.
+func (r *RedisStore) FetchWhere(ctx context.Context, query string) (ss []sessionup.Session, err error) {
+	if !r.search || r.singleValue() {
+		return nil, errNoSearch
+	}
+
+	c, end, err := r.begin(ctx, "FetchWhere")
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { err = end(err) }()
+
+	for offset := 0; ; offset += scanCount {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		vv, err := redis.Values(c.Do("FT.SEARCH", r.index(c), query, "LIMIT", offset, scanCount))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(vv) == 0 {
+			return ss, nil
+		}
+
+		total, err := redis.Int(vv[0], nil)
+		if err != nil {
+			return nil, withKind(ErrParse, err)
+		}
+
+		// the total count is followed by pairs of keys and their
+		// fields
+		for i := 2; i < len(vv); i += 2 {
+			key, err := redis.String(vv[i-1], nil)
+			if err != nil {
+				return nil, withKind(ErrParse, err)
+			}
+
+			s, ok, err := r.decode(r.idFromKey(c, key), vv[i], nil)
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				ss = append(ss, s)
+			}
+		}
+
+		if len(vv) < 3 || offset+scanCount >= total {
+			return ss, nil
+		}
+	}
+}
+
+// index returns the name of the store's search index, scoped to the
+// tenant of the connection.
+func (r *RedisStore) index(c redis.Conn) string {
+	return r.prefixed(r.scopedPrefix(connTenant(c)), "idx")
+}
+
+// indexMeta checks whether metadata entries should be written into
+// separate, indexed fields.
+func (r *RedisStore) indexMeta() bool {
+	return r.search && len(r.searchMeta) > 0 && r.enc == nil && !r.singleValue()
+}
+
+// metaIndexFields returns the indexed fields of the metadata entries
+// present in the map along with their values, as well as the names
+// of the indexed fields whose entries are missing.
+func (r *RedisStore) metaIndexFields(mm map[string]string) ([]interface{}, []interface{}) {
+	if !r.indexMeta() {
+		return nil, nil
+	}
+
+	var ff, missing []interface{}
+
+	for _, k := range r.searchMeta {
+		if v, ok := mm[k]; ok {
+			ff = append(ff, "meta_"+k, v)
+			continue
+		}
+
+		missing = append(missing, "meta_"+k)
+	}
+
+	return ff, missing
+}
@@ -0,0 +1,351 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithSearchIndex(t *testing.T) {
+	r := RedisStore{}
+	WithSearchIndex("role", "org")(&r)
+	assert.True(t, r.search)
+	assert.Equal(t, []string{"role", "org"}, r.searchMeta)
+}
+
+func Test_RedisStore_CreateIndex(t *testing.T) {
+	args := []interface{}{
+		prefix + ":idx", "ON", "HASH",
+		"PREFIX", 1, prefix + ":session:",
+		"SCHEMA",
+		"user_key", "TAG",
+		"ip", "TAG",
+		"agent_os", "TAG",
+		"agent_browser", "TAG",
+	}
+
+	cc := map[string]struct {
+		Search bool
+		JSON   bool
+		Meta   []string
+		Conn   func() (*redigomock.Conn, func(*testing.T))
+		Err    error
+	}{
+		"Disabled search": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: errNoSearch,
+		},
+		"JSON mode": {
+			Search: true,
+			JSON:   true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: errNoSearch,
+		},
+		"Unsupported command": {
+			Search: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("FT.CREATE", args...).ExpectError(redis.Error("ERR unknown command 'FT.CREATE'"))
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: ErrNotSupported,
+		},
+		"Existing index": {
+			Search: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("FT.CREATE", args...).ExpectError(redis.Error("Index already exists"))
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+		},
+		"Successful creation with metadata": {
+			Search: true,
+			Meta:   []string{"role"},
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("FT.CREATE", append(args, "meta_role", "TAG")...)
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+				},
+				prefix:     prefix,
+				search:     c.Search,
+				searchMeta: c.Meta,
+				asJSON:     c.JSON,
+			}
+
+			err := r.CreateIndex(context.Background())
+			check(t)
+
+			if c.Err != nil {
+				assert.True(t, errors.Is(err, c.Err))
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_RedisStore_FetchWhere(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+		CreatedAt: time.Now().UTC().Round(0),
+		IP:        net.ParseIP("127.0.0.1"),
+		Meta:      map[string]string{"role": "admin"},
+	}
+	inp.Agent.OS = "gnu/linux"
+	inp.Agent.Browser = "firefox"
+
+	fields := func(id string) []interface{} {
+		return []interface{}{
+			[]byte("created_at"), []byte(inp.CreatedAt.Format(time.RFC3339Nano)),
+			[]byte("expires_at"), []byte(inp.ExpiresAt.Format(time.RFC3339Nano)),
+			[]byte("id"), []byte(id),
+			[]byte("user_key"), []byte(inp.UserKey),
+			[]byte("ip"), []byte(inp.IP.String()),
+			[]byte("agent_os"), []byte(inp.Agent.OS),
+			[]byte("agent_browser"), []byte(inp.Agent.Browser),
+			[]byte("meta"), []byte("role=admin"),
+			[]byte("meta_role"), []byte("admin"),
+		}
+	}
+
+	query := "@meta_role:{admin}"
+	idx := prefix + ":idx"
+
+	cc := map[string]struct {
+		Search bool
+		Conn   func() (*redigomock.Conn, func(*testing.T))
+		Result []sessionup.Session
+		Err    error
+	}{
+		"Disabled search": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: errNoSearch,
+		},
+		"Error returned during search": {
+			Search: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("FT.SEARCH", idx, query, "LIMIT", 0, scanCount).ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: ErrInternal,
+		},
+		"Invalid total count": {
+			Search: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("FT.SEARCH", idx, query, "LIMIT", 0, scanCount).ExpectSlice([]byte("x"))
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: ErrParse,
+		},
+		"Error returned during parsing": {
+			Search: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("FT.SEARCH", idx, query, "LIMIT", 0, scanCount).ExpectSlice(
+					int64(1),
+					[]byte(prefix+":session:id1"), []interface{}{[]byte("expires_at"), []byte("123")},
+				)
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Err: ErrParse,
+		},
+		"No results": {
+			Search: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("FT.SEARCH", idx, query, "LIMIT", 0, scanCount).ExpectSlice(int64(0))
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+		},
+		"Successful fetch": {
+			Search: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				first := []interface{}{int64(scanCount + 1)}
+				for i := 0; i < scanCount; i++ {
+					first = append(first, []byte(prefix+":session:id123"), fields("id123"))
+				}
+
+				conn := redigomock.NewConn()
+				conn.Command("FT.SEARCH", idx, query, "LIMIT", 0, scanCount).ExpectSlice(first...)
+				conn.Command("FT.SEARCH", idx, query, "LIMIT", scanCount, scanCount).ExpectSlice(
+					int64(scanCount+1),
+					[]byte(prefix+":session:id124"), fields("id124"),
+				)
+
+				return conn, func(t *testing.T) {
+					assert.NoError(t, conn.ExpectationsWereMet())
+				}
+			},
+			Result: func() []sessionup.Session {
+				var ss []sessionup.Session
+				for i := 0; i < scanCount; i++ {
+					ss = append(ss, inp)
+				}
+
+				s := inp
+				s.ID = "id124"
+
+				return append(ss, s)
+			}(),
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+				},
+				prefix: prefix,
+				search: c.Search,
+			}
+
+			ss, err := r.FetchWhere(context.Background(), query)
+			check(t)
+
+			if c.Err != nil {
+				assert.True(t, errors.Is(err, c.Err))
+				assert.Nil(t, ss)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.Result, ss)
+		})
+	}
+}
+
+func Test_RedisStore_metaIndexFields(t *testing.T) {
+	r := RedisStore{}
+	ff, missing := r.metaIndexFields(map[string]string{"role": "admin"})
+	assert.Nil(t, ff)
+	assert.Nil(t, missing)
+
+	r.search = true
+	r.searchMeta = []string{"role", "org"}
+	ff, missing = r.metaIndexFields(map[string]string{"role": "admin", "test": "1"})
+	assert.Equal(t, []interface{}{"meta_role", "admin"}, ff)
+	assert.Equal(t, []interface{}{"meta_org"}, missing)
+
+	r.enc = newEncryption(key1)
+	ff, missing = r.metaIndexFields(map[string]string{"role": "admin"})
+	assert.Nil(t, ff)
+	assert.Nil(t, missing)
+}
+
+func Test_RedisStore_updateMetaTx_indexed(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+		CreatedAt: time.Now().UTC().Round(0),
+		Meta:      map[string]string{"role": "admin", "org": "o1"},
+	}
+
+	sKey := prefix + ":session:" + inp.ID
+
+	r := RedisStore{
+		prefix:     prefix,
+		search:     true,
+		searchMeta: []string{"role", "org"},
+	}
+
+	_, data, err := r.encode(inp)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"meta_role", "admin", "meta_org", "o1"}, data[len(data)-4:])
+
+	conn := redigomock.NewConn()
+	conn.Command("WATCH", sKey)
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": inp.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at": inp.ExpiresAt.Format(time.RFC3339Nano),
+		"id":         inp.ID,
+		"user_key":   inp.UserKey,
+		"meta":       "org=o1&role=admin",
+		"meta_role":  "admin",
+		"meta_org":   "o1",
+	})
+	conn.GenericCommand("MULTI")
+	conn.Command("HSET", sKey, "meta", "role=user", "meta_role", "user")
+	conn.Command("HDEL", sKey, "meta_org")
+	conn.GenericCommand("EXEC").ExpectSlice()
+
+	_, ok, err := r.updateMetaTx(conn, inp.ID, map[string]string{"role": "user"}, false)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
@@ -0,0 +1,73 @@
+package redisstore
+
+import (
+	"sync"
+
+	"github.com/swithek/sessionup"
+)
+
+// flightGroup de-duplicates concurrent retrievals of the same session.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall holds an in-flight or completed session retrieval.
+type flightCall struct {
+	wg  sync.WaitGroup
+	s   sessionup.Session
+	ok  bool
+	err error
+}
+
+// WithSingleflight determines whether concurrent FetchByID calls for
+// the same session ID should be de-duplicated, so that only one of
+// them retrieves the session from Redis while the others wait for
+// and share its result (including its error, e.g. one caused by the
+// cancellation of its context).
+// Defaults to false.
+func WithSingleflight(t bool) setter {
+	return func(r *RedisStore) {
+		r.flights = nil
+
+		if t {
+			r.flights = &flightGroup{}
+		}
+	}
+}
+
+// do executes fn, unless a call with the same key is already in
+// flight, in which case its result is awaited and returned instead.
+func (g *flightGroup) do(key string, fn func() (sessionup.Session, bool, error)) (sessionup.Session, bool, error) {
+	g.mu.Lock()
+
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	if fc, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		fc.wg.Wait()
+
+		// each caller receives its own copy, since sessions are
+		// mutable
+		return copySession(fc.s), fc.ok, fc.err
+	}
+
+	fc := &flightCall{}
+	fc.wg.Add(1)
+	g.calls[key] = fc
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+
+		fc.wg.Done()
+	}()
+
+	fc.s, fc.ok, fc.err = fn()
+
+	return copySession(fc.s), fc.ok, fc.err
+}
@@ -0,0 +1,117 @@
+package redisstore
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithSingleflight(t *testing.T) {
+	r := RedisStore{}
+	WithSingleflight(true)(&r)
+	assert.NotNil(t, r.flights)
+
+	WithSingleflight(false)(&r)
+	assert.Nil(t, r.flights)
+}
+
+func Test_flightGroup_do(t *testing.T) {
+	g := &flightGroup{}
+	inp := sessionup.Session{ID: "id1", Meta: map[string]string{"test": "1"}}
+
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	fn := func() (sessionup.Session, bool, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		close(started)
+		<-release
+
+		return inp, true, assert.AnError
+	}
+
+	var wg sync.WaitGroup
+
+	res := make([]sessionup.Session, 5)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		s, ok, err := g.do("id1", fn)
+		assert.True(t, ok)
+		assert.Equal(t, assert.AnError, err)
+		res[0] = s
+	}()
+
+	<-started
+
+	for i := 1; i < len(res); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			s, ok, err := g.do("id1", fn)
+			assert.True(t, ok)
+			assert.Equal(t, assert.AnError, err)
+			res[i] = s
+		}(i)
+	}
+
+	// give the waiters time to join the in-flight call
+	time.Sleep(time.Millisecond * 10)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, g.calls)
+
+	for i := range res {
+		assert.Equal(t, inp, res[i])
+	}
+
+	res[0].Meta["test"] = "2"
+	assert.Equal(t, "1", res[1].Meta["test"])
+	assert.Equal(t, "1", inp.Meta["test"])
+
+	s, ok, err := g.do("id1", func() (sessionup.Session, bool, error) {
+		return sessionup.Session{}, false, nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, sessionup.Session{}, s)
+}
+
+func Test_RedisStore_FetchByID_singleflight(t *testing.T) {
+	sKey := prefix + ":session:id123"
+
+	conn := redigomock.NewConn()
+	conn.Command("HGETALL", sKey).ExpectError(assert.AnError)
+
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithSingleflight(true))
+
+	_, ok, err := r.FetchByID(context.Background(), "id123")
+	require.Error(t, err)
+	assert.False(t, ok)
+	assert.Empty(t, r.flights.calls)
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
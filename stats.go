@@ -0,0 +1,70 @@
+package redisstore
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Stats holds session usage statistics of the store.
+type Stats struct {
+	// Sessions specifies the total number of active sessions.
+	Sessions int64
+
+	// Users specifies the total number of user session sets.
+	Users int64
+
+	// SessionsPerUser maps a number of active sessions to the number
+	// of users that have exactly that many. Sets that contain only
+	// expired entries are counted under 0.
+	SessionsPerUser map[int64]int64
+}
+
+// Stats collects session usage statistics by iterating over all user
+// session sets of the store with SCAN. The result is not a point-in-time
+// snapshot: sets created or removed during the iteration may or may not
+// be included.
+func (r *RedisStore) Stats(ctx context.Context) (st Stats, err error) {
+	c, end, err := r.begin(ctx, "Stats")
+	if err != nil {
+		return Stats{}, err
+	}
+
+	defer func() { err = end(err) }()
+
+	st.SessionsPerUser = make(map[int64]int64)
+	min := "(" + strconv.FormatInt(r.now().UnixNano(), 10)
+
+	err = scan(ctx, c, r.pattern(c, true), func(keys []string) error {
+		// pipeline all set counts of the batch so that they are done
+		// in a single round trip
+		for i := range keys {
+			if err := c.Send("ZCOUNT", keys[i], min, "+inf"); err != nil {
+				return err
+			}
+		}
+
+		if err := c.Flush(); err != nil {
+			return err
+		}
+
+		for range keys {
+			n, err := redis.Int64(c.Receive())
+			if err != nil {
+				return err
+			}
+
+			st.Users++
+			st.Sessions += n
+			st.SessionsPerUser[n]++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+
+	return st, nil
+}
@@ -0,0 +1,137 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RedisStore_Stats(t *testing.T) {
+	pattern := prefix + ":user:*"
+	uKey1 := prefix + ":user:u1"
+	uKey2 := prefix + ":user:u2"
+	uKey3 := prefix + ":user:u3"
+
+	cc := map[string]struct {
+		Cancelled bool
+		Conn      func() (*redigomock.Conn, func(*testing.T))
+		Result    Stats
+		Err       bool
+	}{
+		"Cancelled context": {
+			Cancelled: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during scan": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SCAN", int64(0), "MATCH", pattern, "COUNT", scanCount).ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during set count": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SCAN", int64(0), "MATCH", pattern, "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{[]byte(uKey1)})
+				conn.Command("ZCOUNT", uKey1, redigomock.NewAnyData(), "+inf").ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Successful execution with no sets": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SCAN", int64(0), "MATCH", pattern, "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{})
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Result: Stats{SessionsPerUser: map[int64]int64{}},
+		},
+		"Successful execution": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SCAN", int64(0), "MATCH", pattern, "COUNT", scanCount).
+					ExpectSlice([]byte("12"), []interface{}{[]byte(uKey1), []byte(uKey2)})
+				conn.Command("SCAN", int64(12), "MATCH", pattern, "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{[]byte(uKey3)})
+				conn.Command("ZCOUNT", uKey1, redigomock.NewAnyData(), "+inf").Expect(int64(2))
+				conn.Command("ZCOUNT", uKey2, redigomock.NewAnyData(), "+inf").Expect(int64(0))
+				conn.Command("ZCOUNT", uKey3, redigomock.NewAnyData(), "+inf").Expect(int64(2))
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Result: Stats{
+				Sessions:        4,
+				Users:           3,
+				SessionsPerUser: map[int64]int64{0: 1, 2: 2},
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+					Wait:      true,
+					MaxActive: 10,
+				},
+				prefix: prefix,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if c.Cancelled {
+				cancel()
+			}
+
+			st, err := r.Stats(ctx)
+			check(t)
+
+			if c.Err {
+				assert.Error(t, err)
+				assert.Zero(t, st)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.Result, st)
+		})
+	}
+}
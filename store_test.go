@@ -2,6 +2,8 @@ package redisstore
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net"
 	"strconv"
 	"testing"
@@ -21,6 +23,196 @@ func Test_New(t *testing.T) {
 	require.NotNil(t, r)
 	assert.NotNil(t, r.pool)
 	assert.Equal(t, prefix, r.prefix)
+	assert.Equal(t, defaultTxAttempts, r.txAttempts)
+	assert.Equal(t, defaultTxBackoff, r.txBackoff)
+
+	r = New(&redis.Pool{}, prefix, WithTxRetries(5, time.Second), WithJSON(true))
+	require.NotNil(t, r)
+	assert.Equal(t, 5, r.txAttempts)
+	assert.Equal(t, time.Second, r.txBackoff)
+	assert.True(t, r.asJSON)
+	assert.NotNil(t, r.stop)
+	assert.False(t, r.ownPool)
+}
+
+type countingPool struct {
+	conn redis.Conn
+	gets int
+}
+
+func (p *countingPool) GetContext(context.Context) (redis.Conn, error) {
+	p.gets++
+	return p.conn, nil
+}
+
+func Test_New_pooler(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.Command("HGETALL", prefix+":session:id123").ExpectMap(map[string]string{})
+
+	p := &countingPool{conn: conn}
+	r := New(p, prefix)
+
+	_, ok, err := r.FetchByID(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 1, p.gets)
+	assert.NoError(t, r.Close(context.Background()))
+}
+
+func Test_NewWithDialer(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.Command("HGETALL", prefix+":session:id123").ExpectMap(map[string]string{})
+
+	var dials int
+
+	r := NewWithDialer(func(ctx context.Context) (redis.Conn, error) {
+		dials++
+		return conn, ctx.Err()
+	}, prefix, WithJSON(false))
+	require.NotNil(t, r)
+	assert.Equal(t, prefix, r.prefix)
+
+	_, ok, err := r.FetchByID(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 1, dials)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err = r.FetchByID(ctx, "id123")
+	assert.Error(t, err)
+}
+
+func Test_NewFromURL(t *testing.T) {
+	r, err := NewFromURL("://", prefix)
+	assert.Error(t, err)
+	assert.Nil(t, r)
+
+	r, err = NewFromURL("http://localhost:6379", prefix)
+	assert.Error(t, err)
+	assert.Nil(t, r)
+
+	r, err = NewFromURL("redis://localhost:6379/0", prefix, WithJSON(true))
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.NotNil(t, r.pool)
+	assert.Equal(t, prefix, r.prefix)
+	assert.True(t, r.ownPool)
+	assert.True(t, r.asJSON)
+}
+
+func Test_WithWriteConcern(t *testing.T) {
+	r := RedisStore{}
+	WithWriteConcern(2, time.Second)(&r)
+	assert.Equal(t, 2, r.replicas)
+	assert.Equal(t, time.Second, r.replicaTimeout)
+}
+
+func Test_WithMaxUserSessions(t *testing.T) {
+	r := RedisStore{}
+	WithMaxUserSessions(3, Reject)(&r)
+	assert.Equal(t, 3, r.maxUserSessions)
+	assert.Equal(t, Reject, r.sessionLimit)
+}
+
+func Test_WithIdleTimeout(t *testing.T) {
+	r := RedisStore{}
+	WithIdleTimeout(time.Minute)(&r)
+	assert.Equal(t, time.Minute, r.idleTimeout)
+}
+
+func Test_WithTTLJitter(t *testing.T) {
+	r := RedisStore{}
+	WithTTLJitter(time.Minute)(&r)
+	assert.Equal(t, time.Minute, r.ttlJitter)
+}
+
+func Test_WithLastSeen(t *testing.T) {
+	r := RedisStore{}
+	WithLastSeen(true, time.Minute)(&r)
+	assert.True(t, r.lastSeen)
+	assert.Equal(t, time.Minute, r.lastSeenInterval)
+}
+
+func Test_WithTTLFromMeta(t *testing.T) {
+	r := RedisStore{}
+	WithTTLFromMeta("remember_me")(&r)
+	assert.Equal(t, "remember_me", r.ttlMetaKey)
+}
+
+func Test_validate(t *testing.T) {
+	valid := sessionup.Session{
+		ID:        "id123",
+		UserKey:   "u123",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	assert.NoError(t, validate(valid))
+
+	s := valid
+	s.ID = ""
+	err := validate(s)
+	assert.True(t, errors.Is(err, ErrInvalidSession))
+	assert.Contains(t, err.Error(), "empty ID")
+
+	s = valid
+	s.UserKey = ""
+	err = validate(s)
+	assert.True(t, errors.Is(err, ErrInvalidSession))
+	assert.Contains(t, err.Error(), "empty user key")
+
+	s = valid
+	s.ExpiresAt = time.Time{}
+	err = validate(s)
+	assert.True(t, errors.Is(err, ErrInvalidSession))
+	assert.Contains(t, err.Error(), "zero expiration time")
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	err = r.Create(context.Background(), s)
+	assert.True(t, errors.Is(err, ErrInvalidSession))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_applyTTL(t *testing.T) {
+	now := time.Now()
+	s := sessionup.Session{
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+		Meta:      map[string]string{"remember_me": "2160h"},
+	}
+
+	r := RedisStore{}
+	assert.Equal(t, s, r.applyTTL(s))
+
+	r.ttlMetaKey = "remember_me"
+	assert.Equal(t, now.Add(time.Hour*2160), r.applyTTL(s).ExpiresAt)
+
+	s.Meta["remember_me"] = "yes"
+	assert.Equal(t, s, r.applyTTL(s))
+
+	s.Meta["remember_me"] = "-1h"
+	assert.Equal(t, s, r.applyTTL(s))
+
+	s.Meta = nil
+	assert.Equal(t, s, r.applyTTL(s))
+
+	s.CreatedAt = time.Time{}
+	s.Meta = map[string]string{"remember_me": "1m"}
+	assert.WithinDuration(t, time.Now().Add(time.Minute), r.applyTTL(s).ExpiresAt, time.Second)
+}
+
+func Test_WithTxRetries(t *testing.T) {
+	r := RedisStore{}
+	WithTxRetries(2, time.Minute)(&r)
+	assert.Equal(t, 2, r.txAttempts)
+	assert.Equal(t, time.Minute, r.txBackoff)
 }
 
 func Test_RedisStore_Create(t *testing.T) {
@@ -38,10 +230,66 @@ func Test_RedisStore_Create(t *testing.T) {
 	uKey := prefix + ":user:" + inp.UserKey
 	sKey := prefix + ":session:" + inp.ID
 
+	sExpNano := inp.ExpiresAt.UnixNano()
+	sExpMilli := sExpNano / int64(time.Millisecond)
+
+	data, err := json.Marshal(toRecord(inp))
+	require.NoError(t, err)
+
+	script := func(conn *redigomock.Conn) *redigomock.Cmd {
+		return conn.Script(
+			[]byte(createScriptSrc), 2,
+			sKey, uKey,
+			redigomock.NewAnyInt(), redigomock.NewAnyInt(),
+			sExpNano, sExpMilli,
+			0, 0,
+			"HGETALL", "HMSET",
+			"created_at", inp.CreatedAt.Format(time.RFC3339Nano),
+			"expires_at", inp.ExpiresAt.Format(time.RFC3339Nano),
+			"id", inp.ID,
+			"user_key", inp.UserKey,
+			"ip", inp.IP.String(),
+			"agent_os", inp.Agent.OS,
+			"agent_browser", inp.Agent.Browser,
+			"meta", "test=1",
+			"schema_version", "1",
+		)
+	}
+
+	tx := func(conn *redigomock.Conn) {
+		conn.Command("WATCH", sKey)
+		conn.Command("WATCH", uKey)
+		conn.Command("EXISTS", sKey).Expect(int64(0))
+		conn.Command("PTTL", uKey).Expect(int64(20))
+		conn.GenericCommand("MULTI")
+		conn.Command("ZREMRANGEBYSCORE", uKey, "-inf", redigomock.NewAnyInt())
+		conn.Command("ZADD", uKey, sExpNano, sKey)
+		conn.Command("PEXPIREAT", uKey, sExpMilli)
+		conn.Command(
+			"HMSET", sKey,
+			"created_at", inp.CreatedAt.Format(time.RFC3339Nano),
+			"expires_at", inp.ExpiresAt.Format(time.RFC3339Nano),
+			"id", inp.ID,
+			"user_key", inp.UserKey,
+			"ip", inp.IP.String(),
+			"agent_os", inp.Agent.OS,
+			"agent_browser", inp.Agent.Browser,
+			"meta", "test=1",
+			"schema_version", "1",
+		)
+		conn.Command("PEXPIREAT", sKey, sExpMilli)
+		conn.GenericCommand("EXEC").ExpectSlice()
+	}
+
 	cc := map[string]struct {
-		Cancelled bool
-		Conn      func() (*redigomock.Conn, func(*testing.T))
-		Err       error
+		Cancelled   bool
+		NoScripts   bool
+		JSON        bool
+		MaxSessions int
+		Reject      bool
+		Replicas    int
+		Conn        func() (*redigomock.Conn, func(*testing.T))
+		Err         error
 	}{
 		"Cancelled context": {
 			Cancelled: true,
@@ -54,6 +302,232 @@ func Test_RedisStore_Create(t *testing.T) {
 			},
 			Err: assert.AnError,
 		},
+		"Error returned by script": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				script(conn).ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Duplicate ID": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				script(conn).Expect(int64(0))
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: sessionup.ErrDuplicateID,
+		},
+		"Session limit reached": {
+			JSON:        true,
+			MaxSessions: 2,
+			Reject:      true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Script(
+					[]byte(createScriptSrc), 2,
+					sKey, uKey,
+					redigomock.NewAnyInt(), redigomock.NewAnyInt(),
+					sExpNano, sExpMilli,
+					2, 1,
+					"GET", "SET", data,
+				).Expect(int64(-1))
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: ErrMaxSessions,
+		},
+		"Write concern not met": {
+			Replicas: 2,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				script(conn).Expect(int64(1))
+				conn.Command("WAIT", 2, int64(0)).Expect(int64(1))
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: ErrWriteConcern,
+		},
+		"Successful execution with write concern": {
+			Replicas: 2,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				script(conn).Expect(int64(1))
+				conn.Command("WAIT", 2, int64(0)).Expect(int64(2))
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful execution with transaction fallback": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				script(conn).ExpectError(redis.Error("ERR unknown command 'EVALSHA'"))
+				tx(conn)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful execution with scripts disabled": {
+			NoScripts: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				tx(conn)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful execution in JSON mode": {
+			JSON: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Script(
+					[]byte(createScriptSrc), 2,
+					sKey, uKey,
+					redigomock.NewAnyInt(), redigomock.NewAnyInt(),
+					sExpNano, sExpMilli,
+					0, 0,
+					"GET", "SET", data,
+				).Expect(int64(1))
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful execution": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				script(conn).Expect(int64(1))
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+					Wait:      true,
+					MaxActive: 10,
+				},
+				prefix:          prefix,
+				asJSON:          c.JSON,
+				maxUserSessions: c.MaxSessions,
+				replicas:        c.Replicas,
+			}
+
+			if c.Reject {
+				r.sessionLimit = Reject
+			}
+
+			if c.NoScripts {
+				r.disableScripts()
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if c.Cancelled {
+				cancel()
+			}
+
+			err := r.Create(ctx, inp)
+			check(t)
+
+			if c.Err != nil {
+				if c.Err == assert.AnError {
+					assert.Error(t, err)
+					return
+				}
+
+				assert.True(t, errors.Is(err, c.Err))
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_RedisStore_createTx(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24),
+		CreatedAt: time.Now().UTC(),
+		IP:        net.ParseIP("127.0.0.1"),
+		Meta:      map[string]string{"test": "1"},
+	}
+	inp.Agent.OS = "gnu/linux"
+	inp.Agent.Browser = "firefox"
+
+	uKey := prefix + ":user:" + inp.UserKey
+	sKey := prefix + ":session:" + inp.ID
+
+	hmset := []interface{}{
+		sKey,
+		"created_at", inp.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at", inp.ExpiresAt.Format(time.RFC3339Nano),
+		"id", inp.ID,
+		"user_key", inp.UserKey,
+		"ip", inp.IP.String(),
+		"agent_os", inp.Agent.OS,
+		"agent_browser", inp.Agent.Browser,
+		"meta", "test=1",
+		"schema_version", "1",
+	}
+
+	evicted := map[string]string{
+		"created_at": inp.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at": inp.ExpiresAt.Format(time.RFC3339Nano),
+		"id":         "s1",
+		"user_key":   inp.UserKey,
+	}
+
+	cc := map[string]struct {
+		MaxSessions int
+		Reject      bool
+		Conn        func() (*redigomock.Conn, func(*testing.T))
+		Evicted     int
+		Err         error
+	}{
 		"Error returned during session key watching": {
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
@@ -221,7 +695,8 @@ func Test_RedisStore_Create(t *testing.T) {
 					"ip", inp.IP.String(),
 					"agent_os", inp.Agent.OS,
 					"agent_browser", inp.Agent.Browser,
-					"meta", "test:1;",
+					"meta", "test=1",
+					"schema_version", "1",
 				).ExpectError(assert.AnError)
 				conn.GenericCommand("DISCARD")
 
@@ -252,7 +727,8 @@ func Test_RedisStore_Create(t *testing.T) {
 					"ip", inp.IP.String(),
 					"agent_os", inp.Agent.OS,
 					"agent_browser", inp.Agent.Browser,
-					"meta", "test:1;",
+					"meta", "test=1",
+					"schema_version", "1",
 				)
 				conn.Command("PEXPIREAT", sKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond)).ExpectError(assert.AnError)
 				conn.GenericCommand("DISCARD")
@@ -284,7 +760,8 @@ func Test_RedisStore_Create(t *testing.T) {
 					"ip", inp.IP.String(),
 					"agent_os", inp.Agent.OS,
 					"agent_browser", inp.Agent.Browser,
-					"meta", "test:1;",
+					"meta", "test=1",
+					"schema_version", "1",
 				)
 				conn.Command("PEXPIREAT", sKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
 				conn.GenericCommand("EXEC").ExpectError(assert.AnError)
@@ -296,17 +773,17 @@ func Test_RedisStore_Create(t *testing.T) {
 			},
 			Err: assert.AnError,
 		},
-		"Successful execution with previous user key expiration": {
+		"Transaction conflict": {
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
 				conn.Command("WATCH", sKey)
 				conn.Command("WATCH", uKey)
 				conn.Command("EXISTS", sKey).Expect(int64(0))
-				conn.Command("PTTL", uKey).Expect(time.Now().UTC().Add(time.Hour * 72).UnixNano())
+				conn.Command("PTTL", uKey).Expect(int64(20))
 				conn.GenericCommand("MULTI")
 				conn.Command("ZREMRANGEBYSCORE", uKey, "-inf", redigomock.NewAnyInt())
 				conn.Command("ZADD", uKey, inp.ExpiresAt.UnixNano(), sKey)
-				conn.Command("PEXPIREAT", uKey, redigomock.NewAnyInt())
+				conn.Command("PEXPIREAT", uKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
 				conn.Command(
 					"HMSET", sKey,
 					"created_at", inp.CreatedAt.Format(time.RFC3339Nano),
@@ -316,7 +793,8 @@ func Test_RedisStore_Create(t *testing.T) {
 					"ip", inp.IP.String(),
 					"agent_os", inp.Agent.OS,
 					"agent_browser", inp.Agent.Browser,
-					"meta", "test:1;",
+					"meta", "test=1",
+					"schema_version", "1",
 				)
 				conn.Command("PEXPIREAT", sKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
 				conn.GenericCommand("EXEC")
@@ -326,47 +804,239 @@ func Test_RedisStore_Create(t *testing.T) {
 					assert.NoError(t, err)
 				}
 			},
+			Err: ErrTxConflict,
 		},
-		"Successful execution": {
+		"Error returned during active user session count": {
+			MaxSessions: 2,
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
 				conn.Command("WATCH", sKey)
 				conn.Command("WATCH", uKey)
 				conn.Command("EXISTS", sKey).Expect(int64(0))
 				conn.Command("PTTL", uKey).Expect(int64(20))
-				conn.GenericCommand("MULTI")
-				conn.Command("ZREMRANGEBYSCORE", uKey, "-inf", redigomock.NewAnyInt())
-				conn.Command("ZADD", uKey, inp.ExpiresAt.UnixNano(), sKey)
-				conn.Command("PEXPIREAT", uKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
-				conn.Command(
-					"HMSET", sKey,
-					"created_at", inp.CreatedAt.Format(time.RFC3339Nano),
-					"expires_at", inp.ExpiresAt.Format(time.RFC3339Nano),
-					"id", inp.ID,
-					"user_key", inp.UserKey,
-					"ip", inp.IP.String(),
-					"agent_os", inp.Agent.OS,
-					"agent_browser", inp.Agent.Browser,
-					"meta", "test:1;",
-				)
-				conn.Command("PEXPIREAT", sKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
-				conn.GenericCommand("EXEC")
+				conn.Command("ZCOUNT", uKey, redigomock.NewAnyData(), "+inf").ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
 				}
 			},
+			Err: assert.AnError,
 		},
-	}
-
-	for cn, c := range cc {
-		c := c
-
-		t.Run(cn, func(t *testing.T) {
-			t.Parallel()
+		"Session limit reached": {
+			MaxSessions: 2,
+			Reject:      true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("WATCH", uKey)
+				conn.Command("EXISTS", sKey).Expect(int64(0))
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.Command("ZCOUNT", uKey, redigomock.NewAnyData(), "+inf").Expect(int64(2))
+				conn.GenericCommand("UNWATCH")
 
-			conn, check := c.Conn()
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: ErrMaxSessions,
+		},
+		"Error returned during evicted session fetch": {
+			MaxSessions: 2,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("WATCH", uKey)
+				conn.Command("EXISTS", sKey).Expect(int64(0))
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.Command("ZCOUNT", uKey, redigomock.NewAnyData(), "+inf").Expect(int64(3))
+				conn.Command("ZRANGEBYSCORE", uKey, redigomock.NewAnyData(), "+inf", "LIMIT", 0, 2).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Error returned during evicted session deletion": {
+			MaxSessions: 2,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("WATCH", uKey)
+				conn.Command("EXISTS", sKey).Expect(int64(0))
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.Command("ZCOUNT", uKey, redigomock.NewAnyData(), "+inf").Expect(int64(2))
+				conn.Command("ZRANGEBYSCORE", uKey, redigomock.NewAnyData(), "+inf", "LIMIT", 0, 1).ExpectSlice("s1")
+				conn.Command("HGETALL", "s1").ExpectMap(evicted)
+				conn.GenericCommand("MULTI")
+				conn.Command("ZREMRANGEBYSCORE", uKey, "-inf", redigomock.NewAnyInt())
+				conn.Command("DEL", "s1").ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Error returned during evicted session removal from user set": {
+			MaxSessions: 2,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("WATCH", uKey)
+				conn.Command("EXISTS", sKey).Expect(int64(0))
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.Command("ZCOUNT", uKey, redigomock.NewAnyData(), "+inf").Expect(int64(2))
+				conn.Command("ZRANGEBYSCORE", uKey, redigomock.NewAnyData(), "+inf", "LIMIT", 0, 1).ExpectSlice("s1")
+				conn.Command("HGETALL", "s1").ExpectMap(evicted)
+				conn.GenericCommand("MULTI")
+				conn.Command("ZREMRANGEBYSCORE", uKey, "-inf", redigomock.NewAnyInt())
+				conn.Command("DEL", "s1")
+				conn.Command("ZREM", uKey, "s1").ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Successful execution with session eviction": {
+			MaxSessions: 2,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("WATCH", uKey)
+				conn.Command("EXISTS", sKey).Expect(int64(0))
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.Command("ZCOUNT", uKey, redigomock.NewAnyData(), "+inf").Expect(int64(3))
+				conn.Command("ZRANGEBYSCORE", uKey, redigomock.NewAnyData(), "+inf", "LIMIT", 0, 2).ExpectSlice("s1", "s2")
+				conn.Command("HGETALL", "s1").ExpectMap(evicted)
+				conn.Command("HGETALL", "s2").ExpectMap(map[string]string{})
+				conn.GenericCommand("MULTI")
+				conn.Command("ZREMRANGEBYSCORE", uKey, "-inf", redigomock.NewAnyInt())
+				conn.Command("DEL", "s1")
+				conn.Command("ZREM", uKey, "s1")
+				conn.Command("DEL", "s2")
+				conn.Command("ZREM", uKey, "s2")
+				conn.Command("ZADD", uKey, inp.ExpiresAt.UnixNano(), sKey)
+				conn.Command("PEXPIREAT", uKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
+				conn.Command("HMSET", hmset...)
+				conn.Command("PEXPIREAT", sKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Evicted: 1,
+		},
+		"Successful execution under session limit": {
+			MaxSessions: 2,
+			Reject:      true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("WATCH", uKey)
+				conn.Command("EXISTS", sKey).Expect(int64(0))
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.Command("ZCOUNT", uKey, redigomock.NewAnyData(), "+inf").Expect(int64(1))
+				conn.GenericCommand("MULTI")
+				conn.Command("ZREMRANGEBYSCORE", uKey, "-inf", redigomock.NewAnyInt())
+				conn.Command("ZADD", uKey, inp.ExpiresAt.UnixNano(), sKey)
+				conn.Command("PEXPIREAT", uKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
+				conn.Command("HMSET", hmset...)
+				conn.Command("PEXPIREAT", sKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful execution with previous user key expiration": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("WATCH", uKey)
+				conn.Command("EXISTS", sKey).Expect(int64(0))
+				conn.Command("PTTL", uKey).Expect(time.Now().UTC().Add(time.Hour * 72).UnixNano())
+				conn.GenericCommand("MULTI")
+				conn.Command("ZREMRANGEBYSCORE", uKey, "-inf", redigomock.NewAnyInt())
+				conn.Command("ZADD", uKey, inp.ExpiresAt.UnixNano(), sKey)
+				conn.Command("PEXPIREAT", uKey, redigomock.NewAnyInt())
+				conn.Command(
+					"HMSET", sKey,
+					"created_at", inp.CreatedAt.Format(time.RFC3339Nano),
+					"expires_at", inp.ExpiresAt.Format(time.RFC3339Nano),
+					"id", inp.ID,
+					"user_key", inp.UserKey,
+					"ip", inp.IP.String(),
+					"agent_os", inp.Agent.OS,
+					"agent_browser", inp.Agent.Browser,
+					"meta", "test=1",
+					"schema_version", "1",
+				)
+				conn.Command("PEXPIREAT", sKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful execution": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("WATCH", uKey)
+				conn.Command("EXISTS", sKey).Expect(int64(0))
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.GenericCommand("MULTI")
+				conn.Command("ZREMRANGEBYSCORE", uKey, "-inf", redigomock.NewAnyInt())
+				conn.Command("ZADD", uKey, inp.ExpiresAt.UnixNano(), sKey)
+				conn.Command("PEXPIREAT", uKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
+				conn.Command(
+					"HMSET", sKey,
+					"created_at", inp.CreatedAt.Format(time.RFC3339Nano),
+					"expires_at", inp.ExpiresAt.Format(time.RFC3339Nano),
+					"id", inp.ID,
+					"user_key", inp.UserKey,
+					"ip", inp.IP.String(),
+					"agent_os", inp.Agent.OS,
+					"agent_browser", inp.Agent.Browser,
+					"meta", "test=1",
+					"schema_version", "1",
+				)
+				conn.Command("PEXPIREAT", sKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
 
 			r := RedisStore{
 				pool: &redis.Pool{
@@ -376,17 +1046,17 @@ func Test_RedisStore_Create(t *testing.T) {
 					Wait:      true,
 					MaxActive: 10,
 				},
-				prefix: prefix,
+				prefix:          prefix,
+				maxUserSessions: c.MaxSessions,
 			}
 
-			ctx, cancel := context.WithCancel(context.Background())
-			defer cancel()
-
-			if c.Cancelled {
-				cancel()
+			if c.Reject {
+				r.sessionLimit = Reject
 			}
 
-			err := r.Create(ctx, inp)
+			rc := r.pool.(*redis.Pool).Get()
+			ss, err := r.createTx(rc, DetailedSession{Session: inp})
+			rc.Close()
 			check(t)
 
 			if c.Err != nil {
@@ -395,11 +1065,12 @@ func Test_RedisStore_Create(t *testing.T) {
 					return
 				}
 
-				assert.Equal(t, c.Err, err)
+				assert.True(t, errors.Is(err, c.Err))
 				return
 			}
 
 			assert.NoError(t, err)
+			assert.Len(t, ss, c.Evicted)
 		})
 	}
 }
@@ -417,14 +1088,131 @@ func Test_RedisStore_FetchByID(t *testing.T) {
 	inp.Agent.Browser = "firefox"
 
 	sKey := prefix + ":session:" + inp.ID
+	pKey := prefix + ":payload:" + inp.ID
+	uKey := prefix + ":user:" + inp.UserKey
+
+	fields := map[string]string{
+		"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
+		"id":            inp.ID,
+		"user_key":      inp.UserKey,
+		"ip":            inp.IP.String(),
+		"agent_os":      inp.Agent.OS,
+		"agent_browser": inp.Agent.Browser,
+		"meta":          "=val&test=1",
+	}
+
+	data, err := json.Marshal(toRecord(inp))
+	require.NoError(t, err)
 
 	cc := map[string]struct {
-		Cancelled bool
-		Conn      func() (*redigomock.Conn, func(*testing.T))
-		Result    bool
-		Found     bool
-		Err       bool
+		Cancelled   bool
+		JSON        bool
+		IdleTimeout time.Duration
+		LastSeen    bool
+		Conn        func() (*redigomock.Conn, func(*testing.T))
+		Result      bool
+		Found       bool
+		Err         bool
 	}{
+		"Error returned during idle timeout refresh": {
+			IdleTimeout: time.Hour,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.Command("WATCH", uKey).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Successful fetch with last seen tracking": {
+			LastSeen: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI")
+				conn.Command("HSET", sKey, "last_seen_at", redigomock.NewAnyData())
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Result: true,
+			Found:  true,
+		},
+		"Not found with idle timeout": {
+			IdleTimeout: time.Hour,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful fetch with idle timeout": {
+			IdleTimeout: time.Hour,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.Command("WATCH", uKey)
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.GenericCommand("MULTI")
+				conn.Command("ZADD", uKey, redigomock.NewAnyInt(), sKey)
+				conn.Command("PEXPIREAT", uKey, redigomock.NewAnyInt())
+				conn.GenericCommand("HMSET")
+				conn.Command("PEXPIREAT", sKey, redigomock.NewAnyInt())
+				conn.Command("PEXPIREAT", pKey, redigomock.NewAnyInt())
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Result: true,
+			Found:  true,
+		},
+		"Not found in JSON mode": {
+			JSON: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("GET", sKey).ExpectError(redis.ErrNil)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful fetch in JSON mode": {
+			JSON: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("GET", sKey).Expect(data)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Result: true,
+			Found:  true,
+		},
 		"Cancelled context": {
 			Cancelled: true,
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
@@ -453,7 +1241,7 @@ func Test_RedisStore_FetchByID(t *testing.T) {
 				conn := redigomock.NewConn()
 				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
 					"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
-					"expires_at":    "123",
+					"expires_at":    "invalid",
 					"id":            inp.ID,
 					"user_key":      inp.UserKey,
 					"ip":            inp.IP.String(),
@@ -531,7 +1319,10 @@ func Test_RedisStore_FetchByID(t *testing.T) {
 					Wait:      true,
 					MaxActive: 10,
 				},
-				prefix: prefix,
+				prefix:      prefix,
+				asJSON:      c.JSON,
+				idleTimeout: c.IdleTimeout,
+				lastSeen:    c.LastSeen,
 			}
 
 			ctx, cancel := context.WithCancel(context.Background())
@@ -541,6 +1332,8 @@ func Test_RedisStore_FetchByID(t *testing.T) {
 				cancel()
 			}
 
+			start := time.Now()
+
 			s, ok, err := r.FetchByID(ctx, inp.ID)
 			if c.Err {
 				assert.Error(t, err)
@@ -548,6 +1341,12 @@ func Test_RedisStore_FetchByID(t *testing.T) {
 				assert.NoError(t, err)
 			}
 
+			if c.Result && c.IdleTimeout > 0 {
+				assert.False(t, s.ExpiresAt.Before(start.Add(c.IdleTimeout)))
+				assert.False(t, s.ExpiresAt.After(time.Now().Add(c.IdleTimeout)))
+				s.ExpiresAt = inp.ExpiresAt
+			}
+
 			if c.Result {
 				assert.Equal(t, inp, s)
 			} else {
@@ -560,6 +1359,44 @@ func Test_RedisStore_FetchByID(t *testing.T) {
 	}
 }
 
+func Test_RedisStore_FetchByID_idleConflict(t *testing.T) {
+	sKey := prefix + ":session:id123"
+	pKey := prefix + ":payload:id123"
+	uKey := prefix + ":user:u123"
+	exp := time.Now().UTC().Add(time.Hour).Round(0)
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithIdleTimeout(time.Hour*2), WithTxRetries(2, 0))
+
+	conn.Command("WATCH", sKey)
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": exp.Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+	conn.Command("WATCH", uKey)
+	conn.Command("PTTL", uKey).Expect(int64(20))
+	conn.GenericCommand("MULTI")
+	conn.Command("ZADD", uKey, redigomock.NewAnyInt(), sKey)
+	conn.Command("PEXPIREAT", uKey, redigomock.NewAnyInt())
+	conn.GenericCommand("HMSET")
+	conn.Command("PEXPIREAT", sKey, redigomock.NewAnyInt())
+	conn.Command("PEXPIREAT", pKey, redigomock.NewAnyInt())
+	exec := conn.GenericCommand("EXEC").Expect(nil)
+
+	s, ok, err := r.FetchByID(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "id123", s.ID)
+	assert.True(t, exp.Equal(s.ExpiresAt))
+	assert.Equal(t, 2, conn.Stats(exec))
+}
+
 func Test_RedisStore_FetchByUserKey(t *testing.T) {
 	inp := make([]sessionup.Session, 5)
 
@@ -622,7 +1459,11 @@ func Test_RedisStore_FetchByUserKey(t *testing.T) {
 				sKey := prefix + ":session:" + inp[0].ID
 				conn.Command("HGETALL", sKey).ExpectError(assert.AnError)
 
-				return conn, func(t *testing.T) {
+				for i := 1; i < 5; i++ {
+					conn.Command("HGETALL", prefix+":session:"+inp[i].ID).ExpectSlice()
+				}
+
+				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
 				}
@@ -643,7 +1484,7 @@ func Test_RedisStore_FetchByUserKey(t *testing.T) {
 				sKey := prefix + ":session:" + inp[0].ID
 				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
 					"created_at":    inp[0].CreatedAt.Format(time.RFC3339Nano),
-					"expires_at":    "123",
+					"expires_at":    "invalid",
 					"id":            inp[0].ID,
 					"user_key":      inp[0].UserKey,
 					"ip":            inp[0].IP.String(),
@@ -652,6 +1493,10 @@ func Test_RedisStore_FetchByUserKey(t *testing.T) {
 					"meta":          "test:1;:val;",
 				})
 
+				for i := 1; i < 5; i++ {
+					conn.Command("HGETALL", prefix+":session:"+inp[i].ID).ExpectSlice()
+				}
+
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
@@ -753,37 +1598,90 @@ func Test_RedisStore_FetchByUserKey(t *testing.T) {
 	}
 }
 
-func Test_RedisStore_DeleteByID(t *testing.T) {
+func Test_RedisStore_Exists(t *testing.T) {
+	sKey := prefix + ":session:id123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	conn.Command("EXISTS", sKey).ExpectError(assert.AnError)
+	_, err := r.Exists(context.Background(), "id123")
+	assert.True(t, errors.Is(err, assert.AnError))
+
+	conn.Clear()
+	conn.Command("EXISTS", sKey).Expect(int64(1))
+	ok, err := r.Exists(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	conn.Clear()
+	conn.Command("EXISTS", sKey).Expect(int64(0))
+	ok, err = r.Exists(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	r.ttlJitter = time.Minute
+
+	conn.Clear()
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().Add(-time.Hour).Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(-time.Second).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+	ok, err = r.Exists(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_fetchSeen(t *testing.T) {
 	inp := sessionup.Session{
 		UserKey:   "u123",
 		ID:        "id123",
 		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
 		CreatedAt: time.Now().UTC().Round(0),
 		IP:        net.ParseIP("127.0.0.1"),
-		Meta:      map[string]string{"test": "1", "": "val"},
+		Meta:      map[string]string{"test": "1"},
 	}
 	inp.Agent.OS = "gnu/linux"
 	inp.Agent.Browser = "firefox"
 
 	sKey := prefix + ":session:" + inp.ID
-	uKey := prefix + ":user:" + inp.UserKey
+
+	fields := func(seen time.Time) map[string]string {
+		ff := map[string]string{
+			"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
+			"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
+			"id":            inp.ID,
+			"user_key":      inp.UserKey,
+			"ip":            inp.IP.String(),
+			"agent_os":      inp.Agent.OS,
+			"agent_browser": inp.Agent.Browser,
+			"meta":          "test=1",
+		}
+
+		if !seen.IsZero() {
+			ff["last_seen_at"] = seen.Format(time.RFC3339Nano)
+		}
+
+		return ff
+	}
+
+	data, err := json.Marshal(toRecord(inp))
+	require.NoError(t, err)
 
 	cc := map[string]struct {
-		Cancelled bool
-		Conn      func() (*redigomock.Conn, func(*testing.T))
-		Err       bool
+		JSON     bool
+		Interval time.Duration
+		Conn     func() (*redigomock.Conn, func(*testing.T))
+		Found    bool
+		Err      error
 	}{
-		"Cancelled context": {
-			Cancelled: true,
-			Conn: func() (*redigomock.Conn, func(*testing.T)) {
-				conn := redigomock.NewConn()
-				return conn, func(t *testing.T) {
-					err := conn.ExpectationsWereMet()
-					assert.NoError(t, err)
-				}
-			},
-			Err: true,
-		},
 		"Error returned during session key watching": {
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
@@ -795,166 +1693,77 @@ func Test_RedisStore_DeleteByID(t *testing.T) {
 					assert.NoError(t, err)
 				}
 			},
-			Err: true,
+			Err: assert.AnError,
 		},
 		"Error returned during session fetch": {
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
 				conn.Command("WATCH", sKey)
 				conn.Command("HGETALL", sKey).ExpectError(assert.AnError)
-				conn.GenericCommand("UNWATCH")
-
-				return conn, func(t *testing.T) {
-					err := conn.ExpectationsWereMet()
-					assert.NoError(t, err)
-				}
-			},
-			Err: true,
-		},
-		"Error returned during parsing": {
-			Conn: func() (*redigomock.Conn, func(*testing.T)) {
-				conn := redigomock.NewConn()
-				conn.Command("WATCH", sKey)
-				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
-					"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
-					"expires_at":    "123",
-					"id":            inp.ID,
-					"user_key":      inp.UserKey,
-					"ip":            inp.IP.String(),
-					"agent_os":      inp.Agent.OS,
-					"agent_browser": inp.Agent.Browser,
-					"meta":          "test:1;:val;",
-				})
-				conn.GenericCommand("UNWATCH")
+				unwatch := conn.GenericCommand("UNWATCH")
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
+					assert.Equal(t, 1, conn.Stats(unwatch))
 				}
 			},
-			Err: true,
+			Err: assert.AnError,
 		},
-		"Error returned during user key watching": {
+		"Not found": {
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
 				conn.Command("WATCH", sKey)
-				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
-					"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
-					"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
-					"id":            inp.ID,
-					"user_key":      inp.UserKey,
-					"ip":            inp.IP.String(),
-					"agent_os":      inp.Agent.OS,
-					"agent_browser": inp.Agent.Browser,
-					"meta":          "test:1;:val;",
-				})
-				conn.Command("WATCH", uKey).ExpectError(assert.AnError)
-				conn.GenericCommand("UNWATCH")
+				conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+				unwatch := conn.GenericCommand("UNWATCH")
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
+					assert.Equal(t, 1, conn.Stats(unwatch))
 				}
 			},
-			Err: true,
 		},
-		"Error returned during user session set fetch": {
+		"Successful fetch within interval": {
+			Interval: time.Minute,
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
 				conn.Command("WATCH", sKey)
-				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
-					"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
-					"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
-					"id":            inp.ID,
-					"user_key":      inp.UserKey,
-					"ip":            inp.IP.String(),
-					"agent_os":      inp.Agent.OS,
-					"agent_browser": inp.Agent.Browser,
-					"meta":          "test:1;:val;",
-				})
-				conn.Command("WATCH", uKey)
-				conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectError(assert.AnError)
-				conn.GenericCommand("UNWATCH")
+				conn.Command("HGETALL", sKey).ExpectMap(fields(time.Now().Add(-time.Second)))
+				unwatch := conn.GenericCommand("UNWATCH")
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
+					assert.Equal(t, 1, conn.Stats(unwatch))
 				}
 			},
-			Err: true,
+			Found: true,
 		},
 		"Error returned during transaction creation": {
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
 				conn.Command("WATCH", sKey)
-				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
-					"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
-					"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
-					"id":            inp.ID,
-					"user_key":      inp.UserKey,
-					"ip":            inp.IP.String(),
-					"agent_os":      inp.Agent.OS,
-					"agent_browser": inp.Agent.Browser,
-					"meta":          "test:1;:val;",
-				})
-				conn.Command("WATCH", uKey)
-				conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectSlice("123")
+				conn.Command("HGETALL", sKey).ExpectMap(fields(time.Time{}))
 				conn.GenericCommand("MULTI").ExpectError(assert.AnError)
 				conn.GenericCommand("DISCARD")
+				unwatch := conn.GenericCommand("UNWATCH")
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
+					assert.Equal(t, 1, conn.Stats(unwatch))
 				}
 			},
-			Err: true,
-		},
-		"Error returned during session key deletion from user set": {
-			Conn: func() (*redigomock.Conn, func(*testing.T)) {
-				conn := redigomock.NewConn()
-				conn.Command("WATCH", sKey)
-				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
-					"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
-					"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
-					"id":            inp.ID,
-					"user_key":      inp.UserKey,
-					"ip":            inp.IP.String(),
-					"agent_os":      inp.Agent.OS,
-					"agent_browser": inp.Agent.Browser,
-					"meta":          "test:1;:val;",
-				})
-				conn.Command("WATCH", uKey)
-				conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectSlice("123")
-				conn.GenericCommand("MULTI")
-				conn.Command("ZREM", uKey, sKey).ExpectError(assert.AnError)
-				conn.GenericCommand("DISCARD")
-
-				return conn, func(t *testing.T) {
-					err := conn.ExpectationsWereMet()
-					assert.NoError(t, err)
-				}
-			},
-			Err: true,
+			Err: assert.AnError,
 		},
-		"Error returned during user key deletion": {
+		"Error returned during last seen time update": {
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
 				conn.Command("WATCH", sKey)
-				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
-					"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
-					"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
-					"id":            inp.ID,
-					"user_key":      inp.UserKey,
-					"ip":            inp.IP.String(),
-					"agent_os":      inp.Agent.OS,
-					"agent_browser": inp.Agent.Browser,
-					"meta":          "test:1;:val;",
-				})
-				conn.Command("WATCH", uKey)
-				conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectSlice(sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields(time.Time{}))
 				conn.GenericCommand("MULTI")
-				conn.Command("ZREM", uKey, sKey)
-				conn.Command("DEL", uKey).ExpectError(assert.AnError)
+				conn.Command("HSET", sKey, "last_seen_at", redigomock.NewAnyData()).ExpectError(assert.AnError)
 				conn.GenericCommand("DISCARD")
 
 				return conn, func(t *testing.T) {
@@ -962,27 +1771,17 @@ func Test_RedisStore_DeleteByID(t *testing.T) {
 					assert.NoError(t, err)
 				}
 			},
-			Err: true,
+			Err: assert.AnError,
 		},
-		"Error returned during session key deletion": {
+		"Error returned during session key expiration update in JSON mode": {
+			JSON: true,
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
 				conn.Command("WATCH", sKey)
-				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
-					"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
-					"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
-					"id":            inp.ID,
-					"user_key":      inp.UserKey,
-					"ip":            inp.IP.String(),
-					"agent_os":      inp.Agent.OS,
-					"agent_browser": inp.Agent.Browser,
-					"meta":          "test:1;:val;",
-				})
-				conn.Command("WATCH", uKey)
-				conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectSlice("111", "222")
+				conn.Command("GET", sKey).Expect(data)
 				conn.GenericCommand("MULTI")
-				conn.Command("ZREM", uKey, sKey)
-				conn.Command("DEL", sKey).ExpectError(assert.AnError)
+				conn.Command("SET", sKey, redigomock.NewAnyData())
+				conn.Command("PEXPIREAT", sKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond)).ExpectError(assert.AnError)
 				conn.GenericCommand("DISCARD")
 
 				return conn, func(t *testing.T) {
@@ -990,27 +1789,15 @@ func Test_RedisStore_DeleteByID(t *testing.T) {
 					assert.NoError(t, err)
 				}
 			},
-			Err: true,
+			Err: assert.AnError,
 		},
 		"Error returned during transaction exec": {
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
 				conn.Command("WATCH", sKey)
-				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
-					"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
-					"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
-					"id":            inp.ID,
-					"user_key":      inp.UserKey,
-					"ip":            inp.IP.String(),
-					"agent_os":      inp.Agent.OS,
-					"agent_browser": inp.Agent.Browser,
-					"meta":          "test:1;:val;",
-				})
-				conn.Command("WATCH", uKey)
-				conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectSlice("111", "222")
+				conn.Command("HGETALL", sKey).ExpectMap(fields(time.Time{}))
 				conn.GenericCommand("MULTI")
-				conn.Command("ZREM", uKey, sKey)
-				conn.Command("DEL", sKey)
+				conn.Command("HSET", sKey, "last_seen_at", redigomock.NewAnyData())
 				conn.GenericCommand("EXEC").ExpectError(assert.AnError)
 
 				return conn, func(t *testing.T) {
@@ -1018,54 +1805,15 @@ func Test_RedisStore_DeleteByID(t *testing.T) {
 					assert.NoError(t, err)
 				}
 			},
-			Err: true,
-		},
-		"Not found": {
-			Conn: func() (*redigomock.Conn, func(*testing.T)) {
-				conn := redigomock.NewConn()
-				conn.Command("WATCH", sKey)
-				conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
-				conn.GenericCommand("UNWATCH")
-
-				return conn, func(t *testing.T) {
-					err := conn.ExpectationsWereMet()
-					assert.NoError(t, err)
-				}
-			},
-		},
-		"Empty session map": {
-			Conn: func() (*redigomock.Conn, func(*testing.T)) {
-				conn := redigomock.NewConn()
-				conn.Command("WATCH", sKey)
-				conn.Command("HGETALL", sKey).ExpectSlice()
-				conn.GenericCommand("UNWATCH")
-
-				return conn, func(t *testing.T) {
-					err := conn.ExpectationsWereMet()
-					assert.NoError(t, err)
-				}
-			},
+			Err: assert.AnError,
 		},
-		"Successful deletion with empty user session set": {
+		"Successful fetch with transaction conflict": {
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
 				conn.Command("WATCH", sKey)
-				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
-					"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
-					"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
-					"id":            inp.ID,
-					"user_key":      inp.UserKey,
-					"ip":            inp.IP.String(),
-					"agent_os":      inp.Agent.OS,
-					"agent_browser": inp.Agent.Browser,
-					"meta":          "test:1;:val;",
-				})
-				conn.Command("WATCH", uKey)
-				conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectSlice(sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields(time.Time{}))
 				conn.GenericCommand("MULTI")
-				conn.Command("ZREM", uKey, sKey)
-				conn.Command("DEL", uKey)
-				conn.Command("DEL", sKey)
+				conn.Command("HSET", sKey, "last_seen_at", redigomock.NewAnyData())
 				conn.GenericCommand("EXEC")
 
 				return conn, func(t *testing.T) {
@@ -1073,60 +1821,42 @@ func Test_RedisStore_DeleteByID(t *testing.T) {
 					assert.NoError(t, err)
 				}
 			},
+			Found: true,
 		},
-		"Successful deletion with different ID in user session set": {
+		"Successful fetch in JSON mode": {
+			JSON: true,
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
 				conn.Command("WATCH", sKey)
-				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
-					"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
-					"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
-					"id":            inp.ID,
-					"user_key":      inp.UserKey,
-					"ip":            inp.IP.String(),
-					"agent_os":      inp.Agent.OS,
-					"agent_browser": inp.Agent.Browser,
-					"meta":          "test:1;:val;",
-				})
-				conn.Command("WATCH", uKey)
-				conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectSlice("111")
+				conn.Command("GET", sKey).Expect(data)
 				conn.GenericCommand("MULTI")
-				conn.Command("ZREM", uKey, sKey)
-				conn.Command("DEL", sKey)
-				conn.GenericCommand("EXEC")
+				conn.Command("SET", sKey, redigomock.NewAnyData())
+				conn.Command("PEXPIREAT", sKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
+				conn.GenericCommand("EXEC").ExpectSlice()
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
 				}
 			},
+			Found: true,
 		},
-		"Successful deletion": {
+		"Successful fetch": {
+			Interval: time.Minute,
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
 				conn.Command("WATCH", sKey)
-				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
-					"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
-					"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
-					"id":            inp.ID,
-					"user_key":      inp.UserKey,
-					"ip":            inp.IP.String(),
-					"agent_os":      inp.Agent.OS,
-					"agent_browser": inp.Agent.Browser,
-					"meta":          "test:1;:val;",
-				})
-				conn.Command("WATCH", uKey)
-				conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectSlice("111", "222")
+				conn.Command("HGETALL", sKey).ExpectMap(fields(time.Now().Add(-time.Hour)))
 				conn.GenericCommand("MULTI")
-				conn.Command("ZREM", uKey, sKey)
-				conn.Command("DEL", sKey)
-				conn.GenericCommand("EXEC")
+				conn.Command("HSET", sKey, "last_seen_at", redigomock.NewAnyData())
+				conn.GenericCommand("EXEC").ExpectSlice()
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
 				}
 			},
+			Found: true,
 		},
 	}
 
@@ -1146,42 +1876,56 @@ func Test_RedisStore_DeleteByID(t *testing.T) {
 					Wait:      true,
 					MaxActive: 10,
 				},
-				prefix: prefix,
-			}
-
-			ctx, cancel := context.WithCancel(context.Background())
-			defer cancel()
-
-			if c.Cancelled {
-				cancel()
+				prefix:           prefix,
+				asJSON:           c.JSON,
+				lastSeen:         true,
+				lastSeenInterval: c.Interval,
 			}
 
-			err := r.DeleteByID(ctx, inp.ID)
+			rc := r.pool.(*redis.Pool).Get()
+			s, ok, err := r.fetchSeen(rc, inp.ID)
+			rc.Close()
 			check(t)
 
-			if c.Err {
-				assert.Error(t, err)
+			if c.Err != nil {
+				assert.True(t, errors.Is(err, c.Err))
+				assert.Zero(t, s)
+				assert.False(t, ok)
 				return
 			}
 
 			assert.NoError(t, err)
-		})
-	}
-}
+			assert.Equal(t, c.Found, ok)
 
-func Test_RedisStore_DeleteByUserKey(t *testing.T) {
-	const (
-		inpKey     = "u123"
-		inpFullKey = prefix + ":user:" + inpKey
-	)
+			if c.Found {
+				assert.Equal(t, inp, s)
+			} else {
+				assert.Zero(t, s)
+			}
+		})
+	}
+}
 
-	inpExceptIDs := []string{"id222", "id333"}
+func Test_RedisStore_FetchDetailedByUserKey(t *testing.T) {
+	inp := DetailedSession{
+		Session: sessionup.Session{
+			UserKey:   "u123",
+			ID:        "id123",
+			ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+			CreatedAt: time.Now().UTC().Round(0),
+			IP:        net.ParseIP("127.0.0.1"),
+		},
+		LastSeenAt: time.Now().UTC().Round(0),
+	}
+
+	uKey := prefix + ":user:" + inp.UserKey
+	sKey := prefix + ":session:" + inp.ID
 
 	cc := map[string]struct {
-		Cancelled      bool
-		Conn           func() (*redigomock.Conn, func(*testing.T))
-		WithExceptions bool
-		Err            bool
+		Cancelled bool
+		Conn      func() (*redigomock.Conn, func(*testing.T))
+		Result    []DetailedSession
+		Err       bool
 	}{
 		"Cancelled context": {
 			Cancelled: true,
@@ -1194,11 +1938,10 @@ func Test_RedisStore_DeleteByUserKey(t *testing.T) {
 			},
 			Err: true,
 		},
-		"Error returned during user key watching": {
+		"Error returned during user session set fetch": {
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
-				conn.Command("WATCH", inpFullKey).ExpectError(assert.AnError)
-				conn.GenericCommand("UNWATCH")
+				conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectError(assert.AnError)
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
@@ -1207,12 +1950,11 @@ func Test_RedisStore_DeleteByUserKey(t *testing.T) {
 			},
 			Err: true,
 		},
-		"Error returned during user session set fetch": {
+		"Error returned during session fetch": {
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
-				conn.Command("WATCH", inpFullKey)
-				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectError(assert.AnError)
-				conn.GenericCommand("UNWATCH")
+				conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectSlice(sKey)
+				conn.Command("HGETALL", sKey).ExpectError(assert.AnError)
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
@@ -1221,38 +1963,130 @@ func Test_RedisStore_DeleteByUserKey(t *testing.T) {
 			},
 			Err: true,
 		},
-		"Error returned during transaction creation": {
+		"Not found": {
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
-				conn.Command("WATCH", inpFullKey)
-				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(
-					prefix+":session:id111",
-					prefix+":session:id222",
-					prefix+":session:id333",
-				)
-				conn.GenericCommand("MULTI").ExpectError(assert.AnError)
-				conn.GenericCommand("DISCARD")
+				conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectError(redis.ErrNil)
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
 				}
 			},
-			Err: true,
 		},
-		"Error returned during single session deletion": {
+		"Successful fetch": {
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
-				conn.Command("WATCH", inpFullKey)
-				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(
-					prefix+":session:id111",
-					prefix+":session:id222",
-					prefix+":session:id333",
-				)
-				conn.GenericCommand("MULTI")
-				conn.Command("DEL", prefix+":session:id111").ExpectError(assert.AnError)
-				conn.GenericCommand("DISCARD")
+				conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectSlice(sKey, prefix+":session:notfound")
+				conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+					"created_at":   inp.CreatedAt.Format(time.RFC3339Nano),
+					"expires_at":   inp.ExpiresAt.Format(time.RFC3339Nano),
+					"id":           inp.ID,
+					"user_key":     inp.UserKey,
+					"ip":           inp.IP.String(),
+					"last_seen_at": inp.LastSeenAt.Format(time.RFC3339Nano),
+				})
+				conn.Command("HGETALL", prefix+":session:notfound").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Result: []DetailedSession{inp},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+					Wait:      true,
+					MaxActive: 10,
+				},
+				prefix: prefix,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if c.Cancelled {
+				cancel()
+			}
+
+			dd, err := r.FetchDetailedByUserKey(ctx, inp.UserKey)
+			check(t)
+
+			if c.Err {
+				assert.Error(t, err)
+				assert.Nil(t, dd)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.Result, dd)
+		})
+	}
+}
+
+func Test_RedisStore_FetchAll(t *testing.T) {
+	inp := make([]sessionup.Session, 3)
+
+	for i := range inp {
+		s := sessionup.Session{
+			UserKey:   "u" + strconv.Itoa(i),
+			ID:        "id" + strconv.Itoa(i),
+			ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+			CreatedAt: time.Now().UTC().Round(0),
+			IP:        net.ParseIP("127.0.0.1"),
+			Meta:      map[string]string{"test": "1"},
+		}
+		s.Agent.OS = "gnu/linux"
+		s.Agent.Browser = "firefox"
+		inp[i] = s
+	}
+
+	pattern := prefix + ":session:*"
+
+	sKey := func(i int) string {
+		return prefix + ":session:" + inp[i].ID
+	}
+
+	fields := func(i int) map[string]string {
+		return map[string]string{
+			"created_at":    inp[i].CreatedAt.Format(time.RFC3339Nano),
+			"expires_at":    inp[i].ExpiresAt.Format(time.RFC3339Nano),
+			"id":            inp[i].ID,
+			"user_key":      inp[i].UserKey,
+			"ip":            inp[i].IP.String(),
+			"agent_os":      inp[i].Agent.OS,
+			"agent_browser": inp[i].Agent.Browser,
+			"meta":          "test=1",
+		}
+	}
 
+	cc := map[string]struct {
+		Cancelled bool
+		Cursor    string
+		Limit     int
+		Conn      func() (*redigomock.Conn, func(*testing.T))
+		Result    []sessionup.Session
+		Next      string
+		Err       bool
+	}{
+		"Cancelled context": {
+			Cancelled: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
@@ -1260,43 +2094,35 @@ func Test_RedisStore_DeleteByUserKey(t *testing.T) {
 			},
 			Err: true,
 		},
-		"Error returned during single session deletion from user set": {
+		"Invalid cursor": {
+			Cursor: "abc",
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
-				conn.Command("WATCH", inpFullKey)
-				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(
-					prefix+":session:id111",
-					prefix+":session:id222",
-					prefix+":session:id333",
-				)
-				conn.GenericCommand("MULTI")
-				conn.Command("DEL", prefix+":session:id111")
-				conn.Command("ZREM", inpFullKey, prefix+":session:id111").ExpectError(assert.AnError)
-				conn.GenericCommand("DISCARD")
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during scan": {
+			Limit: 2,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SCAN", uint64(0), "MATCH", pattern, "COUNT", 2).ExpectError(assert.AnError)
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
 				}
 			},
-			WithExceptions: true,
-			Err:            true,
+			Err: true,
 		},
-		"Error returned during user key deletion": {
+		"Invalid scan reply": {
+			Limit: 2,
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
-				conn.Command("WATCH", inpFullKey)
-				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(
-					prefix+":session:id111",
-					prefix+":session:id222",
-					prefix+":session:id333",
-				)
-				conn.GenericCommand("MULTI")
-				conn.Command("DEL", prefix+":session:id111")
-				conn.Command("DEL", prefix+":session:id222")
-				conn.Command("DEL", prefix+":session:id333")
-				conn.Command("DEL", inpFullKey).ExpectError(assert.AnError)
-				conn.GenericCommand("DISCARD")
+				conn.Command("SCAN", uint64(0), "MATCH", pattern, "COUNT", 2).Expect([]interface{}{[]byte("x")})
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
@@ -1305,21 +2131,14 @@ func Test_RedisStore_DeleteByUserKey(t *testing.T) {
 			},
 			Err: true,
 		},
-		"Error returned during transaction exec": {
+		"Error returned during session fetch": {
+			Limit: 2,
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
-				conn.Command("WATCH", inpFullKey)
-				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(
-					prefix+":session:id111",
-					prefix+":session:id222",
-					prefix+":session:id333",
-				)
-				conn.GenericCommand("MULTI")
-				conn.Command("DEL", prefix+":session:id111")
-				conn.Command("DEL", prefix+":session:id222")
-				conn.Command("DEL", prefix+":session:id333")
-				conn.Command("DEL", inpFullKey)
-				conn.GenericCommand("EXEC").ExpectError(assert.AnError)
+				conn.Command("SCAN", uint64(0), "MATCH", pattern, "COUNT", 2).
+					ExpectSlice([]byte("0"), []interface{}{[]byte(sKey(0)), []byte(sKey(1))})
+				conn.Command("HGETALL", sKey(0)).ExpectError(assert.AnError)
+				conn.Command("HGETALL", sKey(1)).ExpectMap(fields(1))
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
@@ -1328,63 +2147,54 @@ func Test_RedisStore_DeleteByUserKey(t *testing.T) {
 			},
 			Err: true,
 		},
-		"Successful deletion with ID exceptions": {
+		"Successful fetch of empty page": {
+			Cursor: "5",
+			Limit:  2,
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
-				conn.Command("WATCH", inpFullKey)
-				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(
-					prefix+":session:id111",
-					prefix+":session:id222",
-					prefix+":session:id333",
-				)
-				conn.GenericCommand("MULTI")
-				conn.Command("DEL", prefix+":session:id111")
-				conn.Command("ZREM", inpFullKey, prefix+":session:id111")
-				conn.GenericCommand("EXEC")
+				conn.Command("SCAN", uint64(5), "MATCH", pattern, "COUNT", 2).
+					ExpectSlice([]byte("0"), []interface{}{})
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
 				}
 			},
-			WithExceptions: true,
 		},
-		"Successful deletion without sessions": {
+		"Successful fetch of partial page": {
+			Limit: 2,
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
-				conn.Command("WATCH", inpFullKey)
-				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectError(redis.ErrNil)
-				conn.GenericCommand("MULTI")
-				conn.Command("DEL", inpFullKey)
-				conn.GenericCommand("EXEC")
+				conn.Command("SCAN", uint64(0), "MATCH", pattern, "COUNT", 2).
+					ExpectSlice([]byte("3"), []interface{}{[]byte(sKey(0))})
+				conn.Command("SCAN", uint64(3), "MATCH", pattern, "COUNT", 2).
+					ExpectSlice([]byte("7"), []interface{}{[]byte(sKey(1)), []byte(prefix + ":session:notfound")})
+				conn.Command("HGETALL", sKey(0)).ExpectMap(fields(0))
+				conn.Command("HGETALL", sKey(1)).ExpectMap(fields(1))
+				conn.Command("HGETALL", prefix+":session:notfound").ExpectSlice()
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
 				}
 			},
+			Result: inp[:2],
+			Next:   "7",
 		},
-		"Successful deletion": {
+		"Successful fetch of last page": {
+			Cursor: "7",
 			Conn: func() (*redigomock.Conn, func(*testing.T)) {
 				conn := redigomock.NewConn()
-				conn.Command("WATCH", inpFullKey)
-				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(
-					prefix+":session:id111",
-					prefix+":session:id222",
-					prefix+":session:id333",
-				)
-				conn.GenericCommand("MULTI")
-				conn.Command("DEL", prefix+":session:id111")
-				conn.Command("DEL", prefix+":session:id222")
-				conn.Command("DEL", prefix+":session:id333")
-				conn.Command("DEL", inpFullKey)
-				conn.GenericCommand("EXEC")
+				conn.Command("SCAN", uint64(7), "MATCH", pattern, "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{[]byte(sKey(2))})
+				conn.Command("HGETALL", sKey(2)).ExpectMap(fields(2))
 
 				return conn, func(t *testing.T) {
 					err := conn.ExpectationsWereMet()
 					assert.NoError(t, err)
 				}
 			},
+			Result: inp[2:],
 		},
 	}
 
@@ -1414,57 +2224,3029 @@ func Test_RedisStore_DeleteByUserKey(t *testing.T) {
 				cancel()
 			}
 
-			var err error
-
-			if c.WithExceptions {
-				err = r.DeleteByUserKey(ctx, inpKey, inpExceptIDs...)
-			} else {
-				err = r.DeleteByUserKey(ctx, inpKey)
-			}
-
+			ss, next, err := r.FetchAll(ctx, c.Cursor, c.Limit)
 			check(t)
 
 			if c.Err {
 				assert.Error(t, err)
+				assert.Nil(t, ss)
+				assert.Empty(t, next)
 				return
 			}
 
 			assert.NoError(t, err)
+			assert.Equal(t, c.Result, ss)
+			assert.Equal(t, c.Next, next)
 		})
 	}
 }
 
-func Test_RedisStore_key(t *testing.T) {
-	r := RedisStore{prefix: "test"}
-	assert.Equal(t, "test:session:hello", r.key(false, "hello"))
-	assert.Equal(t, "test:user:hello", r.key(true, "hello"))
+// rttConn simulates network round trip time on each executed
+// command or flushed pipeline.
+type rttConn struct {
+	redis.Conn
+	rtt time.Duration
 }
 
-func Test_extract(t *testing.T) {
-	assert.Zero(t, extract(":1"))
-	assert.Equal(t, "123", extract("test:hello:123"))
+func (c rttConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if cmd != "" {
+		time.Sleep(c.rtt)
+	}
+
+	return c.Conn.Do(cmd, args...)
 }
 
-func Test_parse(t *testing.T) {
+func (c rttConn) Flush() error {
+	time.Sleep(c.rtt)
+	return c.Conn.Flush()
+}
+
+func Benchmark_RedisStore_FetchByUserKey(b *testing.B) {
+	const userKey = "u123"
+
+	uKey := prefix + ":user:" + userKey
+	conn := redigomock.NewConn()
+
+	ids := make([]interface{}, 50)
+	for i := range ids {
+		sKey := prefix + ":session:id" + strconv.Itoa(i)
+		ids[i] = sKey
+
+		conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+			"created_at":    time.Now().UTC().Format(time.RFC3339Nano),
+			"expires_at":    time.Now().UTC().Add(time.Hour).Format(time.RFC3339Nano),
+			"id":            "id" + strconv.Itoa(i),
+			"user_key":      userKey,
+			"ip":            "127.0.0.1",
+			"agent_os":      "gnu/linux",
+			"agent_browser": "firefox",
+			"meta":          "test:1;",
+		})
+	}
+
+	conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectSlice(ids...)
+
+	r := RedisStore{
+		pool: &redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return rttConn{Conn: conn, rtt: time.Microsecond * 100}, nil
+			},
+		},
+		prefix: prefix,
+	}
+
+	b.Run("Sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c := r.pool.(*redis.Pool).Get()
+
+			ids, err := redis.Strings(c.Do("ZRANGEBYSCORE", uKey, "-inf", "+inf"))
+			require.NoError(b, err)
+
+			for j := range ids {
+				vv, err := redis.StringMap(c.Do("HGETALL", ids[j]))
+				require.NoError(b, err)
+
+				_, err = parse(vv)
+				require.NoError(b, err)
+			}
+
+			c.Close()
+		}
+	})
+
+	b.Run("Pipelined", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := r.FetchByUserKey(context.Background(), userKey)
+			require.NoError(b, err)
+		}
+	})
+}
+
+func Test_RedisStore_DeleteByID(t *testing.T) {
 	inp := sessionup.Session{
 		UserKey:   "u123",
 		ID:        "id123",
 		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
 		CreatedAt: time.Now().UTC().Round(0),
 		IP:        net.ParseIP("127.0.0.1"),
+		Meta:      map[string]string{"test": "1", "": "val"},
 	}
 	inp.Agent.OS = "gnu/linux"
 	inp.Agent.Browser = "firefox"
 
+	sKey := prefix + ":session:" + inp.ID
+	pKey := prefix + ":payload:" + inp.ID
+	uKey := prefix + ":user:" + inp.UserKey
+
+	fields := map[string]string{
+		"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
+		"id":            inp.ID,
+		"user_key":      inp.UserKey,
+		"ip":            inp.IP.String(),
+		"agent_os":      inp.Agent.OS,
+		"agent_browser": inp.Agent.Browser,
+		"meta":          "test:1;:val;",
+	}
+
 	cc := map[string]struct {
-		Data map[string]string
-		Err  bool
+		Cancelled     bool
+		Invalidations bool
+		Conn          func() (*redigomock.Conn, func(*testing.T))
+		Err           bool
 	}{
-		"Invalid creation format": {
-			Data: map[string]string{
-				"user_key":      inp.UserKey,
-				"id":            inp.ID,
-				"created_at":    "123",
+		"Cancelled context": {
+			Cancelled: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session key watching": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey).ExpectError(assert.AnError)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session fetch": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during parsing": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				invalid := make(map[string]string, len(fields))
+				for k, v := range fields {
+					invalid[k] = v
+				}
+
+				invalid["expires_at"] = "invalid"
+
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(invalid)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during transaction creation": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI").ExpectError(assert.AnError)
+				conn.Command("ZREM", uKey, sKey).Expect("QUEUED")
+				conn.Command("DEL", sKey, pKey).Expect("QUEUED")
+				conn.GenericCommand("EXEC")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session key deletion from user set": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI").Expect("OK")
+				conn.Command("ZREM", uKey, sKey).ExpectError(assert.AnError)
+				conn.Command("DEL", sKey, pKey).Expect("QUEUED")
+				conn.GenericCommand("EXEC")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session key deletion": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI").Expect("OK")
+				conn.Command("ZREM", uKey, sKey).Expect("QUEUED")
+				conn.Command("DEL", sKey, pKey).ExpectError(assert.AnError)
+				conn.GenericCommand("EXEC")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during transaction exec": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI").Expect("OK")
+				conn.Command("ZREM", uKey, sKey).Expect("QUEUED")
+				conn.Command("DEL", sKey, pKey).Expect("QUEUED")
+				conn.GenericCommand("EXEC").ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Transaction conflict": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI").Expect("OK")
+				conn.Command("ZREM", uKey, sKey).Expect("QUEUED")
+				conn.Command("DEL", sKey, pKey).Expect("QUEUED")
+				conn.GenericCommand("EXEC").Expect(nil)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during invalidation publishing": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI").Expect("OK")
+				conn.Command("ZREM", uKey, sKey).Expect("QUEUED")
+				conn.Command("DEL", sKey, pKey).Expect("QUEUED")
+				conn.GenericCommand("EXEC").ExpectSlice()
+				conn.Command("PUBLISH", prefix+":invalidations", []byte(`{"id":"id123"}`)).ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Invalidations: true,
+			Err:           true,
+		},
+		"Not found": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Empty session map": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectSlice()
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful deletion with invalidation": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI").Expect("OK")
+				conn.Command("ZREM", uKey, sKey).Expect("QUEUED")
+				conn.Command("DEL", sKey, pKey).Expect("QUEUED")
+				conn.GenericCommand("EXEC").ExpectSlice()
+				conn.Command("PUBLISH", prefix+":invalidations", []byte(`{"id":"id123"}`))
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Invalidations: true,
+		},
+		"Successful deletion": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI").Expect("OK")
+				conn.Command("ZREM", uKey, sKey).Expect("QUEUED")
+				conn.Command("DEL", sKey, pKey).Expect("QUEUED")
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+					Wait:      true,
+					MaxActive: 10,
+				},
+				prefix:     prefix,
+				txAttempts: 1,
+			}
+
+			if c.Invalidations {
+				r.invalidations = prefix + ":invalidations"
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if c.Cancelled {
+				cancel()
+			}
+
+			err := r.DeleteByID(ctx, inp.ID)
+			check(t)
+
+			if c.Err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_WithNotFoundErrors(t *testing.T) {
+	r := RedisStore{}
+	WithNotFoundErrors(true)(&r)
+	assert.True(t, r.notFoundErrors)
+}
+
+func Test_RedisStore_DeleteByID_notFound(t *testing.T) {
+	sKey := prefix + ":session:id123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+	conn.GenericCommand("UNWATCH")
+
+	assert.NoError(t, r.DeleteByID(context.Background(), "id123"))
+
+	WithNotFoundErrors(true)(r)
+
+	err := r.DeleteByID(context.Background(), "id123")
+	assert.True(t, errors.Is(err, ErrNoSession))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Benchmark_RedisStore_DeleteByID(b *testing.B) {
+	const id = "id123"
+
+	sKey := prefix + ":session:" + id
+	pKey := prefix + ":payload:" + id
+	uKey := prefix + ":user:u123"
+	conn := redigomock.NewConn()
+
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("WATCH", uKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at":    time.Now().UTC().Format(time.RFC3339Nano),
+		"expires_at":    time.Now().UTC().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":            id,
+		"user_key":      "u123",
+		"ip":            "127.0.0.1",
+		"agent_os":      "gnu/linux",
+		"agent_browser": "firefox",
+		"meta":          "test:1;",
+	})
+	conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectSlice([]byte(sKey))
+	conn.GenericCommand("MULTI").Expect("OK")
+	conn.Command("ZREM", uKey, sKey).Expect("QUEUED")
+	conn.Command("DEL", sKey, pKey).Expect("QUEUED")
+	conn.GenericCommand("EXEC").ExpectSlice(int64(1), int64(1))
+
+	r := RedisStore{
+		pool: &redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return rttConn{Conn: conn, rtt: time.Microsecond * 100}, nil
+			},
+		},
+		prefix:     prefix,
+		txAttempts: 1,
+	}
+
+	b.Run("Sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c := r.pool.(*redis.Pool).Get()
+
+			_, err := c.Do("WATCH", sKey)
+			require.NoError(b, err)
+
+			vv, err := redis.StringMap(c.Do("HGETALL", sKey))
+			require.NoError(b, err)
+
+			_, err = parse(vv)
+			require.NoError(b, err)
+
+			_, err = c.Do("WATCH", uKey)
+			require.NoError(b, err)
+
+			_, err = redis.Strings(c.Do("ZRANGEBYSCORE", uKey, "-inf", "+inf"))
+			require.NoError(b, err)
+
+			for _, cmd := range [][]interface{}{{"MULTI"}, {"ZREM", uKey, sKey}, {"DEL", sKey, pKey}} {
+				_, err = c.Do(cmd[0].(string), cmd[1:]...)
+				require.NoError(b, err)
+			}
+
+			require.NoError(b, exec(c))
+
+			c.Close()
+		}
+	})
+
+	b.Run("Pipelined", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			err := r.DeleteByID(context.Background(), id)
+			require.NoError(b, err)
+		}
+	})
+}
+
+func Test_RedisStore_DeleteByIDs(t *testing.T) {
+	sKey1 := prefix + ":session:id1"
+	sKey2 := prefix + ":session:id2"
+	pKey1 := prefix + ":payload:id1"
+	uKey := prefix + ":user:u123"
+
+	setup := func() (*RedisStore, *redigomock.Conn) {
+		conn := redigomock.NewConn()
+		conn.Command("WATCH", sKey1, sKey2)
+		conn.Command("HGETALL", sKey1).ExpectMap(map[string]string{
+			"created_at": time.Now().Format(time.RFC3339Nano),
+			"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+			"id":         "id1",
+			"user_key":   "u123",
+		})
+		conn.Command("HGETALL", sKey2).ExpectSlice()
+
+		r := New(&redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return conn, nil
+			},
+		}, prefix, WithTxRetries(1, 0))
+
+		return r, conn
+	}
+
+	t.Run("No IDs", func(t *testing.T) {
+		r := RedisStore{}
+		assert.NoError(t, r.DeleteByIDs(context.Background()))
+	})
+
+	t.Run("Error returned during session fetch", func(t *testing.T) {
+		r, conn := setup()
+		conn.Command("HGETALL", sKey2).ExpectError(assert.AnError)
+
+		err := r.DeleteByIDs(context.Background(), "id1", "id2")
+		assert.True(t, errors.Is(err, assert.AnError))
+	})
+
+	t.Run("Aborted transaction", func(t *testing.T) {
+		r, conn := setup()
+		conn.GenericCommand("MULTI").Expect("OK")
+		conn.Command("ZREM", uKey, sKey1).Expect("QUEUED")
+		conn.Command("DEL", sKey1, pKey1).Expect("QUEUED")
+		conn.GenericCommand("EXEC").Expect(nil)
+
+		err := r.DeleteByIDs(context.Background(), "id1", "id2")
+		assert.True(t, errors.Is(err, ErrTxConflict))
+	})
+
+	t.Run("Successful deletion", func(t *testing.T) {
+		r, conn := setup()
+		conn.GenericCommand("MULTI").Expect("OK")
+		conn.Command("ZREM", uKey, sKey1).Expect("QUEUED")
+		conn.Command("DEL", sKey1, pKey1).Expect("QUEUED")
+		conn.GenericCommand("EXEC").ExpectSlice(int64(1), int64(1))
+
+		require.NoError(t, r.DeleteByIDs(context.Background(), "id1", "id2"))
+		assert.NoError(t, conn.ExpectationsWereMet())
+	})
+
+	t.Run("Successful deletion with duplicate IDs", func(t *testing.T) {
+		r, conn := setup()
+		conn.GenericCommand("MULTI").Expect("OK")
+		zrem := conn.Command("ZREM", uKey, sKey1).Expect("QUEUED")
+		conn.Command("DEL", sKey1, pKey1).Expect("QUEUED")
+		conn.GenericCommand("EXEC").ExpectSlice(int64(1), int64(1))
+
+		require.NoError(t, r.DeleteByIDs(context.Background(), "id1", "id2", "id1"))
+		assert.NoError(t, conn.ExpectationsWereMet())
+		assert.Equal(t, 1, conn.Stats(zrem))
+	})
+
+	t.Run("Successful deletion with index removal", func(t *testing.T) {
+		r, conn := setup()
+		WithIPIndex(true)(r)
+		WithCreatedIndex(true)(r)
+		conn.Command("HGETALL", sKey1).ExpectMap(map[string]string{
+			"created_at": time.Now().Format(time.RFC3339Nano),
+			"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+			"id":         "id1",
+			"user_key":   "u123",
+			"ip":         "127.0.0.1",
+		})
+		conn.GenericCommand("MULTI").Expect("OK")
+		conn.Command("ZREM", uKey, sKey1).Expect("QUEUED")
+		conn.Command("DEL", sKey1, pKey1).Expect("QUEUED")
+		conn.Command("ZREM", prefix+":ip:127.0.0.1", sKey1).Expect("QUEUED")
+		conn.Command("ZREM", prefix+":created:all", sKey1).Expect("QUEUED")
+		conn.GenericCommand("EXEC").ExpectSlice(int64(1), int64(1), int64(1), int64(1))
+
+		require.NoError(t, r.DeleteByIDs(context.Background(), "id1", "id2"))
+		assert.NoError(t, conn.ExpectationsWereMet())
+	})
+}
+
+func Test_RedisStore_DeleteByUserKey(t *testing.T) {
+	const (
+		inpKey     = "u123"
+		inpFullKey = prefix + ":user:" + inpKey
+	)
+
+	const (
+		sPrefix = prefix + ":session:"
+		pPrefix = prefix + ":payload:"
+	)
+
+	inpExceptIDs := []string{"id222", "id333"}
+
+	cc := map[string]struct {
+		Cancelled      bool
+		NoScripts      bool
+		IPIndex        bool
+		Conn           func() (*redigomock.Conn, func(*testing.T))
+		WithExceptions bool
+		Err            bool
+	}{
+		"Cancelled context": {
+			Cancelled: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned by script": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Script([]byte(deleteByUserKeyScriptSrc), 1, inpFullKey, sPrefix, pPrefix, "").ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Successful execution with transaction fallback": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Script([]byte(deleteByUserKeyScriptSrc), 1, inpFullKey, sPrefix, pPrefix, "").ExpectError(redis.Error("ERR unknown command 'EVALSHA'"))
+				conn.Command("WATCH", inpFullKey)
+				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(prefix + ":session:id111")
+				conn.GenericCommand("MULTI")
+				conn.Command("DEL", prefix+":session:id111", prefix+":payload:id111")
+				conn.Command("DEL", inpFullKey)
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful execution with scripts disabled": {
+			NoScripts: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", inpFullKey)
+				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(prefix + ":session:id111")
+				conn.GenericCommand("MULTI")
+				conn.Command("DEL", prefix+":session:id111", prefix+":payload:id111")
+				conn.Command("DEL", inpFullKey)
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful execution with exceptions": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Script(
+					[]byte(deleteByUserKeyScriptSrc), 1, inpFullKey, sPrefix, pPrefix, "",
+					prefix+":session:id222",
+					prefix+":session:id333",
+				).Expect(int64(1))
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			WithExceptions: true,
+		},
+		"Successful execution with index removal": {
+			IPIndex: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Script([]byte(deleteByUserKeyScriptSrc), 1, inpFullKey, sPrefix, pPrefix, "HGETALL").
+					ExpectSlice(
+						[]byte(sPrefix+"id111"), []interface{}{
+							[]byte("created_at"), []byte(time.Now().Format(time.RFC3339Nano)),
+							[]byte("expires_at"), []byte(time.Now().Add(time.Hour).Format(time.RFC3339Nano)),
+							[]byte("id"), []byte("id111"),
+							[]byte("user_key"), []byte(inpKey),
+							[]byte("ip"), []byte("127.0.0.1"),
+						},
+						[]byte(sPrefix+"id222"), []interface{}{},
+					)
+				conn.Command("ZREM", prefix+":ip:127.0.0.1", sPrefix+"id111").Expect(int64(1))
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful execution": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Script([]byte(deleteByUserKeyScriptSrc), 1, inpFullKey, sPrefix, pPrefix, "").Expect(int64(3))
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+					Wait:      true,
+					MaxActive: 10,
+				},
+				prefix:  prefix,
+				ipIndex: c.IPIndex,
+			}
+
+			if c.NoScripts {
+				r.disableScripts()
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if c.Cancelled {
+				cancel()
+			}
+
+			var err error
+
+			if c.WithExceptions {
+				err = r.DeleteByUserKey(ctx, inpKey, inpExceptIDs...)
+			} else {
+				err = r.DeleteByUserKey(ctx, inpKey)
+			}
+
+			check(t)
+
+			if c.Err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_RedisStore_deleteByUserKeyTx(t *testing.T) {
+	const (
+		inpKey     = "u123"
+		inpFullKey = prefix + ":user:" + inpKey
+	)
+
+	inpExceptIDs := []string{"id222", "id333"}
+
+	cc := map[string]struct {
+		Conn           func() (*redigomock.Conn, func(*testing.T))
+		WithExceptions bool
+		Err            bool
+	}{
+		"Error returned during user key watching": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", inpFullKey).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during user session set fetch": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", inpFullKey)
+				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during transaction creation": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", inpFullKey)
+				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(
+					prefix+":session:id111",
+					prefix+":session:id222",
+					prefix+":session:id333",
+				)
+				conn.GenericCommand("MULTI").ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during single session deletion": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", inpFullKey)
+				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(
+					prefix+":session:id111",
+					prefix+":session:id222",
+					prefix+":session:id333",
+				)
+				conn.GenericCommand("MULTI")
+				conn.Command("DEL", prefix+":session:id111", prefix+":payload:id111").ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during single session deletion from user set": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", inpFullKey)
+				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(
+					prefix+":session:id111",
+					prefix+":session:id222",
+					prefix+":session:id333",
+				)
+				conn.GenericCommand("MULTI")
+				conn.Command("DEL", prefix+":session:id111", prefix+":payload:id111")
+				conn.Command("ZREM", inpFullKey, prefix+":session:id111").ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			WithExceptions: true,
+			Err:            true,
+		},
+		"Error returned during user key deletion": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", inpFullKey)
+				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(
+					prefix+":session:id111",
+					prefix+":session:id222",
+					prefix+":session:id333",
+				)
+				conn.GenericCommand("MULTI")
+				conn.Command("DEL", prefix+":session:id111", prefix+":payload:id111")
+				conn.Command("DEL", prefix+":session:id222", prefix+":payload:id222")
+				conn.Command("DEL", prefix+":session:id333", prefix+":payload:id333")
+				conn.Command("DEL", inpFullKey).ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during transaction exec": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", inpFullKey)
+				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(
+					prefix+":session:id111",
+					prefix+":session:id222",
+					prefix+":session:id333",
+				)
+				conn.GenericCommand("MULTI")
+				conn.Command("DEL", prefix+":session:id111", prefix+":payload:id111")
+				conn.Command("DEL", prefix+":session:id222", prefix+":payload:id222")
+				conn.Command("DEL", prefix+":session:id333", prefix+":payload:id333")
+				conn.Command("DEL", inpFullKey)
+				conn.GenericCommand("EXEC").ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Successful deletion with ID exceptions": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", inpFullKey)
+				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(
+					prefix+":session:id111",
+					prefix+":session:id222",
+					prefix+":session:id333",
+				)
+				conn.GenericCommand("MULTI")
+				conn.Command("DEL", prefix+":session:id111", prefix+":payload:id111")
+				conn.Command("ZREM", inpFullKey, prefix+":session:id111")
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			WithExceptions: true,
+		},
+		"Successful deletion without sessions": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", inpFullKey)
+				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectError(redis.ErrNil)
+				conn.GenericCommand("MULTI")
+				conn.Command("DEL", inpFullKey)
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful deletion": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", inpFullKey)
+				conn.Command("ZRANGEBYSCORE", inpFullKey, "-inf", "+inf").ExpectSlice(
+					prefix+":session:id111",
+					prefix+":session:id222",
+					prefix+":session:id333",
+				)
+				conn.GenericCommand("MULTI")
+				conn.Command("DEL", prefix+":session:id111", prefix+":payload:id111")
+				conn.Command("DEL", prefix+":session:id222", prefix+":payload:id222")
+				conn.Command("DEL", prefix+":session:id333", prefix+":payload:id333")
+				conn.Command("DEL", inpFullKey)
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+					Wait:      true,
+					MaxActive: 10,
+				},
+				prefix: prefix,
+			}
+
+			rc := r.pool.(*redis.Pool).Get()
+
+			var err error
+
+			if c.WithExceptions {
+				err = r.deleteByUserKeyTx(rc, inpKey, inpExceptIDs...)
+			} else {
+				err = r.deleteByUserKeyTx(rc, inpKey)
+			}
+
+			rc.Close()
+			check(t)
+
+			if c.Err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_RedisStore_deleteByUserKeyTx_colons(t *testing.T) {
+	uKey := prefix + ":user:org%3A123"
+
+	conn := redigomock.NewConn()
+	conn.Command("WATCH", uKey)
+	conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").ExpectSlice(
+		prefix+":session:a%3A1",
+		prefix+":session:a%3A2",
+	)
+	conn.GenericCommand("MULTI")
+	conn.Command("DEL", prefix+":session:a%3A1", prefix+":payload:a%3A1")
+	conn.Command("ZREM", uKey, prefix+":session:a%3A1")
+	conn.GenericCommand("EXEC").ExpectSlice()
+
+	r := RedisStore{prefix: prefix}
+	require.NoError(t, r.deleteByUserKeyTx(conn, "org:123", "a:2"))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_DeleteAll(t *testing.T) {
+	sPattern := prefix + ":session:*"
+	uPattern := prefix + ":user:*"
+	sKey1 := prefix + ":session:id1"
+	sKey2 := prefix + ":session:id2"
+	uKey := prefix + ":user:u1"
+	pKey := prefix + ":payload:id1"
+	aKey := prefix + ":active:1600000000"
+
+	cc := map[string]struct {
+		Cancelled bool
+		Conn      func() (*redigomock.Conn, func(*testing.T))
+		Err       bool
+	}{
+		"Cancelled context": {
+			Cancelled: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session key scan": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SCAN", int64(0), "MATCH", sPattern, "COUNT", scanCount).ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session key deletion": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SCAN", int64(0), "MATCH", sPattern, "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{[]byte(sKey1)})
+				conn.Command("UNLINK", sKey1).ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during user key scan": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SCAN", int64(0), "MATCH", sPattern, "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{[]byte(sKey1)})
+				conn.Command("UNLINK", sKey1)
+				conn.GenericCommand("SCAN").ExpectSlice([]byte("0"), []interface{}{})
+				conn.Command("SCAN", int64(0), "MATCH", uPattern, "COUNT", scanCount).ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Successful deletion without UNLINK support": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SCAN", int64(0), "MATCH", sPattern, "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{[]byte(sKey1), []byte(sKey2)})
+				conn.Command("UNLINK", sKey1, sKey2).ExpectError(redis.Error("ERR unknown command 'UNLINK'"))
+				conn.Command("DEL", sKey1, sKey2)
+				conn.GenericCommand("SCAN").ExpectSlice([]byte("0"), []interface{}{})
+				conn.Command("SCAN", int64(0), "MATCH", uPattern, "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{[]byte(uKey)})
+				conn.Command("DEL", uKey)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful deletion": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("SCAN", int64(0), "MATCH", sPattern, "COUNT", scanCount).
+					ExpectSlice([]byte("4"), []interface{}{[]byte(sKey1)})
+				conn.Command("UNLINK", sKey1)
+				conn.Command("SCAN", int64(4), "MATCH", sPattern, "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{[]byte(sKey2)})
+				conn.Command("UNLINK", sKey2)
+				conn.Command("SCAN", int64(0), "MATCH", prefix+":payload:*", "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{[]byte(pKey)})
+				conn.Command("UNLINK", pKey)
+				conn.Command("SCAN", int64(0), "MATCH", prefix+":revoked:*", "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{})
+				conn.Command("SCAN", int64(0), "MATCH", prefix+":active:*", "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{[]byte(aKey)})
+				conn.Command("UNLINK", aKey)
+				conn.Command("SCAN", int64(0), "MATCH", uPattern, "COUNT", scanCount).
+					ExpectSlice([]byte("0"), []interface{}{[]byte(uKey)})
+				conn.Command("UNLINK", uKey)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+					Wait:      true,
+					MaxActive: 10,
+				},
+				prefix: prefix,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if c.Cancelled {
+				cancel()
+			}
+
+			err := r.DeleteAll(ctx)
+			check(t)
+
+			if c.Err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_RedisStore_ExtendByID(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+		CreatedAt: time.Now().UTC().Round(0),
+		IP:        net.ParseIP("127.0.0.1"),
+		Meta:      map[string]string{"test": "1"},
+	}
+	inp.Agent.OS = "gnu/linux"
+	inp.Agent.Browser = "firefox"
+
+	exp := inp.ExpiresAt.Add(time.Hour)
+	expMilli := exp.UnixNano() / int64(time.Millisecond)
+
+	sKey := prefix + ":session:" + inp.ID
+	pKey := prefix + ":payload:" + inp.ID
+	uKey := prefix + ":user:" + inp.UserKey
+
+	fields := map[string]string{
+		"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
+		"id":            inp.ID,
+		"user_key":      inp.UserKey,
+		"ip":            inp.IP.String(),
+		"agent_os":      inp.Agent.OS,
+		"agent_browser": inp.Agent.Browser,
+		"meta":          "test=1",
+	}
+
+	hmset := []interface{}{
+		sKey,
+		"created_at", inp.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at", exp.Format(time.RFC3339Nano),
+		"id", inp.ID,
+		"user_key", inp.UserKey,
+		"ip", inp.IP.String(),
+		"agent_os", inp.Agent.OS,
+		"agent_browser", inp.Agent.Browser,
+		"meta", "test=1",
+		"schema_version", "1",
+	}
+
+	cc := map[string]struct {
+		Cancelled bool
+		Conn      func() (*redigomock.Conn, func(*testing.T))
+		Err       bool
+	}{
+		"Cancelled context": {
+			Cancelled: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session key watching": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session fetch": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Not found": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Error returned during user key watching": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.Command("WATCH", uKey).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during user key expiration fetch": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.Command("WATCH", uKey)
+				conn.Command("PTTL", uKey).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during transaction creation": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.Command("WATCH", uKey)
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.GenericCommand("MULTI").ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during user set score update": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.Command("WATCH", uKey)
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.GenericCommand("MULTI")
+				conn.Command("ZADD", uKey, exp.UnixNano(), sKey).ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during user key expiration update": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.Command("WATCH", uKey)
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.GenericCommand("MULTI")
+				conn.Command("ZADD", uKey, exp.UnixNano(), sKey)
+				conn.Command("PEXPIREAT", uKey, expMilli).ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session update": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.Command("WATCH", uKey)
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.GenericCommand("MULTI")
+				conn.Command("ZADD", uKey, exp.UnixNano(), sKey)
+				conn.Command("PEXPIREAT", uKey, expMilli)
+				conn.Command("HMSET", hmset...).ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session key expiration update": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.Command("WATCH", uKey)
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.GenericCommand("MULTI")
+				conn.Command("ZADD", uKey, exp.UnixNano(), sKey)
+				conn.Command("PEXPIREAT", uKey, expMilli)
+				conn.Command("HMSET", hmset...)
+				conn.Command("PEXPIREAT", sKey, expMilli).ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during transaction exec": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.Command("WATCH", uKey)
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.GenericCommand("MULTI")
+				conn.Command("ZADD", uKey, exp.UnixNano(), sKey)
+				conn.Command("PEXPIREAT", uKey, expMilli)
+				conn.Command("HMSET", hmset...)
+				conn.Command("PEXPIREAT", sKey, expMilli)
+				conn.Command("PEXPIREAT", pKey, expMilli)
+				conn.GenericCommand("EXEC").ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Successful execution with later user key expiration": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.Command("WATCH", uKey)
+				conn.Command("PTTL", uKey).Expect(int64(time.Hour * 72 / time.Millisecond))
+				conn.GenericCommand("MULTI")
+				conn.Command("ZADD", uKey, exp.UnixNano(), sKey)
+				conn.Command("PEXPIREAT", uKey, redigomock.NewAnyInt())
+				conn.Command("HMSET", hmset...)
+				conn.Command("PEXPIREAT", sKey, expMilli)
+				conn.Command("PEXPIREAT", pKey, expMilli)
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful execution": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.Command("WATCH", uKey)
+				conn.Command("PTTL", uKey).Expect(int64(20))
+				conn.GenericCommand("MULTI")
+				conn.Command("ZADD", uKey, exp.UnixNano(), sKey)
+				conn.Command("PEXPIREAT", uKey, expMilli)
+				conn.Command("HMSET", hmset...)
+				conn.Command("PEXPIREAT", sKey, expMilli)
+				conn.Command("PEXPIREAT", pKey, expMilli)
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+					Wait:      true,
+					MaxActive: 10,
+				},
+				prefix: prefix,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if c.Cancelled {
+				cancel()
+			}
+
+			err := r.ExtendByID(ctx, inp.ID, exp)
+			check(t)
+
+			if c.Err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_RedisStore_ExtendByUserKey(t *testing.T) {
+	uKey := prefix + ":user:u123"
+	sKey1 := prefix + ":session:id1"
+	sKey2 := prefix + ":session:id2"
+	exp := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	conn.Command("WATCH", uKey).Expect("OK")
+	conn.Command("ZRANGE", uKey, 0, -1).ExpectError(assert.AnError)
+	assert.True(t, errors.Is(r.ExtendByUserKey(context.Background(), "u123", time.Hour), assert.AnError))
+
+	conn.Clear()
+	conn.Command("WATCH", uKey).Expect("OK")
+	conn.Command("ZRANGE", uKey, 0, -1).ExpectError(redis.ErrNil)
+	conn.Command("UNWATCH").Expect("OK")
+	require.NoError(t, r.ExtendByUserKey(context.Background(), "u123", time.Hour))
+
+	conn.Clear()
+	conn.Command("WATCH", uKey).Expect("OK")
+	conn.Command("ZRANGE", uKey, 0, -1).ExpectStringSlice(sKey1, sKey2)
+	conn.Command("WATCH", sKey1, sKey2).Expect("OK")
+	conn.Command("HGETALL", sKey1).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": exp.Format(time.RFC3339Nano),
+		"id":         "id1",
+		"user_key":   "u123",
+	})
+	conn.Command("HGETALL", sKey2).ExpectError(redis.ErrNil)
+	conn.Command("MULTI").Expect("OK")
+	zadd := conn.Command("ZADD", uKey, exp.Add(time.Hour).UnixNano(), sKey1).Expect("QUEUED")
+	conn.GenericCommand("HMSET").Expect("QUEUED")
+	sExp := conn.Command("PEXPIREAT", sKey1, exp.Add(time.Hour).UnixNano()/int64(time.Millisecond)).
+		Expect("QUEUED")
+	pExp := conn.Command("PEXPIREAT", prefix+":payload:id1", exp.Add(time.Hour).UnixNano()/int64(time.Millisecond)).
+		Expect("QUEUED")
+	uExp := conn.Command("PEXPIREAT", uKey, exp.Add(time.Hour).UnixNano()/int64(time.Millisecond)).
+		Expect("QUEUED")
+	conn.Command("EXEC").ExpectSlice(int64(1), "OK", int64(1), int64(0), int64(1))
+
+	require.NoError(t, r.ExtendByUserKey(context.Background(), "u123", time.Hour))
+	assert.Equal(t, 1, conn.Stats(zadd))
+	assert.Equal(t, 1, conn.Stats(sExp))
+	assert.Equal(t, 1, conn.Stats(pExp))
+	assert.Equal(t, 1, conn.Stats(uExp))
+
+	r = New(&redis.Pool{}, prefix, WithoutUserIndex())
+	assert.Equal(t, errNoIndex, r.ExtendByUserKey(context.Background(), "u123", time.Hour))
+}
+
+func Test_RedisStore_UpdateMeta(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+		CreatedAt: time.Now().UTC().Round(0),
+		IP:        net.ParseIP("127.0.0.1"),
+		Meta:      map[string]string{"test": "1"},
+	}
+	inp.Agent.OS = "gnu/linux"
+	inp.Agent.Browser = "firefox"
+
+	sKey := prefix + ":session:" + inp.ID
+
+	fields := map[string]string{
+		"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
+		"id":            inp.ID,
+		"user_key":      inp.UserKey,
+		"ip":            inp.IP.String(),
+		"agent_os":      inp.Agent.OS,
+		"agent_browser": inp.Agent.Browser,
+		"meta":          "test=1",
+	}
+
+	data, err := json.Marshal(toRecord(inp))
+	require.NoError(t, err)
+
+	out := inp
+	out.Meta = map[string]string{"test": "1", "flag": "on"}
+
+	outData, err := json.Marshal(toRecord(out))
+	require.NoError(t, err)
+
+	cc := map[string]struct {
+		Cancelled bool
+		JSON      bool
+		Merge     bool
+		Meta      map[string]string
+		Conn      func() (*redigomock.Conn, func(*testing.T))
+		Err       bool
+	}{
+		"Cancelled context": {
+			Cancelled: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session key watching": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session fetch": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Not found": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Error returned during transaction creation": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI").ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during metadata update": {
+			Meta: map[string]string{"flag": "on"},
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI")
+				conn.Command("HSET", sKey, "meta", "flag=on").ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during session key expiration update in JSON mode": {
+			JSON:  true,
+			Merge: true,
+			Meta:  map[string]string{"flag": "on"},
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("GET", sKey).Expect(data)
+				conn.GenericCommand("MULTI")
+				conn.Command("SET", sKey, outData)
+				conn.Command("PEXPIREAT", sKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond)).ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Error returned during transaction exec": {
+			Meta: map[string]string{"flag": "on"},
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI")
+				conn.Command("HSET", sKey, "meta", "flag=on")
+				conn.GenericCommand("EXEC").ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: true,
+		},
+		"Successful replacement with empty metadata": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI")
+				conn.Command("HSET", sKey, "meta", "")
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful merge": {
+			Merge: true,
+			Meta:  map[string]string{"flag": "on"},
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI")
+				conn.Command("HSET", sKey, "meta", "flag=on&test=1")
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful merge in JSON mode": {
+			JSON:  true,
+			Merge: true,
+			Meta:  map[string]string{"flag": "on"},
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("GET", sKey).Expect(data)
+				conn.GenericCommand("MULTI")
+				conn.Command("SET", sKey, outData)
+				conn.Command("PEXPIREAT", sKey, inp.ExpiresAt.UnixNano()/int64(time.Millisecond))
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful replacement": {
+			Meta: map[string]string{"flag": "on"},
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", sKey)
+				conn.Command("HGETALL", sKey).ExpectMap(fields)
+				conn.GenericCommand("MULTI")
+				conn.Command("HSET", sKey, "meta", "flag=on")
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+					Wait:      true,
+					MaxActive: 10,
+				},
+				prefix: prefix,
+				asJSON: c.JSON,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if c.Cancelled {
+				cancel()
+			}
+
+			err := r.UpdateMeta(ctx, inp.ID, c.Meta, c.Merge)
+			check(t)
+
+			if c.Err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_RedisStore_updateMetaTx_encrypted(t *testing.T) {
+	conn := redigomock.NewConn()
+	r := RedisStore{
+		pool: &redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return conn, nil
+			},
+		},
+		prefix:      prefix,
+		enc:         newEncryption(key1),
+		encMetaOnly: true,
+	}
+
+	sKey := prefix + ":session:id123"
+
+	conn.Command("WATCH", sKey)
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().UTC().Format(time.RFC3339Nano),
+		"expires_at": time.Now().UTC().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+	conn.GenericCommand("MULTI")
+
+	var meta []byte
+
+	conn.Command("HSET", sKey, "meta", redigomock.NewAnyData()).Handle(func(args []interface{}) (interface{}, error) {
+		meta, _ = args[2].([]byte)
+		return nil, nil
+	})
+	conn.GenericCommand("EXEC").ExpectSlice()
+
+	rc := r.pool.(*redis.Pool).Get()
+	_, ok, err := r.updateMetaTx(rc, "id123", map[string]string{"flag": "on"}, true)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	rc.Close()
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	require.True(t, isSealed(meta))
+
+	res, err := r.enc.open("meta", "id123", meta)
+	require.NoError(t, err)
+	assert.Equal(t, "flag=on", string(res))
+}
+
+func Test_RedisStore_RenewID(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+		CreatedAt: time.Now().UTC().Round(0),
+		IP:        net.ParseIP("127.0.0.1"),
+		Meta:      map[string]string{"test": "1"},
+	}
+	inp.Agent.OS = "gnu/linux"
+	inp.Agent.Browser = "firefox"
+
+	const newID = "id456"
+
+	expMilli := inp.ExpiresAt.UnixNano() / int64(time.Millisecond)
+
+	oldKey := prefix + ":session:" + inp.ID
+	newKey := prefix + ":session:" + newID
+	uKey := prefix + ":user:" + inp.UserKey
+	oldPKey := prefix + ":payload:" + inp.ID
+	newPKey := prefix + ":payload:" + newID
+
+	fields := map[string]string{
+		"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
+		"id":            inp.ID,
+		"user_key":      inp.UserKey,
+		"ip":            inp.IP.String(),
+		"agent_os":      inp.Agent.OS,
+		"agent_browser": inp.Agent.Browser,
+		"meta":          "test=1",
+	}
+
+	hmset := []interface{}{
+		newKey,
+		"created_at", inp.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at", inp.ExpiresAt.Format(time.RFC3339Nano),
+		"id", newID,
+		"user_key", inp.UserKey,
+		"ip", inp.IP.String(),
+		"agent_os", inp.Agent.OS,
+		"agent_browser", inp.Agent.Browser,
+		"meta", "test=1",
+		"schema_version", "1",
+	}
+
+	cc := map[string]struct {
+		Cancelled bool
+		Conn      func() (*redigomock.Conn, func(*testing.T))
+		Err       error
+	}{
+		"Cancelled context": {
+			Cancelled: true,
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: context.Canceled,
+		},
+		"Error returned during old session key watching": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Error returned during new session key watching": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Error returned during new session key check": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey)
+				conn.Command("EXISTS", newKey).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Duplicate ID": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey)
+				conn.Command("EXISTS", newKey).Expect(int64(1))
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: sessionup.ErrDuplicateID,
+		},
+		"Error returned during session fetch": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey)
+				conn.Command("EXISTS", newKey).Expect(int64(0))
+				conn.Command("HGETALL", oldKey).ExpectError(assert.AnError)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Not found": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey)
+				conn.Command("EXISTS", newKey).Expect(int64(0))
+				conn.Command("HGETALL", oldKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("UNWATCH")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Error returned during transaction creation": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey)
+				conn.Command("EXISTS", newKey).Expect(int64(0))
+				conn.Command("HGETALL", oldKey).ExpectMap(fields)
+				conn.Command("GET", oldPKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("MULTI").ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Error returned during new session creation": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey)
+				conn.Command("EXISTS", newKey).Expect(int64(0))
+				conn.Command("HGETALL", oldKey).ExpectMap(fields)
+				conn.Command("GET", oldPKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("MULTI")
+				conn.Command("HMSET", hmset...).ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Error returned during new session key expiration update": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey)
+				conn.Command("EXISTS", newKey).Expect(int64(0))
+				conn.Command("HGETALL", oldKey).ExpectMap(fields)
+				conn.Command("GET", oldPKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("MULTI")
+				conn.Command("HMSET", hmset...)
+				conn.Command("PEXPIREAT", newKey, expMilli).ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Error returned during old session key removal from user set": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey)
+				conn.Command("EXISTS", newKey).Expect(int64(0))
+				conn.Command("HGETALL", oldKey).ExpectMap(fields)
+				conn.Command("GET", oldPKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("MULTI")
+				conn.Command("HMSET", hmset...)
+				conn.Command("PEXPIREAT", newKey, expMilli)
+				conn.Command("ZREM", uKey, oldKey).ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Error returned during new session key addition to user set": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey)
+				conn.Command("EXISTS", newKey).Expect(int64(0))
+				conn.Command("HGETALL", oldKey).ExpectMap(fields)
+				conn.Command("GET", oldPKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("MULTI")
+				conn.Command("HMSET", hmset...)
+				conn.Command("PEXPIREAT", newKey, expMilli)
+				conn.Command("ZREM", uKey, oldKey)
+				conn.Command("ZADD", uKey, inp.ExpiresAt.UnixNano(), newKey).ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Error returned during old session key deletion": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey)
+				conn.Command("EXISTS", newKey).Expect(int64(0))
+				conn.Command("HGETALL", oldKey).ExpectMap(fields)
+				conn.Command("GET", oldPKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("MULTI")
+				conn.Command("HMSET", hmset...)
+				conn.Command("PEXPIREAT", newKey, expMilli)
+				conn.Command("ZREM", uKey, oldKey)
+				conn.Command("ZADD", uKey, inp.ExpiresAt.UnixNano(), newKey)
+				conn.Command("DEL", oldKey, oldPKey).ExpectError(assert.AnError)
+				conn.GenericCommand("DISCARD")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Error returned during transaction exec": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey)
+				conn.Command("EXISTS", newKey).Expect(int64(0))
+				conn.Command("HGETALL", oldKey).ExpectMap(fields)
+				conn.Command("GET", oldPKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("MULTI")
+				conn.Command("HMSET", hmset...)
+				conn.Command("PEXPIREAT", newKey, expMilli)
+				conn.Command("ZREM", uKey, oldKey)
+				conn.Command("ZADD", uKey, inp.ExpiresAt.UnixNano(), newKey)
+				conn.Command("DEL", oldKey, oldPKey)
+				conn.GenericCommand("EXEC").ExpectError(assert.AnError)
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: assert.AnError,
+		},
+		"Transaction conflict": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey)
+				conn.Command("EXISTS", newKey).Expect(int64(0))
+				conn.Command("HGETALL", oldKey).ExpectMap(fields)
+				conn.Command("GET", oldPKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("MULTI")
+				conn.Command("HMSET", hmset...)
+				conn.Command("PEXPIREAT", newKey, expMilli)
+				conn.Command("ZREM", uKey, oldKey)
+				conn.Command("ZADD", uKey, inp.ExpiresAt.UnixNano(), newKey)
+				conn.Command("DEL", oldKey, oldPKey)
+				conn.GenericCommand("EXEC")
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+			Err: ErrTxConflict,
+		},
+		"Successful execution": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey)
+				conn.Command("EXISTS", newKey).Expect(int64(0))
+				conn.Command("HGETALL", oldKey).ExpectMap(fields)
+				conn.Command("GET", oldPKey).ExpectError(redis.ErrNil)
+				conn.GenericCommand("MULTI")
+				conn.Command("HMSET", hmset...)
+				conn.Command("PEXPIREAT", newKey, expMilli)
+				conn.Command("ZREM", uKey, oldKey)
+				conn.Command("ZADD", uKey, inp.ExpiresAt.UnixNano(), newKey)
+				conn.Command("DEL", oldKey, oldPKey)
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+		"Successful execution with payload": {
+			Conn: func() (*redigomock.Conn, func(*testing.T)) {
+				conn := redigomock.NewConn()
+				conn.Command("WATCH", oldKey, oldPKey)
+				conn.Command("WATCH", newKey)
+				conn.Command("EXISTS", newKey).Expect(int64(0))
+				conn.Command("HGETALL", oldKey).ExpectMap(fields)
+				conn.Command("GET", oldPKey).Expect([]byte("data"))
+				conn.GenericCommand("MULTI")
+				conn.Command("HMSET", hmset...)
+				conn.Command("PEXPIREAT", newKey, expMilli)
+				conn.Command("ZREM", uKey, oldKey)
+				conn.Command("ZADD", uKey, inp.ExpiresAt.UnixNano(), newKey)
+				conn.Command("SET", newPKey, []byte("data"))
+				conn.Command("PEXPIREAT", newPKey, expMilli)
+				conn.Command("DEL", oldKey, oldPKey)
+				conn.GenericCommand("EXEC").ExpectSlice()
+
+				return conn, func(t *testing.T) {
+					err := conn.ExpectationsWereMet()
+					assert.NoError(t, err)
+				}
+			},
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			conn, check := c.Conn()
+
+			r := RedisStore{
+				pool: &redis.Pool{
+					Dial: func() (redis.Conn, error) {
+						return conn, nil
+					},
+					Wait:      true,
+					MaxActive: 10,
+				},
+				prefix:     prefix,
+				txAttempts: 1,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if c.Cancelled {
+				cancel()
+			}
+
+			err := r.RenewID(ctx, inp.ID, newID)
+			check(t)
+
+			if c.Err != nil {
+				assert.True(t, errors.Is(err, c.Err))
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_RedisStore_RenewID_invalidID(t *testing.T) {
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	err := r.RenewID(context.Background(), "id123", "")
+	assert.True(t, errors.Is(err, ErrInvalidSession))
+
+	err = r.RenewID(context.Background(), "id123", "id123")
+	assert.True(t, errors.Is(err, ErrInvalidSession))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_RenewID_indexes(t *testing.T) {
+	created := time.Now().UTC().Round(0)
+	expires := created.Add(time.Hour)
+
+	oldKey := prefix + ":session:id123"
+	newKey := prefix + ":session:id456"
+	ipKey := prefix + ":ip:127.0.0.1"
+	cKey := prefix + ":created:all"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithIPIndex(true), WithCreatedIndex(true))
+
+	conn.Command("WATCH", oldKey, prefix+":payload:id123")
+	conn.Command("WATCH", newKey)
+	conn.Command("EXISTS", newKey).Expect(int64(0))
+	conn.Command("HGETALL", oldKey).ExpectMap(map[string]string{
+		"created_at": created.Format(time.RFC3339Nano),
+		"expires_at": expires.Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+		"ip":         "127.0.0.1",
+	})
+	conn.Command("GET", prefix+":payload:id123").ExpectError(redis.ErrNil)
+	conn.Command("PTTL", ipKey).Expect(int64(-2))
+	conn.GenericCommand("MULTI")
+	conn.GenericCommand("HMSET")
+	conn.GenericCommand("PEXPIREAT")
+	conn.GenericCommand("ZREM")
+	conn.GenericCommand("ZADD")
+	ipRem := conn.Command("ZREM", ipKey, oldKey)
+	ipAdd := conn.Command("ZADD", ipKey, expires.UnixNano(), newKey)
+	ipExp := conn.Command("PEXPIREAT", ipKey, expires.UnixNano()/int64(time.Millisecond))
+	cRem := conn.Command("ZREM", cKey, oldKey)
+	cAdd := conn.Command("ZADD", cKey, created.UnixNano(), newKey)
+	conn.Command("DEL", oldKey, prefix+":payload:id123")
+	conn.GenericCommand("EXEC").ExpectSlice()
+
+	require.NoError(t, r.RenewID(context.Background(), "id123", "id456"))
+
+	for _, cmd := range []*redigomock.Cmd{ipRem, ipAdd, ipExp, cRem, cAdd} {
+		assert.Equal(t, 1, conn.Stats(cmd))
+	}
+}
+
+func Test_RedisStore_Close(t *testing.T) {
+	t.Run("Cancelled context", func(t *testing.T) {
+		r := New(&redis.Pool{}, prefix)
+		r.active.Add(1)
+		defer r.active.Done()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.Equal(t, context.Canceled, r.Close(ctx))
+		assert.True(t, r.closed)
+	})
+
+	t.Run("Successful close with in-flight operation", func(t *testing.T) {
+		conn := redigomock.NewConn()
+		r := New(&redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return conn, nil
+			},
+		}, prefix)
+
+		_, end, err := r.begin(context.Background(), "Op")
+		require.NoError(t, err)
+
+		done := make(chan error)
+		go func() {
+			done <- r.Close(context.Background())
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("store closed before in-flight operation finished")
+		case <-time.After(time.Millisecond * 20):
+		}
+
+		assert.NoError(t, end(nil))
+		assert.NoError(t, <-done)
+
+		_, _, err = r.FetchByID(context.Background(), "id")
+		assert.Equal(t, &Error{Op: "fetchByID", Kind: ErrClosed, Err: ErrClosed}, err)
+
+		// closing twice is allowed
+		assert.NoError(t, r.Close(context.Background()))
+	})
+
+	t.Run("Successful close of owned pool", func(t *testing.T) {
+		r, err := NewFromURL("redis://localhost:6379", prefix)
+		require.NoError(t, err)
+		assert.NoError(t, r.Close(context.Background()))
+
+		_, err = r.pool.(*redis.Pool).Get().Do("PING")
+		assert.EqualError(t, err, "redigo: get on closed pool")
+	})
+}
+
+func Test_RedisStore_retryTx(t *testing.T) {
+	cc := map[string]struct {
+		Cancelled bool
+		Errs      []error
+		Calls     int
+		Err       error
+	}{
+		"Cancelled context": {
+			Cancelled: true,
+			Errs:      []error{ErrTxConflict},
+			Calls:     1,
+			Err:       context.Canceled,
+		},
+		"Error returned by function": {
+			Errs:  []error{assert.AnError},
+			Calls: 1,
+			Err:   assert.AnError,
+		},
+		"Attempts exhausted": {
+			Errs:  []error{ErrTxConflict, ErrTxConflict, ErrTxConflict},
+			Calls: 3,
+			Err:   ErrTxConflict,
+		},
+		"Successful execution after conflict": {
+			Errs:  []error{ErrTxConflict, nil},
+			Calls: 2,
+		},
+		"Successful execution": {
+			Errs:  []error{nil},
+			Calls: 1,
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			r := RedisStore{
+				txAttempts: 3,
+				txBackoff:  time.Millisecond,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if c.Cancelled {
+				cancel()
+			}
+
+			var calls int
+
+			err := r.retryTx(ctx, func() error {
+				err := c.Errs[calls]
+				calls++
+
+				return err
+			})
+
+			assert.Equal(t, c.Calls, calls)
+			assert.Equal(t, c.Err, err)
+		})
+	}
+}
+
+func Test_RedisStore_waitReplicas(t *testing.T) {
+	conn := redigomock.NewConn()
+	r := RedisStore{}
+	assert.NoError(t, r.waitReplicas(conn))
+
+	r.replicas = 2
+	r.replicaTimeout = time.Second
+	conn.Command("WAIT", 2, int64(1000)).ExpectError(assert.AnError)
+	assert.Equal(t, assert.AnError, r.waitReplicas(conn))
+
+	conn.Clear()
+	conn.Command("WAIT", 2, int64(1000)).Expect(int64(1))
+	assert.Equal(t, ErrWriteConcern, r.waitReplicas(conn))
+
+	conn.Clear()
+	conn.Command("WAIT", 2, int64(1000)).Expect(int64(3))
+	assert.NoError(t, r.waitReplicas(conn))
+}
+
+func Test_exec(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.GenericCommand("EXEC").ExpectError(assert.AnError)
+	assert.Equal(t, assert.AnError, exec(conn))
+
+	conn = redigomock.NewConn()
+	conn.GenericCommand("EXEC")
+	assert.Equal(t, ErrTxConflict, exec(conn))
+
+	conn = redigomock.NewConn()
+	conn.GenericCommand("EXEC").ExpectSlice("OK")
+	assert.NoError(t, exec(conn))
+}
+
+func Test_WithJSON(t *testing.T) {
+	r := RedisStore{}
+	WithJSON(true)(&r)
+	assert.True(t, r.asJSON)
+}
+
+func Test_WithSessionOrder(t *testing.T) {
+	r := RedisStore{}
+	WithSessionOrder(ByCreatedDesc)(&r)
+	assert.Equal(t, ByCreatedDesc, r.order)
+}
+
+func Test_sortSessions(t *testing.T) {
+	now := time.Now()
+	inp := []sessionup.Session{
+		{ID: "id1", CreatedAt: now.Add(time.Minute)},
+		{ID: "id2", CreatedAt: now},
+		{ID: "id3", CreatedAt: now.Add(time.Hour)},
+		{ID: "id4", CreatedAt: now},
+	}
+
+	ids := func(order SessionOrder) []string {
+		ss := append([]sessionup.Session(nil), inp...)
+		sortSessions(ss, order, func(i int) time.Time { return ss[i].CreatedAt })
+
+		res := make([]string, len(ss))
+		for i := range ss {
+			res[i] = ss[i].ID
+		}
+
+		return res
+	}
+
+	assert.Equal(t, []string{"id1", "id2", "id3", "id4"}, ids(ByExpiration))
+	assert.Equal(t, []string{"id2", "id4", "id1", "id3"}, ids(ByCreatedAsc))
+	assert.Equal(t, []string{"id3", "id1", "id2", "id4"}, ids(ByCreatedDesc))
+}
+
+func Test_WithLeadingColon(t *testing.T) {
+	r := RedisStore{}
+	WithLeadingColon(true)(&r)
+	assert.True(t, r.leadingColon)
+}
+
+func Test_WithKeyFunc(t *testing.T) {
+	r := RedisStore{}
+	WithKeyFunc(func(namespace, value string) string {
+		return namespace + "/" + value
+	})(&r)
+	require.NotNil(t, r.keyFunc)
+	assert.Equal(t, "session/id", r.keyFunc("session", "id"))
+}
+
+func Test_RedisStore_fetchCmd(t *testing.T) {
+	r := RedisStore{}
+	assert.Equal(t, "HGETALL", r.fetchCmd())
+
+	r.asJSON = true
+	assert.Equal(t, "GET", r.fetchCmd())
+}
+
+func Test_RedisStore_encode(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+		CreatedAt: time.Now().UTC().Round(0),
+		IP:        net.ParseIP("127.0.0.1"),
+		Meta:      map[string]string{"test": "1"},
+	}
+
+	r := RedisStore{}
+	cmd, data, err := r.encode(inp)
+	assert.NoError(t, err)
+	assert.Equal(t, "HMSET", cmd)
+	assert.Equal(t, hashFields(inp, RFC3339Nano), data)
+
+	r.asJSON = true
+	cmd, data, err = r.encode(inp)
+	assert.NoError(t, err)
+	assert.Equal(t, "SET", cmd)
+	require.Len(t, data, 1)
+
+	res, err := parseJSON(data[0].([]byte), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, DetailedSession{Session: inp}, res)
+}
+
+func Test_RedisStore_encodeDetailed(t *testing.T) {
+	inp := DetailedSession{
+		Session: sessionup.Session{
+			UserKey:   "u123",
+			ID:        "id123",
+			ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+			CreatedAt: time.Now().UTC().Round(0),
+			IP:        net.ParseIP("127.0.0.1"),
+			Meta:      map[string]string{"test": "1"},
+		},
+		LastSeenAt: time.Now().UTC().Round(0),
+	}
+
+	r := RedisStore{}
+	cmd, data, err := r.encodeDetailed(inp)
+	assert.NoError(t, err)
+	assert.Equal(t, "HMSET", cmd)
+	assert.Equal(t, append(hashFields(inp.Session, RFC3339Nano), "last_seen_at", inp.LastSeenAt.Format(time.RFC3339Nano)), data)
+
+	r.asJSON = true
+	cmd, data, err = r.encodeDetailed(inp)
+	assert.NoError(t, err)
+	assert.Equal(t, "SET", cmd)
+	require.Len(t, data, 1)
+
+	res, err := parseJSON(data[0].([]byte), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, inp, res)
+}
+
+func Test_RedisStore_decode(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+		CreatedAt: time.Now().UTC().Round(0),
+		IP:        net.ParseIP("127.0.0.1"),
+	}
+
+	data, err := json.Marshal(toRecord(inp))
+	require.NoError(t, err)
+
+	cc := map[string]struct {
+		JSON  bool
+		Reply interface{}
+		Err   error
+		Found bool
+		Fail  bool
+	}{
+		"Error returned": {
+			Err:  assert.AnError,
+			Fail: true,
+		},
+		"Nil reply": {
+			Err: redis.ErrNil,
+		},
+		"Empty hash": {
+			Reply: []interface{}{},
+		},
+		"Invalid hash": {
+			Reply: []interface{}{[]byte("created_at"), []byte("123")},
+			Fail:  true,
+		},
+		"Successful hash decode": {
+			Reply: []interface{}{
+				[]byte("created_at"), []byte(inp.CreatedAt.Format(time.RFC3339Nano)),
+				[]byte("expires_at"), []byte(inp.ExpiresAt.Format(time.RFC3339Nano)),
+				[]byte("id"), []byte(inp.ID),
+				[]byte("user_key"), []byte(inp.UserKey),
+				[]byte("ip"), []byte(inp.IP.String()),
+			},
+			Found: true,
+		},
+		"Error returned in JSON mode": {
+			JSON: true,
+			Err:  assert.AnError,
+			Fail: true,
+		},
+		"Nil reply in JSON mode": {
+			JSON: true,
+			Err:  redis.ErrNil,
+		},
+		"Invalid JSON": {
+			JSON:  true,
+			Reply: []byte("{"),
+			Fail:  true,
+		},
+		"Successful JSON decode": {
+			JSON:  true,
+			Reply: data,
+			Found: true,
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			r := RedisStore{asJSON: c.JSON}
+
+			s, ok, err := r.decode("id123", c.Reply, c.Err)
+			if c.Fail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if c.Found {
+				assert.Equal(t, inp, s)
+			} else {
+				assert.Zero(t, s)
+			}
+
+			assert.Equal(t, c.Found, ok)
+		})
+	}
+}
+
+func Test_RedisStore_decodeDetailed(t *testing.T) {
+	inp := DetailedSession{
+		Session: sessionup.Session{
+			UserKey:   "u123",
+			ID:        "id123",
+			ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+			CreatedAt: time.Now().UTC().Round(0),
+			IP:        net.ParseIP("127.0.0.1"),
+		},
+		LastSeenAt: time.Now().UTC().Round(0),
+	}
+
+	fields := func(seen string) []interface{} {
+		return []interface{}{
+			[]byte("created_at"), []byte(inp.CreatedAt.Format(time.RFC3339Nano)),
+			[]byte("expires_at"), []byte(inp.ExpiresAt.Format(time.RFC3339Nano)),
+			[]byte("id"), []byte(inp.ID),
+			[]byte("user_key"), []byte(inp.UserKey),
+			[]byte("ip"), []byte(inp.IP.String()),
+			[]byte("last_seen_at"), []byte(seen),
+		}
+	}
+
+	rec := toRecord(inp.Session)
+	rec.LastSeenAt = &inp.LastSeenAt
+
+	data, err := json.Marshal(rec)
+	require.NoError(t, err)
+
+	cc := map[string]struct {
+		JSON   bool
+		Reply  interface{}
+		Result DetailedSession
+		Fail   bool
+	}{
+		"Invalid last seen time": {
+			Reply: fields("invalid"),
+			Fail:  true,
+		},
+		"Successful hash decode without last seen time": {
+			Reply:  fields(""),
+			Result: DetailedSession{Session: inp.Session},
+		},
+		"Successful hash decode": {
+			Reply:  fields(inp.LastSeenAt.Format(time.RFC3339Nano)),
+			Result: inp,
+		},
+		"Successful JSON decode": {
+			JSON:   true,
+			Reply:  data,
+			Result: inp,
+		},
+	}
+
+	for cn, c := range cc {
+		c := c
+
+		t.Run(cn, func(t *testing.T) {
+			t.Parallel()
+
+			r := RedisStore{asJSON: c.JSON}
+
+			d, ok, err := r.decodeDetailed("id123", c.Reply, nil)
+			if c.Fail {
+				assert.Error(t, err)
+				assert.Zero(t, d)
+				assert.False(t, ok)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, c.Result, d)
+		})
+	}
+}
+
+func Test_RedisStore_decodeDetailed_expired(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(-time.Second).Round(0),
+		CreatedAt: time.Now().UTC().Add(-time.Hour).Round(0),
+	}
+
+	data, err := json.Marshal(toRecord(inp))
+	require.NoError(t, err)
+
+	r := RedisStore{asJSON: true, ttlJitter: time.Minute}
+	d, ok, err := r.decodeDetailed("id123", data, nil)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Zero(t, d)
+
+	r.asJSON = false
+	d, ok, err = r.decodeDetailed("id123", []interface{}{
+		[]byte("created_at"), []byte(inp.CreatedAt.Format(time.RFC3339Nano)),
+		[]byte("expires_at"), []byte(inp.ExpiresAt.Format(time.RFC3339Nano)),
+		[]byte("id"), []byte(inp.ID),
+		[]byte("user_key"), []byte(inp.UserKey),
+	}, nil)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Zero(t, d)
+}
+
+func Test_RedisStore_expireAt(t *testing.T) {
+	exp := time.Now().Add(time.Hour)
+	ms := exp.UnixNano() / int64(time.Millisecond)
+
+	r := RedisStore{}
+	assert.Equal(t, ms, r.expireAt(exp))
+
+	r.ttlJitter = time.Second
+
+	for i := 0; i < 100; i++ {
+		v := r.expireAt(exp)
+		assert.True(t, v >= ms)
+		assert.True(t, v <= ms+1000)
+	}
+}
+
+func Test_RedisStore_expired(t *testing.T) {
+	r := RedisStore{}
+	assert.False(t, r.expired(sessionup.Session{ExpiresAt: time.Now().Add(-time.Hour)}))
+
+	r.ttlJitter = time.Minute
+	assert.True(t, r.expired(sessionup.Session{ExpiresAt: time.Now().Add(-time.Hour)}))
+	assert.False(t, r.expired(sessionup.Session{ExpiresAt: time.Now().Add(time.Hour)}))
+}
+
+func Test_RedisStore_key(t *testing.T) {
+	r := RedisStore{prefix: "test"}
+	assert.Equal(t, "test:session:hello", r.key(nil, false, "hello"))
+	assert.Equal(t, "test:user:hello", r.key(nil, true, "hello"))
+
+	c := &scopedConn{tenant: "t1"}
+	assert.Equal(t, "test:t1:session:hello", r.key(c, false, "hello"))
+	assert.Equal(t, "test:t1:user:hello", r.key(c, true, "hello"))
+
+	assert.Equal(t, "test:user:org%3A123", r.key(nil, true, "org:123"))
+	assert.Equal(t, "test:a%3Ab:session:100%25%3A1", r.key(&scopedConn{tenant: "a:b"}, false, "100%:1"))
+
+	r = RedisStore{}
+	assert.Equal(t, "session:hello", r.key(nil, false, "hello"))
+	assert.Equal(t, "t1:user:hello", r.key(c, true, "hello"))
+
+	r.leadingColon = true
+	assert.Equal(t, ":session:hello", r.key(nil, false, "hello"))
+	assert.Equal(t, ":t1:user:hello", r.key(c, true, "hello"))
+}
+
+func Test_RedisStore_prefixed(t *testing.T) {
+	r := RedisStore{}
+	assert.Equal(t, "audit", r.prefixed("", "audit"))
+	assert.Equal(t, "p:audit", r.prefixed("p", "audit"))
+
+	r.leadingColon = true
+	assert.Equal(t, ":audit", r.prefixed("", "audit"))
+	assert.Equal(t, "p:audit", r.prefixed("p", "audit"))
+}
+
+func Test_keyEscaper(t *testing.T) {
+	for _, v := range []string{"", "abc", "org:123", "a%3Ab", "::%%", "%253A"} {
+		esc := keyEscaper.Replace(v)
+		assert.NotContains(t, esc, ":")
+		assert.Equal(t, v, keyUnescaper.Replace(esc))
+	}
+}
+
+func Test_RedisStore_pattern(t *testing.T) {
+	r := RedisStore{prefix: prefix}
+	assert.Equal(t, prefix+":session:*", r.pattern(nil, false))
+	assert.Equal(t, prefix+":user:*", r.pattern(nil, true))
+
+	r = RedisStore{prefix: `a*b?[c]\`}
+	assert.Equal(t, `a\*b\?\[c\]\\:user:*`, r.pattern(nil, true))
+	assert.Equal(t, `a\*b\?\[c\]\\:t\*:session:*`, r.pattern(&scopedConn{tenant: "t*"}, false))
+}
+
+func Test_RedisStore_buildKey(t *testing.T) {
+	r := RedisStore{prefix: "test"}
+	assert.Equal(t, "test:revoked:hello", r.buildKey("", "revoked", "hello"))
+	assert.Equal(t, "test:t1:revoked:hello", r.buildKey("t1", "revoked", "hello"))
+
+	r.keyFunc = func(namespace, value string) string {
+		return "app:{prod}:" + namespace + "s:" + value
+	}
+
+	assert.Equal(t, "app:{prod}:sessions:hello", r.buildKey("", "session", "hello"))
+	assert.Equal(t, "app:{prod}:t1:users:hello", r.buildKey("t1", "user", "hello"))
+	assert.Equal(t, "app:{prod}:t%3A1:users:org%3A1", r.buildKey("t:1", "user", "org:1"))
+	assert.Equal(t, "app:{prod}:sessions:*", r.pattern(nil, false))
+}
+
+func Test_scan(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.Command("SCAN", int64(0), "MATCH", "p:*", "COUNT", scanCount).
+		ExpectSlice([]byte("7"), []interface{}{[]byte("k1"), []byte("k2")})
+	conn.Command("SCAN", int64(7), "MATCH", "p:*", "COUNT", scanCount).
+		ExpectSlice([]byte("9"), []interface{}{})
+	conn.Command("SCAN", int64(9), "MATCH", "p:*", "COUNT", scanCount).
+		ExpectSlice([]byte("0"), []interface{}{[]byte("k3")})
+
+	var keys []string
+	err := scan(context.Background(), conn, "p:*", func(kk []string) error {
+		keys = append(keys, kk...)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"k1", "k2", "k3"}, keys)
+
+	err = scan(context.Background(), conn, "p:*", func([]string) error {
+		return assert.AnError
+	})
+	assert.Equal(t, assert.AnError, err)
+
+	conn.Command("SCAN", int64(0), "MATCH", "e:*", "COUNT", scanCount).ExpectError(assert.AnError)
+	err = scan(context.Background(), conn, "e:*", func([]string) error {
+		return nil
+	})
+	assert.Equal(t, assert.AnError, err)
+
+	conn.Command("SCAN", int64(0), "MATCH", "i:*", "COUNT", scanCount).Expect([]interface{}{[]byte("x")})
+	err = scan(context.Background(), conn, "i:*", func([]string) error {
+		return nil
+	})
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = scan(ctx, conn, "p:*", func([]string) error {
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func Test_ipToString(t *testing.T) {
+	assert.Equal(t, "", ipToString(nil))
+	assert.Equal(t, "", ipToString(net.IP{1, 2, 3}))
+	assert.Equal(t, "127.0.0.1", ipToString(net.ParseIP("127.0.0.1")))
+	assert.Equal(t, "127.0.0.1", ipToString(net.IPv4(127, 0, 0, 1).To4()))
+	assert.Equal(t, "2001:db8::1", ipToString(net.ParseIP("2001:0db8:0000::0001")))
+	assert.Nil(t, normalizeIP(net.IP{1, 2, 3}))
+}
+
+func Test_hashFields_nilIP(t *testing.T) {
+	ff := hashFields(sessionup.Session{ID: "id123"}, RFC3339Nano)
+
+	for i := 0; i < len(ff); i += 2 {
+		if ff[i] == "ip" {
+			assert.Equal(t, "", ff[i+1])
+		}
+	}
+
+	// legacy placeholder written for nil addresses
+	s, err := parse(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Format(time.RFC3339Nano),
+		"ip":         "<nil>",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, s.IP)
+}
+
+func Test_parse(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+		CreatedAt: time.Now().UTC().Round(0),
+		IP:        net.ParseIP("127.0.0.1"),
+	}
+	inp.Agent.OS = "gnu/linux"
+	inp.Agent.Browser = "firefox"
+
+	cc := map[string]struct {
+		Data map[string]string
+		Err  bool
+	}{
+		"Invalid creation format": {
+			Data: map[string]string{
+				"user_key":      inp.UserKey,
+				"id":            inp.ID,
+				"created_at":    "invalid",
 				"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
 				"ip":            inp.IP.String(),
 				"agent_os":      inp.Agent.OS,
@@ -1477,10 +5259,23 @@ func Test_parse(t *testing.T) {
 				"user_key":      inp.UserKey,
 				"id":            inp.ID,
 				"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
-				"expires_at":    "123",
+				"expires_at":    "invalid",
+				"ip":            inp.IP.String(),
+				"agent_os":      inp.Agent.OS,
+				"agent_browser": inp.Agent.Browser,
+			},
+			Err: true,
+		},
+		"Invalid meta format": {
+			Data: map[string]string{
+				"user_key":      inp.UserKey,
+				"id":            inp.ID,
+				"created_at":    inp.CreatedAt.Format(time.RFC3339Nano),
+				"expires_at":    inp.ExpiresAt.Format(time.RFC3339Nano),
 				"ip":            inp.IP.String(),
 				"agent_os":      inp.Agent.OS,
 				"agent_browser": inp.Agent.Browser,
+				"meta":          "%zz",
 			},
 			Err: true,
 		},
@@ -1517,22 +5312,135 @@ func Test_parse(t *testing.T) {
 	}
 }
 
+func Test_parseJSON(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+		CreatedAt: time.Now().UTC().Round(0),
+		IP:        net.ParseIP("127.0.0.1"),
+		Meta:      map[string]string{"test": "1", "url": "https://example.com/?a=b;c"},
+	}
+	inp.Agent.OS = "gnu/linux"
+	inp.Agent.Browser = "firefox"
+
+	res, err := parseJSON([]byte("{"), nil)
+	assert.Error(t, err)
+	assert.Zero(t, res)
+
+	data, err := json.Marshal(toRecord(inp))
+	require.NoError(t, err)
+
+	res, err = parseJSON(data, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, DetailedSession{Session: inp}, res)
+
+	d := DetailedSession{Session: inp, Login: Login{Method: "passkey", Provider: "google", MFALevel: 2}}
+
+	data, err = json.Marshal(toDetailedRecord(d))
+	require.NoError(t, err)
+
+	res, err = parseJSON(data, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, d, res)
+}
+
 func Test_metaToString(t *testing.T) {
 	assert.Zero(t, metaToString(nil))
 
-	m := map[string]string{"": "1", "key": "", "test1": "2", "3": "", "hello": "hello"}
-	s := metaToString(m)
-	assert.Contains(t, s, ":1;")
-	assert.Contains(t, s, "test1:2;")
-	assert.Contains(t, s, "key:;")
-	assert.Contains(t, s, "hello:hello;")
+	m := map[string]string{"": "1", "key": "", "test1": "2", "hello": "hello", "url": "https://example.com/?a=b&c=d;e:f"}
+	assert.Equal(t, "=1&hello=hello&key=&test1=2&url=https%3A%2F%2Fexample.com%2F%3Fa%3Db%26c%3Dd%3Be%3Af", metaToString(m))
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, metaToString(m), metaToString(map[string]string{
+			"url": m["url"], "hello": "hello", "test1": "2", "key": "", "": "1",
+		}))
+	}
 }
 
 func Test_metaFromString(t *testing.T) {
-	m := metaFromString("")
+	m, err := metaFromString("")
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+
+	m, err = metaFromString("test=1&%zz=1")
+	assert.Error(t, err)
 	assert.Nil(t, m)
 
-	s := "test:1;:;3:3;"
-	m = metaFromString(s)
+	m, err = metaFromString("test:1;:;3:3;")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"test": "1", "": "", "3": "3"}, m)
+
+	inp := map[string]string{"test": "1", "": "", "key:1;": "a;b:c", "url": "https://example.com/?a=b&c=d"}
+	m, err = metaFromString(metaToString(inp))
+	assert.NoError(t, err)
+	assert.Equal(t, inp, m)
+}
+
+func Test_legacyMetaFromString(t *testing.T) {
+	m := legacyMetaFromString("test:1;:;3:3;invalid;")
 	assert.Equal(t, map[string]string{"test": "1", "": "", "3": "3"}, m)
 }
+
+// replyConn is a connection that replies to every command with the
+// same value, so that benchmarks measure only the store's allocations.
+type replyConn struct {
+	redis.Conn
+	reply interface{}
+}
+
+func (c replyConn) Do(string, ...interface{}) (interface{}, error) {
+	return c.reply, nil
+}
+
+func (c replyConn) Close() error {
+	return nil
+}
+
+func (c replyConn) Err() error {
+	return nil
+}
+
+func Benchmark_RedisStore_key(b *testing.B) {
+	r := RedisStore{prefix: prefix}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_ = r.key(nil, false, "id123")
+	}
+}
+
+func Benchmark_RedisStore_FetchByID(b *testing.B) {
+	var reply []interface{}
+
+	for k, v := range map[string]string{
+		"created_at":    time.Now().UTC().Format(time.RFC3339Nano),
+		"expires_at":    time.Now().UTC().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":            "id123",
+		"user_key":      "u123",
+		"ip":            "127.0.0.1",
+		"agent_os":      "gnu/linux",
+		"agent_browser": "firefox",
+	} {
+		reply = append(reply, []byte(k), []byte(v))
+	}
+
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return replyConn{reply: reply}, nil
+		},
+		MaxIdle: 1,
+	}, prefix)
+
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, _, err := r.FetchByID(ctx, "id123"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package redisstore
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// WithTenantFromContext enables scoping of keys per tenant. The tenant
+// returned by fn for the context of each operation is added to all
+// keys used by it, e.g. "<prefix>:<tenant>:session:<id>", so that
+// sessions of one tenant can neither be retrieved nor deleted on
+// behalf of another. Operations that span the whole store (FetchAll,
+// DeleteAll, Cleanup, Stats, Export, Rekey, Subscribe, CreateIndex and
+// FetchWhere) are scoped to the tenant of their context as well.
+// Keys are not scoped when fn returns an empty string.
+// Defaults to disabled.
+func WithTenantFromContext(fn func(context.Context) string) setter {
+	return func(r *RedisStore) {
+		r.tenant = fn
+	}
+}
+
+// scopedConn is a connection used by an operation that is scoped to
+// a tenant.
+type scopedConn struct {
+	redis.Conn
+	tenant string
+}
+
+// tenantOf returns the tenant derived from the provided context or an
+// empty string if keys are not scoped.
+func (r *RedisStore) tenantOf(ctx context.Context) string {
+	if r.tenant == nil {
+		return ""
+	}
+
+	return r.tenant(ctx)
+}
+
+// scope wraps the connection so that all keys prepared for it are
+// scoped to the tenant derived from the provided context.
+func (r *RedisStore) scope(ctx context.Context, c redis.Conn) redis.Conn {
+	tenant := r.tenantOf(ctx)
+	if tenant == "" {
+		return c
+	}
+
+	return &scopedConn{Conn: c, tenant: tenant}
+}
+
+// connTenant returns the tenant the connection is scoped to.
+func connTenant(c redis.Conn) string {
+	if sc, ok := c.(*scopedConn); ok {
+		return sc.tenant
+	}
+
+	return ""
+}
+
+// scopedPrefix returns the prefix of all keys of the provided tenant.
+func (r *RedisStore) scopedPrefix(tenant string) string {
+	if tenant == "" {
+		return r.prefix
+	}
+
+	return r.prefixed(r.prefix, keyEscaper.Replace(tenant))
+}
@@ -0,0 +1,93 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tenantCtxKey struct{}
+
+func tenantFromCtx(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantCtxKey{}).(string)
+	return tenant
+}
+
+func Test_WithTenantFromContext(t *testing.T) {
+	r := RedisStore{}
+	WithTenantFromContext(tenantFromCtx)(&r)
+	require.NotNil(t, r.tenant)
+	assert.Equal(t, "t1", r.tenant(context.WithValue(context.Background(), tenantCtxKey{}, "t1")))
+}
+
+func Test_RedisStore_tenantOf(t *testing.T) {
+	ctx := context.WithValue(context.Background(), tenantCtxKey{}, "t1")
+
+	r := RedisStore{}
+	assert.Zero(t, r.tenantOf(ctx))
+
+	r.tenant = tenantFromCtx
+	assert.Equal(t, "t1", r.tenantOf(ctx))
+	assert.Zero(t, r.tenantOf(context.Background()))
+}
+
+func Test_RedisStore_scope(t *testing.T) {
+	conn := redigomock.NewConn()
+	r := RedisStore{tenant: tenantFromCtx}
+
+	assert.Equal(t, conn, r.scope(context.Background(), conn))
+
+	c := r.scope(context.WithValue(context.Background(), tenantCtxKey{}, "t1"), conn)
+	assert.Equal(t, &scopedConn{Conn: conn, tenant: "t1"}, c)
+	assert.Equal(t, "t1", connTenant(c))
+	assert.Zero(t, connTenant(conn))
+}
+
+func Test_RedisStore_scopedPrefix(t *testing.T) {
+	r := RedisStore{prefix: prefix}
+	assert.Equal(t, prefix, r.scopedPrefix(""))
+	assert.Equal(t, prefix+":t1", r.scopedPrefix("t1"))
+}
+
+func Test_RedisStore_tenantScoping(t *testing.T) {
+	sKey := prefix + ":t1:session:id123"
+	uKey := prefix + ":t1:user:u123"
+	ctx := context.WithValue(context.Background(), tenantCtxKey{}, "t1")
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithTenantFromContext(tenantFromCtx))
+	r.disableScripts()
+
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+
+	s, ok, err := r.FetchByID(ctx, "id123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "id123", s.ID)
+
+	conn.Clear()
+	conn.Command("WATCH", uKey)
+	conn.Command("ZRANGEBYSCORE", uKey, "-inf", "+inf").
+		ExpectSlice([]byte(sKey), []byte(prefix+":t1:session:id124"))
+	conn.GenericCommand("MULTI")
+	conn.Command("DEL", prefix+":t1:session:id124", prefix+":t1:payload:id124")
+	conn.Command("ZREM", uKey, prefix+":t1:session:id124")
+	conn.GenericCommand("EXEC").ExpectSlice()
+
+	require.NoError(t, r.DeleteByUserKey(ctx, "u123", "id123"))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
@@ -0,0 +1,82 @@
+package redisstore
+
+import (
+	"strconv"
+	"time"
+)
+
+// TimeFormat determines how times are written into session hashes.
+type TimeFormat int
+
+const (
+	// RFC3339Nano writes times as RFC 3339 strings with nanosecond
+	// precision, e.g. "2006-01-02T15:04:05.999999999Z".
+	RFC3339Nano TimeFormat = iota
+
+	// UnixMilli writes times as integer numbers of milliseconds
+	// elapsed since January 1, 1970 UTC. Sub-millisecond precision
+	// is lost.
+	UnixMilli
+)
+
+// WithTimeFormat sets the format of the created_at, expires_at and
+// last_seen_at fields of session hashes. Integer times are easier to
+// parse by other (non-Go) services reading the same hashes and can be
+// compared by Redis; they are also indexed as numeric fields by
+// CreateIndex. Times in both formats are recognized when they are
+// read, hence the format can be changed at any time.
+// Sessions stored as JSON values are not affected.
+// Defaults to RFC3339Nano.
+func WithTimeFormat(f TimeFormat) setter {
+	return func(r *RedisStore) {
+		r.timeFormat = f
+	}
+}
+
+// formatTime converts the provided time into the configured format.
+func (r *RedisStore) formatTime(t time.Time) string {
+	return formatTime(t, r.timeFormat)
+}
+
+// formatTime converts the provided time into the specified format.
+func formatTime(t time.Time, f TimeFormat) string {
+	if f == UnixMilli {
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	}
+
+	return t.Format(time.RFC3339Nano)
+}
+
+// parseTime converts a time written in any of the supported formats
+// into time structure. Integer times are returned in UTC.
+func parseTime(v string) (time.Time, error) {
+	// RFC 3339 values are not passed to ParseInt, since its syntax
+	// errors are allocated
+	if isInteger(v) {
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(0, ms*int64(time.Millisecond)).UTC(), nil
+		}
+	}
+
+	return time.Parse(time.RFC3339Nano, v)
+}
+
+// isInteger checks whether the value consists of decimal digits,
+// optionally preceded by a sign.
+func isInteger(v string) bool {
+	if v != "" && (v[0] == '-' || v[0] == '+') {
+		v = v[1:]
+	}
+
+	if v == "" {
+		return false
+	}
+
+	for i := 0; i < len(v); i++ {
+		if v[i] < '0' || v[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,70 @@
+package redisstore
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithTimeFormat(t *testing.T) {
+	r := RedisStore{}
+	WithTimeFormat(UnixMilli)(&r)
+	assert.Equal(t, UnixMilli, r.timeFormat)
+}
+
+func Test_formatTime(t *testing.T) {
+	tm := time.Date(2021, 3, 4, 5, 6, 7, 8009000, time.UTC)
+
+	assert.Equal(t, "2021-03-04T05:06:07.008009Z", formatTime(tm, RFC3339Nano))
+	assert.Equal(t, "1614834367008", formatTime(tm, UnixMilli))
+	assert.Equal(t, "1614834367008", (&RedisStore{timeFormat: UnixMilli}).formatTime(tm))
+}
+
+func Test_parseTime(t *testing.T) {
+	tm := time.Date(2021, 3, 4, 5, 6, 7, 8009000, time.UTC)
+
+	res, err := parseTime("2021-03-04T05:06:07.008009Z")
+	require.NoError(t, err)
+	assert.Equal(t, tm, res)
+
+	res, err = parseTime("1614834367008")
+	require.NoError(t, err)
+	assert.Equal(t, tm.Truncate(time.Millisecond), res)
+
+	_, err = parseTime("invalid")
+	assert.Error(t, err)
+}
+
+func Test_RedisStore_encodeDetailed_unixMilli(t *testing.T) {
+	d := DetailedSession{
+		Session: sessionup.Session{
+			CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+			ExpiresAt: time.Now().UTC().Add(time.Hour).Truncate(time.Millisecond),
+			ID:        "id123",
+			UserKey:   "u123",
+			IP:        net.ParseIP("127.0.0.1"),
+		},
+		LastSeenAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+
+	r := RedisStore{timeFormat: UnixMilli}
+
+	_, data, err := r.encodeDetailed(d)
+	require.NoError(t, err)
+	assert.Equal(t, formatTime(d.CreatedAt, UnixMilli), data[1])
+	assert.Equal(t, formatTime(d.ExpiresAt, UnixMilli), data[3])
+
+	reply := make([]interface{}, len(data))
+	for i := range data {
+		reply[i] = []byte(data[i].(string))
+	}
+
+	res, ok, err := r.decodeDetailed("id123", reply, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, d, res)
+}
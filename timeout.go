@@ -0,0 +1,112 @@
+package redisstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// WithCommandTimeout sets the maximum time each Redis command may take
+// to complete. The timeout is shortened to the remaining time until
+// the operation's context deadline, if it has one, so that a hung
+// Redis node cannot stall an operation past its deadline.
+// Commands that time out fail with an ErrConnection error.
+// Connections that do not support read timeouts (see
+// redis.ConnWithTimeout), e.g. ones returned by custom dialers, are
+// used without them; their commands are not executed once the
+// context is done, but are not interrupted while running.
+// Defaults to 0 (commands are bounded only by the context deadline and
+// the read timeouts of the pool's connections).
+func WithCommandTimeout(d time.Duration) setter {
+	return func(r *RedisStore) {
+		r.cmdTimeout = d
+	}
+}
+
+// timeoutConn executes commands with a read timeout derived from the
+// command timeout and the context deadline. Commands are not executed
+// once the context is done.
+type timeoutConn struct {
+	redis.Conn
+	ctx     context.Context
+	timeout time.Duration
+}
+
+// withTimeout wraps the connection so that its commands time out,
+// if the command timeout is enabled or the context can be cancelled.
+func (r *RedisStore) withTimeout(ctx context.Context, c redis.Conn) redis.Conn {
+	if r.cmdTimeout <= 0 && ctx.Done() == nil {
+		return c
+	}
+
+	return &timeoutConn{Conn: c, ctx: ctx, timeout: r.cmdTimeout}
+}
+
+// Do executes the command with the remaining timeout.
+func (c *timeoutConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	t, err := c.remaining()
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.supported(t) {
+		return c.Conn.Do(cmd, args...)
+	}
+
+	return redis.DoWithTimeout(c.Conn, t, cmd, args...)
+}
+
+// Receive receives a pending reply with the remaining timeout.
+func (c *timeoutConn) Receive() (interface{}, error) {
+	t, err := c.remaining()
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.supported(t) {
+		return c.Conn.Receive()
+	}
+
+	return redis.ReceiveWithTimeout(c.Conn, t)
+}
+
+// supported reports whether the next command should be executed with
+// the provided timeout. Connections that do not support read timeouts
+// (e.g. ones returned by custom dialers) are used without them, so
+// that the context is only checked before each command.
+func (c *timeoutConn) supported(t time.Duration) bool {
+	if t == 0 {
+		return false
+	}
+
+	_, ok := c.Conn.(redis.ConnWithTimeout)
+
+	return ok
+}
+
+// remaining returns the timeout of the next command; 0 means no
+// timeout. An error is returned if the context is done or its
+// deadline has already passed.
+func (c *timeoutConn) remaining() (time.Duration, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	t := c.timeout
+
+	dl, ok := c.ctx.Deadline()
+	if !ok {
+		return t, nil
+	}
+
+	if rem := time.Until(dl); t <= 0 || rem < t {
+		t = rem
+	}
+
+	if t <= 0 {
+		return 0, context.DeadlineExceeded
+	}
+
+	return t, nil
+}
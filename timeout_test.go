@@ -0,0 +1,142 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithCommandTimeout(t *testing.T) {
+	r := RedisStore{}
+	WithCommandTimeout(time.Second)(&r)
+	assert.Equal(t, time.Second, r.cmdTimeout)
+}
+
+func Test_RedisStore_withTimeout(t *testing.T) {
+	conn := redigomock.NewConn()
+
+	r := RedisStore{}
+	assert.Equal(t, conn, r.withTimeout(context.Background(), conn))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert.Equal(t, &timeoutConn{Conn: conn, ctx: ctx}, r.withTimeout(ctx, conn))
+
+	r.cmdTimeout = time.Second
+	assert.Equal(t, &timeoutConn{Conn: conn, ctx: context.Background(), timeout: time.Second},
+		r.withTimeout(context.Background(), conn))
+}
+
+func Test_timeoutConn(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.Command("GET", "key").Expect("value")
+	conn.Command("DEL", "key").Expect(int64(1))
+
+	c := &timeoutConn{Conn: conn, ctx: context.Background(), timeout: time.Second}
+
+	v, err := redis.String(c.Do("GET", "key"))
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+
+	require.NoError(t, c.Send("DEL", "key"))
+	require.NoError(t, c.Flush())
+
+	n, err := redis.Int64(c.Receive())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	c.ctx = ctx
+
+	_, err = c.Do("GET", "key")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	_, err = c.Receive()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// connections without read timeouts are used without them, while
+	// the context is still checked before each command
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	c = &timeoutConn{Conn: struct{ redis.Conn }{conn}, ctx: ctx, timeout: time.Second}
+
+	v, err = redis.String(c.Do("GET", "key"))
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+
+	require.NoError(t, c.Send("DEL", "key"))
+	require.NoError(t, c.Flush())
+
+	n, err = redis.Int64(c.Receive())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	c.ctx = ctx
+
+	_, err = c.Do("GET", "key")
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func Test_timeoutConn_supported(t *testing.T) {
+	conn := redigomock.NewConn()
+
+	c := &timeoutConn{Conn: conn}
+	assert.False(t, c.supported(0))
+	assert.True(t, c.supported(time.Second))
+
+	c.Conn = struct{ redis.Conn }{conn}
+	assert.False(t, c.supported(time.Second))
+
+	c.timeout = time.Second
+	assert.False(t, c.supported(time.Second))
+}
+
+func Test_timeoutConn_remaining(t *testing.T) {
+	c := &timeoutConn{ctx: context.Background(), timeout: time.Hour}
+
+	d, err := c.remaining()
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, d)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	c.ctx = ctx
+
+	d, err = c.remaining()
+	require.NoError(t, err)
+	assert.True(t, d <= time.Minute && d > 0)
+
+	c.timeout = 0
+
+	d, err = c.remaining()
+	require.NoError(t, err)
+	assert.True(t, d <= time.Minute && d > 0)
+
+	c.ctx = context.Background()
+
+	d, err = c.remaining()
+	require.NoError(t, err)
+	assert.Zero(t, d)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	c.ctx = ctx
+
+	_, err = c.remaining()
+	assert.True(t, errors.Is(err, context.Canceled))
+}
@@ -0,0 +1,65 @@
+package redisstore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// WithTLSConfig sets the TLS configuration used to connect to Redis
+// by stores that manage their own connections (see NewFromURL).
+// The configuration is cloned, so later changes to it have no effect;
+// it can be further adjusted by the other TLS options, as long as they
+// are provided after this one.
+// Setting it enables TLS, even if the URL's scheme is redis (it is
+// replaced with rediss).
+// It has no effect on stores that use the provided pool or dialer.
+// Defaults to nil (TLS is used only with rediss URLs, with the
+// system's root CAs).
+func WithTLSConfig(cfg *tls.Config) setter {
+	return func(r *RedisStore) {
+		r.tlsConfig = cfg.Clone()
+	}
+}
+
+// WithRootCAs sets the certificate authorities used to verify the
+// certificate of the Redis server, which is usually needed by managed
+// Redis services that use private CAs. It enables TLS in the same way
+// as WithTLSConfig.
+// Defaults to the system's root CAs.
+func WithRootCAs(pool *x509.CertPool) setter {
+	return func(r *RedisStore) {
+		r.tlsConf().RootCAs = pool
+	}
+}
+
+// WithClientCert sets the certificate presented to the Redis server,
+// if it requires mutual TLS authentication. It enables TLS in the
+// same way as WithTLSConfig.
+// The certificate can be loaded with tls.LoadX509KeyPair.
+func WithClientCert(cert tls.Certificate) setter {
+	return func(r *RedisStore) {
+		cfg := r.tlsConf()
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+}
+
+// WithServerName sets the name used to verify the certificate of the
+// Redis server (and sent with SNI), if it differs from the host of the
+// URL (e.g. when connecting through an IP address or a tunnel). It
+// enables TLS in the same way as WithTLSConfig.
+// Defaults to the host of the URL.
+func WithServerName(name string) setter {
+	return func(r *RedisStore) {
+		r.tlsConf().ServerName = name
+	}
+}
+
+// tlsConf returns the store's TLS configuration, creating it if it is not
+// set yet.
+func (r *RedisStore) tlsConf() *tls.Config {
+	if r.tlsConfig == nil {
+		r.tlsConfig = &tls.Config{}
+	}
+
+	return r.tlsConfig
+}
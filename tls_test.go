@@ -0,0 +1,87 @@
+package redisstore
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithTLSConfig(t *testing.T) {
+	cfg := &tls.Config{ServerName: "redis.local"}
+
+	r := RedisStore{}
+	WithTLSConfig(cfg)(&r)
+	require.NotNil(t, r.tlsConfig)
+	assert.Equal(t, "redis.local", r.tlsConfig.ServerName)
+
+	cfg.ServerName = "other.local"
+	assert.Equal(t, "redis.local", r.tlsConfig.ServerName)
+}
+
+func Test_WithRootCAs(t *testing.T) {
+	pool := x509.NewCertPool()
+
+	r := RedisStore{}
+	WithRootCAs(pool)(&r)
+	require.NotNil(t, r.tlsConfig)
+	assert.Equal(t, pool, r.tlsConfig.RootCAs)
+}
+
+func Test_WithClientCert(t *testing.T) {
+	cert := tls.Certificate{Certificate: [][]byte{[]byte("cert")}}
+
+	r := RedisStore{}
+	WithClientCert(cert)(&r)
+	WithClientCert(cert)(&r)
+	require.NotNil(t, r.tlsConfig)
+	assert.Equal(t, []tls.Certificate{cert, cert}, r.tlsConfig.Certificates)
+}
+
+func Test_WithServerName(t *testing.T) {
+	r := RedisStore{}
+	WithTLSConfig(&tls.Config{InsecureSkipVerify: true})(&r)
+	WithServerName("redis.local")(&r)
+	require.NotNil(t, r.tlsConfig)
+	assert.Equal(t, "redis.local", r.tlsConfig.ServerName)
+	assert.True(t, r.tlsConfig.InsecureSkipVerify)
+}
+
+func Test_NewFromURL_TLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	addr := strings.TrimPrefix(srv.URL, "https://")
+
+	dial := func(rawurl string, opts ...setter) error {
+		r, err := NewFromURL(rawurl, prefix, opts...)
+		require.NoError(t, err)
+
+		c, err := r.pool.(*redis.Pool).DialContext(context.Background())
+		if err != nil {
+			return err
+		}
+
+		return c.Close()
+	}
+
+	// the server's certificate is signed by an unknown authority.
+	assert.Error(t, dial("rediss://"+addr))
+
+	// the scheme is upgraded to rediss.
+	assert.NoError(t, dial("redis://"+addr, WithRootCAs(roots)))
+
+	// the server's certificate is valid for example.com.
+	assert.Error(t, dial("rediss://"+addr, WithRootCAs(roots), WithServerName("redis.local")))
+	assert.NoError(t, dial("rediss://"+addr, WithRootCAs(roots), WithServerName("example.com")))
+}
@@ -0,0 +1,50 @@
+package redisstore
+
+import (
+	"github.com/gomodule/redigo/redis"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the instrumentation library used when
+// creating a tracer.
+const tracerName = "github.com/swithek/sessionup-redisstore"
+
+// WithTracerProvider enables OpenTelemetry tracing. A span is
+// created for each store method call; it contains the store's prefix,
+// a hash of the user key (when it is known upfront), the number of
+// executed Redis commands and the returned error.
+// By default tracing is disabled.
+func WithTracerProvider(tp trace.TracerProvider) setter {
+	return func(r *RedisStore) {
+		r.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// countingConn counts the commands executed or queued through the
+// wrapped connection.
+type countingConn struct {
+	redis.Conn
+	cmds int
+}
+
+// Do executes the command and increments the command counter.
+func (c *countingConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if cmd != "" {
+		c.cmds++
+	}
+
+	return c.Conn.Do(cmd, args...)
+}
+
+// Send queues the command and increments the command counter.
+func (c *countingConn) Send(cmd string, args ...interface{}) error {
+	c.cmds++
+	return c.Conn.Send(cmd, args...)
+}
+
+// userKeyAttr returns a span attribute holding a hash of the provided
+// user key, so that raw user identifiers are not leaked into traces.
+func userKeyAttr(key string) attribute.KeyValue {
+	return attribute.String("redisstore.user_key_hash", hashValue(key))
+}
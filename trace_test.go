@@ -0,0 +1,218 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recorder is a trace.TracerProvider and trace.Tracer that records
+// all started spans.
+type recorder struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *recorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return r
+}
+
+func (r *recorder) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+
+	s := &recordedSpan{
+		Span:  trace.SpanFromContext(context.Background()),
+		name:  name,
+		attrs: cfg.Attributes(),
+	}
+
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+// recordedSpan is a trace.Span that records the data set on it.
+type recordedSpan struct {
+	trace.Span
+
+	name   string
+	attrs  []attribute.KeyValue
+	errs   []error
+	status codes.Code
+	ended  bool
+}
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.attrs = append(s.attrs, kv...)
+}
+
+func (s *recordedSpan) RecordError(err error, _ ...trace.EventOption) {
+	s.errs = append(s.errs, err)
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, _ string) {
+	s.status = code
+}
+
+func (s *recordedSpan) End(...trace.SpanEndOption) {
+	s.ended = true
+}
+
+func (s *recordedSpan) attr(key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range s.attrs {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+func Test_WithTracerProvider(t *testing.T) {
+	r := RedisStore{}
+	WithTracerProvider(&recorder{})(&r)
+	assert.NotNil(t, r.tracer)
+}
+
+func Test_countingConn(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.Command("GET", "key")
+	conn.Command("DEL", "key")
+
+	c := &countingConn{Conn: conn}
+
+	_, err := c.Do("GET", "key")
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.Send("DEL", "key"))
+
+	_, err = c.Do("")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, c.cmds)
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_begin(t *testing.T) {
+	t.Run("Disabled tracing", func(t *testing.T) {
+		conn := redigomock.NewConn()
+		r := RedisStore{
+			pool: &redis.Pool{
+				Dial: func() (redis.Conn, error) {
+					return conn, nil
+				},
+			},
+		}
+
+		c, end, err := r.begin(context.Background(), "Op")
+		require.NoError(t, err)
+		require.NotNil(t, c)
+		assert.NoError(t, end(nil))
+	})
+
+	t.Run("Closed store", func(t *testing.T) {
+		rec := &recorder{}
+		r := RedisStore{
+			closed: true,
+			tracer: rec,
+		}
+
+		c, end, err := r.begin(context.Background(), "Op")
+		assert.Equal(t, &Error{Op: "op", Kind: ErrClosed, Err: ErrClosed}, err)
+		assert.Nil(t, c)
+		assert.Nil(t, end)
+		assert.Empty(t, rec.spans)
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		rec := &recorder{}
+		r := RedisStore{
+			pool: &redis.Pool{
+				Dial: func() (redis.Conn, error) {
+					return redigomock.NewConn(), nil
+				},
+				Wait:      true,
+				MaxActive: 10,
+			},
+			prefix: prefix,
+			tracer: rec,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		c, end, err := r.begin(ctx, "Op")
+		assert.True(t, errors.Is(err, ErrConnection))
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Nil(t, c)
+		assert.Nil(t, end)
+
+		require.Len(t, rec.spans, 1)
+		assert.True(t, rec.spans[0].ended)
+		assert.Equal(t, codes.Error, rec.spans[0].status)
+		assert.Len(t, rec.spans[0].errs, 1)
+	})
+
+	t.Run("Successful execution", func(t *testing.T) {
+		rec := &recorder{}
+		conn := redigomock.NewConn()
+		conn.Command("GET", "key")
+
+		r := RedisStore{
+			pool: &redis.Pool{
+				Dial: func() (redis.Conn, error) {
+					return conn, nil
+				},
+			},
+			prefix: prefix,
+			tracer: rec,
+		}
+
+		c, end, err := r.begin(context.Background(), "Op", userKeyAttr("u123"))
+		require.NoError(t, err)
+
+		_, err = c.Do("GET", "key")
+		require.NoError(t, err)
+
+		err = end(assert.AnError)
+		assert.Equal(t, &Error{Op: "op", Kind: ErrInternal, Err: assert.AnError}, err)
+
+		require.Len(t, rec.spans, 1)
+		s := rec.spans[0]
+		assert.Equal(t, "redisstore.Op", s.name)
+		assert.True(t, s.ended)
+		assert.Equal(t, codes.Error, s.status)
+		assert.Equal(t, []error{err}, s.errs)
+
+		v, ok := s.attr("redisstore.prefix")
+		assert.True(t, ok)
+		assert.Equal(t, prefix, v.AsString())
+
+		v, ok = s.attr("redisstore.user_key_hash")
+		assert.True(t, ok)
+		assert.Equal(t, userKeyAttr("u123").Value, v)
+
+		v, ok = s.attr("redisstore.commands")
+		assert.True(t, ok)
+		assert.Equal(t, int64(1), v.AsInt64())
+	})
+}
+
+func Test_userKeyAttr(t *testing.T) {
+	kv := userKeyAttr("u123")
+	assert.Equal(t, attribute.Key("redisstore.user_key_hash"), kv.Key)
+	assert.Len(t, kv.Value.AsString(), 16)
+	assert.NotContains(t, kv.Value.AsString(), "u123")
+	assert.NotEqual(t, kv, userKeyAttr("u124"))
+}
@@ -0,0 +1,46 @@
+package redisstore
+
+import (
+	"context"
+	"time"
+)
+
+// TTL returns the remaining time to live of the session with the
+// provided ID, as determined by its expiration time. Unlike the TTL of
+// its key, it is not affected by TTL jitter (see WithTTLJitter).
+// If session is not found, 0 is returned. If not found errors are
+// enabled (see WithNotFoundErrors), ErrNoSession is returned as well.
+func (r *RedisStore) TTL(ctx context.Context, id string) (ttl time.Duration, err error) {
+	c, end, err := r.begin(ctx, "TTL")
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() { err = end(err) }()
+
+	d, ok, err := r.fetchDetailed(c, id)
+	if err != nil {
+		return 0, err
+	}
+
+	if !ok || r.expired(d.Session) {
+		return 0, r.notFound(false)
+	}
+
+	return d.ExpiresAt.Sub(r.now()), nil
+}
+
+// SetTTL changes the expiration time of the session with the provided
+// ID so that it expires after the provided duration. The expiration
+// time stored in the session, the expiration time of its key and its
+// position in the user session set are updated together, as done by
+// ExtendByID. If the duration is not positive, the session is deleted
+// instead.
+// If session is not found, this function will be no-op.
+func (r *RedisStore) SetTTL(ctx context.Context, id string, d time.Duration) error {
+	if d <= 0 {
+		return r.DeleteByID(ctx, id)
+	}
+
+	return r.extendByID(ctx, "SetTTL", id, r.now().Add(d))
+}
@@ -0,0 +1,103 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RedisStore_TTL(t *testing.T) {
+	sKey := prefix + ":session:id123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	conn.Command("HGETALL", sKey).ExpectError(assert.AnError)
+	_, err := r.TTL(context.Background(), "id123")
+	assert.True(t, errors.Is(err, assert.AnError))
+
+	conn.Clear()
+	conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+	ttl, err := r.TTL(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.Zero(t, ttl)
+
+	r.notFoundErrors = true
+	_, err = r.TTL(context.Background(), "id123")
+	assert.True(t, errors.Is(err, ErrNoSession))
+
+	conn.Clear()
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+	ttl, err = r.TTL(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour, ttl, float64(time.Second))
+}
+
+func Test_RedisStore_SetTTL(t *testing.T) {
+	sKey := prefix + ":session:id123"
+	uKey := prefix + ":user:u123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+	conn.Command("WATCH", uKey).Expect("OK")
+	conn.Command("PTTL", uKey).Expect(int64(1000))
+	conn.Command("MULTI").Expect("OK")
+
+	var score int64
+
+	conn.Command("ZADD", uKey, redigomock.NewAnyInt(), sKey).Handle(func(args []interface{}) (interface{}, error) {
+		score = args[1].(int64)
+		return "QUEUED", nil
+	})
+	conn.Command("PEXPIREAT", uKey, redigomock.NewAnyInt()).Expect("QUEUED")
+	conn.GenericCommand("HMSET").Expect("QUEUED")
+
+	var exp int64
+
+	conn.Command("PEXPIREAT", sKey, redigomock.NewAnyInt()).Handle(func(args []interface{}) (interface{}, error) {
+		exp = args[1].(int64)
+		return "QUEUED", nil
+	})
+
+	var pExp int64
+
+	conn.Command("PEXPIREAT", prefix+":payload:id123", redigomock.NewAnyInt()).Handle(func(args []interface{}) (interface{}, error) {
+		pExp = args[1].(int64)
+		return "QUEUED", nil
+	})
+	conn.Command("EXEC").ExpectSlice(int64(1), int64(1), "OK", int64(1), int64(0))
+
+	require.NoError(t, r.SetTTL(context.Background(), "id123", time.Hour*2))
+
+	want := time.Now().Add(time.Hour * 2)
+	assert.InDelta(t, want.UnixNano(), score, float64(time.Second))
+	assert.InDelta(t, want.UnixNano()/int64(time.Millisecond), exp, 1000)
+	assert.Equal(t, exp, pExp)
+}
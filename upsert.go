@@ -0,0 +1,268 @@
+package redisstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// Upsert stores the provided session along with the provided payload
+// (see SetPayload) in a single WATCH/MULTI transaction, so that
+// a session is never left without its payload or vice versa. If
+// a session with the same ID already exists, only its expiration time
+// is changed, the same way as by ExtendByID, and its payload is
+// replaced; otherwise the session is created the same way as by
+// Create. Empty data deletes the payload.
+// Note that only the expiration time of an existing session is
+// changed: the provided user key and metadata are ignored.
+// Hooks, events and audit entries of either Create or ExtendByID are
+// used, depending on whether the session was created.
+func (r *RedisStore) Upsert(ctx context.Context, s sessionup.Session, data []byte) (err error) {
+	var (
+		res     sessionup.Session
+		created bool
+	)
+
+	defer func() {
+		if created {
+			if res.ID == "" {
+				res = s
+			}
+
+			r.afterCreate(ctx, res, err)
+
+			return
+		}
+
+		r.afterExtend(ctx, sessionOrID(res, s.ID), err)
+	}()
+
+	c, end, err := r.begin(ctx, "Upsert", userKeyAttr(s.UserKey))
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = end(err) }()
+
+	if err = validate(s); err != nil {
+		return err
+	}
+
+	if err = r.checkMeta(s.Meta); err != nil {
+		return err
+	}
+
+	if len(data) > 0 && r.enc != nil {
+		if data, err = r.enc.seal("payload", s.ID, data); err != nil {
+			return withKind(ErrEncryption, err)
+		}
+	}
+
+	var (
+		evicted  []sessionup.Session
+		prepared *DetailedSession
+	)
+
+	// the new session is prepared only once it is known not to exist,
+	// so that hooks and enrichers are neither called for existing
+	// sessions nor called again when the transaction is retried
+	prepare := func() (DetailedSession, error) {
+		if prepared == nil {
+			d, err := r.prepare(ctx, s)
+			if err != nil {
+				return DetailedSession{}, err
+			}
+
+			prepared = &d
+		}
+
+		return *prepared, nil
+	}
+
+	err = r.retryTx(ctx, func() error {
+		var err error
+		res, created, evicted, err = r.upsertTx(c, s, data, prepare)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		if err = r.invalidate(c, Invalidation{ID: s.ID}); err != nil {
+			return err
+		}
+
+		if err = r.addToIndexes(ctx, c, res); err != nil {
+			return err
+		}
+
+		if err = r.audit(c, AuditExtended, res); err != nil {
+			return err
+		}
+
+		return r.waitReplicas(c)
+	}
+
+	if err = r.addToIndexes(ctx, c, res); err != nil {
+		return err
+	}
+
+	if err = r.dropEvicted(ctx, c, evicted); err != nil {
+		return err
+	}
+
+	if err = r.addToCreatedIndex(c, res); err != nil {
+		return err
+	}
+
+	if err = r.markActive(c, res.UserKey); err != nil {
+		return err
+	}
+
+	if err = r.audit(c, AuditCreated, res); err != nil {
+		return err
+	}
+
+	return r.waitReplicas(c)
+}
+
+// upsertTx creates the session returned by prepare or changes the
+// expiration time of the existing one, and replaces its payload with
+// the provided (already encrypted) data, by using a pipelined
+// WATCH/MULTI transaction or, if transactions are disabled (see
+// WithTransactions), pipelined commands. The stored session is returned along with
+// a boolean that indicates whether it did not exist (even if its
+// creation failed), as well as the sessions evicted to make room for
+// it.
+func (r *RedisStore) upsertTx(c redis.Conn, s sessionup.Session, data []byte, prepare func() (DetailedSession, error)) (sessionup.Session, bool, []sessionup.Session, error) {
+	sKey := r.key(c, false, s.ID)
+
+	if !r.noTx {
+		if _, err := c.Do("WATCH", sKey); err != nil {
+			return sessionup.Session{}, false, nil, err
+		}
+	}
+
+	d, ok, err := r.fetchDetailed(c, s.ID)
+	if err != nil {
+		return sessionup.Session{}, false, nil, err
+	}
+
+	if ok {
+		setAbsoluteDeadline(&d, s.ExpiresAt)
+		r.capLifetime(&d)
+	} else if d, err = prepare(); err != nil {
+		return sessionup.Session{}, !ok, nil, err
+	}
+
+	uKey := r.key(c, true, d.UserKey)
+	now := r.now().UnixNano()
+	sExpNano := d.ExpiresAt.UnixNano()
+	sExpMilli := r.expireAt(d.ExpiresAt)
+
+	var (
+		cmds    [][]interface{}
+		evicted []sessionup.Session
+	)
+
+	if !r.noUserIndex {
+		if !r.noTx {
+			if _, err = c.Do("WATCH", uKey); err != nil {
+				return sessionup.Session{}, !ok, nil, err
+			}
+		}
+
+		// find current user session set's expiration time
+		uExpMilli, err := redis.Int64(c.Do("PTTL", uKey))
+		if err != nil {
+			return sessionup.Session{}, !ok, nil, err
+		}
+
+		uExpMilli += now / int64(time.Millisecond)
+		if sExpMilli > uExpMilli {
+			uExpMilli = sExpMilli
+		}
+
+		if !ok {
+			evict, err := r.overLimit(c, uKey, now)
+			if err != nil {
+				return sessionup.Session{}, !ok, nil, err
+			}
+
+			// sessions are retrieved before they are evicted, so
+			// that the data related to them can be removed
+			// afterwards
+			if evicted, err = r.fetchKeys(c, evict); err != nil {
+				return sessionup.Session{}, !ok, nil, err
+			}
+
+			cmds = append(cmds, []interface{}{"ZREMRANGEBYSCORE", uKey, "-inf", now})
+
+			for i := range evict {
+				cmds = append(cmds, []interface{}{"DEL", evict[i]}, []interface{}{"ZREM", uKey, evict[i]})
+			}
+		}
+
+		cmds = append(cmds,
+			[]interface{}{"ZADD", uKey, sExpNano, sKey},
+			[]interface{}{"PEXPIREAT", uKey, uExpMilli},
+		)
+	}
+
+	cmd, args, err := r.encodeDetailed(d)
+	if err != nil {
+		return sessionup.Session{}, !ok, nil, err
+	}
+
+	cmds = append(cmds,
+		cmdArgs(cmd, sKey, args),
+		[]interface{}{"PEXPIREAT", sKey, sExpMilli},
+	)
+
+	pKey := r.payloadKey(c, s.ID)
+
+	if len(data) > 0 {
+		cmds = append(cmds,
+			[]interface{}{"SET", pKey, data},
+			[]interface{}{"PEXPIREAT", pKey, sExpMilli},
+		)
+	} else {
+		cmds = append(cmds, []interface{}{"DEL", pKey})
+	}
+
+	if r.noTx {
+		_, err = pipeline(c, cmds)
+	} else if err = sendTx(c, cmds); err == nil {
+		err = receiveTxs(c, 1)
+	}
+
+	if err != nil {
+		return sessionup.Session{}, !ok, nil, err
+	}
+
+	return d.Session, !ok, evicted, nil
+}
+
+// prepare validates and prepares the provided session for creation,
+// the same way as Create does.
+func (r *RedisStore) prepare(ctx context.Context, s sessionup.Session) (DetailedSession, error) {
+	s = r.applyTTL(s)
+	d := r.withDeadlines(s)
+	r.capLifetime(&d)
+	s = d.Session
+
+	if err := r.beforeCreate(ctx, s); err != nil {
+		return DetailedSession{}, err
+	}
+
+	d.Attributes = r.enrich(ctx, s)
+	d.Device = r.classify(ctx, s)
+	d.Login = r.login(ctx, s)
+	d.Session = r.Redact(s)
+
+	return d, nil
+}
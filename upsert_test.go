@@ -0,0 +1,165 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_RedisStore_Upsert(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Round(0),
+		CreatedAt: time.Now().UTC().Round(0),
+	}
+
+	exp := inp.ExpiresAt.Add(time.Hour)
+	expMilli := exp.UnixNano() / int64(time.Millisecond)
+
+	sKey := prefix + ":session:" + inp.ID
+	pKey := prefix + ":payload:" + inp.ID
+	uKey := prefix + ":user:" + inp.UserKey
+
+	var (
+		created  []sessionup.Session
+		extended []sessionup.Session
+	)
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithHooks(Hooks{
+		AfterCreate: func(_ context.Context, s sessionup.Session, err error) {
+			if err == nil {
+				created = append(created, s)
+			}
+		},
+		AfterExtend: func(_ context.Context, s sessionup.Session, err error) {
+			if err == nil {
+				extended = append(extended, s)
+			}
+		},
+	}))
+
+	err := r.Upsert(context.Background(), sessionup.Session{ID: "id123"}, nil)
+	assert.True(t, errors.Is(err, ErrInvalidSession))
+
+	conn.Command("WATCH", sKey).ExpectError(assert.AnError)
+	err = r.Upsert(context.Background(), inp, []byte("data"))
+	assert.True(t, errors.Is(err, assert.AnError))
+
+	// new sessions are created along with their payloads.
+	conn.Clear()
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+	conn.Command("WATCH", uKey).Expect("OK")
+	conn.Command("PTTL", uKey).Expect(int64(-2))
+	conn.GenericCommand("MULTI").Expect("OK")
+	conn.GenericCommand("ZREMRANGEBYSCORE").Expect("QUEUED")
+	zadd := conn.Command("ZADD", uKey, inp.ExpiresAt.UnixNano(), sKey).Expect("QUEUED")
+	conn.GenericCommand("PEXPIREAT").Expect("QUEUED")
+	hmset := conn.GenericCommand("HMSET").Expect("QUEUED")
+	set := conn.Command("SET", pKey, []byte("data")).Expect("QUEUED")
+	conn.GenericCommand("EXEC").ExpectSlice("OK")
+	require.NoError(t, r.Upsert(context.Background(), inp, []byte("data")))
+	assert.Equal(t, 1, conn.Stats(zadd))
+	assert.Equal(t, 1, conn.Stats(hmset))
+	assert.Equal(t, 1, conn.Stats(set))
+	require.Len(t, created, 1)
+	assert.Equal(t, inp.ID, created[0].ID)
+	assert.Empty(t, extended)
+
+	// existing sessions are extended and their payloads are replaced.
+	conn.Clear()
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": inp.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at": inp.ExpiresAt.Format(time.RFC3339Nano),
+		"id":         inp.ID,
+		"user_key":   inp.UserKey,
+	})
+	conn.Command("WATCH", uKey).Expect("OK")
+	conn.Command("PTTL", uKey).Expect(int64(1000))
+	conn.GenericCommand("MULTI").Expect("OK")
+	zadd = conn.Command("ZADD", uKey, exp.UnixNano(), sKey).Expect("QUEUED")
+	conn.Command("PEXPIREAT", uKey, expMilli).Expect("QUEUED")
+	conn.GenericCommand("HMSET").Expect("QUEUED")
+	conn.Command("PEXPIREAT", sKey, expMilli).Expect("QUEUED")
+	del := conn.Command("DEL", pKey).Expect("QUEUED")
+	conn.GenericCommand("EXEC").ExpectSlice("OK")
+
+	inp.ExpiresAt = exp
+	require.NoError(t, r.Upsert(context.Background(), inp, nil))
+	assert.Equal(t, 1, conn.Stats(zadd))
+	assert.Equal(t, 1, conn.Stats(del))
+	assert.Len(t, created, 1)
+	require.Len(t, extended, 1)
+	assert.Equal(t, exp, extended[0].ExpiresAt)
+
+	// conflicting transactions are retried.
+	conn.Clear()
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+	conn.Command("WATCH", uKey).Expect("OK")
+	conn.Command("PTTL", uKey).Expect(int64(-2))
+	conn.GenericCommand("MULTI").Expect("OK")
+	conn.GenericCommand("ZREMRANGEBYSCORE").Expect("QUEUED")
+	conn.GenericCommand("ZADD").Expect("QUEUED")
+	conn.GenericCommand("PEXPIREAT").Expect("QUEUED")
+	conn.GenericCommand("HMSET").Expect("QUEUED")
+	conn.GenericCommand("SET").Expect("QUEUED")
+	conn.GenericCommand("EXEC").Expect(nil)
+	err = r.Upsert(context.Background(), inp, []byte("data"))
+	assert.True(t, errors.Is(err, ErrTxConflict))
+}
+
+func Test_RedisStore_Upsert_retried(t *testing.T) {
+	s := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	sKey := prefix + ":session:" + s.ID
+	uKey := prefix + ":user:" + s.UserKey
+
+	var calls int
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithTxRetries(2, 0), WithHooks(Hooks{
+		BeforeCreate: func(context.Context, sessionup.Session) error {
+			calls++
+			return nil
+		},
+	}))
+
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+	conn.Command("WATCH", uKey).Expect("OK")
+	conn.Command("PTTL", uKey).Expect(int64(-2))
+	conn.GenericCommand("MULTI").Expect("OK")
+	conn.GenericCommand("ZREMRANGEBYSCORE").Expect("QUEUED")
+	conn.GenericCommand("ZADD").Expect("QUEUED")
+	conn.GenericCommand("PEXPIREAT").Expect("QUEUED")
+	conn.GenericCommand("HMSET").Expect("QUEUED")
+	conn.GenericCommand("SET").Expect("QUEUED")
+	exec := conn.GenericCommand("EXEC").Expect(nil).ExpectSlice("OK")
+
+	require.NoError(t, r.Upsert(context.Background(), s, []byte("data")))
+	assert.Equal(t, 2, conn.Stats(exec))
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,114 @@
+package redisstore
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// createSimpleScriptSrc checks whether the session key (KEYS[1]) is
+// free and writes the session data.
+// ARGV holds session's expiration time in milliseconds, the name of
+// the command used to write session data and its arguments.
+// Returns 0 when the session key is already taken, 1 otherwise.
+const createSimpleScriptSrc = `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+
+redis.call(ARGV[2], KEYS[1], unpack(ARGV, 3))
+redis.call("PEXPIREAT", KEYS[1], ARGV[1])
+
+return 1
+`
+
+var createSimpleScript = newLuaScript("create_simple", 1, createSimpleScriptSrc)
+
+// WithoutUserIndex disables user session sets, so that only session
+// data is written and no bookkeeping of user's sessions is done on
+// each write. FetchByUserKey, FetchDetailedByUserKey and
+// DeleteByUserKey return errNoIndex, and the user session limit (see
+// WithMaxUserSessions) is not enforced.
+// Defaults to false (user session sets are maintained).
+func WithoutUserIndex() setter {
+	return func(r *RedisStore) {
+		r.noUserIndex = true
+	}
+}
+
+// createSimple inserts the provided session into the store without
+// adding it to the user session set by using a Lua script or, if
+// scripting is not available, a WATCH/MULTI transaction.
+func (r *RedisStore) createSimple(ctx context.Context, c redis.Conn, d DetailedSession) error {
+	if r.scriptsDisabled() {
+		_, err := r.createWithTx(ctx, c, d)
+		return err
+	}
+
+	s := d.Session
+
+	cmd, data, err := r.encodeDetailed(d)
+	if err != nil {
+		return err
+	}
+
+	args := []interface{}{r.key(c, false, s.ID), r.expireAt(s.ExpiresAt), cmd}
+
+	v, err := redis.Int64(r.runScript(c, createSimpleScript, append(args, data...)...))
+	if err != nil {
+		if unsupported(err) {
+			r.disableScripts()
+			_, err = r.createWithTx(ctx, c, d)
+
+			return err
+		}
+
+		return err
+	}
+
+	if v == 0 {
+		return sessionup.ErrDuplicateID
+	}
+
+	return r.addToIndexes(ctx, c, s)
+}
+
+// createSimpleTx inserts the provided session into the store without
+// adding it to the user session set by using a WATCH/MULTI
+// transaction.
+func (r *RedisStore) createSimpleTx(c redis.Conn, d DetailedSession) error {
+	sKey := r.key(c, false, d.ID)
+
+	if _, err := c.Do("WATCH", sKey); err != nil {
+		return err
+	}
+
+	v, err := redis.Int64(c.Do("EXISTS", sKey))
+	if err != nil {
+		return err
+	}
+
+	if v > 0 {
+		return sessionup.ErrDuplicateID
+	}
+
+	cmd, data, err := r.encodeDetailed(d)
+	if err != nil {
+		return err
+	}
+
+	if _, err = c.Do("MULTI"); err != nil {
+		return err
+	}
+
+	if _, err = c.Do(cmd, keyArgs(sKey, data)...); err != nil {
+		return err
+	}
+
+	if _, err = c.Do("PEXPIREAT", sKey, r.expireAt(d.ExpiresAt)); err != nil {
+		return err
+	}
+
+	return exec(c)
+}
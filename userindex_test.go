@@ -0,0 +1,114 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithoutUserIndex(t *testing.T) {
+	r := RedisStore{}
+	WithoutUserIndex()(&r)
+	assert.True(t, r.noUserIndex)
+}
+
+func Test_RedisStore_Create_withoutUserIndex(t *testing.T) {
+	inp := sessionup.Session{
+		UserKey:   "u123",
+		ID:        "id123",
+		ExpiresAt: time.Now().UTC().Add(time.Hour),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	sKey := prefix + ":session:" + inp.ID
+	sExpMilli := inp.ExpiresAt.UnixNano() / int64(time.Millisecond)
+
+	fields := []interface{}{
+		"created_at", inp.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at", inp.ExpiresAt.Format(time.RFC3339Nano),
+		"id", inp.ID,
+		"user_key", inp.UserKey,
+		"ip", "",
+		"agent_os", "",
+		"agent_browser", "",
+		"meta", "",
+		"schema_version", "1",
+	}
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithoutUserIndex())
+
+	script := func() *redigomock.Cmd {
+		args := append([]interface{}{sKey, sExpMilli, "HMSET"}, fields...)
+		return conn.Script([]byte(createSimpleScriptSrc), 1, args...)
+	}
+
+	script().Expect(int64(0))
+	assert.Equal(t, sessionup.ErrDuplicateID, r.Create(context.Background(), inp))
+
+	conn.Clear()
+	script().Expect(int64(1))
+	require.NoError(t, r.Create(context.Background(), inp))
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	conn.Clear()
+	script().ExpectError(redis.Error("ERR unknown command 'EVALSHA'"))
+	conn.Command("WATCH", sKey)
+	conn.Command("EXISTS", sKey).Expect(int64(0))
+	conn.GenericCommand("MULTI")
+	conn.Command("HMSET", append([]interface{}{sKey}, fields...)...)
+	conn.Command("PEXPIREAT", sKey, sExpMilli)
+	conn.GenericCommand("EXEC").ExpectSlice()
+
+	require.NoError(t, r.Create(context.Background(), inp))
+	assert.NoError(t, conn.ExpectationsWereMet())
+	assert.True(t, r.scriptsDisabled())
+}
+
+func Test_RedisStore_DeleteByID_withoutUserIndex(t *testing.T) {
+	sKey := prefix + ":session:id123"
+	pKey := prefix + ":payload:id123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithoutUserIndex())
+
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+	conn.GenericCommand("MULTI").Expect("OK")
+	conn.Command("DEL", sKey, pKey).Expect("QUEUED")
+	conn.GenericCommand("EXEC").ExpectSlice(int64(1))
+
+	require.NoError(t, r.DeleteByID(context.Background(), "id123"))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_userKeyMethods_withoutUserIndex(t *testing.T) {
+	r := New(&redis.Pool{}, prefix, WithoutUserIndex())
+
+	_, err := r.FetchByUserKey(context.Background(), "u123")
+	assert.Equal(t, errNoIndex, err)
+
+	_, err = r.FetchDetailedByUserKey(context.Background(), "u123")
+	assert.Equal(t, errNoIndex, err)
+
+	assert.Equal(t, errNoIndex, r.DeleteByUserKey(context.Background(), "u123"))
+}
@@ -0,0 +1,64 @@
+package redisstore
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ListUserKeys retrieves a page of the keys of users that have active
+// sessions by iterating over user session sets with SCAN. An empty
+// cursor starts a new iteration; the returned cursor should be passed
+// to the next call and is empty once the iteration is complete.
+// As with FetchAll, limit is used as a hint only, and a user key may be
+// returned more than once if its set is created during the iteration.
+// Users whose sets hold only entries of expired sessions are skipped.
+// errNoIndex is returned if user session sets are disabled (see
+// WithoutUserIndex).
+func (r *RedisStore) ListUserKeys(ctx context.Context, cursor string, limit int) (kk []string, next string, err error) {
+	if r.noUserIndex {
+		return nil, "", errNoIndex
+	}
+
+	c, end, err := r.begin(ctx, "ListUserKeys")
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer func() { err = end(err) }()
+
+	keys, next, err := scanPage(ctx, c, r.pattern(c, true), cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := r.now().UnixNano()
+
+	// pipeline all counts so that they are done in a single round
+	// trip
+	for i := range keys {
+		if err = c.Send("ZCOUNT", keys[i], now, "+inf"); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err = c.Flush(); err != nil {
+		return nil, "", err
+	}
+
+	uPrefix := r.key(c, true, "")
+
+	for i := range keys {
+		n, err := redis.Int64(c.Receive())
+		if err != nil {
+			return nil, "", err
+		}
+
+		if n > 0 {
+			kk = append(kk, keyUnescaper.Replace(strings.TrimPrefix(keys[i], uPrefix)))
+		}
+	}
+
+	return kk, next, nil
+}
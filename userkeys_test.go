@@ -0,0 +1,46 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RedisStore_ListUserKeys(t *testing.T) {
+	uKey1 := prefix + ":user:u%3A1"
+	uKey2 := prefix + ":user:u2"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	_, _, err := r.ListUserKeys(context.Background(), "x", 10)
+	assert.True(t, errors.Is(err, ErrParse))
+
+	conn.Command("SCAN", uint64(0), "MATCH", prefix+":user:*", "COUNT", 10).ExpectError(assert.AnError)
+	_, _, err = r.ListUserKeys(context.Background(), "", 10)
+	assert.True(t, errors.Is(err, assert.AnError))
+
+	conn.Clear()
+	conn.Command("SCAN", uint64(0), "MATCH", prefix+":user:*", "COUNT", 2).
+		ExpectSlice([]byte("5"), []interface{}{[]byte(uKey1), []byte(uKey2)})
+	conn.Command("ZCOUNT", uKey1, redigomock.NewAnyInt(), "+inf").Expect(int64(2))
+	conn.Command("ZCOUNT", uKey2, redigomock.NewAnyInt(), "+inf").Expect(int64(0))
+
+	kk, next, err := r.ListUserKeys(context.Background(), "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"u:1"}, kk)
+	assert.Equal(t, "5", next)
+
+	r = New(&redis.Pool{}, prefix, WithoutUserIndex())
+	_, _, err = r.ListUserKeys(context.Background(), "", 10)
+	assert.Equal(t, errNoIndex, err)
+}
@@ -0,0 +1,210 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// WithUserScanBatch enables chunked iteration over user session sets
+// with ZSCAN, so that very large sets do not have to be loaded (and
+// replied by Redis) at once. FetchByUserKey and FetchDetailedByUserKey
+// retrieve sessions in batches of roughly n, while DeleteByUserKey
+// deletes them batch by batch instead of using a single Lua script
+// or transaction, which means that the deletion is no longer atomic.
+// Sessions retrieved with ByExpiration order are sorted in memory.
+// Defaults to 0 (whole sets are loaded with ZRANGEBYSCORE).
+func WithUserScanBatch(n int) setter {
+	return func(r *RedisStore) {
+		r.userScanBatch = n
+	}
+}
+
+// errStopIteration is used to stop ZSCAN iterations early.
+var errStopIteration = errors.New("redisstore: iteration stopped")
+
+// IterateByUserKey calls fn with each session associated with the
+// provided user key, until it returns false or an error (which is then
+// returned by this function). Unlike FetchByUserKey, sessions are
+// retrieved in batches with ZSCAN (of the size set with
+// WithUserScanBatch or, if it is not set, of 100), so that they do not
+// have to be held in memory at once. Sessions are passed in no
+// particular order; sessions created or deleted during the iteration
+// may or may not be passed to fn.
+// errNoIndex is returned if user session sets are disabled (see
+// WithoutUserIndex).
+func (r *RedisStore) IterateByUserKey(ctx context.Context, key string, fn func(sessionup.Session) (bool, error)) (err error) {
+	if r.noUserIndex {
+		return errNoIndex
+	}
+
+	c, end, err := r.begin(ctx, "IterateByUserKey", userKeyAttr(key))
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = end(err) }()
+
+	count := r.userScanBatch
+	if count <= 0 {
+		count = scanCount
+	}
+
+	err = zscan(ctx, c, r.key(c, true, key), count, func(keys []string) error {
+		ss, err := r.fetchKeys(c, keys)
+		if err != nil {
+			return err
+		}
+
+		for i := range ss {
+			cont, err := fn(ss[i])
+			if err != nil {
+				return err
+			}
+
+			if !cont {
+				return errStopIteration
+			}
+		}
+
+		return nil
+	})
+	if errors.Is(err, errStopIteration) {
+		return nil
+	}
+
+	return err
+}
+
+// userSessionKeys calls fn with the session keys found in the user
+// session set, either all at once or in batches returned by ZSCAN.
+func (r *RedisStore) userSessionKeys(ctx context.Context, c redis.Conn, uKey string, fn func([]string) error) error {
+	if r.userScanBatch > 0 {
+		return zscan(ctx, c, uKey, r.userScanBatch, fn)
+	}
+
+	keys, err := redis.Strings(c.Do("ZRANGEBYSCORE", uKey, "-inf", "+inf"))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			err = nil
+		}
+
+		return err
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return fn(keys)
+}
+
+// zscan iterates over all members of the sorted set and calls fn with
+// each batch of members returned by ZSCAN. Members that are returned
+// more than once (which ZSCAN permits) are passed to fn only once.
+func zscan(ctx context.Context, c redis.Conn, key string, count int, fn func([]string) error) error {
+	var cursor int64
+
+	seen := make(map[string]struct{})
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		vv, err := redis.Values(c.Do("ZSCAN", key, cursor, "COUNT", count))
+		if err != nil {
+			return err
+		}
+
+		var pairs []string
+		if _, err = redis.Scan(vv, &cursor, &pairs); err != nil {
+			return err
+		}
+
+		// members are followed by their scores
+		keys := make([]string, 0, len(pairs)/2)
+
+		for i := 0; i < len(pairs); i += 2 {
+			if _, ok := seen[pairs[i]]; ok {
+				continue
+			}
+
+			seen[pairs[i]] = struct{}{}
+			keys = append(keys, pairs[i])
+		}
+
+		if len(keys) > 0 {
+			if err = fn(keys); err != nil {
+				return err
+			}
+		}
+
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// sortScanned sorts the slice of sessions retrieved from a user
+// session set by their expiration time (returned by expires), since
+// ZSCAN does not preserve the order of the set.
+func (r *RedisStore) sortScanned(ss interface{}, expires func(int) time.Time) {
+	if r.userScanBatch <= 0 {
+		return
+	}
+
+	sort.SliceStable(ss, func(i, j int) bool {
+		return expires(i).Before(expires(j))
+	})
+}
+
+// removeByUserKeyScan deletes all sessions associated with the
+// provided user key (except the ones specified) batch by batch.
+// The user session set is deleted by Redis once its last member is
+// removed.
+func (r *RedisStore) removeByUserKeyScan(ctx context.Context, c redis.Conn, key string, expIDs ...string) error {
+	uKey := r.key(c, true, key)
+
+	keep := make(map[string]struct{}, len(expIDs))
+	for i := range expIDs {
+		keep[r.key(c, false, expIDs[i])] = struct{}{}
+	}
+
+	return zscan(ctx, c, uKey, r.userScanBatch, func(keys []string) error {
+		del := make([]string, 0, len(keys))
+
+		for i := range keys {
+			if _, ok := keep[keys[i]]; !ok {
+				del = append(del, keys[i])
+			}
+		}
+
+		if len(del) == 0 {
+			return nil
+		}
+
+		unindex, err := r.unindexKeyCmds(c, del)
+		if err != nil {
+			return err
+		}
+
+		dargs := redis.Args{"DEL"}.AddFlat(del)
+		for i := range del {
+			dargs = dargs.Add(r.payloadKey(c, r.idFromKey(c, del[i])))
+		}
+
+		cmds := append([][]interface{}{
+			dargs,
+			redis.Args{"ZREM", uKey}.AddFlat(del),
+		}, unindex...)
+
+		_, err = pipeline(c, cmds)
+
+		return err
+	})
+}
@@ -0,0 +1,200 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithUserScanBatch(t *testing.T) {
+	r := RedisStore{}
+	WithUserScanBatch(500)(&r)
+	assert.Equal(t, 500, r.userScanBatch)
+}
+
+func zscanReply(cursor string, kk ...string) []interface{} {
+	pairs := make([]interface{}, 0, len(kk)*2)
+	for i := range kk {
+		pairs = append(pairs, []byte(kk[i]), []byte("1"))
+	}
+
+	return []interface{}{[]byte(cursor), pairs}
+}
+
+func Test_zscan(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.Command("ZSCAN", "key", int64(0), "COUNT", 2).ExpectError(assert.AnError)
+
+	err := zscan(context.Background(), conn, "key", 2, func([]string) error { return nil })
+	assert.Error(t, err)
+
+	conn.Clear()
+	conn.Command("ZSCAN", "key", int64(0), "COUNT", 2).Expect(zscanReply("5", "a", "b"))
+	conn.Command("ZSCAN", "key", int64(5), "COUNT", 2).Expect(zscanReply("7", "b"))
+	conn.Command("ZSCAN", "key", int64(7), "COUNT", 2).Expect(zscanReply("0", "c"))
+
+	var batches [][]string
+
+	err = zscan(context.Background(), conn, "key", 2, func(kk []string) error {
+		batches = append(batches, kk)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, batches)
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	conn.Clear()
+	conn.Command("ZSCAN", "key", int64(0), "COUNT", 2).Expect(zscanReply("5", "a", "b"))
+
+	err = zscan(context.Background(), conn, "key", 2, func([]string) error { return assert.AnError })
+	assert.Equal(t, assert.AnError, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = zscan(ctx, conn, "key", 2, func([]string) error { return nil })
+	assert.Equal(t, context.Canceled, err)
+}
+
+func Test_RedisStore_FetchByUserKey_scan(t *testing.T) {
+	uKey := prefix + ":user:u123"
+	now := time.Now().UTC()
+
+	session := func(id string, exp time.Time) map[string]string {
+		return map[string]string{
+			"created_at": now.Format(time.RFC3339Nano),
+			"expires_at": exp.Format(time.RFC3339Nano),
+			"id":         id,
+			"user_key":   "u123",
+		}
+	}
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithUserScanBatch(1))
+
+	conn.Command("ZSCAN", uKey, int64(0), "COUNT", 1).Expect(zscanReply("3", prefix+":session:id1"))
+	conn.Command("ZSCAN", uKey, int64(3), "COUNT", 1).Expect(zscanReply("0", prefix+":session:id2"))
+	conn.Command("HGETALL", prefix+":session:id1").ExpectMap(session("id1", now.Add(time.Hour*2)))
+	conn.Command("HGETALL", prefix+":session:id2").ExpectMap(session("id2", now.Add(time.Hour)))
+
+	ss, err := r.FetchByUserKey(context.Background(), "u123")
+	require.NoError(t, err)
+	require.Len(t, ss, 2)
+	assert.Equal(t, "id2", ss[0].ID)
+	assert.Equal(t, "id1", ss[1].ID)
+
+	dd, err := r.FetchDetailedByUserKey(context.Background(), "u123")
+	require.NoError(t, err)
+	require.Len(t, dd, 2)
+	assert.Equal(t, "id2", dd[0].ID)
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	conn.Clear()
+	conn.Command("ZSCAN", uKey, int64(0), "COUNT", 1).ExpectError(assert.AnError)
+
+	_, err = r.FetchByUserKey(context.Background(), "u123")
+	assert.Error(t, err)
+}
+
+func Test_RedisStore_IterateByUserKey(t *testing.T) {
+	uKey := prefix + ":user:u123"
+
+	session := func(id string) map[string]string {
+		return map[string]string{
+			"created_at": time.Now().Format(time.RFC3339Nano),
+			"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+			"id":         id,
+			"user_key":   "u123",
+		}
+	}
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	r.noUserIndex = true
+	err := r.IterateByUserKey(context.Background(), "u123", nil)
+	assert.Equal(t, errNoIndex, err)
+
+	r.noUserIndex = false
+
+	conn.Command("ZSCAN", uKey, int64(0), "COUNT", scanCount).ExpectError(assert.AnError)
+	err = r.IterateByUserKey(context.Background(), "u123", nil)
+	assert.Error(t, err)
+
+	conn.Clear()
+	conn.Command("ZSCAN", uKey, int64(0), "COUNT", scanCount).Expect(zscanReply("3", prefix+":session:id1", prefix+":session:id2"))
+	conn.Command("ZSCAN", uKey, int64(3), "COUNT", scanCount).Expect(zscanReply("0", prefix+":session:id3"))
+	conn.Command("HGETALL", prefix+":session:id1").ExpectMap(session("id1"))
+	conn.Command("HGETALL", prefix+":session:id2").ExpectMap(map[string]string{})
+	conn.Command("HGETALL", prefix+":session:id3").ExpectMap(session("id3"))
+
+	var ids []string
+
+	err = r.IterateByUserKey(context.Background(), "u123", func(s sessionup.Session) (bool, error) {
+		ids = append(ids, s.ID)
+		return true, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id1", "id3"}, ids)
+
+	// the iteration stops once fn returns false or an error
+	ids = nil
+
+	err = r.IterateByUserKey(context.Background(), "u123", func(s sessionup.Session) (bool, error) {
+		ids = append(ids, s.ID)
+		return false, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id1"}, ids)
+
+	err = r.IterateByUserKey(context.Background(), "u123", func(s sessionup.Session) (bool, error) {
+		return true, assert.AnError
+	})
+	assert.True(t, errors.Is(err, assert.AnError))
+}
+
+func Test_RedisStore_DeleteByUserKey_scan(t *testing.T) {
+	uKey := prefix + ":user:u123"
+	sKey1 := prefix + ":session:id1"
+	sKey2 := prefix + ":session:id2"
+	sKey3 := prefix + ":session:id3"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithUserScanBatch(2))
+
+	conn.Command("ZSCAN", uKey, int64(0), "COUNT", 2).Expect(zscanReply("4", sKey1, sKey2))
+	conn.Command("ZSCAN", uKey, int64(4), "COUNT", 2).Expect(zscanReply("0", sKey3))
+	conn.Command("DEL", sKey1, prefix+":payload:id1").Expect(int64(1))
+	conn.Command("ZREM", uKey, sKey1).Expect(int64(1))
+	conn.Command("DEL", sKey3, prefix+":payload:id3").Expect(int64(1))
+	conn.Command("ZREM", uKey, sKey3).Expect(int64(1))
+
+	require.NoError(t, r.DeleteByUserKey(context.Background(), "u123", "id2"))
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	conn.Clear()
+	conn.Command("ZSCAN", uKey, int64(0), "COUNT", 2).Expect(zscanReply("0", sKey1, sKey2))
+	conn.Command("DEL", sKey1, sKey2, prefix+":payload:id1", prefix+":payload:id2").ExpectError(assert.AnError)
+	conn.Command("ZREM", uKey, sKey1, sKey2).Expect(int64(2))
+
+	assert.Error(t, r.DeleteByUserKey(context.Background(), "u123"))
+}
@@ -0,0 +1,161 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/swithek/sessionup"
+	"xojoc.pw/useragent"
+)
+
+var (
+	// errIPChanged is returned by IPChanged when the session is used
+	// from a different IP address.
+	errIPChanged = errors.New("redisstore: session used from a different IP address")
+
+	// errBrowserChanged is returned by BrowserChanged when the session
+	// is used by a different browser.
+	errBrowserChanged = errors.New("redisstore: session used by a different browser")
+)
+
+// FetchValidator checks a session retrieved by FetchByID before it is
+// returned, e.g. to detect session hijacking. ctx is the context
+// passed to FetchByID. A non-nil error rejects the session.
+type FetchValidator func(ctx context.Context, s sessionup.Session) error
+
+// WithFetchValidator sets the functions that check each session
+// retrieved by FetchByID, in the provided order. If any of them
+// returns an error, FetchByID returns it as an ErrSuspiciousSession
+// error instead of the session. Since the validators usually need the
+// incoming request, it should be added to the context passed to
+// FetchByID with NewRequestContext (or RequestMiddleware).
+// The validators are called for each call, even if it is
+// de-duplicated by singleflight or served by the local cache.
+// Defaults to no validators.
+func WithFetchValidator(vv ...FetchValidator) setter {
+	return func(r *RedisStore) {
+		r.validators = vv
+	}
+}
+
+// IPChanged is a FetchValidator that rejects sessions whose IP address
+// is set and does not match the IP address of the request in the
+// context (see NewRequestContext). The address is extracted from the
+// request in the same way as by sessionup.Manager and redacted the
+// same way as stored addresses (see WithRedaction).
+// Sessions are not checked if the context holds no request.
+func IPChanged(ctx context.Context, s sessionup.Session) error {
+	req, ok := RequestFromContext(ctx)
+	if !ok || len(s.IP) == 0 {
+		return nil
+	}
+
+	rs := redactRequest(ctx, sessionup.Session{IP: requestIP(req)})
+	if !s.IP.Equal(rs.IP) {
+		return errIPChanged
+	}
+
+	return nil
+}
+
+// BrowserChanged is a FetchValidator that rejects sessions whose
+// browser name is set and does not match the browser of the request
+// in the context (see NewRequestContext), as determined by its
+// User-Agent header and redacted the same way as stored browser names
+// (see WithRedaction). Requests whose User-Agent header is missing or
+// cannot be recognized are treated as coming from another browser.
+// Sessions are not checked if the context holds no request.
+func BrowserChanged(ctx context.Context, s sessionup.Session) error {
+	req, ok := RequestFromContext(ctx)
+	if !ok || s.Agent.Browser == "" {
+		return nil
+	}
+
+	a := useragent.Parse(req.Header.Get("User-Agent"))
+	if a == nil {
+		return errBrowserChanged
+	}
+
+	var rs sessionup.Session
+	rs.Agent.Browser = a.Name
+
+	if redactRequest(ctx, rs).Agent.Browser != s.Agent.Browser {
+		return errBrowserChanged
+	}
+
+	return nil
+}
+
+// requestIP extracts the IP address of the client from the request in
+// the same way as sessionup.Manager.
+func requestIP(req *http.Request) net.IP {
+	ips := strings.Split(req.Header.Get("X-Forwarded-For"), ", ")
+	ip := ips[len(ips)-1]
+
+	if ip == "" {
+		ip, _, _ = net.SplitHostPort(req.RemoteAddr)
+	}
+
+	return net.ParseIP(ip)
+}
+
+// storeKey is the context key of the store that calls fetch
+// validators.
+type storeKey struct{}
+
+// redactRequest redacts the session built from request data the same
+// way as the store that calls the fetch validators redacts stored
+// sessions (see Redact), so that both can be compared.
+func redactRequest(ctx context.Context, s sessionup.Session) sessionup.Session {
+	if r, ok := ctx.Value(storeKey{}).(*RedisStore); ok {
+		return r.Redact(s)
+	}
+
+	return s
+}
+
+// requestKey is the context key of the incoming request.
+type requestKey struct{}
+
+// NewRequestContext returns a copy of the context that holds the
+// provided request, for use by fetch validators.
+func NewRequestContext(ctx context.Context, req *http.Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, req)
+}
+
+// RequestFromContext returns the request held by the context, if any.
+func RequestFromContext(ctx context.Context) (*http.Request, bool) {
+	req, ok := ctx.Value(requestKey{}).(*http.Request)
+	return req, ok && req != nil
+}
+
+// RequestMiddleware adds each request to its own context, so that it
+// is available to fetch validators. It should wrap the handlers
+// returned by sessionup.Manager's Auth and Public methods.
+func RequestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(w, req.WithContext(NewRequestContext(req.Context(), req)))
+	})
+}
+
+// validateFetched calls all fetch validators with the session. The
+// store is added to the context, so that the validators provided by
+// this package can redact request data (see redactRequest).
+func (r *RedisStore) validateFetched(ctx context.Context, s sessionup.Session) error {
+	if len(r.validators) == 0 {
+		return nil
+	}
+
+	ctx = context.WithValue(ctx, storeKey{}, r)
+
+	for _, v := range r.validators {
+		if err := v(ctx, s); err != nil {
+			return wrapErr("fetchByID", withKind(ErrSuspiciousSession, err))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swithek/sessionup"
+)
+
+func Test_WithFetchValidator(t *testing.T) {
+	r := RedisStore{}
+	WithFetchValidator(IPChanged, BrowserChanged)(&r)
+	assert.Len(t, r.validators, 2)
+}
+
+func Test_RequestFromContext(t *testing.T) {
+	_, ok := RequestFromContext(context.Background())
+	assert.False(t, ok)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	res, ok := RequestFromContext(NewRequestContext(context.Background(), req))
+	assert.True(t, ok)
+	assert.Equal(t, req, res)
+}
+
+func Test_RequestMiddleware(t *testing.T) {
+	var ok bool
+
+	h := RequestMiddleware(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		_, ok = RequestFromContext(req.Context())
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.True(t, ok)
+}
+
+func Test_IPChanged(t *testing.T) {
+	s := sessionup.Session{IP: net.ParseIP("127.0.0.1")}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+
+	assert.NoError(t, IPChanged(context.Background(), s))
+	assert.NoError(t, IPChanged(NewRequestContext(context.Background(), req), s))
+	assert.NoError(t, IPChanged(NewRequestContext(context.Background(), req), sessionup.Session{}))
+
+	req.RemoteAddr = "10.0.0.1:1234"
+	assert.Equal(t, errIPChanged, IPChanged(NewRequestContext(context.Background(), req), s))
+}
+
+func Test_BrowserChanged(t *testing.T) {
+	var s sessionup.Session
+	s.Agent.Browser = "Firefox"
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "curl/7.68.0")
+
+	assert.NoError(t, BrowserChanged(context.Background(), s))
+	assert.NoError(t, BrowserChanged(NewRequestContext(context.Background(), req), sessionup.Session{}))
+	assert.Equal(t, errBrowserChanged, BrowserChanged(NewRequestContext(context.Background(), req), s))
+
+	req.Header.Del("User-Agent")
+	assert.Equal(t, errBrowserChanged, BrowserChanged(NewRequestContext(context.Background(), req), s))
+
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0")
+	assert.NoError(t, BrowserChanged(NewRequestContext(context.Background(), req), s))
+}
+
+func Test_RedisStore_FetchByID_validators(t *testing.T) {
+	sKey := prefix + ":session:id123"
+
+	var checked []string
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithFetchValidator(
+		func(_ context.Context, s sessionup.Session) error {
+			checked = append(checked, s.ID)
+			return nil
+		},
+		func(ctx context.Context, _ sessionup.Session) error {
+			if ctx.Value(requestKey{}) == nil {
+				return assert.AnError
+			}
+
+			return nil
+		},
+	))
+
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+
+	s, ok, err := r.FetchByID(context.Background(), "id123")
+	assert.True(t, errors.Is(err, ErrSuspiciousSession))
+	assert.True(t, errors.Is(err, assert.AnError))
+	assert.False(t, ok)
+	assert.Empty(t, s)
+
+	ctx := NewRequestContext(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	s, ok, err = r.FetchByID(ctx, "id123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "id123", s.ID)
+	assert.Equal(t, []string{"id123", "id123"}, checked)
+
+	conn.Clear()
+	conn.Command("HGETALL", sKey).ExpectError(redis.ErrNil)
+
+	_, ok, err = r.FetchByID(ctx, "id123")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Len(t, checked, 2)
+}
+
+func Test_RedisStore_FetchByID_redactedValidators(t *testing.T) {
+	sKey := prefix + ":session:id123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithRedaction(RedactIP, RedactAgent), WithRedactionKey([]byte("key")),
+		WithFetchValidator(IPChanged, BrowserChanged))
+
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at":    time.Now().Format(time.RFC3339Nano),
+		"expires_at":    time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":            "id123",
+		"user_key":      "u123",
+		"ip":            "127.0.0.0",
+		"agent_browser": r.hashAgent("Firefox"),
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0")
+
+	// request data is redacted before being compared with the
+	// redacted session.
+	s, ok, err := r.FetchByID(NewRequestContext(context.Background(), req), "id123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "id123", s.ID)
+
+	req.RemoteAddr = "10.0.0.1:1234"
+
+	_, _, err = r.FetchByID(NewRequestContext(context.Background(), req), "id123")
+	assert.True(t, errors.Is(err, errIPChanged))
+
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set("User-Agent", "curl/7.68.0")
+
+	_, _, err = r.FetchByID(NewRequestContext(context.Background(), req), "id123")
+	assert.True(t, errors.Is(err, errBrowserChanged))
+}
+
+func Test_requestIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	assert.Equal(t, net.ParseIP("127.0.0.1"), requestIP(req))
+
+	req.Header.Set("X-Forwarded-For", "10.0.0.1, 10.0.0.2")
+	assert.Equal(t, net.ParseIP("10.0.0.2"), requestIP(req))
+}
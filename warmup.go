@@ -0,0 +1,55 @@
+package redisstore
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// WithWarmup determines the number of connections that New and
+// NewFromURL dial and verify with PING before returning the store, so
+// that traffic arriving right after a deployment does not cause
+// a burst of dials. The pool should keep at least as many idle
+// connections (see redis.Pool.MaxIdle), otherwise the surplus ones are
+// closed once they are returned to it. Failures are ignored by New and
+// NewFromURL; Warmup can be called to check them. It has no effect on
+// stores without a connection pool (see NewWithDialer).
+// Defaults to 0 (disabled).
+func WithWarmup(n int) setter {
+	return func(r *RedisStore) {
+		r.warmup = n
+	}
+}
+
+// Warmup dials the number of connections set by WithWarmup, verifies
+// them with PING and returns them to the pool. Connections are held
+// until all of them are verified, so that the pool does not hand out
+// the same idle connection more than once.
+func (r *RedisStore) Warmup(ctx context.Context) error {
+	if _, ok := r.pool.(dialer); ok || r.warmup <= 0 {
+		return nil
+	}
+
+	conns := make([]redis.Conn, 0, r.warmup)
+
+	defer func() {
+		for _, c := range conns {
+			c.Close()
+		}
+	}()
+
+	for i := 0; i < r.warmup; i++ {
+		c, err := r.pool.GetContext(ctx)
+		if err != nil {
+			return wrapErr("warmup", withKind(ErrConnection, err))
+		}
+
+		conns = append(conns, c)
+
+		if _, err = c.Do("PING"); err != nil {
+			return wrapErr("warmup", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package redisstore
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithWarmup(t *testing.T) {
+	r := RedisStore{}
+	WithWarmup(3)(&r)
+	assert.Equal(t, 3, r.warmup)
+}
+
+func Test_RedisStore_Warmup(t *testing.T) {
+	var dials int
+
+	conn := redigomock.NewConn()
+	ping := conn.Command("PING").Expect("PONG")
+
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			dials++
+			return conn, nil
+		},
+		MaxIdle: 3,
+	}
+
+	New(pool, prefix, WithWarmup(3))
+	assert.Equal(t, 3, dials)
+	assert.Equal(t, 3, conn.Stats(ping))
+	assert.Equal(t, 3, pool.Stats().IdleCount)
+
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return nil, assert.AnError
+		},
+	}, prefix, WithWarmup(1))
+
+	err := r.Warmup(context.Background())
+	assert.True(t, errors.Is(err, ErrConnection))
+
+	conn.Clear()
+	conn.Command("PING").ExpectError(assert.AnError)
+	assert.NoError(t, New(pool, prefix).Warmup(context.Background()))
+
+	r = New(pool, prefix, WithWarmup(1))
+	assert.True(t, errors.Is(r.Warmup(context.Background()), assert.AnError))
+
+	r = NewWithDialer(func(context.Context) (redis.Conn, error) {
+		t.Fatal("unexpected dial")
+		return nil, nil
+	}, prefix, WithWarmup(1))
+	assert.NoError(t, r.Warmup(context.Background()))
+}
+
+func Test_NewFromURL_warmup(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer ln.Close()
+
+	var dials int64
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			atomic.AddInt64(&dials, 1)
+
+			go func() {
+				defer c.Close()
+
+				br := bufio.NewReader(c)
+				for {
+					if _, err := readCommand(br); err != nil {
+						return
+					}
+
+					fmt.Fprint(c, "+PONG\r\n")
+				}
+			}()
+		}
+	}()
+
+	r, err := NewFromURL("redis://"+ln.Addr().String(), prefix, WithWarmup(2))
+	require.NoError(t, err)
+
+	defer r.Close(context.Background())
+
+	assert.Equal(t, int64(2), atomic.LoadInt64(&dials))
+	assert.Equal(t, 2, r.pool.(*redis.Pool).Stats().IdleCount)
+}
@@ -0,0 +1,67 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// WithNotValidBeforeCheck determines whether FetchByID and Exists
+// should treat sessions created before the time set with
+// SetNotValidBefore as not found.
+// The check requires an additional command on each retrieval.
+// Defaults to false.
+func WithNotValidBeforeCheck(t bool) setter {
+	return func(r *RedisStore) {
+		r.notValidBeforeCheck = t
+	}
+}
+
+// SetNotValidBefore invalidates all sessions created before the
+// provided time at once, without deleting them: if the check is
+// enabled (see WithNotValidBeforeCheck), FetchByID and Exists treat
+// such sessions as not found. The time is stored in a single key, hence
+// the cost of this operation does not depend on the number of
+// sessions. Sessions are still returned by other fetch operations
+// until they expire or are deleted.
+// A zero time removes the stored time.
+func (r *RedisStore) SetNotValidBefore(ctx context.Context, t time.Time) (err error) {
+	c, end, err := r.begin(ctx, "SetNotValidBefore")
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = end(err) }()
+
+	if t.IsZero() {
+		_, err = c.Do("DEL", r.notValidBeforeKey(c))
+		return err
+	}
+
+	_, err = c.Do("SET", r.notValidBeforeKey(c), t.UnixNano())
+
+	return err
+}
+
+// notValidBefore retrieves the time before which sessions are
+// invalid. Zero time is returned if it is not set.
+func (r *RedisStore) notValidBefore(c redis.Conn) (time.Time, error) {
+	v, err := redis.Int64(c.Do("GET", r.notValidBeforeKey(c)))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			err = nil
+		}
+
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, v), nil
+}
+
+// notValidBeforeKey returns the key of the time before which sessions
+// are invalid, scoped to the tenant of the connection.
+func (r *RedisStore) notValidBeforeKey(c redis.Conn) string {
+	return r.prefixed(r.scopedPrefix(connTenant(c)), "not_valid_before")
+}
@@ -0,0 +1,137 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithNotValidBeforeCheck(t *testing.T) {
+	r := RedisStore{}
+	WithNotValidBeforeCheck(true)(&r)
+	assert.True(t, r.notValidBeforeCheck)
+}
+
+func Test_RedisStore_SetNotValidBefore(t *testing.T) {
+	nKey := prefix + ":not_valid_before"
+	now := time.Now()
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix)
+
+	conn.Command("SET", nKey, now.UnixNano()).ExpectError(assert.AnError)
+	assert.Error(t, r.SetNotValidBefore(context.Background(), now))
+
+	conn.Clear()
+	conn.Command("SET", nKey, now.UnixNano()).Expect("OK")
+	assert.NoError(t, r.SetNotValidBefore(context.Background(), now))
+	assert.NoError(t, conn.ExpectationsWereMet())
+
+	conn.Clear()
+	conn.Command("DEL", nKey).Expect(int64(1))
+	assert.NoError(t, r.SetNotValidBefore(context.Background(), time.Time{}))
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_FetchByID_notValidBefore(t *testing.T) {
+	nKey := prefix + ":not_valid_before"
+	sKey := prefix + ":session:id123"
+	created := time.Now().Add(-time.Hour)
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithNotValidBeforeCheck(true))
+
+	session := map[string]string{
+		"created_at": created.Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	}
+
+	conn.Command("GET", nKey).ExpectError(assert.AnError)
+	_, _, err := r.FetchByID(context.Background(), "id123")
+	assert.Error(t, err)
+
+	conn.Clear()
+	conn.Command("GET", nKey).ExpectError(redis.ErrNil)
+	conn.Command("HGETALL", sKey).ExpectMap(session)
+
+	_, ok, err := r.FetchByID(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	conn.Clear()
+	conn.Command("GET", nKey).Expect(created.Add(-time.Minute).UnixNano())
+	conn.Command("HGETALL", sKey).ExpectMap(session)
+
+	_, ok, err = r.FetchByID(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	conn.Clear()
+	conn.Command("GET", nKey).Expect(created.Add(time.Minute).UnixNano())
+	conn.Command("HGETALL", sKey).ExpectMap(session)
+
+	s, ok, err := r.FetchByID(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Zero(t, s)
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
+
+func Test_RedisStore_Exists_notValidBefore(t *testing.T) {
+	nKey := prefix + ":not_valid_before"
+	sKey := prefix + ":session:id123"
+	created := time.Now().Add(-time.Hour)
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithNotValidBeforeCheck(true))
+
+	conn.Command("GET", nKey).ExpectError(assert.AnError)
+	_, err := r.Exists(context.Background(), "id123")
+	assert.Error(t, err)
+
+	conn.Clear()
+	conn.Command("GET", nKey).Expect(created.Add(-time.Minute).UnixNano())
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": created.Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+
+	ok, err := r.Exists(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	conn.Clear()
+	conn.Command("GET", nKey).Expect(created.Add(time.Minute).UnixNano())
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": created.Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+
+	ok, err = r.Exists(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, conn.ExpectationsWereMet())
+}
@@ -0,0 +1,447 @@
+package redisstore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/swithek/sessionup"
+)
+
+// writeBehind holds the updates of sessions retrieved by FetchByID
+// that have not been written yet.
+type writeBehind struct {
+	mu       sync.Mutex
+	interval time.Duration
+	pending  map[cacheKey]seenUpdate
+	started  bool
+}
+
+// seenUpdate holds the buffered changes of a session. Zero times are
+// not applied.
+type seenUpdate struct {
+	seen time.Time
+	idle time.Time
+}
+
+// WithWriteBehind enables buffering of the updates made by FetchByID
+// when idle timeout (see WithIdleTimeout) or last seen tracking (see
+// WithLastSeen) are enabled. Instead of writing to each retrieved
+// session, FetchByID only reads it and the latest update of each
+// session is written by a background worker every interval, with
+// sessions batched into pipelined transactions. All other operations
+// remain synchronous.
+// Buffered updates are lost if the process exits before they are
+// written, while the store being closed writes them first. Updates
+// that fail to be written are buffered again and retried on the next
+// flush. Since the expiration times of sessions are moved only once
+// their updates are written, the interval should be much shorter than
+// the idle timeout.
+// Defaults to 0 (disabled).
+func WithWriteBehind(interval time.Duration) setter {
+	return func(r *RedisStore) {
+		r.writeBehind = nil
+
+		if interval > 0 {
+			r.writeBehind = &writeBehind{
+				interval: interval,
+				pending:  make(map[cacheKey]seenUpdate),
+			}
+		}
+	}
+}
+
+// FlushUpdates writes all updates buffered by the write-behind mode
+// (see WithWriteBehind) immediately. Updates of sessions that no
+// longer exist are discarded. It is no-op if the mode is disabled.
+func (r *RedisStore) FlushUpdates(ctx context.Context) (err error) {
+	if r.writeBehind == nil {
+		return nil
+	}
+
+	c, end, err := r.begin(ctx, "FlushUpdates")
+	if err != nil {
+		return err
+	}
+
+	defer func() { err = end(err) }()
+
+	return r.flushUpdates(ctx, c)
+}
+
+// fetchDeferred retrieves a session by the provided ID and buffers the
+// refresh of its expiration time or the time it was last seen at.
+func (r *RedisStore) fetchDeferred(c redis.Conn, id string) (sessionup.Session, bool, error) {
+	d, ok, err := r.fetchDetailed(c, id)
+	if err != nil || !ok {
+		return sessionup.Session{}, false, err
+	}
+
+	now := r.now()
+
+	var u seenUpdate
+
+	if r.idleTimeout > 0 {
+		u.idle = now.Add(r.idleTimeout)
+		setIdleDeadline(&d, u.idle)
+		r.capLifetime(&d)
+	}
+
+	if r.lastSeen && (r.idleTimeout > 0 || now.Sub(d.LastSeenAt) >= r.lastSeenInterval) {
+		u.seen = now
+	}
+
+	if !u.idle.IsZero() || !u.seen.IsZero() {
+		r.deferUpdate(cacheKey{tenant: connTenant(c), id: id}, u)
+	}
+
+	return d.Session, true, nil
+}
+
+// deferUpdate buffers the update of the session, merging it with its
+// previous update (see mergeUpdates), and starts the background worker
+// if it is not running yet.
+func (r *RedisStore) deferUpdate(key cacheKey, u seenUpdate) {
+	wb := r.writeBehind
+
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	wb.pending[key] = mergeUpdates(wb.pending[key], u)
+
+	if wb.started {
+		return
+	}
+
+	r.closeMu.RLock()
+	defer r.closeMu.RUnlock()
+
+	if r.closed {
+		return
+	}
+
+	wb.started = true
+	r.workers.Add(1)
+
+	go func() {
+		defer r.workers.Done()
+
+		t := time.NewTicker(wb.interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				r.flushOnClose()
+				return
+			case <-t.C:
+				_ = r.FlushUpdates(context.Background())
+			}
+		}
+	}()
+}
+
+// flushOnClose writes the remaining buffered updates once the store
+// is closed, bypassing the check of its state.
+func (r *RedisStore) flushOnClose() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.writeBehind.interval)
+	defer cancel()
+
+	c, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return
+	}
+
+	defer c.Close()
+
+	_ = r.flushUpdates(ctx, r.withTimeout(ctx, c))
+}
+
+// flushUpdates writes all buffered updates, grouped by their tenants,
+// in batches. Updates of batches that fail are buffered again (see
+// requeueUpdates) and the first error is returned.
+func (r *RedisStore) flushUpdates(ctx context.Context, c redis.Conn) error {
+	wb := r.writeBehind
+
+	wb.mu.Lock()
+	pending := wb.pending
+	wb.pending = make(map[cacheKey]seenUpdate)
+	wb.mu.Unlock()
+
+	batches := make(map[string][]map[string]seenUpdate)
+
+	for key, u := range pending {
+		bb := batches[key.tenant]
+		if len(bb) == 0 || len(bb[len(bb)-1]) >= scanCount {
+			bb = append(bb, make(map[string]seenUpdate))
+			batches[key.tenant] = bb
+		}
+
+		bb[len(bb)-1][key.id] = u
+	}
+
+	var ferr error
+
+	for tenant, bb := range batches {
+		tc := withTenant(c, tenant)
+
+		for _, batch := range bb {
+			err := r.flushBatch(ctx, tc, batch)
+			if err == nil {
+				continue
+			}
+
+			r.requeueUpdates(tenant, batch)
+
+			if ferr == nil {
+				ferr = err
+			}
+		}
+	}
+
+	return ferr
+}
+
+// requeueUpdates buffers the updates of a batch that failed to be
+// written again, so that they are retried on the next flush. They are
+// merged with updates buffered in the meantime (see mergeUpdates).
+func (r *RedisStore) requeueUpdates(tenant string, batch map[string]seenUpdate) {
+	wb := r.writeBehind
+
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	for id, u := range batch {
+		key := cacheKey{tenant: tenant, id: id}
+		wb.pending[key] = mergeUpdates(u, wb.pending[key])
+	}
+}
+
+// flushBatch writes the batch of updates in a single transaction and
+// refreshes the secondary indexes of sessions whose expiration times
+// were moved.
+func (r *RedisStore) flushBatch(ctx context.Context, c redis.Conn, batch map[string]seenUpdate) error {
+	var ss []sessionup.Session
+
+	err := r.retryTx(ctx, func() error {
+		var err error
+		ss, err = r.flushBatchTx(c, batch)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, s := range ss {
+		if err = r.addToIndexes(ctx, c, s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flushBatchTx writes the batch of updates by using a pipelined
+// WATCH/MULTI transaction and returns the sessions whose expiration
+// times were changed. Updates that would change neither the
+// expiration time of a session nor move the time it was last seen at
+// forward are skipped.
+func (r *RedisStore) flushBatchTx(c redis.Conn, batch map[string]seenUpdate) ([]sessionup.Session, error) {
+	keys := make([]string, 0, len(batch))
+	for id := range batch {
+		keys = append(keys, r.key(c, false, id))
+	}
+
+	if _, err := c.Do("WATCH", redis.Args{}.AddFlat(keys)...); err != nil {
+		return nil, err
+	}
+
+	dd, err := r.fetchKeysDetailed(c, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		changed []DetailedSession
+		idle    []sessionup.Session
+	)
+
+	for i := range dd {
+		seen, moved := applyUpdate(&dd[i], batch[dd[i].ID])
+		if !seen && !moved {
+			continue
+		}
+
+		r.capLifetime(&dd[i])
+
+		changed = append(changed, dd[i])
+
+		if moved {
+			idle = append(idle, dd[i].Session)
+		}
+	}
+
+	if len(changed) == 0 {
+		_, err = c.Do("UNWATCH")
+		return nil, err
+	}
+
+	uExps, err := r.userSetExpirations(c, idle)
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([][]interface{}, 0, len(changed)*3+len(idle)*2)
+
+	for _, d := range changed {
+		sKey := r.key(c, false, d.ID)
+
+		cmd, data, err := r.encodeDetailed(d)
+		if err != nil {
+			return nil, err
+		}
+
+		exp := r.expireAt(d.ExpiresAt)
+
+		cmds = append(cmds,
+			cmdArgs(cmd, sKey, data),
+			[]interface{}{"PEXPIREAT", sKey, exp},
+			[]interface{}{"PEXPIREAT", r.payloadKey(c, d.ID), exp},
+		)
+	}
+
+	for _, s := range idle {
+		if r.noUserIndex {
+			break
+		}
+
+		uKey := r.key(c, true, s.UserKey)
+		cmds = append(cmds, []interface{}{"ZADD", uKey, s.ExpiresAt.UnixNano(), r.key(c, false, s.ID)})
+
+		if exp := r.expireAt(s.ExpiresAt); exp > uExps[uKey] {
+			uExps[uKey] = exp
+			cmds = append(cmds, []interface{}{"PEXPIREAT", uKey, exp})
+		}
+	}
+
+	if err = sendTx(c, cmds); err != nil {
+		return nil, err
+	}
+
+	if err = receiveTxs(c, 1); err != nil {
+		return nil, err
+	}
+
+	return idle, nil
+}
+
+// userSetExpirations watches the user session sets of the provided
+// sessions and returns their current expiration times (in
+// milliseconds), in a single round trip.
+func (r *RedisStore) userSetExpirations(c redis.Conn, ss []sessionup.Session) (map[string]int64, error) {
+	exps := make(map[string]int64)
+	if r.noUserIndex || len(ss) == 0 {
+		return exps, nil
+	}
+
+	var uKeys []string
+
+	for _, s := range ss {
+		uKey := r.key(c, true, s.UserKey)
+		if _, ok := exps[uKey]; !ok {
+			exps[uKey] = 0
+			uKeys = append(uKeys, uKey)
+		}
+	}
+
+	if err := c.Send("WATCH", redis.Args{}.AddFlat(uKeys)...); err != nil {
+		return nil, err
+	}
+
+	for _, uKey := range uKeys {
+		if err := c.Send("PTTL", uKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+
+	if _, err := c.Receive(); err != nil {
+		return nil, err
+	}
+
+	now := r.now().UnixNano() / int64(time.Millisecond)
+
+	for _, uKey := range uKeys {
+		ttl, err := redis.Int64(c.Receive())
+		if err != nil {
+			return nil, err
+		}
+
+		exps[uKey] = now + ttl
+	}
+
+	return exps, nil
+}
+
+// applyUpdate applies the buffered update to the session. The idle
+// expiration time is set the same way as when updates are not
+// buffered, hence it may be moved back. The first returned value
+// indicates whether the time the session was last seen at was moved,
+// while the second one indicates whether its expiration time was
+// changed.
+func applyUpdate(d *DetailedSession, u seenUpdate) (bool, bool) {
+	var seen, moved bool
+
+	if !u.idle.IsZero() {
+		cur := d.ExpiresAt
+		if !d.AbsoluteExpiresAt.IsZero() {
+			cur = d.IdleExpiresAt
+		}
+
+		if !u.idle.Equal(cur) {
+			setIdleDeadline(d, u.idle)
+			moved = true
+		}
+	}
+
+	if u.seen.After(d.LastSeenAt) {
+		d.LastSeenAt = u.seen
+		seen = true
+	}
+
+	return seen, moved
+}
+
+// mergeUpdates returns the newer of the times of both updates, so that
+// updates buffered out of order do not move the expiration time or the
+// time the session was last seen at back.
+func mergeUpdates(a, b seenUpdate) seenUpdate {
+	if b.idle.After(a.idle) {
+		a.idle = b.idle
+	}
+
+	if b.seen.After(a.seen) {
+		a.seen = b.seen
+	}
+
+	return a
+}
+
+// withTenant scopes the connection to the provided tenant, replacing
+// its current scope.
+func withTenant(c redis.Conn, tenant string) redis.Conn {
+	if sc, ok := c.(*scopedConn); ok {
+		c = sc.Conn
+	}
+
+	if tenant == "" {
+		return c
+	}
+
+	return &scopedConn{Conn: c, tenant: tenant}
+}
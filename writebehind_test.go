@@ -0,0 +1,171 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithWriteBehind(t *testing.T) {
+	r := RedisStore{}
+	WithWriteBehind(time.Second)(&r)
+	require.NotNil(t, r.writeBehind)
+	assert.Equal(t, time.Second, r.writeBehind.interval)
+	assert.NotNil(t, r.writeBehind.pending)
+
+	WithWriteBehind(0)(&r)
+	assert.Nil(t, r.writeBehind)
+}
+
+func Test_applyUpdate(t *testing.T) {
+	now := time.Now()
+
+	d := DetailedSession{LastSeenAt: now}
+	d.ExpiresAt = now.Add(time.Hour)
+
+	// idle expiration times are applied even if they are sooner, the
+	// same way as when updates are not buffered.
+	seen, moved := applyUpdate(&d, seenUpdate{seen: now.Add(-time.Second), idle: now.Add(time.Minute)})
+	assert.False(t, seen)
+	assert.True(t, moved)
+	assert.Equal(t, now, d.LastSeenAt)
+	assert.Equal(t, now.Add(time.Minute), d.ExpiresAt)
+
+	seen, moved = applyUpdate(&d, seenUpdate{idle: now.Add(time.Minute)})
+	assert.False(t, seen)
+	assert.False(t, moved)
+
+	seen, moved = applyUpdate(&d, seenUpdate{seen: now.Add(time.Second), idle: now.Add(time.Hour * 2)})
+	assert.True(t, seen)
+	assert.True(t, moved)
+	assert.Equal(t, now.Add(time.Second), d.LastSeenAt)
+	assert.Equal(t, now.Add(time.Hour*2), d.ExpiresAt)
+
+	d = DetailedSession{AbsoluteExpiresAt: now.Add(time.Hour), IdleExpiresAt: now.Add(time.Minute)}
+	d.ExpiresAt = d.IdleExpiresAt
+
+	seen, moved = applyUpdate(&d, seenUpdate{idle: now.Add(time.Minute * 2)})
+	assert.False(t, seen)
+	assert.True(t, moved)
+	assert.Equal(t, now.Add(time.Minute*2), d.IdleExpiresAt)
+	assert.Equal(t, now.Add(time.Minute*2), d.ExpiresAt)
+}
+
+func Test_mergeUpdates(t *testing.T) {
+	now := time.Now()
+
+	assert.Equal(t, seenUpdate{seen: now, idle: now.Add(time.Hour)}, mergeUpdates(
+		seenUpdate{seen: now, idle: now.Add(time.Minute)},
+		seenUpdate{seen: now.Add(-time.Second), idle: now.Add(time.Hour)},
+	))
+	assert.Equal(t, seenUpdate{idle: now}, mergeUpdates(seenUpdate{}, seenUpdate{idle: now}))
+}
+
+func Test_withTenant(t *testing.T) {
+	conn := redigomock.NewConn()
+
+	assert.Equal(t, redis.Conn(conn), withTenant(conn, ""))
+	assert.Equal(t, redis.Conn(conn), withTenant(&scopedConn{Conn: conn, tenant: "t1"}, ""))
+	assert.Equal(t, &scopedConn{Conn: conn, tenant: "t2"}, withTenant(&scopedConn{Conn: conn, tenant: "t1"}, "t2"))
+}
+
+func Test_RedisStore_FlushUpdates(t *testing.T) {
+	r := New(&redis.Pool{}, prefix)
+	assert.NoError(t, r.FlushUpdates(context.Background()))
+}
+
+func Test_RedisStore_FetchByID_writeBehind(t *testing.T) {
+	sKey := prefix + ":session:id123"
+	uKey := prefix + ":user:u123"
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithLastSeen(true, 0), WithWriteBehind(time.Hour))
+
+	conn.Command("HGETALL", sKey).ExpectMap(map[string]string{
+		"created_at": time.Now().Format(time.RFC3339Nano),
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+		"id":         "id123",
+		"user_key":   "u123",
+	})
+
+	s, ok, err := r.FetchByID(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "id123", s.ID)
+	assert.Len(t, r.writeBehind.pending, 1)
+	assert.True(t, r.writeBehind.started)
+
+	conn.Command("WATCH", sKey).Expect("OK")
+	conn.GenericCommand("MULTI").Expect("OK")
+	hmset := conn.GenericCommand("HMSET").Expect("QUEUED")
+	pexpireat := conn.GenericCommand("PEXPIREAT").Expect("QUEUED")
+	conn.GenericCommand("EXEC").ExpectSlice(int64(1), int64(1))
+
+	require.NoError(t, r.FlushUpdates(context.Background()))
+	assert.Empty(t, r.writeBehind.pending)
+	assert.Equal(t, 1, conn.Stats(hmset))
+
+	// sessions whose expiration times are moved are updated in their
+	// user session sets as well.
+	r.idleTimeout = time.Hour * 2
+
+	_, ok, err = r.FetchByID(context.Background(), "id123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	conn.Command("WATCH", uKey).Expect("OK")
+	conn.Command("PTTL", uKey).Expect(int64(time.Hour / time.Millisecond))
+	zadd := conn.GenericCommand("ZADD").Expect("QUEUED")
+	conn.GenericCommand("EXEC").ExpectSlice(int64(1), int64(1), int64(0), int64(1), int64(1))
+
+	require.NoError(t, r.FlushUpdates(context.Background()))
+	assert.Equal(t, 2, conn.Stats(hmset))
+	assert.Equal(t, 1, conn.Stats(zadd))
+	assert.Equal(t, 5, conn.Stats(pexpireat))
+
+	// remaining updates are written when the store is closed.
+	_, _, err = r.FetchByID(context.Background(), "id123")
+	require.NoError(t, err)
+
+	require.NoError(t, r.Close(context.Background()))
+	assert.Empty(t, r.writeBehind.pending)
+	assert.Equal(t, 3, conn.Stats(hmset))
+}
+
+func Test_RedisStore_FlushUpdates_failed(t *testing.T) {
+	now := time.Now()
+	key := cacheKey{id: "id123"}
+
+	conn := redigomock.NewConn()
+	r := New(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}, prefix, WithWriteBehind(time.Hour))
+
+	r.writeBehind.pending[key] = seenUpdate{seen: now, idle: now.Add(time.Minute)}
+
+	conn.Command("WATCH", prefix+":session:id123").ExpectError(assert.AnError)
+	assert.Error(t, r.FlushUpdates(context.Background()))
+	assert.Equal(t, map[cacheKey]seenUpdate{
+		key: {seen: now, idle: now.Add(time.Minute)},
+	}, r.writeBehind.pending)
+
+	// newer times of updates buffered in the meantime are kept.
+	r.writeBehind.pending = map[cacheKey]seenUpdate{key: {seen: now, idle: now.Add(time.Minute)}}
+	r.requeueUpdates("", map[string]seenUpdate{
+		"id123": {seen: now.Add(time.Second), idle: now.Add(-time.Minute)},
+	})
+	assert.Equal(t, map[cacheKey]seenUpdate{
+		key: {seen: now.Add(time.Second), idle: now.Add(time.Minute)},
+	}, r.writeBehind.pending)
+}